@@ -1,16 +1,93 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/mail"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/msgid"
+	"github.com/rgabriel/mcp-icloud-email/internal/tlsconf"
+	"github.com/rgabriel/mcp-icloud-email/smtp"
 )
 
+// DefaultProtectedFolders are the folders that delete_folder, purge_deleted,
+// and remove_duplicates refuse to touch when PROTECTED_FOLDERS is unset.
+var DefaultProtectedFolders = []string{"INBOX", "Sent Messages", "Drafts", "Deleted Messages"}
+
+// Account is one iCloud account the server serves, with the settings that
+// belong to its address
+type Account struct {
+	ID         string `json:"id"` // value of the tools' account parameter
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	OAuthToken string `json:"oauth_token"`
+
+	// Domain of generated Message-IDs; defaults to the domain of Email
+	MessageIDDomain string `json:"message_id_domain"`
+
+	// Aliases that send_email may use as From besides Email
+	AllowedFrom []string `json:"allowed_from"`
+
+	// Addresses blind-copied on every message the account sends
+	AutoBCC []string `json:"auto_bcc"`
+}
+
 // Config holds the application configuration
 type Config struct {
-	ICloudEmail    string
-	ICloudPassword string
+	// Accounts from ICLOUD_ACCOUNTS, or the single account given by
+	// ICLOUD_EMAIL. The first is used when a tool call names none.
+	Accounts []Account
+
+	IMAPPoolSize     int
+	ProtectedFolders []string
+	DefaultFolder    string
+	SendRate         int // messages per minute; 0 means unlimited
+	MaxFolderDepth   int // levels create_folder may nest; 0 means unlimited
+
+	// Glob patterns choosing the folders list_folders shows; a leading !
+	// hides matches instead. Hidden folders stay usable by name.
+	FolderFilter []string
+
+	// Attachment types get_attachment may return: MIME types (image/*
+	// allowed) or extensions like .exe
+	AllowedAttachmentTypes []string
+	BlockedAttachmentTypes []string
+	InlineAttachmentMax    int64 // bytes; get_email inlines attachments up to this size on request
+
+	// TLS hardening for the IMAP and SMTP connections
+	TLSMinVersion uint16
+	TLSPins       [][]byte // SHA-256 certificate fingerprints
+
+	// Reply composition
+	ReplyPrefix         string
+	AttributionTemplate string
+	ReplyAllDefault     bool // reply_email replies to all when reply_all is omitted
+
+	IMAPDebug bool // log the IMAP wire protocol at debug level
+	ReadOnly  bool // register only the tools annotated read-only
+
+	ExplainEmptyResults bool // search_emails explains empty results when explain_empty is omitted
+	AutoPlaintext       bool // fill the plain body of HTML-only emails from their HTML
+
+	BodyCharset  string // charset of outgoing text parts
+	SMTPHeloHost string // hostname sent in EHLO; net/smtp's default when empty
+
+	FetchBatchSize int // messages a search fetches per UID FETCH
+
+	// Retries of IMAP searches and fetches that fail transiently
+	IMAPRetries      int
+	IMAPRetryBackoff time.Duration
+
+	// Retries of the connection test at startup, before the server gives up
+	StartupRetries      int
+	StartupRetryBackoff time.Duration
 }
 
 // Load reads configuration from environment variables and .env file
@@ -18,20 +95,409 @@ func Load() (*Config, error) {
 	// Try to load .env file (ignore error if file doesn't exist)
 	_ = godotenv.Load()
 
-	email := os.Getenv("ICLOUD_EMAIL")
-	password := os.Getenv("ICLOUD_PASSWORD")
+	// One account from ICLOUD_EMAIL, or several from ICLOUD_ACCOUNTS
+	accounts, err := accountsEnv()
+	if err != nil {
+		return nil, err
+	}
 
-	// Validate required fields
-	if email == "" {
-		return nil, fmt.Errorf("ICLOUD_EMAIL environment variable is required")
+	// Concurrent IMAP connections; iCloud limits connections per account
+	poolSize := 1
+	if v := os.Getenv("IMAP_POOL_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("IMAP_POOL_SIZE must be a positive integer, got %q", v)
+		}
+		poolSize = n
+	}
+
+	// Outgoing messages per minute, to stay under iCloud's sending limits
+	sendRate := 0
+	if v := os.Getenv("SEND_RATE_PER_MINUTE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("SEND_RATE_PER_MINUTE must be a non-negative integer, got %q", v)
+		}
+		sendRate = n
+	}
+
+	// Deepest folder hierarchy create_folder may build
+	maxDepth := 0
+	if v := os.Getenv("MAX_FOLDER_DEPTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("MAX_FOLDER_DEPTH must be a non-negative integer, got %q", v)
+		}
+		maxDepth = n
+	}
+
+	// Folders that delete_folder and other destructive tools must not touch
+	protected := DefaultProtectedFolders
+	if v := os.Getenv("PROTECTED_FOLDERS"); v != "" {
+		protected = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				protected = append(protected, name)
+			}
+		}
+	}
+
+	// Folders shown by list_folders
+	folderFilter, err := folderFilterEnv("FOLDER_FILTER")
+	if err != nil {
+		return nil, err
+	}
+
+	// Folder that tools use when no folder argument is given
+	defaultFolder := strings.TrimSpace(os.Getenv("DEFAULT_FOLDER"))
+	if defaultFolder == "" {
+		defaultFolder = "INBOX"
+	}
+
+	// Minimum TLS version and optional certificate pins
+	tlsMinVersion, err := tlsconf.ParseVersion(os.Getenv("TLS_MIN_VERSION"))
+	if err != nil {
+		return nil, fmt.Errorf("TLS_MIN_VERSION: %w", err)
+	}
+	tlsPins, err := tlsconf.ParsePins(os.Getenv("TLS_PIN"))
+	if err != nil {
+		return nil, fmt.Errorf("TLS_PIN: %w", err)
+	}
+
+	// Attachment download restrictions
+	allowedTypes, err := attachmentTypesEnv("ALLOWED_ATTACHMENT_TYPES")
+	if err != nil {
+		return nil, err
+	}
+	blockedTypes, err := attachmentTypesEnv("BLOCKED_ATTACHMENT_TYPES")
+	if err != nil {
+		return nil, err
+	}
+
+	// Largest attachment get_email returns inline with include_small_attachments
+	inlineMaxKB := 100
+	if v := os.Getenv("INLINE_ATTACHMENT_MAX_KB"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("INLINE_ATTACHMENT_MAX_KB must be a positive integer, got %q", v)
+		}
+		inlineMaxKB = n
 	}
 
-	if password == "" {
-		return nil, fmt.Errorf("ICLOUD_PASSWORD environment variable is required (use app-specific password from appleid.apple.com)")
+	// Whether reply_email replies to everyone unless told otherwise
+	replyAllDefault := false
+	if v := os.Getenv("REPLY_ALL_DEFAULT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("REPLY_ALL_DEFAULT must be true or false, got %q", v)
+		}
+		replyAllDefault = b
+	}
+
+	// Wire-level IMAP logging for troubleshooting
+	imapDebug := false
+	if v := os.Getenv("IMAP_DEBUG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("IMAP_DEBUG must be true or false, got %q", v)
+		}
+		imapDebug = b
+	}
+
+	// Read-only mode: nothing can be sent, moved, flagged, or deleted
+	readOnly := false
+	if v := os.Getenv("READ_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("READ_ONLY must be true or false, got %q", v)
+		}
+		readOnly = b
+	}
+
+	// Whether search_emails explains an empty result by default
+	explainEmpty := false
+	if v := os.Getenv("EXPLAIN_EMPTY_RESULTS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("EXPLAIN_EMPTY_RESULTS must be true or false, got %q", v)
+		}
+		explainEmpty = b
+	}
+
+	// Plain text for HTML-only emails, on unless turned off
+	autoPlaintext := true
+	if v := os.Getenv("AUTO_PLAINTEXT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("AUTO_PLAINTEXT must be true or false, got %q", v)
+		}
+		autoPlaintext = b
+	}
+
+	// Charset declared on outgoing text
+	bodyCharset := strings.ToLower(strings.TrimSpace(os.Getenv("BODY_CHARSET")))
+	if bodyCharset == "" {
+		bodyCharset = smtp.DefaultCharset
+	} else if !smtp.ValidCharset(bodyCharset) {
+		return nil, fmt.Errorf("BODY_CHARSET must be one of %s, got %q", strings.Join(smtp.Charsets, ", "), bodyCharset)
+	}
+
+	// Hostname announced to the SMTP server instead of "localhost"
+	heloHost := strings.TrimSpace(os.Getenv("SMTP_HELO_HOST"))
+	if strings.ContainsAny(heloHost, " \t\r\n") {
+		return nil, fmt.Errorf("SMTP_HELO_HOST must be a single hostname, got %q", heloHost)
+	}
+
+	// Messages fetched per UID FETCH, so large result windows stay small on the wire
+	fetchBatchSize := imap.DefaultFetchBatchSize
+	if v := os.Getenv("FETCH_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("FETCH_BATCH_SIZE must be a positive integer, got %q", v)
+		}
+		fetchBatchSize = n
+	}
+
+	// Retries for searches and fetches hit by dropped connections or busy servers
+	retries := 2
+	if v := os.Getenv("IMAP_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("IMAP_RETRIES must be a non-negative integer, got %q", v)
+		}
+		retries = n
+	}
+	retryBackoff := 500 * time.Millisecond
+	if v := os.Getenv("IMAP_RETRY_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("IMAP_RETRY_BACKOFF must be a non-negative duration such as 500ms, got %q", v)
+		}
+		retryBackoff = d
+	}
+
+	// Retries of the startup connection test, for iCloud blips during boot
+	startupRetries := 3
+	if v := os.Getenv("STARTUP_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("STARTUP_RETRIES must be a non-negative integer, got %q", v)
+		}
+		startupRetries = n
+	}
+	startupRetryBackoff := 2 * time.Second
+	if v := os.Getenv("STARTUP_RETRY_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("STARTUP_RETRY_BACKOFF must be a non-negative duration such as 2s, got %q", v)
+		}
+		startupRetryBackoff = d
 	}
 
 	return &Config{
-		ICloudEmail:    email,
-		ICloudPassword: password,
+		Accounts: accounts,
+
+		IMAPPoolSize:     poolSize,
+		ProtectedFolders: protected,
+		DefaultFolder:    defaultFolder,
+		SendRate:         sendRate,
+		MaxFolderDepth:   maxDepth,
+		FolderFilter:     folderFilter,
+
+		AllowedAttachmentTypes: allowedTypes,
+		BlockedAttachmentTypes: blockedTypes,
+		InlineAttachmentMax:    int64(inlineMaxKB) * 1024,
+
+		TLSMinVersion: tlsMinVersion,
+		TLSPins:       tlsPins,
+
+		ReplyPrefix:         os.Getenv("REPLY_PREFIX"),
+		AttributionTemplate: os.Getenv("REPLY_ATTRIBUTION"),
+		ReplyAllDefault:     replyAllDefault,
+
+		IMAPDebug: imapDebug,
+		ReadOnly:  readOnly,
+
+		ExplainEmptyResults: explainEmpty,
+		AutoPlaintext:       autoPlaintext,
+
+		BodyCharset:  bodyCharset,
+		SMTPHeloHost: heloHost,
+
+		FetchBatchSize: fetchBatchSize,
+
+		IMAPRetries:      retries,
+		IMAPRetryBackoff: retryBackoff,
+
+		StartupRetries:      startupRetries,
+		StartupRetryBackoff: startupRetryBackoff,
 	}, nil
 }
+
+// DefaultAccountID is the id of the account given by ICLOUD_EMAIL
+const DefaultAccountID = "default"
+
+// accountsEnv reads the accounts to serve. ICLOUD_ACCOUNTS holds a JSON
+// array of accounts, each with an id, email, and password or oauth_token,
+// and optionally message_id_domain, allowed_from, and auto_bcc. Without it,
+// ICLOUD_EMAIL, ICLOUD_PASSWORD, and ICLOUD_OAUTH_TOKEN describe a single
+// account with id "default", whose other settings come from
+// MESSAGE_ID_DOMAIN, ALLOWED_FROM, and AUTO_BCC.
+func accountsEnv() ([]Account, error) {
+	v := strings.TrimSpace(os.Getenv("ICLOUD_ACCOUNTS"))
+	if v == "" {
+		return singleAccountEnv()
+	}
+
+	// Account settings come from one place, so a leftover variable cannot
+	// apply to some accounts or all of them by surprise
+	for _, name := range []string{"ICLOUD_EMAIL", "ICLOUD_PASSWORD", "ICLOUD_OAUTH_TOKEN", "MESSAGE_ID_DOMAIN", "ALLOWED_FROM", "AUTO_BCC"} {
+		if os.Getenv(name) != "" {
+			return nil, fmt.Errorf("ICLOUD_ACCOUNTS cannot be combined with %s; set it per account instead", name)
+		}
+	}
+
+	var accounts []Account
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&accounts); err != nil {
+		return nil, fmt.Errorf("ICLOUD_ACCOUNTS must be a JSON array of accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("ICLOUD_ACCOUNTS must list at least one account")
+	}
+
+	seen := make(map[string]bool, len(accounts))
+	for i := range accounts {
+		a := &accounts[i]
+		if a.ID == "" || strings.ContainsAny(a.ID, " \t\r\n") {
+			return nil, fmt.Errorf("ICLOUD_ACCOUNTS entry %d needs an id without spaces, got %q", i+1, a.ID)
+		}
+		if seen[a.ID] {
+			return nil, fmt.Errorf("ICLOUD_ACCOUNTS has more than one account with id %q", a.ID)
+		}
+		seen[a.ID] = true
+		if _, err := mail.ParseAddress(a.Email); err != nil {
+			return nil, fmt.Errorf("ICLOUD_ACCOUNTS account %q has invalid email %q: %w", a.ID, a.Email, err)
+		}
+		if a.Password == "" && a.OAuthToken == "" {
+			return nil, fmt.Errorf("ICLOUD_ACCOUNTS account %q needs a password (app-specific password from appleid.apple.com) or oauth_token", a.ID)
+		}
+
+		var err error
+		if a.AllowedFrom, err = parseAddresses(fmt.Sprintf("ICLOUD_ACCOUNTS account %q allowed_from", a.ID), a.AllowedFrom); err != nil {
+			return nil, err
+		}
+		if a.AutoBCC, err = parseAddresses(fmt.Sprintf("ICLOUD_ACCOUNTS account %q auto_bcc", a.ID), a.AutoBCC); err != nil {
+			return nil, err
+		}
+		a.MessageIDDomain = strings.TrimSpace(a.MessageIDDomain)
+		if a.MessageIDDomain == "" {
+			a.MessageIDDomain = msgid.DomainOf(a.Email)
+		}
+	}
+	return accounts, nil
+}
+
+// singleAccountEnv reads the account given by ICLOUD_EMAIL
+func singleAccountEnv() ([]Account, error) {
+	a := Account{
+		ID:         DefaultAccountID,
+		Email:      os.Getenv("ICLOUD_EMAIL"),
+		Password:   os.Getenv("ICLOUD_PASSWORD"),
+		OAuthToken: os.Getenv("ICLOUD_OAUTH_TOKEN"),
+	}
+
+	// Validate required fields
+	if a.Email == "" {
+		return nil, fmt.Errorf("ICLOUD_EMAIL environment variable is required")
+	}
+
+	// An OAuth2 access token replaces the password
+	if a.Password == "" && a.OAuthToken == "" {
+		return nil, fmt.Errorf("ICLOUD_PASSWORD environment variable is required (use app-specific password from appleid.apple.com) unless ICLOUD_OAUTH_TOKEN is set")
+	}
+
+	// Message-IDs default to the account's own domain
+	a.MessageIDDomain = os.Getenv("MESSAGE_ID_DOMAIN")
+	if a.MessageIDDomain == "" {
+		a.MessageIDDomain = msgid.DomainOf(a.Email)
+	}
+
+	// Every sent message can be blind-copied to fixed addresses
+	var err error
+	if a.AutoBCC, err = addressListEnv("AUTO_BCC"); err != nil {
+		return nil, err
+	}
+
+	// Aliases that send_email may use as From besides the account address
+	if a.AllowedFrom, err = addressListEnv("ALLOWED_FROM"); err != nil {
+		return nil, err
+	}
+
+	return []Account{a}, nil
+}
+
+// addressListEnv parses a comma-separated list of addresses from the named
+// environment variable, rejecting any entry that is not a valid address.
+func addressListEnv(name string) ([]string, error) {
+	return parseAddresses(name, strings.Split(os.Getenv(name), ","))
+}
+
+// parseAddresses returns the bare addresses of entries, skipping blank ones
+// and rejecting any that does not parse. source names where the entries
+// came from in errors.
+func parseAddresses(source string, entries []string) ([]string, error) {
+	var addrs []string
+	for _, addr := range entries {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s contains invalid address %q: %w", source, addr, err)
+		}
+		addrs = append(addrs, parsed.Address)
+	}
+	return addrs, nil
+}
+
+// folderFilterEnv parses a comma-separated list of path.Match glob patterns
+// from the named environment variable, each optionally prefixed with ! to
+// exclude rather than include, rejecting patterns that do not compile.
+func folderFilterEnv(name string) ([]string, error) {
+	var patterns []string
+	for _, entry := range strings.Split(os.Getenv(name), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || entry == "!" {
+			continue
+		}
+		if _, err := path.Match(strings.TrimPrefix(entry, "!"), ""); err != nil {
+			return nil, fmt.Errorf("%s contains invalid pattern %q: %w", name, entry, err)
+		}
+		patterns = append(patterns, entry)
+	}
+	return patterns, nil
+}
+
+// attachmentTypesEnv parses a comma-separated list of MIME types and file
+// extensions from the named environment variable. Entries are lowercased;
+// extensions get a leading dot if it is missing ("exe" becomes ".exe").
+func attachmentTypesEnv(name string) ([]string, error) {
+	var types []string
+	for _, entry := range strings.Split(os.Getenv(name), ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.ContainsAny(entry, " \t;") || strings.Count(entry, "/") > 1 {
+			return nil, fmt.Errorf("%s contains invalid entry %q: use a MIME type like application/pdf or an extension like .exe", name, entry)
+		}
+		if !strings.Contains(entry, "/") && !strings.HasPrefix(entry, ".") {
+			entry = "." + entry
+		}
+		types = append(types, entry)
+	}
+	return types, nil
+}
@@ -0,0 +1,707 @@
+package config
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		token    string
+		wantErr  bool
+	}{
+		{name: "password only", password: "app-pass"},
+		{name: "oauth token only", token: "tok123"},
+		{name: "neither", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", tt.password)
+			t.Setenv("ICLOUD_OAUTH_TOKEN", tt.token)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Accounts[0].OAuthToken != tt.token {
+				t.Errorf("OAuthToken = %q, want %q", cfg.Accounts[0].OAuthToken, tt.token)
+			}
+		})
+	}
+}
+
+func TestLoadAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		accounts string
+		wantIDs  string
+		wantErr  bool
+	}{
+		{name: "single account", email: "me@icloud.com", wantIDs: "default"},
+		{
+			name:     "list",
+			accounts: `[{"id":"personal","email":"me@icloud.com","password":"p1"},{"id":"work","email":"work@me.com","oauth_token":"tok"}]`,
+			wantIDs:  "personal,work",
+		},
+		{name: "combined with ICLOUD_EMAIL", email: "me@icloud.com", accounts: `[{"id":"a","email":"me@icloud.com","password":"p"}]`, wantErr: true},
+		{name: "not json", accounts: "me@icloud.com", wantErr: true},
+		{name: "empty list", accounts: "[]", wantErr: true},
+		{name: "unknown field", accounts: `[{"id":"a","email":"me@icloud.com","pasword":"p"}]`, wantErr: true},
+		{name: "missing id", accounts: `[{"email":"me@icloud.com","password":"p"}]`, wantErr: true},
+		{name: "duplicate id", accounts: `[{"id":"a","email":"me@icloud.com","password":"p"},{"id":"a","email":"work@me.com","password":"p"}]`, wantErr: true},
+		{name: "invalid email", accounts: `[{"id":"a","email":"me","password":"p"}]`, wantErr: true},
+		{name: "no credentials", accounts: `[{"id":"a","email":"me@icloud.com"}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", tt.email)
+			t.Setenv("ICLOUD_PASSWORD", "")
+			t.Setenv("ICLOUD_OAUTH_TOKEN", "")
+			if tt.email != "" {
+				t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			}
+			t.Setenv("ICLOUD_ACCOUNTS", tt.accounts)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []string
+			for _, a := range cfg.Accounts {
+				ids = append(ids, a.ID)
+			}
+			if strings.Join(ids, ",") != tt.wantIDs {
+				t.Errorf("account ids = %v, want %s", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestLoadAccountSettings(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "")
+	t.Setenv("ICLOUD_PASSWORD", "")
+	t.Setenv("ICLOUD_OAUTH_TOKEN", "")
+	t.Setenv("MESSAGE_ID_DOMAIN", "")
+	t.Setenv("ALLOWED_FROM", "")
+	t.Setenv("AUTO_BCC", "")
+	t.Setenv("ICLOUD_ACCOUNTS", `[
+		{"id":"personal","email":"me@icloud.com","password":"p","allowed_from":["Me <me@example.com>"]},
+		{"id":"work","email":"work@icloud.com","password":"p","message_id_domain":"corp.example","auto_bcc":["archive@corp.example"]}
+	]`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	personal, work := cfg.Accounts[0], cfg.Accounts[1]
+	if personal.MessageIDDomain != "icloud.com" || work.MessageIDDomain != "corp.example" {
+		t.Errorf("MessageIDDomain = %q, %q, want icloud.com, corp.example", personal.MessageIDDomain, work.MessageIDDomain)
+	}
+	if strings.Join(personal.AllowedFrom, ",") != "me@example.com" || len(work.AllowedFrom) != 0 {
+		t.Errorf("AllowedFrom = %v, %v, want each account's own aliases", personal.AllowedFrom, work.AllowedFrom)
+	}
+	if len(personal.AutoBCC) != 0 || strings.Join(work.AutoBCC, ",") != "archive@corp.example" {
+		t.Errorf("AutoBCC = %v, %v, want each account's own addresses", personal.AutoBCC, work.AutoBCC)
+	}
+
+	// The single-account variables cannot leak into a list of accounts
+	for _, name := range []string{"MESSAGE_ID_DOMAIN", "ALLOWED_FROM", "AUTO_BCC"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, "x@example.com")
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("err = %v, want one naming %s", err, name)
+			}
+		})
+	}
+
+	t.Setenv("ICLOUD_ACCOUNTS", `[{"id":"a","email":"me@icloud.com","password":"p","auto_bcc":["not-an-address"]}]`)
+	if _, err := Load(); err == nil {
+		t.Error("expected error for an invalid auto_bcc address")
+	}
+}
+
+func TestLoadMessageIDDomain(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("MESSAGE_ID_DOMAIN", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Accounts[0].MessageIDDomain != "icloud.com" {
+		t.Errorf("default MessageIDDomain = %q, want icloud.com", cfg.Accounts[0].MessageIDDomain)
+	}
+
+	t.Setenv("MESSAGE_ID_DOMAIN", "example.org")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Accounts[0].MessageIDDomain != "example.org" {
+		t.Errorf("MessageIDDomain = %q, want example.org", cfg.Accounts[0].MessageIDDomain)
+	}
+}
+
+func TestLoadAutoBCC(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "single", value: "archive@example.com", want: []string{"archive@example.com"}},
+		{name: "list with spaces", value: " a@example.com, ,Backup <b@example.com>", want: []string{"a@example.com", "b@example.com"}},
+		{name: "invalid", value: "a@example.com,not-an-address", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("AUTO_BCC", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(cfg.Accounts[0].AutoBCC, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("AutoBCC = %v, want %v", cfg.Accounts[0].AutoBCC, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIMAPPoolSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset", want: 1},
+		{name: "explicit", value: "4", want: 4},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "not a number", value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("IMAP_POOL_SIZE", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.IMAPPoolSize != tt.want {
+				t.Errorf("IMAPPoolSize = %d, want %d", cfg.IMAPPoolSize, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFetchBatchSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset", want: 50},
+		{name: "explicit", value: "25", want: 25},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "not a number", value: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("FETCH_BATCH_SIZE", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.FetchBatchSize != tt.want {
+				t.Errorf("FetchBatchSize = %d, want %d", cfg.FetchBatchSize, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProtectedFolders(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "unset uses defaults", want: DefaultProtectedFolders},
+		{name: "custom list", value: "INBOX, Taxes ,,Receipts", want: []string{"INBOX", "Taxes", "Receipts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("PROTECTED_FOLDERS", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(cfg.ProtectedFolders, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("ProtectedFolders = %v, want %v", cfg.ProtectedFolders, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFolderFilter(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+	t.Setenv("FOLDER_FILTER", "INBOX, Archive/* ,,!Notes")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(cfg.FolderFilter, "|"); got != "INBOX|Archive/*|!Notes" {
+		t.Errorf("FolderFilter = %s", got)
+	}
+
+	t.Setenv("FOLDER_FILTER", "Archive/[")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "FOLDER_FILTER") {
+		t.Errorf("err = %v, want an invalid pattern error", err)
+	}
+}
+
+func TestLoadDefaultFolder(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "unset", want: "INBOX"},
+		{name: "explicit", value: "All Mail", want: "All Mail"},
+		{name: "blank", value: "  ", want: "INBOX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("DEFAULT_FOLDER", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.DefaultFolder != tt.want {
+				t.Errorf("DefaultFolder = %q, want %q", cfg.DefaultFolder, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	pin := strings.Repeat("ab", 32)
+	tests := []struct {
+		name     string
+		version  string
+		pin      string
+		want     uint16
+		wantPins int
+		wantErr  string
+	}{
+		{name: "defaults", want: tls.VersionTLS12},
+		{name: "tls 1.3 with pins", version: "1.3", pin: pin + "," + pin, want: tls.VersionTLS13, wantPins: 2},
+		{name: "old version", version: "1.0", wantErr: "TLS_MIN_VERSION"},
+		{name: "bad pin", pin: "not-hex", wantErr: "TLS_PIN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("TLS_MIN_VERSION", tt.version)
+			t.Setenv("TLS_PIN", tt.pin)
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want mentioning %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.TLSMinVersion != tt.want || len(cfg.TLSPins) != tt.wantPins {
+				t.Errorf("TLSMinVersion = %x, pins = %d; want %x, %d", cfg.TLSMinVersion, len(cfg.TLSPins), tt.want, tt.wantPins)
+			}
+		})
+	}
+}
+
+func TestLoadReplyAllDefault(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("REPLY_ALL_DEFAULT", "")
+	if cfg, err := Load(); err != nil || cfg.ReplyAllDefault {
+		t.Errorf("unset: ReplyAllDefault = %v, %v; want false", cfg != nil && cfg.ReplyAllDefault, err)
+	}
+
+	t.Setenv("REPLY_ALL_DEFAULT", "true")
+	if cfg, err := Load(); err != nil || !cfg.ReplyAllDefault {
+		t.Errorf("true: ReplyAllDefault = %v, %v; want true", cfg != nil && cfg.ReplyAllDefault, err)
+	}
+
+	t.Setenv("REPLY_ALL_DEFAULT", "sometimes")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "REPLY_ALL_DEFAULT") {
+		t.Errorf("invalid value: error = %v", err)
+	}
+}
+
+func TestLoadSendRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset", want: 0},
+		{name: "explicit", value: "20", want: 20},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "not a number", value: "fast", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("SEND_RATE_PER_MINUTE", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "SEND_RATE_PER_MINUTE") {
+					t.Fatalf("error = %v, want SEND_RATE_PER_MINUTE error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.SendRate != tt.want {
+				t.Errorf("SendRate = %d, want %d", cfg.SendRate, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAttachmentTypes(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+	t.Setenv("ALLOWED_ATTACHMENT_TYPES", " application/PDF, image/* ,")
+	t.Setenv("BLOCKED_ATTACHMENT_TYPES", "exe,.JS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(cfg.AllowedAttachmentTypes, ","); got != "application/pdf,image/*" {
+		t.Errorf("AllowedAttachmentTypes = %q", got)
+	}
+	if got := strings.Join(cfg.BlockedAttachmentTypes, ","); got != ".exe,.js" {
+		t.Errorf("BlockedAttachmentTypes = %q", got)
+	}
+
+	t.Setenv("BLOCKED_ATTACHMENT_TYPES", "application/x/y")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "BLOCKED_ATTACHMENT_TYPES") {
+		t.Errorf("error = %v, want BLOCKED_ATTACHMENT_TYPES error", err)
+	}
+}
+
+func TestLoadMaxFolderDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset", want: 0},
+		{name: "explicit", value: "4", want: 4},
+		{name: "negative", value: "-2", wantErr: true},
+		{name: "not a number", value: "deep", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+			t.Setenv("ICLOUD_PASSWORD", "app-pass")
+			t.Setenv("MAX_FOLDER_DEPTH", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "MAX_FOLDER_DEPTH") {
+					t.Fatalf("error = %v, want MAX_FOLDER_DEPTH error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.MaxFolderDepth != tt.want {
+				t.Errorf("MaxFolderDepth = %d, want %d", cfg.MaxFolderDepth, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadReadOnly(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("READ_ONLY", "")
+	if cfg, err := Load(); err != nil || cfg.ReadOnly {
+		t.Errorf("unset: ReadOnly = %v, %v; want false", cfg != nil && cfg.ReadOnly, err)
+	}
+
+	t.Setenv("READ_ONLY", "true")
+	if cfg, err := Load(); err != nil || !cfg.ReadOnly {
+		t.Errorf("true: ReadOnly = %v, %v; want true", cfg != nil && cfg.ReadOnly, err)
+	}
+
+	t.Setenv("READ_ONLY", "maybe")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "READ_ONLY") {
+		t.Errorf("invalid value: error = %v", err)
+	}
+}
+
+func TestLoadExplainEmptyResults(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("EXPLAIN_EMPTY_RESULTS", "")
+	if cfg, err := Load(); err != nil || cfg.ExplainEmptyResults {
+		t.Errorf("unset: ExplainEmptyResults = %v, %v; want false", cfg != nil && cfg.ExplainEmptyResults, err)
+	}
+
+	t.Setenv("EXPLAIN_EMPTY_RESULTS", "1")
+	if cfg, err := Load(); err != nil || !cfg.ExplainEmptyResults {
+		t.Errorf("1: ExplainEmptyResults = %v, %v; want true", cfg != nil && cfg.ExplainEmptyResults, err)
+	}
+
+	t.Setenv("EXPLAIN_EMPTY_RESULTS", "sometimes")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "EXPLAIN_EMPTY_RESULTS") {
+		t.Errorf("invalid value: error = %v", err)
+	}
+}
+
+func TestLoadAutoPlaintext(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("AUTO_PLAINTEXT", "")
+	if cfg, err := Load(); err != nil || !cfg.AutoPlaintext {
+		t.Errorf("unset: AutoPlaintext = %v, %v; want true", cfg != nil && cfg.AutoPlaintext, err)
+	}
+
+	t.Setenv("AUTO_PLAINTEXT", "false")
+	if cfg, err := Load(); err != nil || cfg.AutoPlaintext {
+		t.Errorf("false: AutoPlaintext = %v, %v; want false", cfg != nil && cfg.AutoPlaintext, err)
+	}
+
+	t.Setenv("AUTO_PLAINTEXT", "nope")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "AUTO_PLAINTEXT") {
+		t.Errorf("invalid value: error = %v", err)
+	}
+}
+
+func TestLoadIMAPDebug(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("IMAP_DEBUG", "")
+	if cfg, err := Load(); err != nil || cfg.IMAPDebug {
+		t.Errorf("unset: IMAPDebug = %v, %v; want false", cfg != nil && cfg.IMAPDebug, err)
+	}
+
+	t.Setenv("IMAP_DEBUG", "true")
+	if cfg, err := Load(); err != nil || !cfg.IMAPDebug {
+		t.Errorf("true: IMAPDebug = %v, %v; want true", cfg != nil && cfg.IMAPDebug, err)
+	}
+
+	t.Setenv("IMAP_DEBUG", "verbose")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "IMAP_DEBUG") {
+		t.Errorf("invalid value: error = %v", err)
+	}
+}
+
+func TestLoadIMAPRetries(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("IMAP_RETRIES", "")
+	t.Setenv("IMAP_RETRY_BACKOFF", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.IMAPRetries != 2 || cfg.IMAPRetryBackoff != 500*time.Millisecond {
+		t.Errorf("defaults = %d, %v; want 2, 500ms", cfg.IMAPRetries, cfg.IMAPRetryBackoff)
+	}
+
+	t.Setenv("IMAP_RETRIES", "0")
+	t.Setenv("IMAP_RETRY_BACKOFF", "2s")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.IMAPRetries != 0 || cfg.IMAPRetryBackoff != 2*time.Second {
+		t.Errorf("set = %d, %v; want 0, 2s", cfg.IMAPRetries, cfg.IMAPRetryBackoff)
+	}
+
+	for name, value := range map[string]string{"IMAP_RETRIES": "-1", "IMAP_RETRY_BACKOFF": "soon"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("error = %v, want one naming %s", err, name)
+			}
+		})
+	}
+}
+
+func TestLoadInlineAttachmentMax(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("INLINE_ATTACHMENT_MAX_KB", "")
+	if cfg, err := Load(); err != nil || cfg.InlineAttachmentMax != 100*1024 {
+		t.Errorf("unset: InlineAttachmentMax = %v, %v; want 102400", cfg != nil && cfg.InlineAttachmentMax == 100*1024, err)
+	}
+
+	t.Setenv("INLINE_ATTACHMENT_MAX_KB", "250")
+	if cfg, err := Load(); err != nil || cfg.InlineAttachmentMax != 250*1024 {
+		t.Errorf("250: got %v, %v", cfg != nil && cfg.InlineAttachmentMax == 250*1024, err)
+	}
+
+	t.Setenv("INLINE_ATTACHMENT_MAX_KB", "0")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "INLINE_ATTACHMENT_MAX_KB") {
+		t.Errorf("zero: error = %v", err)
+	}
+}
+
+func TestLoadBodyCharset(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("BODY_CHARSET", "")
+	if cfg, err := Load(); err != nil || cfg.BodyCharset != "utf-8" {
+		t.Errorf("unset: got %v, %v; want utf-8", cfg, err)
+	}
+
+	t.Setenv("BODY_CHARSET", "US-ASCII")
+	if cfg, err := Load(); err != nil || cfg.BodyCharset != "us-ascii" {
+		t.Errorf("US-ASCII: got %v, %v; want us-ascii", cfg, err)
+	}
+
+	t.Setenv("BODY_CHARSET", "iso-8859-1")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "BODY_CHARSET") {
+		t.Errorf("unsupported: error = %v", err)
+	}
+}
+
+func TestLoadSMTPHeloHost(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("SMTP_HELO_HOST", "")
+	if cfg, err := Load(); err != nil || cfg.SMTPHeloHost != "" {
+		t.Errorf("unset: got %v, %v; want empty", cfg, err)
+	}
+
+	t.Setenv("SMTP_HELO_HOST", " mail.example.com ")
+	if cfg, err := Load(); err != nil || cfg.SMTPHeloHost != "mail.example.com" {
+		t.Errorf("set: got %v, %v; want mail.example.com", cfg, err)
+	}
+
+	t.Setenv("SMTP_HELO_HOST", "mail example.com")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "SMTP_HELO_HOST") {
+		t.Errorf("with space: error = %v", err)
+	}
+}
+
+func TestLoadStartupRetries(t *testing.T) {
+	t.Setenv("ICLOUD_EMAIL", "me@icloud.com")
+	t.Setenv("ICLOUD_PASSWORD", "app-pass")
+
+	t.Setenv("STARTUP_RETRIES", "")
+	t.Setenv("STARTUP_RETRY_BACKOFF", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.StartupRetries != 3 || cfg.StartupRetryBackoff != 2*time.Second {
+		t.Errorf("defaults = %d, %v; want 3, 2s", cfg.StartupRetries, cfg.StartupRetryBackoff)
+	}
+
+	t.Setenv("STARTUP_RETRIES", "0")
+	t.Setenv("STARTUP_RETRY_BACKOFF", "250ms")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.StartupRetries != 0 || cfg.StartupRetryBackoff != 250*time.Millisecond {
+		t.Errorf("set = %d, %v; want 0, 250ms", cfg.StartupRetries, cfg.StartupRetryBackoff)
+	}
+
+	for name, value := range map[string]string{"STARTUP_RETRIES": "many", "STARTUP_RETRY_BACKOFF": "-1s"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("error = %v, want one naming %s", err, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// LargeAttachment describes an email and the largest attachment it carries
+type LargeAttachment struct {
+	EmailID        string    `json:"email_id"`
+	Folder         string    `json:"folder"`
+	From           string    `json:"from"`
+	Subject        string    `json:"subject"`
+	Date           time.Time `json:"date,omitzero"`
+	Size           uint32    `json:"size"` // whole message, RFC822.SIZE
+	Filename       string    `json:"filename"`
+	AttachmentSize int64     `json:"attachment_size"` // decoded, approximate for base64
+}
+
+// FindLargeAttachments searches each folder for messages larger than minSize
+// bytes, reads their BODYSTRUCTURE, and returns those with at least one
+// attachment, largest attachment first, truncated to limit entries when
+// limit > 0. Only message metadata is fetched, never bodies.
+func (c *Client) FindLargeAttachments(ctx context.Context, folders []string, minSize uint32, limit int) ([]LargeAttachment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []LargeAttachment{}
+	for _, name := range folders {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		folder, err := c.resolveFolder(name)
+		if err != nil {
+			return nil, err
+		}
+		found, err := c.findLargeAttachments(ctx, folder, minSize)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].AttachmentSize > results[j].AttachmentSize
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// findLargeAttachments handles one folder (caller must hold c.mu)
+func (c *Client) findLargeAttachments(ctx context.Context, folder string, minSize uint32) ([]LargeAttachment, error) {
+	if _, err := c.selectFolder(folder, true); err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Larger = minSize
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search emails: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	var found []LargeAttachment
+	err = c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchInternalDate, imap.FetchUid, imap.FetchRFC822Size, imap.FetchBodyStructure}, messages)
+	}, func(msg *imap.Message) {
+		filename, size := largestAttachment(msg.BodyStructure)
+		if filename == "" {
+			return
+		}
+		item := LargeAttachment{
+			EmailID:        fmt.Sprintf("%d", msg.Uid),
+			Folder:         folder,
+			Size:           msg.Size,
+			Filename:       filename,
+			AttachmentSize: size,
+			Date:           displayDate(msg),
+		}
+		if msg.Envelope != nil {
+			item.Subject = msg.Envelope.Subject
+			if len(msg.Envelope.From) > 0 {
+				item.From = formatAddress(msg.Envelope.From[0])
+			}
+		}
+		found = append(found, item)
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message structure: %w", err)
+	}
+	return found, nil
+}
+
+// largestAttachment returns the filename and decoded size of the biggest
+// attachment in a body structure, or "" if it has none. Parts count as
+// attachments when they have a filename or an attachment disposition.
+func largestAttachment(bs *imap.BodyStructure) (string, int64) {
+	if bs == nil {
+		return "", 0
+	}
+
+	var name string
+	var largest int64
+	bs.Walk(func(path []int, part *imap.BodyStructure) bool {
+		if strings.EqualFold(part.MIMEType, "multipart") {
+			return true
+		}
+		filename, _ := part.Filename()
+		if filename == "" && !strings.EqualFold(part.Disposition, "attachment") {
+			return true
+		}
+		if filename == "" {
+			filename = "unnamed"
+		}
+
+		// BODYSTRUCTURE reports the encoded size; base64 adds a third
+		size := int64(part.Size)
+		if strings.EqualFold(part.Encoding, "base64") {
+			size = size * 3 / 4
+		}
+		if name == "" || size > largest {
+			name, largest = filename, size
+		}
+		return true
+	})
+	return name, largest
+}
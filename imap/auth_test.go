@@ -0,0 +1,85 @@
+package imap
+
+import (
+	"testing"
+
+	"github.com/emersion/go-sasl"
+)
+
+// fakeAuthenticator records which login path was taken.
+type fakeAuthenticator struct {
+	loginUser string
+	loginPass string
+	mech      string
+	ir        []byte
+}
+
+func (f *fakeAuthenticator) Login(username, password string) error {
+	f.loginUser = username
+	f.loginPass = password
+	return nil
+}
+
+func (f *fakeAuthenticator) Authenticate(auth sasl.Client) error {
+	mech, ir, err := auth.Start()
+	f.mech = mech
+	f.ir = ir
+	return err
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		token     string
+		wantMech  string
+		wantIR    string
+		wantLogin bool
+	}{
+		{
+			name:      "password uses LOGIN",
+			password:  "app-pass",
+			wantLogin: true,
+		},
+		{
+			name:     "token uses XOAUTH2",
+			token:    "tok123",
+			wantMech: "XOAUTH2",
+			wantIR:   "user=me@icloud.com\x01auth=Bearer tok123\x01\x01",
+		},
+		{
+			name:     "token takes precedence over password",
+			password: "app-pass",
+			token:    "tok123",
+			wantMech: "XOAUTH2",
+			wantIR:   "user=me@icloud.com\x01auth=Bearer tok123\x01\x01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAuthenticator{}
+			if err := authenticate(f, "me@icloud.com", tt.password, tt.token); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantLogin {
+				if f.loginUser != "me@icloud.com" || f.loginPass != tt.password {
+					t.Errorf("Login(%q, %q), want (me@icloud.com, %q)", f.loginUser, f.loginPass, tt.password)
+				}
+				if f.mech != "" {
+					t.Errorf("unexpected SASL mechanism %q", f.mech)
+				}
+				return
+			}
+			if f.loginUser != "" {
+				t.Error("Login should not be called when a token is set")
+			}
+			if f.mech != tt.wantMech {
+				t.Errorf("mech = %q, want %q", f.mech, tt.wantMech)
+			}
+			if string(f.ir) != tt.wantIR {
+				t.Errorf("initial response = %q, want %q", f.ir, tt.wantIR)
+			}
+		})
+	}
+}
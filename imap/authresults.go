@@ -0,0 +1,97 @@
+package imap
+
+import (
+	"strings"
+)
+
+// AuthResults holds the sender authentication verdicts recorded by the
+// receiving server. Each field is the lowercased result keyword, such as
+// "pass", "fail", "softfail", "neutral", or "none"; empty means not reported.
+type AuthResults struct {
+	DKIM  string `json:"dkim,omitempty"`
+	SPF   string `json:"spf,omitempty"`
+	DMARC string `json:"dmarc,omitempty"`
+}
+
+// parseAuthResults builds AuthResults from Authentication-Results headers
+// (RFC 8601) and a Received-SPF header (RFC 7208). Headers are given top
+// first; the topmost verdict for each method wins because it was added by
+// the server closest to the mailbox. Received-SPF only fills SPF when no
+// Authentication-Results header reports it. Returns nil if nothing is found.
+func parseAuthResults(authResults []string, receivedSPF string) *AuthResults {
+	res := &AuthResults{}
+	for _, header := range authResults {
+		for method, result := range parseAuthResultsHeader(header) {
+			switch method {
+			case "dkim":
+				if res.DKIM == "" {
+					res.DKIM = result
+				}
+			case "spf":
+				if res.SPF == "" {
+					res.SPF = result
+				}
+			case "dmarc":
+				if res.DMARC == "" {
+					res.DMARC = result
+				}
+			}
+		}
+	}
+
+	if res.SPF == "" {
+		if fields := strings.Fields(stripComments(receivedSPF)); len(fields) > 0 {
+			res.SPF = strings.ToLower(fields[0])
+		}
+	}
+
+	if *res == (AuthResults{}) {
+		return nil
+	}
+	return res
+}
+
+// parseAuthResultsHeader returns the first result for each method in one
+// Authentication-Results header value, e.g.
+//
+//	mx.icloud.com; dkim=pass header.d=example.com; spf=fail smtp.mailfrom=x
+func parseAuthResultsHeader(value string) map[string]string {
+	results := make(map[string]string)
+
+	// The first segment is the authserv-id; the rest are method results
+	segments := strings.Split(stripComments(value), ";")
+	for _, segment := range segments[1:] {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			continue
+		}
+		// Drop an optional method version, as in "dkim/1=pass"
+		method, _, _ = strings.Cut(strings.ToLower(method), "/")
+		if _, seen := results[method]; !seen {
+			results[method] = strings.ToLower(result)
+		}
+	}
+
+	return results
+}
+
+// stripComments removes parenthesized RFC 5322 comments, which may nest.
+func stripComments(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package imap
+
+import (
+	"strings"
+)
+
+// isAutoReply reports whether a message was sent by an autoresponder, such
+// as a vacation or out-of-office notice. It checks Auto-Submitted (RFC 3834)
+// for "auto-replied", the non-standard X-Autoreply and X-Autorespond
+// headers, and "Precedence: auto_reply".
+func isAutoReply(autoSubmitted, xAutoreply, xAutorespond, precedence string) bool {
+	if headerKeyword(autoSubmitted) == "auto-replied" {
+		return true
+	}
+	for _, v := range []string{xAutoreply, xAutorespond} {
+		if v := headerKeyword(v); v != "" && v != "no" {
+			return true
+		}
+	}
+	return headerKeyword(precedence) == "auto_reply"
+}
+
+// isBulk reports whether the Precedence header marks a message as bulk mail
+// (newsletters, notifications) rather than a personal message.
+func isBulk(precedence string) bool {
+	switch headerKeyword(precedence) {
+	case "bulk", "junk":
+		return true
+	}
+	return false
+}
+
+// headerKeyword returns the lowercased first token of a header value,
+// dropping any parameters or comment after it
+func headerKeyword(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, ";( \t"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,169 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/msgid"
+)
+
+// iCloud's server-side rules aren't reachable over IMAP, so blocked senders
+// are kept as a note: a single message in blockListFolder whose plain text
+// body lists one address per line. Each update appends a new note and
+// expunges the old one.
+const (
+	blockListFolder  = "Blocked Senders"
+	blockListSubject = "Blocked senders"
+)
+
+// BlockResult describes the outcome of BlockSender
+type BlockResult struct {
+	Sender         string   `json:"sender"`
+	JunkFolder     string   `json:"junk_folder"`
+	AlreadyBlocked bool     `json:"already_blocked"`
+	Blocked        []string `json:"blocked_senders"`
+}
+
+// BlockSender moves a message to the Junk folder and adds its sender to the
+// blocked senders list, returning the updated list. Re-blocking a sender
+// leaves the list unchanged.
+func (c *Client) BlockSender(ctx context.Context, folder, emailID string) (*BlockResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := c.getEmail(folder, emailID)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sender %q: %w", email.From, err)
+	}
+	sender := strings.ToLower(addr.Address)
+
+	junkFolder, err := c.specialFolder(imap.JunkAttr, "Junk", "Spam")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.moveEmail(folder, junkFolder, emailID); err != nil {
+		return nil, fmt.Errorf("failed to move email to %s: %w", junkFolder, err)
+	}
+
+	blocked, noteID, err := c.readBlockList()
+	if err != nil {
+		return nil, fmt.Errorf("moved to %s but failed to read block list: %w", junkFolder, err)
+	}
+
+	result := &BlockResult{Sender: sender, JunkFolder: junkFolder, Blocked: blocked}
+	for _, b := range blocked {
+		if b == sender {
+			result.AlreadyBlocked = true
+			return result, nil
+		}
+	}
+
+	blocked = append(blocked, sender)
+	sort.Strings(blocked)
+	if err := c.writeBlockList(blocked, noteID); err != nil {
+		return nil, fmt.Errorf("moved to %s but failed to update block list: %w", junkFolder, err)
+	}
+	result.Blocked = blocked
+
+	return result, nil
+}
+
+// readBlockList returns the blocked addresses and the UID of the note holding
+// them, creating the folder on first use. noteID is empty when no note exists
+// yet. Caller must hold c.mu.
+func (c *Client) readBlockList() (blocked []string, noteID string, err error) {
+	folders, err := c.listFolders()
+	if err != nil {
+		return nil, "", err
+	}
+	exists := false
+	for _, f := range folders {
+		if f == blockListFolder {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		if err := c.client.Create(blockListFolder); err != nil {
+			return nil, "", fmt.Errorf("failed to create folder %s: %w", blockListFolder, err)
+		}
+		return []string{}, "", nil
+	}
+
+	if _, err := c.selectFolder(blockListFolder, false); err != nil {
+		return nil, "", fmt.Errorf("failed to select folder %s: %w", blockListFolder, err)
+	}
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Set("Subject", blockListSubject)
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search block list: %w", err)
+	}
+	if len(uids) == 0 {
+		return []string{}, "", nil
+	}
+
+	// The newest note wins
+	latest := uids[0]
+	for _, uid := range uids[1:] {
+		if uid > latest {
+			latest = uid
+		}
+	}
+	noteID = fmt.Sprintf("%d", latest)
+
+	note, err := c.getEmail(blockListFolder, noteID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read block list: %w", err)
+	}
+
+	blocked = []string{}
+	for _, line := range strings.Split(note.BodyPlain, "\n") {
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			blocked = append(blocked, line)
+		}
+	}
+
+	return blocked, noteID, nil
+}
+
+// writeBlockList stores blocked as a new note and removes the previous note
+// (if any). Caller must hold c.mu.
+func (c *Client) writeBlockList(blocked []string, oldNoteID string) error {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", c.username))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", blockListSubject))
+	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	buf.WriteString(fmt.Sprintf("Message-ID: %s\r\n", msgid.New(c.messageIDDomain())))
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Join(blocked, "\r\n"))
+	buf.WriteString("\r\n")
+
+	flags := []string{imap.SeenFlag}
+	if err := c.client.Append(blockListFolder, flags, time.Now(), strings.NewReader(buf.String())); err != nil {
+		return fmt.Errorf("failed to append block list: %w", err)
+	}
+
+	if oldNoteID != "" {
+		if err := c.expungeEmail(blockListFolder, oldNoteID); err != nil {
+			return fmt.Errorf("failed to remove previous block list: %w", err)
+		}
+	}
+
+	return nil
+}
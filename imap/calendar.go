@@ -0,0 +1,132 @@
+package imap
+
+import (
+	"bufio"
+	"strings"
+	"time"
+)
+
+// CalendarEvent is the VEVENT of a text/calendar invitation
+type CalendarEvent struct {
+	Summary   string    `json:"summary,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Organizer string    `json:"organizer,omitempty"`
+	Location  string    `json:"location,omitempty"`
+}
+
+// parseCalendarEvent extracts the first VEVENT from an iCalendar document.
+// It returns nil if the document contains no event. Only the handful of
+// properties exposed on CalendarEvent are read; everything else is skipped.
+func parseCalendarEvent(data string) *CalendarEvent {
+	var event *CalendarEvent
+	for _, line := range unfoldICalLines(data) {
+		name, params, value := splitICalProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &CalendarEvent{}
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT") && event != nil:
+			return event
+		}
+		if event == nil {
+			continue
+		}
+
+		switch name {
+		case "SUMMARY":
+			event.Summary = unescapeICalText(value)
+		case "LOCATION":
+			event.Location = unescapeICalText(value)
+		case "DTSTART":
+			event.Start = parseICalTime(value, params["TZID"])
+		case "DTEND":
+			event.End = parseICalTime(value, params["TZID"])
+		case "ORGANIZER":
+			addr := value
+			if len(addr) >= 7 && strings.EqualFold(addr[:7], "mailto:") {
+				addr = addr[7:]
+			}
+			if cn := strings.Trim(params["CN"], `"`); cn != "" {
+				event.Organizer = cn + " <" + addr + ">"
+			} else {
+				event.Organizer = addr
+			}
+		}
+	}
+
+	// Tolerate a missing END:VEVENT
+	return event
+}
+
+// unfoldICalLines splits content into logical lines, joining continuation
+// lines that begin with a space or tab (RFC 5545 section 3.1).
+func unfoldICalLines(data string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitICalProperty splits "NAME;PARAM=x:VALUE" into its parts. Parameter
+// names are upper-cased; quoted parameter values may contain ':' and ';'.
+func splitICalProperty(line string) (name string, params map[string]string, value string) {
+	params = map[string]string{}
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), params, ""
+	}
+
+	head := line[:colon]
+	value = line[colon+1:]
+
+	parts := strings.Split(head, ";")
+	name = strings.ToUpper(parts[0])
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = v
+		}
+	}
+	return name, params, value
+}
+
+// parseICalTime parses DATE-TIME and DATE values. Floating times use the
+// TZID location if it is known and UTC otherwise.
+func parseICalTime(value, tzid string) time.Time {
+	loc := time.UTC
+	if tzid != "" {
+		if l, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+			loc = l
+		}
+	}
+
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// unescapeICalText reverses TEXT value escaping (RFC 5545 section 3.3.11).
+func unescapeICalText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
@@ -0,0 +1,65 @@
+package imap
+
+import (
+	"context"
+
+	"github.com/emersion/go-imap"
+)
+
+// fetchMessages runs fetch, which streams messages into the channel it is
+// given the way UidFetch does, and hands each message to fn. It returns
+// fetch's error, or ctx.Err() as soon as ctx ends: a cancelled client or an
+// expired deadline stops the work at the next message instead of after the
+// whole response. The server keeps sending the rest of the response, so it
+// is discarded in the background and the connection is busy until that
+// finishes; see settle. Caller must hold c.mu.
+func (c *Client) fetchMessages(ctx context.Context, fetch func(chan *imap.Message) error, fn func(*imap.Message)) error {
+	c.settle()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- fetch(messages)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return <-done
+			}
+			fn(msg)
+		case <-ctx.Done():
+			busy := make(chan struct{})
+			go func() {
+				for range messages {
+				}
+				<-done
+				close(busy)
+			}()
+			c.busy = busy
+			return ctx.Err()
+		}
+	}
+}
+
+// settle waits for the rest of a fetch abandoned by fetchMessages to be
+// discarded, so the next command does not interleave with it (caller must
+// hold c.mu)
+func (c *Client) settle() {
+	if c.busy != nil {
+		<-c.busy
+		c.busy = nil
+	}
+}
+
+// busyUntil returns a channel closed once an abandoned fetch has been
+// discarded, or nil if the connection is ready for its next command
+func (c *Client) busyUntil() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.busy
+}
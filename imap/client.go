@@ -2,18 +2,24 @@ package imap
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/mail"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
-	"github.com/google/uuid"
+	"github.com/emersion/go-imap/responses"
 	message "github.com/emersion/go-message/mail"
+	"github.com/rgabriel/mcp-icloud-email/internal/htmltext"
+	"github.com/rgabriel/mcp-icloud-email/internal/msgid"
+	subjectpkg "github.com/rgabriel/mcp-icloud-email/internal/subject"
 )
 
 const (
@@ -22,11 +28,89 @@ const (
 	timeout    = 30 * time.Second
 )
 
+// ErrPartialResults is returned alongside a non-empty result slice when a
+// fetch fails partway through. Callers may use the results that were returned.
+var ErrPartialResults = errors.New("partial results")
+
+// ErrNotFound is wrapped by errors for a message, attachment, or folder that
+// does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidID is wrapped by errors for an email ID that is not a numeric UID.
+var ErrInvalidID = errors.New("invalid email ID")
+
+// ErrAlreadyExists is wrapped by CreateFolder errors when the folder exists.
+var ErrAlreadyExists = errors.New("already exists")
+
+// backend is the subset of *client.Client used by Client. It lets tests
+// substitute an in-memory server.
+type backend interface {
+	Select(name string, readOnly bool) (*imap.MailboxStatus, error)
+	List(ref, name string, ch chan *imap.MailboxInfo) error
+	UidSearch(criteria *imap.SearchCriteria) ([]uint32, error)
+	Fetch(seqset *imap.SeqSet, items []imap.FetchItem, ch chan *imap.Message) error
+	UidFetch(seqset *imap.SeqSet, items []imap.FetchItem, ch chan *imap.Message) error
+	UidStore(seqset *imap.SeqSet, item imap.StoreItem, value interface{}, ch chan *imap.Message) error
+	UidCopy(seqset *imap.SeqSet, dest string) error
+	UidMove(seqset *imap.SeqSet, dest string) error
+	Expunge(ch chan uint32) error
+	Append(mbox string, flags []string, date time.Time, msg imap.Literal) error
+	Create(name string) error
+	Delete(name string) error
+	Subscribe(name string) error
+	Unsubscribe(name string) error
+	Support(capability string) (bool, error)
+	Execute(cmd imap.Commander, h responses.Handler) (*imap.StatusResp, error)
+	Logout() error
+}
+
 // Client wraps the IMAP client with iCloud-specific functionality
 type Client struct {
-	mu       sync.Mutex
-	client   *client.Client
-	username string
+	mu        sync.Mutex
+	client    backend
+	username  string
+	opts      ClientOptions
+	selected  string // currently selected mailbox, "" if none
+	delimiter string // hierarchy delimiter, discovered on first use
+
+	uidValidity uint32 // UIDVALIDITY of the selected mailbox
+
+	busy chan struct{} // closed once a cancelled fetch is drained; see fetchMessages
+
+	redial func() (backend, error) // opens a replacement connection; nil in tests
+}
+
+// ClientOptions contains optional settings for the IMAP client
+type ClientOptions struct {
+	// OAuthToken, when set, authenticates with XOAUTH2 instead of the password
+	OAuthToken string
+	// MessageIDDomain is the domain used in generated Message-IDs. Defaults to
+	// the domain of the account address.
+	MessageIDDomain string
+	// ReplyPrefix replaces "Re:" in reply draft subjects (e.g. "AW:", "Odp:")
+	ReplyPrefix string
+	// ProtectedFolders refuse DeleteFolder and PurgeDeleted
+	ProtectedFolders []string
+	// MaxFolderDepth limits how many levels deep CreateFolder may nest a
+	// folder; 0 means unlimited
+	MaxFolderDepth int
+	// Aliases are other addresses of this account (e.g. ALLOWED_FROM);
+	// messages from them count as sent by the account, like the username
+	Aliases []string
+	// Debug logs every IMAP command and response at debug level, with
+	// credentials redacted
+	Debug bool
+	// TLSConfig, when set, replaces the default TLS settings (e.g. to raise
+	// the minimum version or pin certificates)
+	TLSConfig *tls.Config
+	// Retry controls retries of searches and fetches that fail transiently
+	Retry RetryOptions
+	// AutoPlaintext fills BodyPlain from the HTML part of emails that have
+	// no text/plain part, so every email has a plain text body
+	AutoPlaintext bool
+	// FetchBatchSize is how many messages a search fetches per UID FETCH;
+	// 0 means DefaultFetchBatchSize
+	FetchBatchSize int
 }
 
 // Email represents a complete email message
@@ -37,20 +121,38 @@ type Email struct {
 	CC          []string     `json:"cc"`
 	BCC         []string     `json:"bcc"`
 	Subject     string       `json:"subject"`
-	Date        time.Time    `json:"date"`
+	Date        time.Time    `json:"date,omitzero"` // envelope date, else arrival time; omitted when neither is known
 	BodyPlain   string       `json:"bodyPlain,omitempty"`
 	BodyHTML    string       `json:"bodyHTML,omitempty"`
+	BestBody    string       `json:"bestBody,omitempty"` // plain text or text extracted from HTML
+	Flowed      bool         `json:"flowed,omitempty"`   // BodyPlain is format=flowed; see UnfoldFlowed
 	Snippet     string       `json:"snippet,omitempty"`
 	Unread      bool         `json:"unread"`
+	Answered    bool         `json:"answered,omitempty"`
+	Flagged     bool         `json:"flagged,omitempty"`
+	IsFromMe    bool         `json:"isFromMe"`       // From is the account address or an alias
+	Size        uint32       `json:"size,omitempty"` // RFC822.SIZE, when fetched
 	Attachments []Attachment `json:"attachments,omitempty"`
 	MessageID   string       `json:"messageId,omitempty"`
 	References  []string     `json:"references,omitempty"`
+
+	CalendarEvent    *CalendarEvent `json:"calendarEvent,omitempty"`
+	AuthResults      *AuthResults   `json:"authResults,omitempty"`
+	AutoReply        bool           `json:"autoReply,omitempty"`        // vacation or out-of-office response; set when the body is fetched
+	Bulk             bool           `json:"bulk,omitempty"`             // Precedence: bulk or junk; set when the body is fetched
+	Truncated        bool           `json:"truncated,omitempty"`        // body cut off at FetchOptions.BodyMaxBytes
+	PlainFromHTML    bool           `json:"plainFromHtml,omitempty"`    // BodyPlain was converted from BodyHTML; see ClientOptions.AutoPlaintext
+	EmbeddedMessages []Email        `json:"embeddedMessages,omitempty"` // attached message/rfc822 parts, e.g. forwarded emails
+
+	flowedDelSp bool // format=flowed body uses delsp=yes
 }
 
 // Attachment represents an email attachment
 type Attachment struct {
 	Filename string `json:"filename"`
 	Size     int64  `json:"size"`
+	MIMEType string `json:"mimeType,omitempty"` // set with Content
+	Content  string `json:"content,omitempty"`  // base64; only with FetchOptions.InlineAttachmentMax
 }
 
 // AttachmentData contains full attachment data including content
@@ -68,31 +170,104 @@ type DraftOptions struct {
 	HTML      bool
 	ReplyToID string
 	Folder    string
+	Headers   map[string]string
+}
+
+// CreateFolderOptions contains options for creating folders
+type CreateFolderOptions struct {
+	// SkipSubscribe leaves the new folder unsubscribed. By default it is
+	// subscribed so clients that only show subscribed folders list it.
+	SkipSubscribe bool
+	// Use tags the folder with a special-use attribute: archive, drafts,
+	// junk, sent, or trash. It needs CREATE-SPECIAL-USE on the server;
+	// without it the folder is created untagged.
+	Use string
+}
+
+// MoveOptions contains options for moving emails
+type MoveOptions struct {
+	SkipIfDuplicate bool
+	// PreserveFlags re-applies the source message's flags (\Seen,
+	// \Answered, \Flagged, keywords) to the moved copy, for servers whose
+	// COPY fallback or MOVE drops them
+	PreserveFlags bool
+}
+
+// FetchOptions controls how FetchEmail addresses and retrieves a message
+type FetchOptions struct {
+	// BySequence treats the email ID as a message sequence number instead
+	// of a UID. Sequence numbers shift whenever an earlier message is
+	// expunged, so a number is only meaningful until the folder changes.
+	BySequence bool
+	// Peek fetches with BODY.PEEK[] so the message is not marked as read
+	Peek bool
+	// BodyMaxBytes, when positive, fetches only the header and the first
+	// BodyMaxBytes bytes of the text (BODY.PEEK[TEXT]<0.N>) and sets
+	// Email.Truncated if the body was cut off. Such a preview never marks
+	// the message as read.
+	BodyMaxBytes int
+	// InlineAttachmentMax, when positive, returns the content of every
+	// attachment of at most this many bytes (decoded) in Attachment.Content.
+	// Ignored when the body is truncated by BodyMaxBytes.
+	InlineAttachmentMax int64
 }
 
 // EmailFilters contains filter options for searching emails
 type EmailFilters struct {
-	LastDays   int
-	Since      *time.Time
-	Before     *time.Time
-	UnreadOnly bool
-	Limit      int
-	Offset     int
+	LastDays    int
+	Since       *time.Time
+	Before      *time.Time
+	UnreadOnly  bool
+	From        string   // server-side FROM search, a substring of the sender
+	DeliveredTo string   // Delivered-To or X-Original-To, e.g. a plus-address
+	Recipient   string   // server-side TO or CC search, a substring of a recipient
+	LargerThan  uint32   // server-side LARGER search, in bytes
+	Keywords    []string // server-side KEYWORD search; every flag must be set
+	RawQuery    string   // IMAP SEARCH keys (see ParseRawQuery); replaces the query and every filter above
+	Limit       int
+	Offset      int
 }
 
 // NewClient creates a new IMAP client configured for iCloud
-func NewClient(email, password string) (*Client, error) {
+func NewClient(email, password string, opts ClientOptions) (*Client, error) {
+	c, err := dial(email, password, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		client:   c,
+		username: email,
+		opts:     opts,
+		selected: "INBOX",
+		redial: func() (backend, error) {
+			c, err := dial(email, password, opts)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+	}, nil
+}
+
+// dial connects and logs in to the iCloud IMAP server
+func dial(email, password string, opts ClientOptions) (*client.Client, error) {
 	// Connect to iCloud IMAP server with TLS
 	addr := fmt.Sprintf("%s:%d", imapServer, imapPort)
-	c, err := client.DialTLS(addr, nil)
+	c, err := client.DialTLS(addr, opts.TLSConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
 	}
 
+	// Log the wire protocol before login so the handshake is captured too
+	if opts.Debug {
+		c.SetDebug(newDebugWriter())
+	}
+
 	// Login
-	if err := c.Login(email, password); err != nil {
+	if err := authenticate(c, email, password, opts.OAuthToken); err != nil {
 		_ = c.Logout()
-		return nil, fmt.Errorf("failed to login: %w", err)
+		return nil, fmt.Errorf("failed to login: %w", authError{err})
 	}
 
 	// Test connection by selecting INBOX
@@ -101,10 +276,64 @@ func NewClient(email, password string) (*Client, error) {
 		return nil, fmt.Errorf("failed to select INBOX: %w", err)
 	}
 
-	return &Client{
-		client:   c,
-		username: email,
-	}, nil
+	return c, nil
+}
+
+// selectFolder selects a mailbox and records it as the current one
+// (caller must hold c.mu)
+func (c *Client) selectFolder(name string, readOnly bool) (*imap.MailboxStatus, error) {
+	status, err := c.client.Select(name, readOnly)
+	if err != nil {
+		c.selected = ""
+		if isMissingMailbox(err) {
+			err = missingMailboxError{err}
+		}
+		return nil, err
+	}
+	c.selected = name
+	c.uidValidity = status.UidValidity
+	return status, nil
+}
+
+// missingMailboxError marks a SELECT failure as meaning the mailbox does not
+// exist, keeping the server's wording while matching ErrNotFound.
+type missingMailboxError struct{ error }
+
+func (e missingMailboxError) Unwrap() error        { return e.error }
+func (e missingMailboxError) Is(target error) bool { return target == ErrNotFound }
+
+// isMissingMailbox reports whether a SELECT error says the mailbox does not
+// exist. Servers only put the NONEXISTENT response code in the text, so this
+// matches the common phrasings.
+func isMissingMailbox(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, phrase := range []string{"nonexistent", "doesn't exist", "does not exist", "no such", "not found", "unknown mailbox"} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingMailboxError marks a CREATE failure as meaning the mailbox already
+// exists, keeping the server's wording while matching ErrAlreadyExists.
+type existingMailboxError struct{ error }
+
+func (e existingMailboxError) Unwrap() error        { return e.error }
+func (e existingMailboxError) Is(target error) bool { return target == ErrAlreadyExists }
+
+// isExistingMailbox reports whether a CREATE error says the mailbox already
+// exists, from the ALREADYEXISTS response code (RFC 5530) or its wording.
+func isExistingMailbox(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "alreadyexists") || strings.Contains(msg, "already exists")
+}
+
+// SelectedFolder returns the mailbox currently selected on this connection
+func (c *Client) SelectedFolder() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.selected
 }
 
 // Close closes the IMAP connection
@@ -145,13 +374,90 @@ func (c *Client) listFolders() ([]string, error) {
 	return folders, nil
 }
 
-// SearchEmails searches for emails in a folder with filters
-func (c *Client) SearchEmails(ctx context.Context, folder, query string, filters EmailFilters) ([]Email, int, error) {
+// SearchEmails searches for emails in a folder with filters. Along with
+// the emails and their total it returns the folder's UIDVALIDITY as of the
+// search, so that the IDs and the value that scopes them come from the same
+// connection.
+func (c *Client) SearchEmails(ctx context.Context, folder, query string, filters EmailFilters) (emails []Email, total int, uidValidity uint32, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err = c.resolveFolder(folder)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	emails, total, err = c.searchEmails(ctx, folder, query, filters)
+	if emails == nil {
+		return nil, 0, 0, err
+	}
+	return emails, total, c.selectedUIDValidity(folder), err
+}
+
+// selectedUIDValidity returns the UIDVALIDITY of folder if it is the
+// selected one, or 0 (caller must hold c.mu)
+func (c *Client) selectedUIDValidity(folder string) uint32 {
+	if c.selected != folder {
+		return 0
+	}
+	return c.uidValidity
+}
+
+// SearchUIDs runs the same search as SearchEmails but returns only the
+// matching UIDs, oldest first, the total before offset and limit, and the
+// folder's UIDVALIDITY. No message data is fetched, which makes it cheap for
+// bulk operations.
+func (c *Client) SearchUIDs(ctx context.Context, folder, query string, filters EmailFilters) (ids []string, total int, uidValidity uint32, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	folder, err = c.resolveFolder(folder)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var uids []uint32
+	err = c.withRetry(ctx, func() error {
+		var err error
+		uids, total, err = c.searchUIDs(folder, query, filters)
+		return err
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	ids = make([]string, len(uids))
+	for i, uid := range uids {
+		ids[i] = fmt.Sprintf("%d", uid)
+	}
+	return ids, total, c.selectedUIDValidity(folder), nil
+}
+
+// searchEmails is the internal implementation, collecting the pages of
+// searchPages into one list. Fetching in batches of FetchBatchSize bounds
+// the size of each server response (caller must hold c.mu).
+func (c *Client) searchEmails(ctx context.Context, folder, query string, filters EmailFilters) ([]Email, int, error) {
+	batch := c.opts.FetchBatchSize
+	if batch <= 0 {
+		batch = DefaultFetchBatchSize
+	}
+
+	emails := []Email{}
+	total, err := c.searchPages(ctx, folder, query, filters, batch, func(page []Email) error {
+		emails = append(emails, page...)
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrPartialResults) {
+		return nil, 0, err
+	}
+	return emails, total, err
+}
+
+// searchUIDs selects folder and returns the UIDs matching query and filters,
+// after offset and limit, with the total before them (caller must hold c.mu)
+func (c *Client) searchUIDs(folder, query string, filters EmailFilters) ([]uint32, int, error) {
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return nil, 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
@@ -175,76 +481,152 @@ func (c *Client) SearchEmails(ctx context.Context, folder, query string, filters
 		criteria.WithoutFlags = []string{imap.SeenFlag}
 	}
 
+	// Apply sender filter
+	if filters.From != "" {
+		criteria.Header.Add("From", filters.From)
+	}
+
+	// Apply delivery address filter
+	if filters.DeliveredTo != "" {
+		criteria.Or = append(criteria.Or, deliveredToCriteria(filters.DeliveredTo))
+	}
+
+	// Apply recipient filter
+	if filters.Recipient != "" {
+		criteria.Or = append(criteria.Or, recipientCriteria(filters.Recipient))
+	}
+
+	// Apply size filter
+	if filters.LargerThan > 0 {
+		criteria.Larger = filters.LargerThan
+	}
+
+	// Apply flag filter
+	criteria.WithFlags = append(criteria.WithFlags, filters.Keywords...)
+
 	// Apply text search if provided
 	if query != "" {
 		criteria.Text = []string{query}
 	}
 
+	// Raw SEARCH keys replace everything above
+	if filters.RawQuery != "" {
+		raw, err := ParseRawQuery(filters.RawQuery)
+		if err != nil {
+			return nil, 0, err
+		}
+		criteria = raw
+	}
+
 	// Search for messages
 	uids, err := c.client.UidSearch(criteria)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to search emails: %w", err)
 	}
 
-	total := len(uids)
-	if total == 0 {
-		return []Email{}, 0, nil
-	}
+	return pageUIDs(uids, filters.Offset, filters.Limit), len(uids), nil
+}
 
-	// Apply offset and limit (UIDs are ascending, most recent = highest)
-	if filters.Offset > 0 && filters.Offset < len(uids) {
-		uids = uids[:len(uids)-filters.Offset]
-	} else if filters.Offset >= len(uids) {
-		return []Email{}, total, nil
+// pageUIDs applies offset and limit to ascending UIDs: offset skips the
+// most recent (highest) and limit keeps the most recent of the rest
+func pageUIDs(uids []uint32, offset, limit int) []uint32 {
+	if offset >= len(uids) {
+		return nil
 	}
-	if filters.Limit > 0 && len(uids) > filters.Limit {
-		uids = uids[len(uids)-filters.Limit:]
+	uids = uids[:len(uids)-offset]
+	if limit > 0 && len(uids) > limit {
+		uids = uids[len(uids)-limit:]
 	}
+	return uids
+}
 
-	// Create sequence set
-	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uids...)
+// deliveredToCriteria matches addr in either header that records the
+// envelope recipient: Delivered-To, or X-Original-To where the MTA writes
+// the pre-expansion address.
+func deliveredToCriteria(addr string) [2]*imap.SearchCriteria {
+	deliveredTo := imap.NewSearchCriteria()
+	deliveredTo.Header.Add("Delivered-To", addr)
+	originalTo := imap.NewSearchCriteria()
+	originalTo.Header.Add("X-Original-To", addr)
+	return [2]*imap.SearchCriteria{deliveredTo, originalTo}
+}
 
-	// Fetch envelope and flags for the messages
-	messages := make(chan *imap.Message, 10)
-	done := make(chan error, 1)
-	go func() {
-		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid}, messages)
-	}()
+// recipientCriteria matches addr in the To or CC header
+func recipientCriteria(addr string) [2]*imap.SearchCriteria {
+	to := imap.NewSearchCriteria()
+	to.Header.Add("To", addr)
+	cc := imap.NewSearchCriteria()
+	cc.Header.Add("Cc", addr)
+	return [2]*imap.SearchCriteria{to, cc}
+}
 
-	emails := []Email{}
-	for msg := range messages {
-		email := c.parseMessageData(msg, false)
-		if email != nil {
-			emails = append(emails, *email)
-		}
-	}
+// GetEmail retrieves a full email by UID
+func (c *Client) GetEmail(ctx context.Context, folder, emailID string) (*Email, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if err := <-done; err != nil {
-		return nil, 0, fmt.Errorf("failed to fetch messages: %w", err)
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
 	}
+	return c.fetchEmailRetry(ctx, folder, emailID, FetchOptions{})
+}
+
+// PeekEmail retrieves a full email by UID like GetEmail, but with
+// BODY.PEEK[] so the message is not marked as read
+func (c *Client) PeekEmail(ctx context.Context, folder, emailID string) (*Email, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return emails, total, nil
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchEmailRetry(ctx, folder, emailID, FetchOptions{Peek: true})
 }
 
-// GetEmail retrieves a full email by UID
-func (c *Client) GetEmail(ctx context.Context, folder, emailID string) (*Email, error) {
+// FetchEmail retrieves a full email by UID or, with opts.BySequence, by
+// sequence number. The returned email's ID is always its UID.
+func (c *Client) FetchEmail(ctx context.Context, folder, emailID string, opts FetchOptions) (*Email, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.getEmail(folder, emailID)
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchEmailRetry(ctx, folder, emailID, opts)
+}
+
+// fetchEmailRetry is fetchEmail with transient failures retried (caller
+// must hold c.mu)
+func (c *Client) fetchEmailRetry(ctx context.Context, folder, emailID string, opts FetchOptions) (*Email, error) {
+	var email *Email
+	err := c.withRetry(ctx, func() error {
+		var err error
+		email, err = c.fetchEmail(folder, emailID, opts)
+		return err
+	})
+	return email, err
 }
 
 // getEmail is the internal implementation (caller must hold c.mu)
 func (c *Client) getEmail(folder, emailID string) (*Email, error) {
+	return c.fetchEmail(folder, emailID, FetchOptions{})
+}
+
+// fetchEmail fetches and parses one full message, leaving its \Seen flag
+// alone when opts.Peek is set (caller must hold c.mu)
+func (c *Client) fetchEmail(folder, emailID string, opts FetchOptions) (*Email, error) {
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
 	// Parse UID
 	var uid uint32
 	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-		return nil, fmt.Errorf("invalid email ID format: %w", err)
+		return nil, fmt.Errorf("%w format: %w", ErrInvalidID, err)
 	}
 
 	// Create sequence set
@@ -254,15 +636,44 @@ func (c *Client) getEmail(folder, emailID string) (*Email, error) {
 	// Fetch full message
 	messages := make(chan *imap.Message, 1)
 	done := make(chan error, 1)
-	section := &imap.BodySectionName{}
+	section := &imap.BodySectionName{Peek: opts.Peek}
+	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchInternalDate, imap.FetchFlags, imap.FetchUid, imap.FetchRFC822Size, section.FetchItem()}
+	var header, text *imap.BodySectionName
+	if opts.BodyMaxBytes > 0 {
+		header, text = previewSections(opts.BodyMaxBytes)
+		items = append(items[:len(items)-1], header.FetchItem(), text.FetchItem())
+	}
 	go func() {
-		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid, section.FetchItem()}, messages)
+		if opts.BySequence {
+			done <- c.client.Fetch(seqSet, items, messages)
+		} else {
+			done <- c.client.UidFetch(seqSet, items, messages)
+		}
 	}()
 
 	msg := <-messages
 	if msg == nil {
 		<-done
-		return nil, fmt.Errorf("email not found")
+		return nil, fmt.Errorf("email %w", ErrNotFound)
+	}
+
+	truncated := false
+	if opts.BodyMaxBytes > 0 {
+		var err error
+		if truncated, err = joinPreview(msg, header, text, opts.BodyMaxBytes); err != nil {
+			<-done
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+	}
+
+	// Keep the raw message to read attachments from after parsing
+	var raw []byte
+	if opts.InlineAttachmentMax > 0 && !truncated {
+		var err error
+		if raw, err = bufferBody(msg); err != nil {
+			<-done
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
 	}
 
 	email := c.parseMessageData(msg, true)
@@ -275,6 +686,10 @@ func (c *Client) getEmail(folder, emailID string) (*Email, error) {
 		return nil, fmt.Errorf("failed to parse email")
 	}
 
+	email.Truncated = truncated
+	if raw != nil {
+		inlineAttachments(email, raw, opts.InlineAttachmentMax)
+	}
 	return email, nil
 }
 
@@ -282,13 +697,25 @@ func (c *Client) getEmail(folder, emailID string) (*Email, error) {
 func (c *Client) CountEmails(ctx context.Context, folder string, filters EmailFilters) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.countEmails(folder, filters)
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = c.withRetry(ctx, func() error {
+		var err error
+		count, err = c.countEmails(folder, filters)
+		return err
+	})
+	return count, err
 }
 
 // countEmails is the internal implementation (caller must hold c.mu)
 func (c *Client) countEmails(folder string, filters EmailFilters) (int, error) {
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
@@ -310,6 +737,24 @@ func (c *Client) countEmails(folder string, filters EmailFilters) (int, error) {
 		criteria.WithoutFlags = []string{imap.SeenFlag}
 	}
 
+	if filters.From != "" {
+		criteria.Header.Add("From", filters.From)
+	}
+
+	if filters.DeliveredTo != "" {
+		criteria.Or = append(criteria.Or, deliveredToCriteria(filters.DeliveredTo))
+	}
+
+	if filters.Recipient != "" {
+		criteria.Or = append(criteria.Or, recipientCriteria(filters.Recipient))
+	}
+
+	if filters.LargerThan > 0 {
+		criteria.Larger = filters.LargerThan
+	}
+
+	criteria.WithFlags = append(criteria.WithFlags, filters.Keywords...)
+
 	// Search for messages
 	uids, err := c.client.UidSearch(criteria)
 	if err != nil {
@@ -324,60 +769,219 @@ func (c *Client) MarkRead(ctx context.Context, folder, emailID string, read bool
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
 	// Parse UID
 	var uid uint32
 	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-		return fmt.Errorf("invalid email ID format: %w", err)
+		return fmt.Errorf("%w format: %w", ErrInvalidID, err)
 	}
 
-	// Create sequence set
+	if err := c.storeSeen([]uint32{uid}, read); err != nil {
+		return fmt.Errorf("failed to mark email: %w", err)
+	}
+
+	return nil
+}
+
+// MarkReadBulk marks several emails in one folder as read or unread with a
+// single UID STORE, returning how many were marked
+func (c *Client) MarkReadBulk(ctx context.Context, folder string, emailIDs []string, read bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	uids, err := parseUIDs(emailIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+	if err := c.storeSeen(uids, read); err != nil {
+		return 0, fmt.Errorf("failed to mark emails: %w", err)
+	}
+	return len(uids), nil
+}
+
+// storeSeen adds or removes \Seen on the given UIDs of the selected folder
+// (caller must hold c.mu)
+func (c *Client) storeSeen(uids []uint32, read bool) error {
 	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uid)
+	seqSet.AddNum(uids...)
 
-	// Store flags
 	var item imap.StoreItem
 	if read {
 		item = imap.FormatFlagsOp(imap.AddFlags, true)
 	} else {
 		item = imap.FormatFlagsOp(imap.RemoveFlags, true)
 	}
-	
+
 	flags := []interface{}{imap.SeenFlag}
-	if err := c.client.UidStore(seqSet, item, flags, nil); err != nil {
-		return fmt.Errorf("failed to mark email: %w", err)
+	return c.client.UidStore(seqSet, item, flags, nil)
+}
+
+// parseUIDs converts email IDs to UIDs, rejecting any that is not numeric
+func parseUIDs(emailIDs []string) ([]uint32, error) {
+	uids := make([]uint32, 0, len(emailIDs))
+	for _, id := range emailIDs {
+		var uid uint32
+		if _, err := fmt.Sscanf(id, "%d", &uid); err != nil {
+			return nil, fmt.Errorf("%w format %q: %w", ErrInvalidID, id, err)
+		}
+		uids = append(uids, uid)
 	}
+	return uids, nil
+}
 
-	return nil
+// MoveEmail moves an email from one folder to another. With
+// opts.SkipIfDuplicate, a message whose Message-ID already exists in the
+// destination is left in place and skipped is reported as true.
+func (c *Client) MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string, opts MoveOptions) (skipped bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fromFolder, err = c.resolveFolder(fromFolder); err != nil {
+		return false, err
+	}
+	if toFolder, err = c.resolveFolder(toFolder); err != nil {
+		return false, err
+	}
+
+	if opts.SkipIfDuplicate {
+		present, err := c.presentInFolder(fromFolder, toFolder, emailID)
+		if err != nil {
+			return false, err
+		}
+		if present {
+			return true, nil
+		}
+	}
+
+	if opts.PreserveFlags {
+		return false, c.moveEmailWithFlags(fromFolder, toFolder, emailID)
+	}
+	return false, c.moveEmail(fromFolder, toFolder, emailID)
 }
 
-// MoveEmail moves an email from one folder to another
-func (c *Client) MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string) error {
+// presentInFolder reports whether the message identified by emailID in
+// fromFolder already exists in toFolder, matched by Message-ID. Messages
+// without a Message-ID are never considered present. Caller must hold c.mu.
+func (c *Client) presentInFolder(fromFolder, toFolder, emailID string) (bool, error) {
+	if _, err := c.selectFolder(fromFolder, false); err != nil {
+		return false, fmt.Errorf("failed to select folder %s: %w", fromFolder, err)
+	}
+
+	var uid uint32
+	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
+		return false, fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	// Fetch the source envelope for its Message-ID
+	messages := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchUid}, messages)
+	}()
+
+	var messageID string
+	for msg := range messages {
+		if msg.Envelope != nil {
+			messageID = msg.Envelope.MessageId
+		}
+	}
+
+	if err := <-done; err != nil {
+		return false, fmt.Errorf("failed to fetch message: %w", err)
+	}
+
+	if messageID == "" {
+		return false, nil
+	}
+
+	// Search the destination for the same Message-ID
+	if _, err := c.selectFolder(toFolder, false); err != nil {
+		return false, fmt.Errorf("failed to select folder %s: %w", toFolder, err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Set("Message-Id", messageID)
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return false, fmt.Errorf("failed to search folder %s: %w", toFolder, err)
+	}
+
+	return len(uids) > 0, nil
+}
+
+// MoveEmailBulk moves several emails between folders with a single MOVE
+// (or COPY + delete) command and returns how many were moved
+func (c *Client) MoveEmailBulk(ctx context.Context, fromFolder, toFolder string, emailIDs []string) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.moveEmail(fromFolder, toFolder, emailID)
+
+	var err error
+	if fromFolder, err = c.resolveFolder(fromFolder); err != nil {
+		return 0, err
+	}
+	if toFolder, err = c.resolveFolder(toFolder); err != nil {
+		return 0, err
+	}
+
+	uids, err := parseUIDs(emailIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	if err := c.moveEmails(fromFolder, toFolder, uids); err != nil {
+		return 0, err
+	}
+	return len(uids), nil
 }
 
 // moveEmail is the internal implementation (caller must hold c.mu)
 func (c *Client) moveEmail(fromFolder, toFolder, emailID string) error {
-	// Select the source mailbox
-	if _, err := c.client.Select(fromFolder, false); err != nil {
-		return fmt.Errorf("failed to select folder %s: %w", fromFolder, err)
-	}
-
 	// Parse UID
 	var uid uint32
 	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-		return fmt.Errorf("invalid email ID format: %w", err)
+		return fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	return c.moveEmails(fromFolder, toFolder, []uint32{uid})
+}
+
+// moveEmails moves the given UIDs in one command (caller must hold c.mu)
+func (c *Client) moveEmails(fromFolder, toFolder string, uids []uint32) error {
+	// Select the source mailbox
+	if _, err := c.selectFolder(fromFolder, false); err != nil {
+		return fmt.Errorf("failed to select folder %s: %w", fromFolder, err)
 	}
 
 	// Create sequence set
 	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uid)
+	seqSet.AddNum(uids...)
 
 	// Try to use MOVE command (if supported)
 	// Otherwise fall back to COPY + DELETE
@@ -408,73 +1012,103 @@ func (c *Client) DeleteEmail(ctx context.Context, folder, emailID string, perman
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+
 	if permanent {
-		// Select the mailbox
-		if _, err := c.client.Select(folder, false); err != nil {
-			return fmt.Errorf("failed to select folder %s: %w", folder, err)
-		}
+		return c.expungeEmail(folder, emailID)
+	}
 
-		// Parse UID
-		var uid uint32
-		if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-			return fmt.Errorf("invalid email ID format: %w", err)
+	// Move to Trash folder (use internal moveEmail to avoid deadlock)
+	trashFolder := "Deleted Messages"
+	if err := c.moveEmail(folder, trashFolder, emailID); err != nil {
+		// Try alternate trash folder name
+		trashFolder = "Trash"
+		if err := c.moveEmail(folder, trashFolder, emailID); err != nil {
+			return fmt.Errorf("failed to move to trash: %w", err)
 		}
+	}
 
-		// Create sequence set
-		seqSet := new(imap.SeqSet)
-		seqSet.AddNum(uid)
+	return nil
+}
 
-		// Mark as deleted
-		item := imap.FormatFlagsOp(imap.AddFlags, true)
-		flags := []interface{}{imap.DeletedFlag}
-		if err := c.client.UidStore(seqSet, item, flags, nil); err != nil {
-			return fmt.Errorf("failed to mark email as deleted: %w", err)
-		}
+// expungeEmail permanently removes a message (caller must hold c.mu)
+func (c *Client) expungeEmail(folder, emailID string) error {
+	// Select the mailbox
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
 
-		// Expunge to permanently delete
-		if err := c.client.Expunge(nil); err != nil {
-			return fmt.Errorf("failed to expunge: %w", err)
-		}
-	} else {
-		// Move to Trash folder (use internal moveEmail to avoid deadlock)
-		trashFolder := "Deleted Messages"
-		if err := c.moveEmail(folder, trashFolder, emailID); err != nil {
-			// Try alternate trash folder name
-			trashFolder = "Trash"
-			if err := c.moveEmail(folder, trashFolder, emailID); err != nil {
-				return fmt.Errorf("failed to move to trash: %w", err)
-			}
-		}
+	// Parse UID
+	var uid uint32
+	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
+		return fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	// Create sequence set
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	// Mark as deleted
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	flags := []interface{}{imap.DeletedFlag}
+	if err := c.client.UidStore(seqSet, item, flags, nil); err != nil {
+		return fmt.Errorf("failed to mark email as deleted: %w", err)
+	}
+
+	// Expunge to permanently delete
+	if err := c.client.Expunge(nil); err != nil {
+		return fmt.Errorf("failed to expunge: %w", err)
 	}
 
 	return nil
 }
 
+// displayDate returns the envelope date, falling back to the server's
+// arrival time (INTERNALDATE) when the Date header is missing or malformed.
+// Both are zero only if neither was fetched.
+func displayDate(msg *imap.Message) time.Time {
+	if msg.Envelope != nil && !msg.Envelope.Date.IsZero() {
+		return msg.Envelope.Date
+	}
+	return msg.InternalDate
+}
+
 // parseMessageData parses IMAP message data into Email struct
 func (c *Client) parseMessageData(msg *imap.Message, fetchBody bool) *Email {
 	if msg.Envelope == nil {
 		return nil
 	}
 
-	// Check if message has Seen flag
-	unread := true
+	// Check system flags
+	unread, answered, flagged := true, false, false
 	for _, flag := range msg.Flags {
-		if flag == imap.SeenFlag {
+		switch flag {
+		case imap.SeenFlag:
 			unread = false
-			break
+		case imap.AnsweredFlag:
+			answered = true
+		case imap.FlaggedFlag:
+			flagged = true
 		}
 	}
 
 	email := &Email{
-		ID:      fmt.Sprintf("%d", msg.Uid),
-		Subject: msg.Envelope.Subject,
-		Date:    msg.Envelope.Date,
-		Unread:  unread,
+		ID:       fmt.Sprintf("%d", msg.Uid),
+		Subject:  msg.Envelope.Subject,
+		Date:     displayDate(msg),
+		Unread:   unread,
+		Answered: answered,
+		Flagged:  flagged,
+		Size:     msg.Size,
 	}
 
 	// Parse From
 	if len(msg.Envelope.From) > 0 {
 		email.From = formatAddress(msg.Envelope.From[0])
+		email.IsFromMe = c.isSelf(msg.Envelope.From[0].Address())
 	}
 
 	// Parse To
@@ -499,9 +1133,7 @@ func (c *Client) parseMessageData(msg *imap.Message, fetchBody bool) *Email {
 	email.MessageID = msg.Envelope.MessageId
 
 	// Parse In-Reply-To and References
-	if msg.Envelope.InReplyTo != "" {
-		email.References = append(email.References, msg.Envelope.InReplyTo)
-	}
+	email.References = referenceIDs(msg.Envelope.InReplyTo, fetchedReferences(msg))
 
 	// Parse body if requested
 	if fetchBody {
@@ -527,21 +1159,32 @@ func (c *Client) parseEmailBody(email *Email, bodyLiteral imap.Literal) {
 		return
 	}
 	
-	msg, err := mail.ReadMessage(bodyLiteral)
-	if err != nil {
-		slog.Warn("failed to read email message", "error", err)
-		return
-	}
-
-	// Parse the message using go-message
-	mr, err := message.CreateReader(msg.Body)
+	// Parse the message using go-message, which needs the headers to
+	// discover the MIME structure
+	mr, err := message.CreateReader(bodyLiteral)
 	if err != nil {
 		slog.Warn("failed to create message reader", "error", err)
 		return
 	}
 
+	// The full header has References, which the envelope lacks
+	email.References = referenceIDs(mr.Header.Get("In-Reply-To"), mr.Header.Get("References"))
+
+	// Sender authentication verdicts from the receiving server
+	email.AuthResults = parseAuthResults(mr.Header.Values("Authentication-Results"), mr.Header.Get("Received-SPF"))
+
+	// Autoresponder and bulk-mail markers
+	precedence := mr.Header.Get("Precedence")
+	email.AutoReply = isAutoReply(mr.Header.Get("Auto-Submitted"), mr.Header.Get("X-Autoreply"), mr.Header.Get("X-Autorespond"), precedence)
+	email.Bulk = isBulk(precedence)
+
 	// Process message parts
 	c.processMessagePart(email, mr)
+	if c.opts.AutoPlaintext && email.BodyPlain == "" && email.BodyHTML != "" {
+		email.BodyPlain = htmltext.ToText(email.BodyHTML)
+		email.PlainFromHTML = email.BodyPlain != ""
+	}
+	email.BestBody = bestBody(email.BodyPlain, email.BodyHTML)
 
 	// Create snippet from plain text body
 	if email.BodyPlain != "" {
@@ -562,6 +1205,25 @@ func (c *Client) parseEmailBody(email *Email, bodyLiteral imap.Literal) {
 	}
 }
 
+// bestBody picks a single text body for readers that want one field. The
+// plain part wins unless it is missing or a stub (such as "view this email in
+// your browser") much shorter than the text of the HTML part.
+func bestBody(plain, html string) string {
+	plain = strings.TrimSpace(plain)
+	if html == "" {
+		return plain
+	}
+
+	htmlText := htmltext.ToText(html)
+	if plain != "" && len(plain)*4 >= len(htmlText) {
+		return plain
+	}
+	if htmlText != "" {
+		return htmlText
+	}
+	return plain
+}
+
 // processMessagePart recursively processes message parts
 func (c *Client) processMessagePart(email *Email, mr *message.Reader) {
 	for {
@@ -576,17 +1238,53 @@ func (c *Client) processMessagePart(email *Email, mr *message.Reader) {
 
 		switch h := part.Header.(type) {
 		case *message.InlineHeader:
-			contentType, _, _ := h.ContentType()
+			contentType, params, _ := h.ContentType()
 			body, _ := io.ReadAll(part.Body)
 
 			if strings.HasPrefix(contentType, "text/plain") {
 				email.BodyPlain = string(body)
+				email.Flowed = strings.EqualFold(params["format"], "flowed")
+				email.flowedDelSp = strings.EqualFold(params["delsp"], "yes")
 			} else if strings.HasPrefix(contentType, "text/html") {
 				email.BodyHTML = string(body)
+			} else if strings.HasPrefix(contentType, "text/calendar") && email.CalendarEvent == nil {
+				email.CalendarEvent = parseCalendarEvent(string(body))
+			} else if contentType == "message/rfc822" {
+				if embedded := c.parseEmbeddedMessage(body); embedded != nil {
+					email.EmbeddedMessages = append(email.EmbeddedMessages, *embedded)
+				}
 			}
 
 		case *message.AttachmentHeader:
 			filename, _ := h.Filename()
+			contentType, _, _ := h.ContentType()
+			if strings.HasPrefix(contentType, "text/calendar") && email.CalendarEvent == nil {
+				// Invitations are often sent as an attached invite.ics
+				body, _ := io.ReadAll(part.Body)
+				email.CalendarEvent = parseCalendarEvent(string(body))
+				if filename != "" {
+					email.Attachments = append(email.Attachments, Attachment{
+						Filename: filename,
+						Size:     int64(len(body)),
+					})
+				}
+				continue
+			}
+			if contentType == "message/rfc822" {
+				// A forwarded email stays listed as an attachment and is
+				// also parsed into EmbeddedMessages
+				body, _ := io.ReadAll(part.Body)
+				if embedded := c.parseEmbeddedMessage(body); embedded != nil {
+					email.EmbeddedMessages = append(email.EmbeddedMessages, *embedded)
+				}
+				if filename != "" {
+					email.Attachments = append(email.Attachments, Attachment{
+						Filename: filename,
+						Size:     int64(len(body)),
+					})
+				}
+				continue
+			}
 			if filename != "" {
 				// Count size without reading full content
 				size, _ := io.Copy(io.Discard, part.Body)
@@ -608,6 +1306,28 @@ func formatAddress(addr *imap.Address) string {
 	return fmt.Sprintf("%s@%s", addr.MailboxName, addr.HostName)
 }
 
+// messageIDDomain returns the domain for generated Message-IDs
+func (c *Client) messageIDDomain() string {
+	if c.opts.MessageIDDomain != "" {
+		return c.opts.MessageIDDomain
+	}
+	return msgid.DomainOf(c.username)
+}
+
+// isSelf reports whether the bare address addr is the account address or one
+// of its aliases. Addresses are compared case-insensitively.
+func (c *Client) isSelf(addr string) bool {
+	if strings.EqualFold(addr, c.username) {
+		return true
+	}
+	for _, alias := range c.opts.Aliases {
+		if strings.EqualFold(addr, alias) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetUsername returns the authenticated username
 func (c *Client) GetUsername() string {
 	return c.username
@@ -618,30 +1338,10 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Try common draft folder names
-	draftFolders := []string{"Drafts", "INBOX.Drafts", "[Gmail]/Drafts"}
-	var draftFolder string
-
-	// Find which draft folder exists
-	folders, err := c.listFolders()
+	// Find the Drafts folder
+	draftFolder, err := c.draftsFolder()
 	if err != nil {
-		return "", fmt.Errorf("failed to list folders: %w", err)
-	}
-	
-	for _, df := range draftFolders {
-		for _, f := range folders {
-			if f == df {
-				draftFolder = df
-				break
-			}
-		}
-		if draftFolder != "" {
-			break
-		}
-	}
-	
-	if draftFolder == "" {
-		draftFolder = "Drafts" // fallback default
+		return "", err
 	}
 
 	// Build email message
@@ -665,6 +1365,10 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 		if folder == "" {
 			folder = "INBOX"
 		}
+		folder, err = c.resolveFolder(folder)
+		if err != nil {
+			return "", err
+		}
 		
 		originalEmail, err := c.getEmail(folder, opts.ReplyToID)
 		if err != nil {
@@ -672,13 +1376,7 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 		}
 		
 		// Build reply subject
-		var replySubject string
-		if !strings.HasPrefix(strings.ToLower(originalEmail.Subject), "re:") {
-			replySubject = "Re: " + originalEmail.Subject
-		} else {
-			replySubject = originalEmail.Subject
-		}
-		subject = replySubject
+		subject = subjectpkg.ReplyWith(c.opts.ReplyPrefix, originalEmail.Subject)
 		
 		// Add reply headers
 		if originalEmail.MessageID != "" {
@@ -701,8 +1399,18 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
 	
 	// Generate Message-ID
-	messageID := fmt.Sprintf("<%s.%s@mcp-icloud-email>", uuid.New().String(), c.username)
+	messageID := msgid.New(c.messageIDDomain())
 	buf.WriteString(fmt.Sprintf("Message-ID: %s\r\n", messageID))
+
+	// Custom headers, sorted for stable output
+	headerKeys := make([]string, 0, len(opts.Headers))
+	for key := range opts.Headers {
+		headerKeys = append(headerKeys, key)
+	}
+	sort.Strings(headerKeys)
+	for _, key := range headerKeys {
+		buf.WriteString(fmt.Sprintf("%s: %s\r\n", key, opts.Headers[key]))
+	}
 	
 	// Content type
 	if opts.HTML {
@@ -723,7 +1431,7 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 	}
 	
 	// Get the UID of the appended message (select folder and get last message)
-	mbox, err := c.client.Select(draftFolder, false)
+	mbox, err := c.selectFolder(draftFolder, false)
 	if err != nil {
 		return "", fmt.Errorf("failed to select draft folder: %w", err)
 	}
@@ -734,20 +1442,59 @@ func (c *Client) SaveDraft(ctx context.Context, from string, to []string, subjec
 	return draftID, nil
 }
 
+// draftsFolder resolves the \Drafts mailbox, falling back to common names
+// (caller must hold c.mu)
+func (c *Client) draftsFolder() (string, error) {
+	drafts := folderAliases["drafts"]
+	return c.specialFolder(drafts.attr, drafts.fallbacks...)
+}
+
+// ListDrafts returns the envelopes of all messages in the Drafts folder
+func (c *Client) ListDrafts(ctx context.Context) ([]Email, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.draftsFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	emails, _, err := c.searchEmails(ctx, folder, "", EmailFilters{})
+	return emails, err
+}
+
+// DeleteDraft permanently removes a draft from the Drafts folder
+func (c *Client) DeleteDraft(ctx context.Context, emailID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.draftsFolder()
+	if err != nil {
+		return err
+	}
+
+	return c.expungeEmail(folder, emailID)
+}
+
 // GetAttachment downloads a specific attachment from an email
 func (c *Client) GetAttachment(ctx context.Context, folder, emailID, filename string) (*AttachmentData, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
 	// Parse UID
 	var uid uint32
 	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-		return nil, fmt.Errorf("invalid email ID format: %w", err)
+		return nil, fmt.Errorf("%w format: %w", ErrInvalidID, err)
 	}
 
 	// Create sequence set
@@ -755,20 +1502,21 @@ func (c *Client) GetAttachment(ctx context.Context, folder, emailID, filename st
 	seqSet.AddNum(uid)
 
 	// First, fetch BODYSTRUCTURE to find the attachment
-	messages := make(chan *imap.Message, 1)
-	done := make(chan error, 1)
-	
-	go func() {
-		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchBodyStructure}, messages)
-	}()
-
-	msg := <-messages
+	var msg *imap.Message
+	err = c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchBodyStructure}, messages)
+	}, func(m *imap.Message) {
+		if msg == nil {
+			msg = m
+		}
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
 	if msg == nil {
-		<-done
-		return nil, fmt.Errorf("email not found")
+		return nil, fmt.Errorf("email %w", ErrNotFound)
 	}
-
-	if err := <-done; err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch email structure: %w", err)
 	}
 
@@ -777,21 +1525,22 @@ func (c *Client) GetAttachment(ctx context.Context, folder, emailID, filename st
 	// For now, we'll fetch the entire message and parse it
 	
 	// Fetch full message body
-	messages2 := make(chan *imap.Message, 1)
-	done2 := make(chan error, 1)
+	var msg2 *imap.Message
 	section := &imap.BodySectionName{}
-	
-	go func() {
-		done2 <- c.client.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, messages2)
-	}()
-
-	msg2 := <-messages2
+	err = c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, messages)
+	}, func(m *imap.Message) {
+		if msg2 == nil {
+			msg2 = m
+		}
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
 	if msg2 == nil {
-		<-done2
-		return nil, fmt.Errorf("email not found")
+		return nil, fmt.Errorf("email %w", ErrNotFound)
 	}
-
-	if err := <-done2; err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch message body: %w", err)
 	}
 
@@ -819,6 +1568,9 @@ func (c *Client) GetAttachment(ctx context.Context, folder, emailID, filename st
 
 	// Look for the attachment
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		part, err := mr.NextPart()
 		if err == io.EOF {
 			break
@@ -848,7 +1600,7 @@ func (c *Client) GetAttachment(ctx context.Context, folder, emailID, filename st
 		}
 	}
 
-	return nil, fmt.Errorf("attachment '%s' not found in email", filename)
+	return nil, fmt.Errorf("attachment '%s' %w in email", filename, ErrNotFound)
 }
 
 // FlagEmail sets or removes flags on an email
@@ -856,20 +1608,56 @@ func (c *Client) FlagEmail(ctx context.Context, folder, emailID, flagType, color
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+
 	// Select the mailbox
-	if _, err := c.client.Select(folder, false); err != nil {
+	if _, err := c.selectFolder(folder, false); err != nil {
 		return fmt.Errorf("failed to select folder %s: %w", folder, err)
 	}
 
 	// Parse UID
 	var uid uint32
 	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
-		return fmt.Errorf("invalid email ID format: %w", err)
+		return fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	return c.storeFlags([]uint32{uid}, flagType, color)
+}
+
+// FlagEmailBulk sets or removes flags on several emails in one folder with a
+// single UID STORE. An empty emailIDs is a no-op.
+func (c *Client) FlagEmailBulk(ctx context.Context, folder string, emailIDs []string, flagType, color string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+
+	uids, err := parseUIDs(emailIDs)
+	if err != nil {
+		return err
+	}
+	if len(uids) == 0 {
+		return nil
 	}
 
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+	return c.storeFlags(uids, flagType, color)
+}
+
+// storeFlags sets flagType and color on the given UIDs of the selected
+// folder, or with flagType "none" removes every flag (caller must hold c.mu)
+func (c *Client) storeFlags(uids []uint32, flagType, color string) error {
 	// Create sequence set
 	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uid)
+	seqSet.AddNum(uids...)
 
 	if flagType == "none" {
 		// Remove all flags
@@ -893,38 +1681,16 @@ func (c *Client) FlagEmail(ctx context.Context, folder, emailID, flagType, color
 	}
 
 	// Build flag list
+	if flagType == "" {
+		return errors.New("flag type is required")
+	}
+	keywords, err := FlagKeywords(flagType, color)
+	if err != nil {
+		return err
+	}
 	flags := []interface{}{imap.FlaggedFlag}
-	
-	// Add flag type keyword
-	switch flagType {
-	case "follow-up":
-		flags = append(flags, "$FollowUp")
-	case "important":
-		flags = append(flags, "$Important")
-	case "deadline":
-		flags = append(flags, "$Deadline")
-	default:
-		return fmt.Errorf("invalid flag type: %s", flagType)
-	}
-
-	// Add color keyword if provided
-	if color != "" {
-		switch color {
-		case "red":
-			flags = append(flags, "$FlagRed")
-		case "orange":
-			flags = append(flags, "$FlagOrange")
-		case "yellow":
-			flags = append(flags, "$FlagYellow")
-		case "green":
-			flags = append(flags, "$FlagGreen")
-		case "blue":
-			flags = append(flags, "$FlagBlue")
-		case "purple":
-			flags = append(flags, "$FlagPurple")
-		default:
-			return fmt.Errorf("invalid color: %s", color)
-		}
+	for _, keyword := range keywords {
+		flags = append(flags, keyword)
 	}
 
 	// Set the flags
@@ -936,23 +1702,46 @@ func (c *Client) FlagEmail(ctx context.Context, folder, emailID, flagType, color
 	return nil
 }
 
-// CreateFolder creates a new mailbox folder
-func (c *Client) CreateFolder(ctx context.Context, name, parent string) error {
+// CreateFolder creates a new mailbox folder, nested under parent when one is
+// given, and returns its full path. Paths deeper than opts.MaxFolderDepth are
+// refused with ErrFolderTooDeep. With opts.Use, the folder is tagged with
+// that special-use attribute where the server allows it. The folder is
+// subscribed unless opts.SkipSubscribe is set; a failed subscribe is logged,
+// not returned, since the folder itself exists.
+func (c *Client) CreateFolder(ctx context.Context, name, parent string, opts CreateFolderOptions) (string, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Construct full folder path
-	folderPath := name
-	if parent != "" {
-		folderPath = parent + "/" + name
+	// A parent such as "archive" names the special-use folder
+	parent, err := c.resolveFolder(parent)
+	if err != nil {
+		return "", err
+	}
+
+	// Construct full folder path with the server's delimiter
+	folderPath, err := c.folderPath(parent, name)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkFolderDepth(folderPath); err != nil {
+		return "", err
 	}
 
 	// Create the folder
-	if err := c.client.Create(folderPath); err != nil {
-		return fmt.Errorf("failed to create folder %s: %w", folderPath, err)
+	if err := c.createMailbox(folderPath, opts.Use); err != nil {
+		if isExistingMailbox(err) {
+			err = existingMailboxError{err}
+		}
+		return "", fmt.Errorf("failed to create folder %s: %w", folderPath, err)
 	}
 
-	return nil
+	if !opts.SkipSubscribe {
+		if err := c.client.Subscribe(folderPath); err != nil {
+			slog.Warn("failed to subscribe new folder", "folder", folderPath, "error", err)
+		}
+	}
+
+	return folderPath, nil
 }
 
 // DeleteFolder deletes a mailbox folder
@@ -960,6 +1749,13 @@ func (c *Client) DeleteFolder(ctx context.Context, name string, force bool) (was
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if err := c.checkProtected(name); err != nil {
+		return false, 0, err
+	}
+	if name, err = c.resolveFolder(name); err != nil {
+		return false, 0, err
+	}
+
 	// Check if folder exists and count emails
 	count, countErr := c.countEmails(name, EmailFilters{})
 	if countErr != nil {
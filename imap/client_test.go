@@ -0,0 +1,2862 @@
+package imap
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// --- MoveEmail ---
+
+func TestMoveEmailSkipIfDuplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		archive     []*imap.Message
+		opts        MoveOptions
+		wantSkipped bool
+		wantMove    bool
+	}{
+		{
+			name:        "duplicate found skips move",
+			archive:     []*imap.Message{newTestMessage(7, "Hello", "<abc@example.com>")},
+			opts:        MoveOptions{SkipIfDuplicate: true},
+			wantSkipped: true,
+		},
+		{
+			name:     "not found moves",
+			opts:     MoveOptions{SkipIfDuplicate: true},
+			wantMove: true,
+		},
+		{
+			name:     "option off moves without lookup",
+			archive:  []*imap.Message{newTestMessage(7, "Hello", "<abc@example.com>")},
+			wantMove: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{
+				Mailboxes: map[string][]*imap.Message{
+					"INBOX":   {newTestMessage(42, "Hello", "<abc@example.com>")},
+					"Archive": tt.archive,
+				},
+			}
+			c := newTestClient(m)
+
+			skipped, err := c.MoveEmail(context.Background(), "INBOX", "Archive", "42", tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+			if moved := m.Called("UidMove") > 0; moved != tt.wantMove {
+				t.Errorf("UidMove called = %v, want %v", moved, tt.wantMove)
+			}
+			if tt.opts.SkipIfDuplicate {
+				if got := m.LastCriteria.Header.Get("Message-Id"); got != "<abc@example.com>" {
+					t.Errorf("searched Message-Id = %q, want <abc@example.com>", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveEmailPreserveFlags(t *testing.T) {
+	source := newTestMessage(42, "Hello", "<abc@example.com>")
+	source.Flags = []string{imap.SeenFlag, imap.FlaggedFlag, imap.RecentFlag}
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{
+			"INBOX":   {source},
+			"Archive": nil,
+		},
+		// No MOVE support: COPY + delete fallback, then the copy is found
+		Errs:          map[string]error{"UidMove": errors.New("MOVE not supported")},
+		SearchResults: map[string][]uint32{"Archive": {3, 9}},
+	}
+	c := newTestClient(m)
+
+	if _, err := c.MoveEmail(context.Background(), "INBOX", "Archive", "42", MoveOptions{PreserveFlags: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("UidCopy") != 1 || m.Called("Expunge") != 1 {
+		t.Fatalf("UidCopy %d, Expunge %d; want the copy fallback", m.Called("UidCopy"), m.Called("Expunge"))
+	}
+	if got := m.LastCriteria.Header.Get("Message-Id"); got != "<abc@example.com>" {
+		t.Errorf("searched Message-Id = %q", got)
+	}
+
+	// The last store adds the source flags to the newest matching copy
+	if m.Selected != "Archive" || !m.LastSeqSet.Contains(9) || m.LastSeqSet.Contains(3) {
+		t.Errorf("stored on %v in %s, want UID 9 in Archive", m.LastSeqSet, m.Selected)
+	}
+	if m.LastStoreItem != imap.FormatFlagsOp(imap.AddFlags, true) {
+		t.Errorf("store item = %s", m.LastStoreItem)
+	}
+	values, _ := m.LastStoreValue.([]interface{})
+	if len(values) != 2 || values[0] != imap.SeenFlag || values[1] != imap.FlaggedFlag {
+		t.Errorf("stored flags = %v, want \\Seen \\Flagged without \\Recent", values)
+	}
+
+	// Without the option only the move's own \Deleted store happens
+	m.Calls = nil
+	if _, err := c.MoveEmail(context.Background(), "INBOX", "Archive", "42", MoveOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("UidStore") != 1 || m.Called("UidSearch") != 0 {
+		t.Errorf("UidStore %d, UidSearch %d without preserve_flags", m.Called("UidStore"), m.Called("UidSearch"))
+	}
+}
+
+func TestMoveEmailPreserveFlagsCopyUID(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities []string
+		copyUID      uint32
+		wantCommand  string
+		wantUID      uint32
+		wantSearch   bool
+	}{
+		{name: "move", capabilities: []string{"UIDPLUS", "MOVE"}, copyUID: 17, wantCommand: "UID MOVE", wantUID: 17},
+		{name: "copy", capabilities: []string{"UIDPLUS"}, copyUID: 17, wantCommand: "UID COPY", wantUID: 17},
+		{name: "no COPYUID sent", capabilities: []string{"UIDPLUS", "MOVE"}, wantCommand: "UID MOVE", wantUID: 9, wantSearch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newTestMessage(42, "Hello", "<abc@example.com>")
+			source.Flags = []string{imap.SeenFlag, imap.FlaggedFlag}
+			m := &MockBackend{
+				Mailboxes: map[string][]*imap.Message{
+					"INBOX":   {source},
+					"Archive": nil,
+				},
+				Capabilities: tt.capabilities,
+				CopyUID:      tt.copyUID,
+				// A Message-ID search finds UID 9, which COPYUID overrides
+				SearchResults: map[string][]uint32{"Archive": {3, 9}},
+			}
+			c := newTestClient(m)
+
+			if _, err := c.MoveEmail(context.Background(), "INBOX", "Archive", "42", MoveOptions{PreserveFlags: true}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Called(tt.wantCommand) != 1 || m.LastDest != "Archive" {
+				t.Errorf("calls = %v, dest %q; want one %s to Archive", m.Calls, m.LastDest, tt.wantCommand)
+			}
+			if tt.wantCommand == "UID COPY" && m.Called("Expunge") != 1 {
+				t.Error("source not expunged after UID COPY")
+			}
+			if searched := m.Called("UidSearch") > 0; searched != tt.wantSearch {
+				t.Errorf("searched by Message-ID = %v, want %v", searched, tt.wantSearch)
+			}
+			if m.Selected != "Archive" || !m.LastSeqSet.Contains(tt.wantUID) || m.LastSeqSet.Contains(42) {
+				t.Errorf("stored on %v in %s, want UID %d in Archive", m.LastSeqSet, m.Selected, tt.wantUID)
+			}
+		})
+	}
+}
+
+func TestFindByMessageID(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:     map[string][]*imap.Message{"INBOX": nil, "Archive": nil},
+		SearchResults: map[string][]uint32{"Archive": {3, 9, 5}},
+	}
+	c := newTestClient(m)
+
+	id, err := c.FindByMessageID(context.Background(), "Archive", "<abc@example.com>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "9" {
+		t.Errorf("id = %s, want the newest UID 9", id)
+	}
+	if got := m.LastCriteria.Header.Get("Message-Id"); got != "<abc@example.com>" {
+		t.Errorf("searched Message-Id = %q", got)
+	}
+
+	if _, err := c.FindByMessageID(context.Background(), "INBOX", "<abc@example.com>"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+// --- SearchEmails ---
+
+func TestSearchEmailsPartialFetch(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{
+			"INBOX": {newTestMessage(1, "First", "<1@x>"), newTestMessage(2, "Second", "<2@x>")},
+		},
+		SearchResults: map[string][]uint32{"INBOX": {1, 2, 3}},
+		Errs:          map[string]error{"UidFetch": errors.New("server hiccup")},
+	}
+	c := newTestClient(m)
+
+	emails, total, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if !errors.Is(err, ErrPartialResults) {
+		t.Fatalf("err = %v, want ErrPartialResults", err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("got %d emails, want 2", len(emails))
+	}
+	if emails[0].Subject != "First" || emails[1].Subject != "Second" {
+		t.Errorf("unexpected emails: %+v", emails)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+}
+
+func TestSearchEmailsFetchFailsWithNothing(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:     map[string][]*imap.Message{"INBOX": nil},
+		SearchResults: map[string][]uint32{"INBOX": {1}},
+		Errs:          map[string]error{"UidFetch": errors.New("boom")},
+	}
+	c := newTestClient(m)
+
+	emails, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if err == nil || errors.Is(err, ErrPartialResults) {
+		t.Fatalf("err = %v, want hard failure", err)
+	}
+	if emails != nil {
+		t.Errorf("emails = %v, want nil", emails)
+	}
+}
+
+func TestSearchEmailsBatchedFetch(t *testing.T) {
+	var msgs []*imap.Message
+	for uid := uint32(1); uid <= 120; uid++ {
+		msgs = append(msgs, newTestMessage(uid, fmt.Sprintf("Message %d", uid), fmt.Sprintf("<%d@x>", uid)))
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": msgs}}
+	c := newTestClient(m)
+	c.opts.FetchBatchSize = 50
+
+	emails, total, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := m.Called("UidFetch"); n != 3 {
+		t.Errorf("UidFetch called %d times, want 3 batches", n)
+	}
+	if total != 120 || len(emails) != 120 {
+		t.Fatalf("got %d of %d emails, want 120", len(emails), total)
+	}
+	for i, e := range emails {
+		if want := fmt.Sprint(i + 1); e.ID != want {
+			t.Fatalf("emails[%d].ID = %s, want %s", i, e.ID, want)
+		}
+	}
+}
+
+func TestSearchEmailsReferences(t *testing.T) {
+	msg := newTestMessage(1, "Re: Budget", "<c@x>")
+	msg.Envelope.InReplyTo = "<b@x>"
+	msg.Body = map[*imap.BodySectionName]imap.Literal{
+		{BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier, Fields: []string{"References"}}}: bytes.NewBufferString("References: <a@x>\r\n <b@x>\r\n\r\n"),
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {msg}}}
+	c := newTestClient(m)
+
+	emails, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(emails[0].References, " "); got != "<a@x> <b@x>" {
+		t.Errorf("references = %q, want <a@x> <b@x>", got)
+	}
+}
+
+func TestSearchEmailsPaged(t *testing.T) {
+	var msgs []*imap.Message
+	for uid := uint32(1); uid <= 7; uid++ {
+		msgs = append(msgs, newTestMessage(uid, fmt.Sprintf("Message %d", uid), fmt.Sprintf("<%d@x>", uid)))
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": msgs}}
+	c := newTestClient(m)
+
+	var pages [][]string
+	total, err := c.SearchEmailsPaged(context.Background(), "INBOX", "", EmailFilters{}, 3, func(page []Email) error {
+		var ids []string
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		pages = append(pages, ids)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 7 {
+		t.Errorf("total = %d, want 7", total)
+	}
+	if n := m.Called("UidFetch"); n != 3 {
+		t.Errorf("UidFetch called %d times, want one per page", n)
+	}
+	if got := fmt.Sprint(pages); got != "[[1 2 3] [4 5 6] [7]]" {
+		t.Errorf("pages = %s, want [[1 2 3] [4 5 6] [7]]", got)
+	}
+}
+
+func TestSearchEmailsPagedStops(t *testing.T) {
+	var msgs []*imap.Message
+	for uid := uint32(1); uid <= 4; uid++ {
+		msgs = append(msgs, newTestMessage(uid, "Hi", fmt.Sprintf("<%d@x>", uid)))
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": msgs}}
+	c := newTestClient(m)
+
+	stop := errors.New("client went away")
+	_, err := c.SearchEmailsPaged(context.Background(), "INBOX", "", EmailFilters{}, 2, func(page []Email) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want the page error", err)
+	}
+	if n := m.Called("UidFetch"); n != 1 {
+		t.Errorf("UidFetch called %d times, want no fetch after the page error", n)
+	}
+}
+
+func TestSearchEmailsPagedRetriesPage(t *testing.T) {
+	var msgs []*imap.Message
+	for uid := uint32(1); uid <= 4; uid++ {
+		msgs = append(msgs, newTestMessage(uid, "Hi", fmt.Sprintf("<%d@x>", uid)))
+	}
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{"INBOX": msgs},
+		// The second page's first fetch is refused
+		Transient: map[string][]error{"UidFetch": {nil, errors.New("Server busy, please try again later")}},
+	}
+	c := newTestClient(m)
+	c.opts.Retry = RetryOptions{Attempts: 1}
+
+	var ids []string
+	_, err := c.SearchEmailsPaged(context.Background(), "INBOX", "", EmailFilters{}, 2, func(page []Email) error {
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(ids, ","); got != "1,2,3,4" {
+		t.Errorf("emails = %s, want each once in order", got)
+	}
+	if n := m.Called("UidSearch"); n != 1 {
+		t.Errorf("UidSearch called %d times, want the search left alone by a page retry", n)
+	}
+}
+
+// --- Calendar invitations ---
+
+const inviteMessage = "From: organizer@example.com\r\n" +
+	"To: me@icloud.com\r\n" +
+	"Subject: Invitation: Planning\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"You have been invited.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/calendar; charset=utf-8; method=REQUEST\r\n" +
+	"\r\n" +
+	"BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Quarterly planning\\, Q3\r\n" +
+	"DTSTART:20240115T150000Z\r\n" +
+	"DTEND:20240115T160000Z\r\n" +
+	"ORGANIZER;CN=\"Olivia Org\":mailto:organizer@example.com\r\n" +
+	"LOCATION:Room 4\r\n" +
+	" B\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n" +
+	"--b1--\r\n"
+
+func TestParseEmailBodyCalendarEvent(t *testing.T) {
+	c := newTestClient(&MockBackend{})
+	email := &Email{}
+	c.parseEmailBody(email, bytes.NewBufferString(inviteMessage))
+
+	ev := email.CalendarEvent
+	if ev == nil {
+		t.Fatal("expected CalendarEvent to be parsed")
+	}
+	if ev.Summary != "Quarterly planning, Q3" {
+		t.Errorf("Summary = %q", ev.Summary)
+	}
+	if want := time.Date(2024, 1, 15, 15, 0, 0, 0, time.UTC); !ev.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", ev.Start, want)
+	}
+	if want := time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC); !ev.End.Equal(want) {
+		t.Errorf("End = %v, want %v", ev.End, want)
+	}
+	if ev.Organizer != "Olivia Org <organizer@example.com>" {
+		t.Errorf("Organizer = %q", ev.Organizer)
+	}
+	if ev.Location != "Room 4B" {
+		t.Errorf("Location = %q, want folded line joined", ev.Location)
+	}
+	if email.BodyPlain == "" {
+		t.Error("expected plain text body to still be parsed")
+	}
+}
+
+func TestParseCalendarEventNoEvent(t *testing.T) {
+	if ev := parseCalendarEvent("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"); ev != nil {
+		t.Errorf("expected nil event, got %+v", ev)
+	}
+}
+
+// --- SaveDraft ---
+
+func TestSaveDraftWritesHeaders(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil, "Drafts": nil}}
+	c := newTestClient(m)
+
+	opts := DraftOptions{Headers: map[string]string{"X-Priority": "1 (Highest)", "Importance": "high"}}
+	if _, err := c.SaveDraft(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Body", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.AppendedBodies) != 1 || m.Appended[0] != "Drafts" {
+		t.Fatalf("expected one append to Drafts, got %v", m.Appended)
+	}
+	raw := m.AppendedBodies[0]
+	for _, want := range []string{"X-Priority: 1 (Highest)\r\n", "Importance: high\r\n"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("draft missing header %q:\n%s", want, raw)
+		}
+	}
+}
+
+func TestSaveDraftMessageIDDomain(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ClientOptions
+		wantSuffix string
+	}{
+		{name: "defaults to account domain", wantSuffix: "@icloud.com>"},
+		{name: "configured domain", opts: ClientOptions{MessageIDDomain: "example.org"}, wantSuffix: "@example.org>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Drafts": nil}}
+			c := newTestClient(m)
+			c.opts = tt.opts
+
+			if _, err := c.SaveDraft(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Body", DraftOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(m.AppendedBodies[0]))
+			if err != nil {
+				t.Fatalf("failed to parse draft: %v", err)
+			}
+			if id := msg.Header.Get("Message-Id"); !strings.HasSuffix(id, tt.wantSuffix) {
+				t.Errorf("Message-ID = %q, want suffix %q", id, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestListDrafts(t *testing.T) {
+	tests := []struct {
+		name       string
+		mailboxes  map[string][]*imap.Message
+		attributes map[string][]string
+		wantFolder string
+	}{
+		{
+			name: "special-use folder",
+			mailboxes: map[string][]*imap.Message{
+				"INBOX":      {newTestMessage(1, "Inbox mail", "<a@x>")},
+				"Brouillons": {newTestMessage(7, "Draft one", "<d1@x>"), newTestMessage(9, "Draft two", "<d2@x>")},
+			},
+			attributes: map[string][]string{"Brouillons": {imap.DraftsAttr}},
+			wantFolder: "Brouillons",
+		},
+		{
+			name: "fallback name",
+			mailboxes: map[string][]*imap.Message{
+				"INBOX":  {newTestMessage(1, "Inbox mail", "<a@x>")},
+				"Drafts": {newTestMessage(7, "Draft one", "<d1@x>"), newTestMessage(9, "Draft two", "<d2@x>")},
+			},
+			wantFolder: "Drafts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: tt.mailboxes, Attributes: tt.attributes}
+			c := newTestClient(m)
+
+			drafts, err := c.ListDrafts(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Selected != tt.wantFolder {
+				t.Errorf("selected %q, want %q", m.Selected, tt.wantFolder)
+			}
+			if len(drafts) != 2 || drafts[0].Subject != "Draft one" || drafts[1].ID != "9" {
+				t.Errorf("drafts = %+v", drafts)
+			}
+		})
+	}
+}
+
+func TestDeleteDraft(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:  map[string][]*imap.Message{"INBOX": nil, "Drafts": {newTestMessage(7, "Draft", "<d@x>")}},
+		Attributes: map[string][]string{"Drafts": {imap.DraftsAttr}},
+	}
+	c := newTestClient(m)
+
+	if err := c.DeleteDraft(context.Background(), "7"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Selected != "Drafts" {
+		t.Errorf("selected %q, want Drafts", m.Selected)
+	}
+	if m.Called("UidStore") != 1 || m.Called("Expunge") != 1 {
+		t.Errorf("calls = %v, want UidStore then Expunge", m.Calls)
+	}
+	if flags, ok := m.LastStoreValue.([]interface{}); !ok || len(flags) != 1 || flags[0] != imap.DeletedFlag {
+		t.Errorf("stored flags = %v, want [\\Deleted]", m.LastStoreValue)
+	}
+}
+
+func TestParseEmailBodyBestBody(t *testing.T) {
+	const header = "From: alice@example.com\r\nSubject: Hi\r\n"
+
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "plain only",
+			msg:  header + "Content-Type: text/plain; charset=utf-8\r\n\r\nHello Bob,\r\nSee you soon.\r\n",
+			want: "Hello Bob,\r\nSee you soon.",
+		},
+		{
+			name: "html only",
+			msg:  header + "Content-Type: text/html; charset=utf-8\r\n\r\n<p>Hello <b>Bob</b> &amp; team</p>",
+			want: "Hello Bob & team",
+		},
+		{
+			name: "both present prefers plain",
+			msg: header + "Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+				"--b1\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nHello Bob, see you soon.\r\n" +
+				"--b1\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Hello <b>Bob</b>, see you soon.</p>\r\n" +
+				"--b1--\r\n",
+			want: "Hello Bob, see you soon.",
+		},
+		{
+			name: "plain stub falls back to html",
+			msg: header + "Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+				"--b1\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nView in browser\r\n" +
+				"--b1\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Our quarterly newsletter has plenty of news for you this season, including product updates.</p>\r\n" +
+				"--b1--\r\n",
+			want: "Our quarterly newsletter has plenty of news for you this season, including product updates.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&MockBackend{})
+			email := &Email{}
+			c.parseEmailBody(email, bytes.NewBufferString(tt.msg))
+			if email.BestBody != tt.want {
+				t.Errorf("BestBody = %q, want %q", email.BestBody, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmailBodyAutoPlaintext(t *testing.T) {
+	const header = "From: alice@example.com\r\nSubject: Hi\r\n"
+	htmlOnly := header + "Content-Type: text/html; charset=utf-8\r\n\r\n<p>Hello <b>Bob</b> &amp; team</p>"
+	both := header + "Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+		"--b1\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nHello Bob\r\n" +
+		"--b1\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Hello <b>Bob</b></p>\r\n" +
+		"--b1--\r\n"
+
+	tests := []struct {
+		name      string
+		auto      bool
+		msg       string
+		wantPlain string
+		wantFrom  bool
+	}{
+		{name: "html only, on", auto: true, msg: htmlOnly, wantPlain: "Hello Bob & team", wantFrom: true},
+		{name: "html only, off", auto: false, msg: htmlOnly, wantPlain: ""},
+		{name: "plain part kept", auto: true, msg: both, wantPlain: "Hello Bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&MockBackend{})
+			c.opts.AutoPlaintext = tt.auto
+			email := &Email{}
+			c.parseEmailBody(email, bytes.NewBufferString(tt.msg))
+			if email.BodyPlain != tt.wantPlain || email.PlainFromHTML != tt.wantFrom {
+				t.Errorf("BodyPlain = %q, PlainFromHTML = %v; want %q, %v", email.BodyPlain, email.PlainFromHTML, tt.wantPlain, tt.wantFrom)
+			}
+			if tt.wantFrom && email.Snippet != tt.wantPlain {
+				t.Errorf("Snippet = %q, want the converted text", email.Snippet)
+			}
+		})
+	}
+}
+
+func TestParseEmailBodyAuthResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		want    *AuthResults
+	}{
+		{
+			name:    "all pass",
+			headers: "Authentication-Results: mx.icloud.com; dkim=pass (2048-bit key) header.d=example.com; spf=pass smtp.mailfrom=alice@example.com; dmarc=pass (p=REJECT) header.from=example.com\r\n",
+			want:    &AuthResults{DKIM: "pass", SPF: "pass", DMARC: "pass"},
+		},
+		{
+			name: "failures",
+			headers: "Authentication-Results: mx.icloud.com;\r\n dkim=fail reason=\"signature verification failed\" header.d=examp1e.com;\r\n spf=softfail smtp.mailfrom=examp1e.com; dmarc=FAIL header.from=examp1e.com\r\n" +
+				"Authentication-Results: relay.example.net; dkim=pass header.d=examp1e.com\r\n",
+			want: &AuthResults{DKIM: "fail", SPF: "softfail", DMARC: "fail"},
+		},
+		{
+			name:    "received-spf fallback",
+			headers: "Authentication-Results: mx.icloud.com; dkim=none\r\nReceived-SPF: Neutral (mx.icloud.com: 192.0.2.1 is neither permitted nor denied) client-ip=192.0.2.1\r\n",
+			want:    &AuthResults{DKIM: "none", SPF: "neutral"},
+		},
+		{
+			name: "no headers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := "From: alice@example.com\r\nSubject: Hi\r\n" + tt.headers +
+				"Content-Type: text/plain; charset=utf-8\r\n\r\nHello\r\n"
+			c := newTestClient(&MockBackend{})
+			email := &Email{}
+			c.parseEmailBody(email, bytes.NewBufferString(msg))
+
+			if (email.AuthResults == nil) != (tt.want == nil) {
+				t.Fatalf("AuthResults = %+v, want %+v", email.AuthResults, tt.want)
+			}
+			if tt.want != nil && *email.AuthResults != *tt.want {
+				t.Errorf("AuthResults = %+v, want %+v", *email.AuthResults, *tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmailBodyAutoReply(t *testing.T) {
+	tests := []struct {
+		name          string
+		headers       string
+		wantAutoReply bool
+		wantBulk      bool
+	}{
+		{name: "auto-submitted", headers: "Auto-Submitted: auto-replied (vacation)\r\n", wantAutoReply: true},
+		{name: "x-autoreply", headers: "X-Autoreply: yes\r\n", wantAutoReply: true},
+		{name: "precedence auto_reply", headers: "Precedence: auto_reply\r\n", wantAutoReply: true},
+		{name: "auto-generated is not a reply", headers: "Auto-Submitted: auto-generated\r\n"},
+		{name: "auto-submitted no", headers: "Auto-Submitted: no\r\n"},
+		{name: "bulk", headers: "Precedence: Bulk\r\n", wantBulk: true},
+		{name: "mailing list is not bulk", headers: "Precedence: list\r\n"},
+		{name: "no headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := "From: alice@example.com\r\nSubject: Away\r\n" + tt.headers +
+				"Content-Type: text/plain; charset=utf-8\r\n\r\nI am out of the office\r\n"
+			c := newTestClient(&MockBackend{})
+			email := &Email{}
+			c.parseEmailBody(email, bytes.NewBufferString(msg))
+
+			if email.AutoReply != tt.wantAutoReply || email.Bulk != tt.wantBulk {
+				t.Errorf("AutoReply = %v, Bulk = %v; want %v, %v", email.AutoReply, email.Bulk, tt.wantAutoReply, tt.wantBulk)
+			}
+		})
+	}
+}
+
+func TestParseEmailBodyEmbeddedMessage(t *testing.T) {
+	msg := "From: bob@example.com\r\n" +
+		"Subject: Fwd: Quarterly numbers\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=outer\r\n" +
+		"\r\n" +
+		"--outer\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"See the attached email.\r\n" +
+		"--outer\r\n" +
+		"Content-Type: message/rfc822\r\n" +
+		"Content-Disposition: attachment; filename=\"original.eml\"\r\n" +
+		"\r\n" +
+		"From: Alice Smith <alice@example.com>\r\n" +
+		"To: bob@example.com\r\n" +
+		"Subject: Quarterly numbers\r\n" +
+		"Date: Mon, 15 Jan 2024 10:00:00 +0000\r\n" +
+		"Message-Id: <q1@example.com>\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Revenue is up.\r\n" +
+		"--outer--\r\n"
+
+	c := newTestClient(&MockBackend{})
+	email := &Email{}
+	c.parseEmailBody(email, bytes.NewBufferString(msg))
+
+	if len(email.EmbeddedMessages) != 1 {
+		t.Fatalf("got %d embedded messages, want 1", len(email.EmbeddedMessages))
+	}
+	embedded := email.EmbeddedMessages[0]
+	if embedded.Subject != "Quarterly numbers" {
+		t.Errorf("Subject = %q", embedded.Subject)
+	}
+	if embedded.From != "Alice Smith <alice@example.com>" {
+		t.Errorf("From = %q", embedded.From)
+	}
+	if strings.Join(embedded.To, ",") != "bob@example.com" || embedded.MessageID != "<q1@example.com>" {
+		t.Errorf("To = %q, MessageID = %q", embedded.To, embedded.MessageID)
+	}
+	if !embedded.Date.Equal(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date = %v", embedded.Date)
+	}
+	if strings.TrimSpace(embedded.BodyPlain) != "Revenue is up." {
+		t.Errorf("BodyPlain = %q", embedded.BodyPlain)
+	}
+
+	// The outer message keeps its own body and still lists the .eml
+	if strings.TrimSpace(email.BodyPlain) != "See the attached email." {
+		t.Errorf("outer BodyPlain = %q", email.BodyPlain)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "original.eml" {
+		t.Errorf("Attachments = %+v, want original.eml", email.Attachments)
+	}
+}
+
+func TestBlockSender(t *testing.T) {
+	spam := func() *imap.Message {
+		msg := newTestMessage(42, "You won!", "<spam@x>")
+		msg.Envelope.From = []*imap.Address{{PersonalName: "Prize Desk", MailboxName: "Winner", HostName: "Spam.example"}}
+		return withBody(msg, "From: Prize Desk <Winner@Spam.example>\r\nSubject: You won!\r\n\r\nClaim now\r\n")
+	}
+	note := func(uid uint32, body string) *imap.Message {
+		return withBody(newTestMessage(uid, blockListSubject, "<note@x>"),
+			"Subject: "+blockListSubject+"\r\nContent-Type: text/plain\r\n\r\n"+body)
+	}
+
+	tests := []struct {
+		name        string
+		blockFolder []*imap.Message // nil means the folder doesn't exist yet
+		wantBlocked []string
+		wantAlready bool
+		wantAppend  bool
+		wantExpunge bool
+	}{
+		{
+			name:        "first block creates folder",
+			wantBlocked: []string{"winner@spam.example"},
+			wantAppend:  true,
+		},
+		{
+			name:        "adds to existing list",
+			blockFolder: []*imap.Message{note(3, "a@example.com\r\nz@example.com\r\n")},
+			wantBlocked: []string{"a@example.com", "winner@spam.example", "z@example.com"},
+			wantAppend:  true,
+			wantExpunge: true,
+		},
+		{
+			name:        "already blocked is idempotent",
+			blockFolder: []*imap.Message{note(3, "winner@spam.example\r\n")},
+			wantBlocked: []string{"winner@spam.example"},
+			wantAlready: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{
+				Mailboxes:  map[string][]*imap.Message{"INBOX": {spam()}, "Junk": nil},
+				Attributes: map[string][]string{"Junk": {imap.JunkAttr}},
+			}
+			if tt.blockFolder != nil {
+				m.Mailboxes[blockListFolder] = tt.blockFolder
+			}
+			c := newTestClient(m)
+
+			res, err := c.BlockSender(context.Background(), "INBOX", "42")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Sender != "winner@spam.example" || res.JunkFolder != "Junk" || m.LastDest != "Junk" {
+				t.Errorf("result = %+v, moved to %q", res, m.LastDest)
+			}
+			if res.AlreadyBlocked != tt.wantAlready {
+				t.Errorf("AlreadyBlocked = %v, want %v", res.AlreadyBlocked, tt.wantAlready)
+			}
+			if strings.Join(res.Blocked, ",") != strings.Join(tt.wantBlocked, ",") {
+				t.Errorf("Blocked = %v, want %v", res.Blocked, tt.wantBlocked)
+			}
+			if tt.blockFolder == nil && m.Called("Create") != 1 {
+				t.Error("expected block list folder to be created")
+			}
+
+			if got := m.Called("Append") == 1; got != tt.wantAppend {
+				t.Fatalf("appended = %v, want %v", got, tt.wantAppend)
+			}
+			if tt.wantAppend {
+				if m.Appended[0] != blockListFolder {
+					t.Errorf("appended to %q, want %q", m.Appended[0], blockListFolder)
+				}
+				for _, addr := range tt.wantBlocked {
+					if !strings.Contains(m.AppendedBodies[0], addr+"\r\n") {
+						t.Errorf("note body missing %s:\n%s", addr, m.AppendedBodies[0])
+					}
+				}
+			}
+			if got := m.Called("Expunge") == 1; got != tt.wantExpunge {
+				t.Errorf("expunged old note = %v, want %v", got, tt.wantExpunge)
+			}
+		})
+	}
+}
+
+func TestResolveFolder(t *testing.T) {
+	folders := map[string][]*imap.Message{
+		"INBOX":            nil,
+		"Sent Messages":    nil,
+		"Deleted Messages": nil,
+		"Junk":             nil,
+		"Projects":         nil,
+		"Archive":          nil,
+	}
+
+	tests := []struct {
+		name       string
+		in         string
+		attributes map[string][]string
+		want       string
+	}{
+		{name: "sent by name", in: "sent", want: "Sent Messages"},
+		{name: "case-insensitive", in: "Trash", want: "Deleted Messages"},
+		{name: "inbox", in: "inbox", want: "INBOX"},
+		{name: "special-use wins", in: "archive", attributes: map[string][]string{"Projects": {imap.ArchiveAttr}}, want: "Projects"},
+		{name: "exact folder name", in: "Archive", attributes: map[string][]string{"Projects": {imap.ArchiveAttr}}, want: "Archive"},
+		{name: "unknown passes through", in: "Receipts/2024", want: "Receipts/2024"},
+		{name: "alias without match passes through", in: "drafts", want: "drafts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: folders, Attributes: tt.attributes}
+			c := newTestClient(m)
+
+			got, err := c.ResolveFolder(context.Background(), tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveFolder(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchEmailsResolvesAlias(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"INBOX":         nil,
+		"Sent Messages": {newTestMessage(5, "Sent one", "<s@x>")},
+	}}
+	c := newTestClient(m)
+
+	emails, _, _, err := c.SearchEmails(context.Background(), "sent", "", EmailFilters{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Selected != "Sent Messages" || len(emails) != 1 {
+		t.Errorf("selected %q with %d emails, want Sent Messages with 1", m.Selected, len(emails))
+	}
+}
+
+func TestUnfoldFlowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		body   string
+		want   string
+	}{
+		{
+			name:   "soft wraps joined, hard breaks kept",
+			params: "; format=flowed",
+			body:   "This is a long \r\nparagraph that was \r\nwrapped.\r\nNew line.\r\n\r\nSecond paragraph.\r\n",
+			want:   "This is a long paragraph that was wrapped.\nNew line.\n\nSecond paragraph.\n",
+		},
+		{
+			name:   "delsp removes wrap spaces",
+			params: "; format=flowed; delsp=yes",
+			body:   "super \r\ncalifragilistic\r\n",
+			want:   "supercalifragilistic\n",
+		},
+		{
+			name:   "quoted text and space-stuffing",
+			params: "; format=flowed",
+			body:   "I agree.\r\n> You said \r\n> this.\r\n>> Older \r\n>> quote.\r\n From here\r\n-- \r\nSig\r\n",
+			want:   "I agree.\n> You said this.\n>> Older quote.\nFrom here\n-- \nSig\n",
+		},
+		{
+			name: "fixed format untouched",
+			body: "Ends with space \r\nnext line\r\n",
+			want: "Ends with space \r\nnext line\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := "From: alice@example.com\r\nSubject: Hi\r\n" +
+				"Content-Type: text/plain; charset=utf-8" + tt.params + "\r\n\r\n" + tt.body
+			c := newTestClient(&MockBackend{})
+			email := &Email{}
+			c.parseEmailBody(email, bytes.NewBufferString(msg))
+
+			if email.Flowed != (tt.params != "") {
+				t.Errorf("Flowed = %v, want %v", email.Flowed, tt.params != "")
+			}
+			email.UnfoldFlowed()
+			if email.BodyPlain != tt.want {
+				t.Errorf("BodyPlain = %q, want %q", email.BodyPlain, tt.want)
+			}
+			if email.BestBody != strings.TrimSpace(tt.want) {
+				t.Errorf("BestBody = %q, want %q", email.BestBody, strings.TrimSpace(tt.want))
+			}
+		})
+	}
+}
+
+func TestFindLargeAttachments(t *testing.T) {
+	withParts := func(uid uint32, size uint32, parts ...*imap.BodyStructure) *imap.Message {
+		msg := newTestMessage(uid, "Files", "")
+		msg.Size = size
+		msg.BodyStructure = &imap.BodyStructure{MIMEType: "multipart", MIMESubType: "mixed", Parts: parts}
+		return msg
+	}
+	text := &imap.BodyStructure{MIMEType: "text", MIMESubType: "plain", Size: 2000}
+	file := func(name string, encoded uint32) *imap.BodyStructure {
+		return &imap.BodyStructure{
+			MIMEType: "application", MIMESubType: "pdf", Encoding: "base64", Size: encoded,
+			Disposition: "attachment", DispositionParams: map[string]string{"filename": name},
+		}
+	}
+
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"INBOX": {
+			withParts(1, 900_000, text, file("small.pdf", 800_000)), // under the threshold
+			withParts(2, 4_000_000, text, file("report.pdf", 2_000_000), file("scan.pdf", 3_600_000)),
+			withParts(3, 3_000_000, text), // large but no attachment
+		},
+		"Archive": {
+			withParts(7, 8_000_000, file("video.mov", 7_600_000)),
+		},
+	}}
+	c := newTestClient(m)
+
+	got, err := c.FindLargeAttachments(context.Background(), []string{"INBOX", "Archive"}, 1_000_000, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %+v, want emails 7 and 2", got)
+	}
+	if got[0].EmailID != "7" || got[0].Folder != "Archive" || got[0].Filename != "video.mov" || got[0].AttachmentSize != 5_700_000 {
+		t.Errorf("[0] = %+v", got[0])
+	}
+	// The largest attachment of email 2 is reported, not the first
+	if got[1].EmailID != "2" || got[1].Filename != "scan.pdf" || got[1].AttachmentSize != 2_700_000 || got[1].Size != 4_000_000 {
+		t.Errorf("[1] = %+v", got[1])
+	}
+	if m.LastCriteria.Larger != 1_000_000 {
+		t.Errorf("LARGER = %d, want 1000000", m.LastCriteria.Larger)
+	}
+
+	if got, _ := c.FindLargeAttachments(context.Background(), []string{"INBOX", "Archive"}, 1_000_000, 1); len(got) != 1 || got[0].EmailID != "7" {
+		t.Errorf("limited = %+v", got)
+	}
+}
+
+func TestRecentRecipients(t *testing.T) {
+	sentTo := func(uid uint32, day int, to ...*imap.Address) *imap.Message {
+		msg := newTestMessage(uid, "Hi", "")
+		msg.Envelope.Date = time.Date(2024, 1, day, 9, 0, 0, 0, time.UTC)
+		msg.Envelope.To = to
+		return msg
+	}
+	alice := &imap.Address{PersonalName: "Alice", MailboxName: "alice", HostName: "example.com"}
+	aliceUpper := &imap.Address{MailboxName: "ALICE", HostName: "Example.com"}
+	bob := &imap.Address{MailboxName: "bob", HostName: "example.com"}
+	carol := &imap.Address{PersonalName: "Carol", MailboxName: "carol", HostName: "example.org"}
+
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{
+			"INBOX": {sentTo(9, 20, &imap.Address{MailboxName: "inbox-only", HostName: "example.com"})},
+			"Sent Items": {
+				sentTo(1, 3, alice, bob),
+				sentTo(2, 10, carol),
+				sentTo(3, 12, aliceUpper),
+				sentTo(4, 5, bob),
+			},
+		},
+		Attributes: map[string][]string{"Sent Items": {imap.SentAttr}},
+	}
+	c := newTestClient(m)
+
+	got, err := c.RecentRecipients(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Correspondent{
+		{Address: "alice@example.com", Name: "Alice", LastContacted: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC)},
+		{Address: "carol@example.org", Name: "Carol", LastContacted: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)},
+		{Address: "bob@example.com", LastContacted: time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if m.Selected != "Sent Items" {
+		t.Errorf("scanned %q, want the \\Sent folder", m.Selected)
+	}
+
+	if got, _ := c.RecentRecipients(context.Background(), 0, 1); len(got) != 1 || got[0].Address != "alice@example.com" {
+		t.Errorf("limited = %+v", got)
+	}
+}
+
+func TestSearchEmailsExcluding(t *testing.T) {
+	var msgs []*imap.Message
+	for uid := uint32(1); uid <= 5; uid++ {
+		msgs = append(msgs, newTestMessage(uid, fmt.Sprintf("Message %d", uid), fmt.Sprintf("<%d@x>", uid)))
+	}
+	msgs = append(msgs, newTestMessage(6, "No ID", ""))
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": msgs}}
+	c := newTestClient(m)
+
+	// 4 and 5 are excluded first, so offset 1 skips 6 and limit 2 keeps 2, 3
+	emails, total, excluded, _, err := c.SearchEmailsExcluding(context.Background(), "INBOX", "", EmailFilters{Offset: 1, Limit: 2}, []string{"4@x", "<5@x>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, e := range emails {
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "2,3" || total != 4 || excluded != 2 {
+		t.Errorf("ids = %v, total = %d, excluded = %d; want 2,3 of 4 with 2 excluded", ids, total, excluded)
+	}
+
+	// Envelopes are fetched only for the page
+	for _, item := range m.LastFetchItems {
+		if item == imap.FetchEnvelope {
+			return
+		}
+	}
+	t.Errorf("last fetch items = %v, want the page's envelopes", m.LastFetchItems)
+}
+
+func TestMessageIDs(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Done": {
+		newTestMessage(1, "Invoice", "<inv-1@shop.example>"),
+		newTestMessage(2, "No ID", ""),
+		newTestMessage(3, "Plans", " <plans@example.com> "),
+	}}}
+	c := newTestClient(m)
+
+	ids, err := c.MessageIDs(context.Background(), "Done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(ids, ",") != "inv-1@shop.example,plans@example.com" {
+		t.Errorf("ids = %v", ids)
+	}
+
+	// A partial fetch must not yield an incomplete exclusion set
+	m.Errs = map[string]error{"UidFetch": errors.New("connection reset")}
+	if _, err := c.MessageIDs(context.Background(), "Done"); err == nil {
+		t.Error("expected error for a failed fetch")
+	}
+}
+
+func TestCountBySender(t *testing.T) {
+	from := func(uid uint32, name, mailbox, host string) *imap.Message {
+		msg := newTestMessage(uid, "Hi", "")
+		msg.Envelope.From = []*imap.Address{{PersonalName: name, MailboxName: mailbox, HostName: host}}
+		return msg
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {
+		from(1, "News", "news", "shop.example"),
+		from(2, "Alice", "alice", "example.com"),
+		from(3, "", "NEWS", "shop.example"),
+		from(4, "Bob", "bob", "example.com"),
+		from(5, "News", "news", "shop.example"),
+		from(6, "", "alice", "example.com"),
+	}}}
+	c := newTestClient(m)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []SenderCount
+	}{
+		{
+			name: "all senders",
+			want: []SenderCount{
+				{Address: "news@shop.example", Name: "News", Count: 3},
+				{Address: "alice@example.com", Name: "Alice", Count: 2},
+				{Address: "bob@example.com", Name: "Bob", Count: 1},
+			},
+		},
+		{
+			name:  "limited",
+			limit: 2,
+			want: []SenderCount{
+				{Address: "news@shop.example", Name: "News", Count: 3},
+				{Address: "alice@example.com", Name: "Alice", Count: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.CountBySender(context.Background(), "INBOX", 30, tt.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if m.LastCriteria.Since.IsZero() {
+				t.Error("expected a since bound for lastDays")
+			}
+		})
+	}
+}
+
+func TestCountByDay(t *testing.T) {
+	on := func(uid uint32, date time.Time) *imap.Message {
+		msg := newTestMessage(uid, "Hi", "")
+		msg.Envelope.Date = date
+		return msg
+	}
+	day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.Local) }
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {
+		on(1, day(1, 9)),
+		on(2, day(1, 23)),
+		on(3, day(2, 0)),
+		on(4, day(4, 12)),
+		on(5, day(4, 13)),
+		on(6, day(4, 14)),
+		on(7, time.Time{}),
+	}}}
+	c := newTestClient(m)
+
+	got, err := c.CountByDay(context.Background(), "INBOX", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]int{"2024-03-01": 2, "2024-03-02": 1, "2024-03-04": 3}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for d, n := range want {
+		if got[d] != n {
+			t.Errorf("%s = %d, want %d", d, got[d], n)
+		}
+	}
+	if m.LastCriteria.Since.IsZero() {
+		t.Error("expected a since bound for lastDays")
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	at := func(msg *imap.Message, day int) *imap.Message {
+		msg.Envelope.Date = time.Date(2024, 1, day, 10, 0, 0, 0, time.UTC)
+		return msg
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {
+		at(newTestMessage(1, "Report", "<dup@x>"), 5),
+		at(newTestMessage(2, "Other", "<other@x>"), 5),
+		at(newTestMessage(3, "Report", "dup@x"), 2),
+		at(newTestMessage(4, "No ID", ""), 3),
+		at(newTestMessage(5, "No ID", ""), 3),
+		at(newTestMessage(6, "No ID", ""), 4),
+	}}}
+	c := newTestClient(m)
+
+	tests := []struct {
+		name         string
+		matchHeaders bool
+		want         []DuplicateGroup
+	}{
+		{
+			name: "by message-id",
+			want: []DuplicateGroup{
+				{MessageID: "dup@x", Subject: "Report", IDs: []string{"3", "1"}},
+			},
+		},
+		{
+			name:         "with header match",
+			matchHeaders: true,
+			want: []DuplicateGroup{
+				{MessageID: "dup@x", Subject: "Report", IDs: []string{"3", "1"}},
+				{Subject: "No ID", IDs: []string{"4", "5"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.FindDuplicates(context.Background(), "INBOX", tt.matchHeaders)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].MessageID != tt.want[i].MessageID || got[i].Subject != tt.want[i].Subject || strings.Join(got[i].IDs, ",") != strings.Join(tt.want[i].IDs, ",") {
+					t.Errorf("[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFolderFlags(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:      map[string][]*imap.Message{"INBOX": nil},
+		Flags:          []string{imap.SeenFlag, imap.FlaggedFlag, imap.DeletedFlag},
+		PermanentFlags: []string{imap.SeenFlag, imap.FlaggedFlag, imap.TryCreateFlag},
+	}
+	c := newTestClient(m)
+
+	flags, permanent, err := c.FolderFlags(context.Background(), "INBOX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(flags, " ") != `\Seen \Flagged \Deleted` {
+		t.Errorf("flags = %v", flags)
+	}
+	if strings.Join(permanent, " ") != `\Seen \Flagged \*` {
+		t.Errorf("permanent flags = %v", permanent)
+	}
+
+	if _, _, err := c.FolderFlags(context.Background(), "Missing"); err == nil {
+		t.Error("expected error for missing folder")
+	}
+}
+
+func newExportBackend() *MockBackend {
+	first := withBody(newTestMessage(7, "Hello", "<7@x>"), "From: alice@example.com\r\nSubject: Hello\r\n\r\nFrom now on\r\n>From the top\r\n")
+	first.InternalDate = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	second := withBody(newTestMessage(9, "Again", "<9@x>"), "From: alice@example.com\r\nSubject: Again\r\n\r\nBye\r\n")
+	second.InternalDate = time.Date(2024, 1, 16, 8, 30, 0, 0, time.UTC)
+	return &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {first, second}}}
+}
+
+func TestExportFolderMbox(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "inbox.mbox")
+	c := newTestClient(newExportBackend())
+
+	count, total, err := c.ExportFolder(context.Background(), "INBOX", dest, ExportMbox)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if total != 119 {
+		t.Errorf("total = %d, want 119", total)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "From alice@example.com Mon Jan 15 10:00:00 2024\n" +
+		"From: alice@example.com\nSubject: Hello\n\n>From now on\n>>From the top\n\n" +
+		"From alice@example.com Tue Jan 16 08:30:00 2024\n" +
+		"From: alice@example.com\nSubject: Again\n\nBye\n\n"
+	if string(data) != want {
+		t.Errorf("mbox =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestExportFolderZip(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "inbox.zip")
+	c := newTestClient(newExportBackend())
+
+	count, _, err := c.ExportFolder(context.Background(), "INBOX", dest, ExportZip)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	zr, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	want := map[string]string{"7.eml": "Subject: Hello", "9.eml": "Subject: Again"}
+	if len(zr.File) != len(want) {
+		t.Fatalf("zip has %d entries, want %d", len(zr.File), len(want))
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(body), want[f.Name]) || !strings.Contains(string(body), "\r\n") {
+			t.Errorf("%s = %q", f.Name, body)
+		}
+	}
+}
+
+func TestExportFolderErrors(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestClient(newExportBackend())
+
+	if _, _, err := c.ExportFolder(context.Background(), "INBOX", filepath.Join(dir, "x.tar"), "tar"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	m := newExportBackend()
+	m.Errs = map[string]error{"UidFetch": errors.New("connection reset")}
+	dest := filepath.Join(dir, "broken.mbox")
+	if _, _, err := newTestClient(m).ExportFolder(context.Background(), "INBOX", dest, ExportMbox); err == nil {
+		t.Fatal("expected fetch error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("partial export file was not removed")
+	}
+}
+
+func TestExportEmail(t *testing.T) {
+	raw := "From: alice@example.com\r\nSubject: Report\r\nDate: Mon, 15 Jan 2024 10:00:00 +0000\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nSee attached.\r\n" +
+		"--b\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment; filename=report.pdf\r\nContent-Transfer-Encoding: base64\r\n\r\nJVBERi0xLjQ=\r\n" +
+		"--b\r\nContent-Type: text/csv\r\nContent-Disposition: attachment; filename=\"../data.csv\"\r\n\r\na,b\r\n1,2\r\n" +
+		"--b\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment; filename=REPORT.pdf\r\n\r\nsecond\r\n" +
+		"--b\r\nContent-Type: application/x-msdownload\r\nContent-Disposition: attachment; filename=setup.exe\r\n\r\nMZ\r\n" +
+		"--b--\r\n"
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {withBody(newTestMessage(42, "Report", "<42@x>"), raw)}}}
+	c := newTestClient(m)
+	dest := filepath.Join(t.TempDir(), "report.zip")
+
+	noExe := func(filename, mimeType string) error {
+		if strings.HasSuffix(filename, ".exe") {
+			return errors.New("blocked")
+		}
+		return nil
+	}
+	exported, err := c.ExportEmail(context.Background(), "INBOX", "42", dest, noExe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(exported.Skipped, ","); got != "setup.exe" {
+		t.Errorf("skipped = %s, want setup.exe", got)
+	}
+	if exported.Size != int64(len(raw)) {
+		t.Errorf("size = %d, want %d", exported.Size, len(raw))
+	}
+
+	zr, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	want := []struct{ name, body string }{
+		{"42.eml", raw},
+		{"report.pdf", "%PDF-1.4"},
+		{".._data.csv", "a,b\r\n1,2"},
+		{"REPORT (2).pdf", "second"},
+	}
+	if len(zr.File) != len(want) {
+		t.Fatalf("zip has %d entries, want %d", len(zr.File), len(want))
+	}
+	for i, f := range zr.File {
+		if f.Name != want[i].name || exported.Entries[i] != want[i].name {
+			t.Errorf("entry %d = %s (reported %s), want %s", i, f.Name, exported.Entries[i], want[i].name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(body) != want[i].body {
+			t.Errorf("%s = %q, want %q", f.Name, body, want[i].body)
+		}
+	}
+}
+
+func TestExportEmailNotFound(t *testing.T) {
+	c := newTestClient(&MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}})
+	dest := filepath.Join(t.TempDir(), "missing.zip")
+
+	if _, err := c.ExportEmail(context.Background(), "INBOX", "42", dest, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("a file was left behind")
+	}
+}
+
+func TestImportMbox(t *testing.T) {
+	mbox := "From alice@example.com Mon Jan 15 10:00:00 2024\n" +
+		"From: alice@example.com\nDate: Mon, 15 Jan 2024 10:00:00 +0000\nSubject: Hello\n\n>From now on\n>>From the top\n\n" +
+		"From bob@example.com Tue Jan 16 08:30:00 2024\r\n" +
+		"From: bob@example.com\r\nDate: Tue, 16 Jan 2024 09:30:00 +0100\r\nSubject: Again\r\n\r\nBye\r\n\r\n"
+
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Imported": nil}}
+	c := newTestClient(m)
+
+	err := ReadMbox(strings.NewReader(mbox), func(raw []byte) error {
+		return c.AppendMessage(context.Background(), "Imported", raw, nil)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(m.Appended) != 2 {
+		t.Fatalf("appended %d messages, want 2", len(m.Appended))
+	}
+	wantBodies := []string{
+		"From: alice@example.com\r\nDate: Mon, 15 Jan 2024 10:00:00 +0000\r\nSubject: Hello\r\n\r\nFrom now on\r\n>From the top\r\n",
+		"From: bob@example.com\r\nDate: Tue, 16 Jan 2024 09:30:00 +0100\r\nSubject: Again\r\n\r\nBye\r\n",
+	}
+	wantDates := []time.Time{
+		time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 16, 8, 30, 0, 0, time.UTC),
+	}
+	for i := range wantBodies {
+		if m.Appended[i] != "Imported" {
+			t.Errorf("[%d] folder = %q", i, m.Appended[i])
+		}
+		if m.AppendedBodies[i] != wantBodies[i] {
+			t.Errorf("[%d] body = %q, want %q", i, m.AppendedBodies[i], wantBodies[i])
+		}
+		if !m.AppendedDates[i].Equal(wantDates[i]) {
+			t.Errorf("[%d] date = %v, want %v", i, m.AppendedDates[i], wantDates[i])
+		}
+	}
+}
+
+func TestReadMboxStopsOnError(t *testing.T) {
+	mbox := "From a Mon Jan 15 10:00:00 2024\nSubject: one\n\nx\n\nFrom b Mon Jan 15 10:00:00 2024\nSubject: two\n\ny\n"
+	calls := 0
+	err := ReadMbox(strings.NewReader(mbox), func(raw []byte) error {
+		calls++
+		return errors.New("quota exceeded")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v after %d calls, want error after 1", err, calls)
+	}
+}
+
+func TestGetHeaders(t *testing.T) {
+	raw := "Received: from a.example.com\r\nReceived: from b.example.com\r\nFrom: alice@example.com\r\n" +
+		"Subject: =?UTF-8?Q?Caf=C3=A9?=\r\nX-Mailer: Mail 16.0\r\n\r\nSecret body\r\n"
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"INBOX": {withBody(newTestMessage(5, "Café", "<5@x>"), raw)},
+	}}
+	c := newTestClient(m)
+
+	got, err := c.GetHeaders(context.Background(), "INBOX", "5", []string{"subject", "Received", "x-mailer", "List-Id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"Subject":  {"Café"},
+		"Received": {"from a.example.com", "from b.example.com"},
+		"X-Mailer": {"Mail 16.0"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if strings.Join(got[k], "|") != strings.Join(v, "|") {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	// Only the header fields were requested, never the body or envelope
+	var items []string
+	for _, item := range m.LastFetchItems {
+		items = append(items, string(item))
+	}
+	if strings.Join(items, " ") != "UID BODY.PEEK[HEADER.FIELDS (subject Received x-mailer List-Id)]" {
+		t.Errorf("fetch items = %v", items)
+	}
+
+	if _, err := c.GetHeaders(context.Background(), "INBOX", "5", nil); err == nil {
+		t.Error("expected error for empty field list")
+	}
+	if _, err := c.GetHeaders(context.Background(), "INBOX", "99", []string{"Subject"}); err == nil {
+		t.Error("expected error for missing email")
+	}
+}
+
+func TestMoveEmailBulk(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil, "Shopping": nil}}
+	c := newTestClient(m)
+
+	n, err := c.MoveEmailBulk(context.Background(), "INBOX", "Shopping", []string{"3", "7", "8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("moved = %d, want 3", n)
+	}
+	if m.Called("UidMove") != 1 {
+		t.Errorf("UidMove called %d times, want 1", m.Called("UidMove"))
+	}
+	if m.LastDest != "Shopping" || m.LastSeqSet.String() != "3,7:8" {
+		t.Errorf("moved %v to %q", m.LastSeqSet, m.LastDest)
+	}
+
+	if _, err := c.MoveEmailBulk(context.Background(), "INBOX", "Shopping", []string{"3", "abc"}); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+}
+
+func TestMarkReadBulk(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+	c := newTestClient(m)
+
+	n, err := c.MarkReadBulk(context.Background(), "INBOX", []string{"4", "5", "9"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("marked = %d, want 3", n)
+	}
+	if m.Called("UidStore") != 1 {
+		t.Errorf("UidStore called %d times, want 1", m.Called("UidStore"))
+	}
+	if m.LastSeqSet.String() != "4:5,9" || m.LastStoreItem != imap.FormatFlagsOp(imap.AddFlags, true) {
+		t.Errorf("stored %v on %v", m.LastStoreItem, m.LastSeqSet)
+	}
+
+	if n, err := c.MarkReadBulk(context.Background(), "INBOX", nil, true); err != nil || n != 0 {
+		t.Errorf("empty list = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := c.MarkReadBulk(context.Background(), "INBOX", []string{"4", "abc"}, true); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msgs := []*imap.Message{newTestMessage(1, "One", "<1@x>"), newTestMessage(2, "Two", "<2@x>")}
+	tests := []struct {
+		name string
+		call func(c *Client) error
+	}{
+		{"SearchEmails", func(c *Client) error {
+			_, _, _, err := c.SearchEmails(ctx, "INBOX", "", EmailFilters{})
+			return err
+		}},
+		{"GetAttachment", func(c *Client) error {
+			_, err := c.GetAttachment(ctx, "INBOX", "1", "a.pdf")
+			return err
+		}},
+		{"CountBySender", func(c *Client) error {
+			_, err := c.CountBySender(ctx, "INBOX", 0, 0)
+			return err
+		}},
+		{"MailboxStats", func(c *Client) error {
+			_, err := c.MailboxStats(ctx, StatsOptions{Folders: []string{"INBOX"}})
+			return err
+		}},
+		{"FindLargeAttachments", func(c *Client) error {
+			_, err := c.FindLargeAttachments(ctx, []string{"INBOX"}, 1, 0)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": msgs}}
+			c := newTestClient(m)
+
+			if err := tt.call(c); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+			if n := m.Called("UidFetch") + m.Called("Fetch"); n != 0 {
+				t.Errorf("fetched %d times with a cancelled context", n)
+			}
+		})
+	}
+}
+
+func TestFetchMessagesCancelMidFetch(t *testing.T) {
+	c := newTestClient(&MockBackend{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The fetch delivers one message, then stalls until released, like a
+	// server still streaming a large response
+	release := make(chan struct{})
+	fetch := func(messages chan *imap.Message) error {
+		defer close(messages)
+		messages <- newTestMessage(1, "One", "")
+		<-release
+		for uid := uint32(2); uid <= 20; uid++ {
+			messages <- newTestMessage(uid, "More", "")
+		}
+		return nil
+	}
+
+	seen := 0
+	err := c.fetchMessages(ctx, fetch, func(*imap.Message) {
+		seen++
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if seen != 1 {
+		t.Errorf("handled %d messages after cancelling, want 1", seen)
+	}
+
+	// The rest of the response is drained before the connection is reused
+	busy := c.busyUntil()
+	if busy == nil {
+		t.Fatal("connection not marked busy while the fetch drains")
+	}
+	close(release)
+	select {
+	case <-busy:
+	case <-time.After(time.Second):
+		t.Fatal("abandoned fetch was not drained")
+	}
+}
+
+func TestFlagEmailBulk(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+	c := newTestClient(m)
+
+	if err := c.FlagEmailBulk(context.Background(), "INBOX", []string{"4", "5", "9"}, "important", "red"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("UidStore") != 1 {
+		t.Errorf("UidStore called %d times, want 1", m.Called("UidStore"))
+	}
+	if m.LastSeqSet.String() != "4:5,9" || m.LastStoreItem != imap.FormatFlagsOp(imap.AddFlags, true) {
+		t.Errorf("stored %v on %v", m.LastStoreItem, m.LastSeqSet)
+	}
+
+	if err := c.FlagEmailBulk(context.Background(), "INBOX", nil, "important", ""); err != nil {
+		t.Errorf("empty list: %v", err)
+	}
+	if m.Called("UidStore") != 1 {
+		t.Error("empty list sent a UID STORE")
+	}
+	if err := c.FlagEmailBulk(context.Background(), "INBOX", []string{"4", "abc"}, "important", ""); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+}
+
+func TestSearchEmailsFromFilter(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+	c := newTestClient(m)
+
+	if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{From: "news@store.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := m.LastCriteria.Header.Get("From"); got != "news@store.com" {
+		t.Errorf("FROM criterion = %q", got)
+	}
+}
+
+func TestSearchEmailsRecipientFilter(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Sent Messages": nil}}
+	c := newTestClient(m)
+
+	if _, _, _, err := c.SearchEmails(context.Background(), "Sent Messages", "", EmailFilters{Recipient: "bob@example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.LastCriteria.Or) != 1 {
+		t.Fatalf("got %d OR criteria, want 1", len(m.LastCriteria.Or))
+	}
+	or := m.LastCriteria.Or[0]
+	if got := or[0].Header.Get("To"); got != "bob@example.com" {
+		t.Errorf("TO criterion = %q", got)
+	}
+	if got := or[1].Header.Get("Cc"); got != "bob@example.com" {
+		t.Errorf("CC criterion = %q", got)
+	}
+}
+
+func TestSearchUIDs(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:     map[string][]*imap.Message{"INBOX": nil},
+		SearchResults: map[string][]uint32{"INBOX": {3, 8, 12, 20, 41}},
+	}
+	c := newTestClient(m)
+
+	tests := []struct {
+		name    string
+		filters EmailFilters
+		want    string
+	}{
+		{name: "all", want: "3,8,12,20,41"},
+		{name: "limit keeps the most recent", filters: EmailFilters{Limit: 2}, want: "20,41"},
+		{name: "offset and limit", filters: EmailFilters{Offset: 1, Limit: 2}, want: "12,20"},
+		{name: "offset past the end", filters: EmailFilters{Offset: 5}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, total, _, err := c.SearchUIDs(context.Background(), "INBOX", "", tt.filters)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(ids, ","); got != tt.want || total != 5 {
+				t.Errorf("got %q of %d, want %q of 5", got, total, tt.want)
+			}
+		})
+	}
+	if n := m.Called("UidFetch"); n != 0 {
+		t.Errorf("UidFetch called %d times, want none", n)
+	}
+}
+
+func TestSearchEmailsKeywords(t *testing.T) {
+	tests := []struct {
+		flagType string
+		color    string
+		want     string
+	}{
+		{flagType: "follow-up", want: "$FollowUp"},
+		{flagType: "important", want: "$Important"},
+		{flagType: "deadline", want: "$Deadline"},
+		{color: "red", want: "$FlagRed"},
+		{color: "orange", want: "$FlagOrange"},
+		{color: "yellow", want: "$FlagYellow"},
+		{color: "green", want: "$FlagGreen"},
+		{color: "blue", want: "$FlagBlue"},
+		{color: "purple", want: "$FlagPurple"},
+		{flagType: "important", color: "green", want: "$Important,$FlagGreen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flagType+tt.color, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+			c := newTestClient(m)
+
+			keywords, err := FlagKeywords(tt.flagType, tt.color)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{Keywords: keywords}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(m.LastCriteria.WithFlags, ","); got != tt.want {
+				t.Errorf("KEYWORD criteria = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagEmailKeywords(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {newTestMessage(1, "Hi", "<1@x>")}}}
+	c := newTestClient(m)
+
+	if err := c.FlagEmail(context.Background(), "INBOX", "1", "deadline", "purple"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flags, _ := m.LastStoreValue.([]interface{})
+	if len(flags) != 3 || flags[0] != imap.FlaggedFlag || flags[1] != "$Deadline" || flags[2] != "$FlagPurple" {
+		t.Errorf("stored %v, want \\Flagged $Deadline $FlagPurple", m.LastStoreValue)
+	}
+
+	if err := c.FlagEmail(context.Background(), "INBOX", "1", "urgent", ""); err == nil || !strings.Contains(err.Error(), "invalid flag type") {
+		t.Errorf("error = %v, want an invalid flag type error", err)
+	}
+}
+
+func TestDeleteFolderAlias(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:  map[string][]*imap.Message{"INBOX": nil, "Deleted Messages": nil},
+		Attributes: map[string][]string{"Deleted Messages": {imap.TrashAttr}},
+	}
+	c := newTestClient(m)
+
+	if _, _, err := c.DeleteFolder(context.Background(), "trash", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := m.Mailboxes["Deleted Messages"]; ok {
+		t.Errorf("mailboxes = %v, want the \\Trash folder deleted", m.Mailboxes)
+	}
+}
+
+func TestDeleteFolderProtected(t *testing.T) {
+	tests := []struct {
+		name          string
+		folder        string
+		wantProtected bool
+	}{
+		{name: "inbox", folder: "INBOX", wantProtected: true},
+		{name: "inbox any case", folder: "inbox", wantProtected: true},
+		{name: "sent", folder: "Sent Messages", wantProtected: true},
+		{name: "trash by server name", folder: "Deleted Messages", wantProtected: true},
+		{name: "trash by alias", folder: "Trash", wantProtected: true},
+		{name: "ordinary folder", folder: "Projects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+				"INBOX":            {newTestMessage(1, "a", "<1@x>")},
+				"Sent Messages":    {newTestMessage(2, "b", "<2@x>")},
+				"Deleted Messages": {newTestMessage(3, "c", "<3@x>")},
+				"Projects":         {newTestMessage(4, "d", "<4@x>")},
+			}}
+			c := newTestClient(m)
+			c.opts.ProtectedFolders = []string{"INBOX", "Sent Messages", "trash"}
+
+			_, _, err := c.DeleteFolder(context.Background(), tt.folder, true)
+			if tt.wantProtected {
+				if !errors.Is(err, ErrProtectedFolder) {
+					t.Fatalf("err = %v, want ErrProtectedFolder", err)
+				}
+				if m.Called("Delete") != 0 {
+					t.Error("protected folder was deleted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Called("Delete") != 1 {
+				t.Error("folder was not deleted")
+			}
+		})
+	}
+}
+
+func TestGetEmailErrorSentinels(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {newTestMessage(1, "Hi", "<1@x>")}}}
+	c := newTestClient(m)
+
+	if _, err := c.GetEmail(context.Background(), "INBOX", "99"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing email: err = %v, want ErrNotFound", err)
+	}
+	if _, err := c.GetEmail(context.Background(), "Nowhere", "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing folder: err = %v, want ErrNotFound", err)
+	}
+	_, err := c.GetEmail(context.Background(), "INBOX", "abc")
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("bad id: err = %v, want ErrInvalidID", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "invalid email ID format") {
+		t.Errorf("bad id message = %q", err)
+	}
+}
+
+func TestGetEmailZeroDateFallsBackToInternalDate(t *testing.T) {
+	arrived := time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC)
+	noDate := withBody(newTestMessage(1, "No date", "<1@x>"), "Subject: No date\r\n\r\nBody\r\n")
+	noDate.Envelope.Date = time.Time{}
+	noDate.InternalDate = arrived
+	unknown := withBody(newTestMessage(2, "Unknown", "<2@x>"), "Subject: Unknown\r\n\r\nBody\r\n")
+	unknown.Envelope.Date = time.Time{}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {noDate, unknown}}}
+	c := newTestClient(m)
+
+	email, err := c.GetEmail(context.Background(), "INBOX", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !email.Date.Equal(arrived) {
+		t.Errorf("Date = %v, want internal date %v", email.Date, arrived)
+	}
+
+	summaries, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(summaries))
+	}
+	for _, summary := range summaries {
+		if summary.ID == "1" && !summary.Date.Equal(arrived) {
+			t.Errorf("search Date = %v, want internal date %v", summary.Date, arrived)
+		}
+	}
+
+	// With neither date known the field is left out rather than 0001-01-01
+	email, err = c.GetEmail(context.Background(), "INBOX", "2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := json.Marshal(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"date"`) {
+		t.Errorf("zero date serialized: %s", data)
+	}
+}
+
+func TestFetchEmailBySequence(t *testing.T) {
+	first := withBody(newTestMessage(40, "First", "<40@x>"), "Subject: First\r\n\r\nOne\r\n")
+	second := withBody(newTestMessage(57, "Second", "<57@x>"), "Subject: Second\r\n\r\nTwo\r\n")
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {first, second}}}
+	c := newTestClient(m)
+
+	email, err := c.FetchEmail(context.Background(), "INBOX", "2", FetchOptions{BySequence: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("Fetch") != 1 || m.Called("UidFetch") != 0 {
+		t.Errorf("Fetch called %d times, UidFetch %d; want sequence fetch only", m.Called("Fetch"), m.Called("UidFetch"))
+	}
+	// The response reports the stable UID, not the sequence number
+	if email.ID != "57" || email.Subject != "Second" {
+		t.Errorf("email = %s %q, want 57 Second", email.ID, email.Subject)
+	}
+
+	if _, err := c.FetchEmail(context.Background(), "INBOX", "3", FetchOptions{BySequence: true}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+
+	// UIDs remain the default
+	if _, err := c.FetchEmail(context.Background(), "INBOX", "40", FetchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("UidFetch") != 1 || m.Called("Fetch") != 2 {
+		t.Errorf("UidFetch called %d times, Fetch %d", m.Called("UidFetch"), m.Called("Fetch"))
+	}
+}
+
+func TestPeekEmail(t *testing.T) {
+	msg := withBody(newTestMessage(4, "Re: Plans", "<4@x>"), "From: alice@example.com\r\nSubject: Re: Plans\r\n\r\nSee you at noon.\r\n")
+	msg.Flags = []string{imap.AnsweredFlag, imap.FlaggedFlag}
+	msg.Size = 2048
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {msg}}}
+	c := newTestClient(m)
+
+	email, err := c.PeekEmail(context.Background(), "INBOX", "4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !email.Unread || !email.Answered || !email.Flagged || email.Size != 2048 {
+		t.Errorf("unread/answered/flagged/size = %v/%v/%v/%d", email.Unread, email.Answered, email.Flagged, email.Size)
+	}
+	if email.BestBody != "See you at noon." {
+		t.Errorf("BestBody = %q", email.BestBody)
+	}
+
+	// The body is fetched with PEEK so the server leaves \Seen unset
+	var items []string
+	for _, item := range m.LastFetchItems {
+		items = append(items, string(item))
+	}
+	got := strings.Join(items, " ")
+	if !strings.Contains(got, "BODY.PEEK[]") || !strings.Contains(got, "RFC822.SIZE") {
+		t.Errorf("fetch items = %s", got)
+	}
+	if m.Called("UidStore") != 0 {
+		t.Error("PeekEmail changed flags")
+	}
+
+	// GetEmail still uses a plain BODY[] fetch
+	if _, err := c.GetEmail(context.Background(), "INBOX", "4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, item := range m.LastFetchItems {
+		if item == "BODY.PEEK[]" {
+			t.Error("GetEmail fetched with PEEK")
+		}
+	}
+}
+
+func TestSearchEmailsDeliveredTo(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+	c := newTestClient(m)
+
+	filters := EmailFilters{DeliveredTo: "me+shopping@icloud.com"}
+	if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", filters); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.LastCriteria.Or) != 1 {
+		t.Fatalf("Or = %v, want one Delivered-To/X-Original-To pair", m.LastCriteria.Or)
+	}
+	pair := m.LastCriteria.Or[0]
+	if got := pair[0].Header.Get("Delivered-To"); got != "me+shopping@icloud.com" {
+		t.Errorf("Delivered-To = %q", got)
+	}
+	if got := pair[1].Header.Get("X-Original-To"); got != "me+shopping@icloud.com" {
+		t.Errorf("X-Original-To = %q", got)
+	}
+
+	if _, err := c.CountEmails(context.Background(), "INBOX", filters); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.LastCriteria.Or) != 1 {
+		t.Error("CountEmails ignored DeliveredTo")
+	}
+}
+
+func TestGetStoredMessage(t *testing.T) {
+	alt := "From: Me <me@icloud.com>\r\nTo: Alice <alice@example.com>, bob@example.com\r\nCc: carol@example.com\r\n" +
+		"Subject: Quarterly report\r\nIn-Reply-To: <2@x>\r\nReferences: <1@x> <2@x>\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nSee attached\r\n" +
+		"--b\r\nContent-Type: text/html\r\n\r\n<p>See attached</p>\r\n--b--\r\n"
+	plain := "From: me@icloud.com\r\nTo: alice@example.com\r\nSubject: Hi\r\n\r\nHello there\r\n"
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"Sent Messages": {
+			withBody(newTestMessage(7, "Quarterly report", "<7@x>"), alt),
+			withBody(newTestMessage(8, "Hi", "<8@x>"), plain),
+		},
+	}}
+	c := newTestClient(m)
+
+	got, err := c.GetStoredMessage(context.Background(), "sent", "7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.From != "me@icloud.com" || got.Subject != "Quarterly report" {
+		t.Errorf("from/subject = %q/%q", got.From, got.Subject)
+	}
+	if strings.Join(got.To, ",") != "alice@example.com,bob@example.com" || strings.Join(got.CC, ",") != "carol@example.com" {
+		t.Errorf("to = %v, cc = %v", got.To, got.CC)
+	}
+	if !got.HTML || got.Body != "<p>See attached</p>" {
+		t.Errorf("body = %q (html %v), want the HTML part", got.Body, got.HTML)
+	}
+	if got.InReplyTo != "<2@x>" || strings.Join(got.References, " ") != "<1@x> <2@x>" {
+		t.Errorf("in-reply-to = %q, references = %v", got.InReplyTo, got.References)
+	}
+
+	// Fetching the source must not mark the message as read
+	var items []string
+	for _, item := range m.LastFetchItems {
+		items = append(items, string(item))
+	}
+	if strings.Join(items, " ") != "UID BODY.PEEK[]" {
+		t.Errorf("fetch items = %v", items)
+	}
+
+	got, err = c.GetStoredMessage(context.Background(), "sent", "8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.HTML || strings.TrimSpace(got.Body) != "Hello there" || len(got.References) != 0 {
+		t.Errorf("plain message = %+v", got)
+	}
+
+	if _, err := c.GetStoredMessage(context.Background(), "sent", "99"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing email: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		backend    *MockBackend
+		wantPrefix string
+		wantDelim  string
+		wantList   bool // fell back to LIST "" ""
+	}{
+		{
+			name:       "dot namespace",
+			backend:    &MockBackend{Capabilities: []string{"IMAP4rev1", "NAMESPACE"}, Namespace: []interface{}{"INBOX.", "."}},
+			wantPrefix: "INBOX.",
+			wantDelim:  ".",
+		},
+		{
+			name:      "top-level namespace",
+			backend:   &MockBackend{Capabilities: []string{"NAMESPACE"}, Namespace: []interface{}{"", "/"}},
+			wantDelim: "/",
+		},
+		{
+			name:      "no personal namespace",
+			backend:   &MockBackend{Capabilities: []string{"NAMESPACE"}, Delimiter: "."},
+			wantDelim: ".",
+			wantList:  true,
+		},
+		{
+			name:      "extension not supported",
+			backend:   &MockBackend{Delimiter: "/"},
+			wantDelim: "/",
+			wantList:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(tt.backend)
+			prefix, delim, err := c.Namespace(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prefix != tt.wantPrefix || delim != tt.wantDelim {
+				t.Errorf("Namespace() = %q, %q; want %q, %q", prefix, delim, tt.wantPrefix, tt.wantDelim)
+			}
+			if got := tt.backend.Called("List") > 0; got != tt.wantList {
+				t.Errorf("LIST fallback used = %v, want %v", got, tt.wantList)
+			}
+		})
+	}
+
+	m := &MockBackend{Capabilities: []string{"NAMESPACE"}, Errs: map[string]error{"NAMESPACE": errors.New("connection reset")}}
+	if _, _, err := newTestClient(m).Namespace(context.Background()); err == nil {
+		t.Error("expected error when NAMESPACE fails")
+	}
+}
+
+func TestCreateFolderDelimiter(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:    map[string][]*imap.Message{"INBOX": nil, "INBOX.Work": nil},
+		Capabilities: []string{"NAMESPACE"},
+		Namespace:    []interface{}{"INBOX.", "."},
+	}
+	c := newTestClient(m)
+
+	path, err := c.CreateFolder(context.Background(), "Projects", "INBOX.Work", CreateFolderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "INBOX.Work.Projects" {
+		t.Errorf("path = %q, want INBOX.Work.Projects", path)
+	}
+	if _, ok := m.Mailboxes["INBOX.Work.Projects"]; !ok {
+		t.Errorf("mailboxes = %v, want INBOX.Work.Projects", m.Mailboxes)
+	}
+
+	// The delimiter is discovered once per connection
+	if _, err := c.CreateFolder(context.Background(), "Archive", "INBOX.Work", CreateFolderOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := m.Called("NAMESPACE"); n != 1 {
+		t.Errorf("NAMESPACE called %d times, want 1", n)
+	}
+
+	// Top-level folders need no delimiter at all
+	if _, err := c.CreateFolder(context.Background(), "Receipts", "", CreateFolderOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := m.Mailboxes["Receipts"]; !ok {
+		t.Errorf("mailboxes = %v, want Receipts", m.Mailboxes)
+	}
+}
+
+func TestCreateFolderAliasParent(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:  map[string][]*imap.Message{"INBOX": nil, "Old Mail": nil},
+		Attributes: map[string][]string{"Old Mail": {imap.ArchiveAttr}},
+	}
+	c := newTestClient(m)
+
+	path, err := c.CreateFolder(context.Background(), "2023", "archive", CreateFolderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "Old Mail/2023" {
+		t.Errorf("path = %q, want Old Mail/2023 under the \\Archive folder", path)
+	}
+}
+
+func TestCreateFolderMaxDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		folder  string
+		parent  string
+		wantErr bool
+	}{
+		{name: "top level", folder: "Receipts"},
+		{name: "at the limit with parent", folder: "Projects", parent: "INBOX.Work"},
+		{name: "beyond the limit with parent", folder: "Old", parent: "INBOX.Work.Projects", wantErr: true},
+		{name: "at the limit in the name", folder: "INBOX.Work.Clients"},
+		{name: "beyond the limit in the name", folder: "INBOX.Work.Clients.Acme", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{
+				Mailboxes:    map[string][]*imap.Message{"INBOX": nil},
+				Capabilities: []string{"NAMESPACE"},
+				Namespace:    []interface{}{"INBOX.", "."},
+			}
+			c := newTestClient(m)
+			c.opts.MaxFolderDepth = 3
+
+			_, err := c.CreateFolder(context.Background(), tt.folder, tt.parent, CreateFolderOptions{})
+			if tt.wantErr {
+				if !errors.Is(err, ErrFolderTooDeep) {
+					t.Errorf("error = %v, want ErrFolderTooDeep", err)
+				}
+				if m.Called("Create") != 0 {
+					t.Error("CREATE was sent for a folder beyond the limit")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateFolderAlreadyExists(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{"INBOX": nil},
+		Errs:      map[string]error{"Create": errors.New("[ALREADYEXISTS] Mailbox already exists")},
+	}
+	c := newTestClient(m)
+
+	_, err := c.CreateFolder(context.Background(), "Work", "", CreateFolderOptions{})
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("error = %v, want ErrAlreadyExists", err)
+	}
+
+	m.Errs["Create"] = errors.New("[CANNOT] Invalid mailbox name")
+	if _, err := c.CreateFolder(context.Background(), "Work", "", CreateFolderOptions{}); err == nil || errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("error = %v, want a plain create failure", err)
+	}
+}
+
+func TestCreateFolderListDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter string
+		want      string
+	}{
+		{name: "dot", delimiter: ".", want: "Work.Projects"},
+		{name: "slash", delimiter: "/", want: "Work/Projects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No NAMESPACE extension: the delimiter comes from LIST "" ""
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Work": nil}, Delimiter: tt.delimiter}
+			path, err := newTestClient(m).CreateFolder(context.Background(), "Projects", "Work", CreateFolderOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != tt.want {
+				t.Errorf("path = %q, want %q", path, tt.want)
+			}
+			if _, ok := m.Mailboxes[tt.want]; !ok {
+				t.Errorf("mailboxes = %v, want %s", m.Mailboxes, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateFolderSubscribes(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Work": nil}}
+	c := newTestClient(m)
+
+	if _, err := c.CreateFolder(context.Background(), "Projects", "Work", CreateFolderOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(m.Subscribed, ",") != "Work/Projects" {
+		t.Errorf("subscribed = %v, want [Work/Projects]", m.Subscribed)
+	}
+
+	if _, err := c.CreateFolder(context.Background(), "Hidden", "", CreateFolderOptions{SkipSubscribe: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Subscribed) != 1 {
+		t.Errorf("subscribed = %v, want Hidden left unsubscribed", m.Subscribed)
+	}
+
+	// The folder exists even if the subscribe fails
+	m.Errs = map[string]error{"Subscribe": errors.New("NO subscribe failed")}
+	if _, err := c.CreateFolder(context.Background(), "Receipts", "", CreateFolderOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := m.Mailboxes["Receipts"]; !ok {
+		t.Error("expected Receipts to be created")
+	}
+}
+
+func TestCreateFolderSpecialUse(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:    map[string][]*imap.Message{},
+		Capabilities: []string{"CREATE-SPECIAL-USE"},
+	}
+	c := newTestClient(m)
+
+	if _, err := c.CreateFolder(context.Background(), "Archive", "", CreateFolderOptions{Use: "archive"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("Create") != 0 || m.LastCommand == nil {
+		t.Fatal("plain CREATE used although the server supports CREATE-SPECIAL-USE")
+	}
+	var b bytes.Buffer
+	if err := m.LastCommand.WriteTo(imap.NewWriter(&b)); err != nil {
+		t.Fatalf("failed to format command: %v", err)
+	}
+	if got := b.String(); !strings.HasSuffix(got, `CREATE "Archive" (USE (\Archive))`+"\r\n") {
+		t.Errorf("command = %q, want CREATE \"Archive\" (USE (\\Archive))", got)
+	}
+	if _, ok := m.Mailboxes["Archive"]; !ok {
+		t.Error("expected Archive to be created")
+	}
+
+	// Without the capability the folder is still created, untagged
+	m.Capabilities = nil
+	m.LastCommand = nil
+	if _, err := c.CreateFolder(context.Background(), "Old Mail", "", CreateFolderOptions{Use: "archive"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Called("Create") != 1 || m.LastCommand != nil {
+		t.Errorf("Create called %d times, Execute %v; want the plain CREATE fallback", m.Called("Create"), m.LastCommand)
+	}
+
+	if _, err := c.CreateFolder(context.Background(), "Stuff", "", CreateFolderOptions{Use: "important"}); err == nil || !strings.Contains(err.Error(), "invalid special use") {
+		t.Errorf("err = %v, want invalid special use", err)
+	}
+}
+
+func TestSubscribeUnsubscribe(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:  map[string][]*imap.Message{"INBOX": nil, "Junk": nil},
+		Attributes: map[string][]string{"Junk": {imap.JunkAttr}},
+	}
+	c := newTestClient(m)
+
+	if err := c.Subscribe(context.Background(), "junk"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(m.Subscribed, ",") != "Junk" {
+		t.Errorf("subscribed = %v, want alias resolved to [Junk]", m.Subscribed)
+	}
+	if err := c.Unsubscribe(context.Background(), "Junk"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(m.Unsubscribed, ",") != "Junk" {
+		t.Errorf("unsubscribed = %v, want [Junk]", m.Unsubscribed)
+	}
+
+	m.Errs = map[string]error{"Unsubscribe": errors.New("NO not subscribed")}
+	if err := c.Unsubscribe(context.Background(), "Junk"); err == nil || !strings.Contains(err.Error(), "failed to unsubscribe from folder Junk") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+// --- Snooze ---
+
+func TestSnoozeDue(t *testing.T) {
+	now := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		day    string
+		want   bool
+	}{
+		{name: "header in the past", header: "2024-01-20T11:59:00Z", day: "2024-01-20", want: true},
+		{name: "header exactly now", header: "2024-01-20T12:00:00Z", day: "2024-01-20", want: true},
+		{name: "header in the future", header: "2024-01-20T12:01:00Z", day: "2024-01-20"},
+		{name: "header wins over folder date", header: "2024-01-25T09:00:00Z", day: "2024-01-19"},
+		{name: "no header, folder day reached", day: "2024-01-20", want: true},
+		{name: "no header, folder day ahead", day: "2024-01-21"},
+		{name: "unreadable header and folder", header: "soon", day: "someday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snoozeDue(tt.header, tt.day, now); got != tt.want {
+				t.Errorf("snoozeDue(%q, %q) = %v, want %v", tt.header, tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSnoozeHeaderRoundTrip(t *testing.T) {
+	until := time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)
+	raw := "From: alice@example.com\r\nX-Snooze-Until: 2023-12-01T00:00:00Z\r\n folded\r\nSubject: Hi\r\n\r\nBody line\r\n"
+
+	snoozed := withSnoozeHeader([]byte(raw), until)
+	headers, err := selectHeaders(snoozed, []string{SnoozeHeader, "Subject"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers[SnoozeHeader]; len(got) != 1 || got[0] != "2024-01-20T09:00:00Z" {
+		t.Errorf("X-Snooze-Until = %q, want the new value only", got)
+	}
+	if headers["Subject"][0] != "Hi" || !strings.HasSuffix(string(snoozed), "\r\n\r\nBody line\r\n") {
+		t.Errorf("message altered:\n%s", snoozed)
+	}
+	if strings.Contains(string(snoozed), "folded") {
+		t.Error("continuation of the old header was kept")
+	}
+	if !snoozeDue(headers[SnoozeHeader][0], "", until) || snoozeDue(headers[SnoozeHeader][0], "", until.Add(-time.Second)) {
+		t.Error("round-tripped header not due exactly at until")
+	}
+}
+
+func TestSnoozeEmail(t *testing.T) {
+	msg := withBody(newTestMessage(7, "Hi", "<7@x>"), "Subject: Hi\r\nDate: Mon, 15 Jan 2024 10:00:00 +0000\r\n\r\nBody\r\n")
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {msg}}}
+	c := newTestClient(m)
+
+	until := time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)
+	snoozed, err := c.SnoozeEmail(context.Background(), "INBOX", "7", until)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snoozed.Folder != "Snoozed/2024-01-20" {
+		t.Errorf("folder = %q, want Snoozed/2024-01-20", snoozed.Folder)
+	}
+	if m.Called("Create") != 2 || len(m.Appended) != 1 || m.Appended[0] != "Snoozed/2024-01-20" {
+		t.Fatalf("created %d folders, appended to %v", m.Called("Create"), m.Appended)
+	}
+	if !strings.HasPrefix(m.AppendedBodies[0], "X-Snooze-Until: 2024-01-20T09:00:00Z\r\nSubject: Hi\r\n") {
+		t.Errorf("appended body = %q", m.AppendedBodies[0])
+	}
+	if !m.AppendedDates[0].Equal(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("append date = %v, want the original Date header", m.AppendedDates[0])
+	}
+	if m.Called("Expunge") != 1 {
+		t.Error("original was not removed")
+	}
+
+	// Snoozing again into an existing folder is fine
+	m.Errs = map[string]error{"Create": errors.New("[ALREADYEXISTS] Mailbox exists")}
+	if _, err := c.SnoozeEmail(context.Background(), "INBOX", "7", until); err != nil {
+		t.Errorf("unexpected error with existing folders: %v", err)
+	}
+}
+
+func TestFlushSnoozed(t *testing.T) {
+	due := withBody(newTestMessage(1, "Due", "<1@x>"), "X-Snooze-Until: 2024-01-20T09:00:00Z\r\nSubject: Due\r\n\r\nBody\r\n")
+	later := withBody(newTestMessage(2, "Later", "<2@x>"), "X-Snooze-Until: 2024-01-20T18:00:00Z\r\nSubject: Later\r\n\r\nBody\r\n")
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"INBOX":              nil,
+		"Snoozed":            nil,
+		"Snoozed/2024-01-20": {due, later},
+	}}
+	c := newTestClient(m)
+
+	result, err := c.FlushSnoozed(context.Background(), time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Returned != 1 || result.Remaining != 1 {
+		t.Errorf("returned %d, remaining %d; want 1 and 1", result.Returned, result.Remaining)
+	}
+	if m.LastDest != "INBOX" || !m.LastSeqSet.Contains(1) || m.LastSeqSet.Contains(2) {
+		t.Errorf("moved %v to %q, want UID 1 to INBOX", m.LastSeqSet, m.LastDest)
+	}
+	if m.Called("Delete") != 0 {
+		t.Error("deleted a snooze folder that still holds mail")
+	}
+}
+
+func TestMailboxStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 9, 0, 0, 0, time.UTC) }
+	message := func(uid uint32, received time.Time, size uint32, flags ...string) *imap.Message {
+		msg := newTestMessage(uid, "Hi", "<stats@x>")
+		msg.InternalDate = received
+		msg.Size = size
+		msg.Flags = flags
+		return msg
+	}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{
+		"INBOX": {
+			message(1, day(3), 1000, imap.SeenFlag),
+			message(2, day(5), 2000),
+			message(3, day(9), 3000),
+		},
+		"Archive": {
+			message(10, day(1), 500, imap.SeenFlag),
+			message(11, day(2), 700, imap.SeenFlag, imap.FlaggedFlag),
+		},
+	}}
+	c := newTestClient(m)
+
+	stats, err := c.MailboxStats(context.Background(), StatsOptions{IncludeSize: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Total != 5 || stats.Unread != 2 || stats.Size != 7200 {
+		t.Errorf("totals = %d/%d/%d, want 5 messages, 2 unread, 7200 bytes", stats.Total, stats.Unread, stats.Size)
+	}
+	want := []FolderStats{
+		{Folder: "Archive", Total: 2, Unread: 0, Size: 1200, Oldest: day(1), Newest: day(2)},
+		{Folder: "INBOX", Total: 3, Unread: 2, Size: 6000, Oldest: day(3), Newest: day(9)},
+	}
+	if len(stats.Folders) != len(want) {
+		t.Fatalf("got %d folders, want %d", len(stats.Folders), len(want))
+	}
+	for i, fs := range stats.Folders {
+		if fs != want[i] {
+			t.Errorf("folder %d = %+v, want %+v", i, fs, want[i])
+		}
+	}
+
+	// Without sizes only the first and last messages are fetched
+	stats, err = c.MailboxStats(context.Background(), StatsOptions{Folders: []string{"INBOX"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Size != 0 || len(stats.Folders) != 1 || stats.Folders[0].Size != 0 {
+		t.Errorf("size reported without IncludeSize: %+v", stats)
+	}
+	if got := stats.Folders[0]; got.Oldest != day(3) || got.Newest != day(9) || got.Unread != 2 {
+		t.Errorf("INBOX = %+v, want oldest %v, newest %v, 2 unread", got, day(3), day(9))
+	}
+	for _, item := range m.LastFetchItems {
+		if item == imap.FetchRFC822Size {
+			t.Error("fetched RFC822.SIZE without IncludeSize")
+		}
+	}
+}
+
+func TestMailboxStatsFolderError(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {newTestMessage(1, "Hi", "<1@x>")}}}
+	c := newTestClient(m)
+
+	stats, err := c.MailboxStats(context.Background(), StatsOptions{Folders: []string{"INBOX", "Missing"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Total != 1 || len(stats.Folders) != 2 {
+		t.Fatalf("stats = %+v, want INBOX counted and both folders listed", stats)
+	}
+	if stats.Folders[1].Error == "" {
+		t.Error("expected an error for the missing folder")
+	}
+}
+
+func TestFetchEmailBodyMaxBytes(t *testing.T) {
+	const header = "Subject: Long\r\nContent-Type: text/plain\r\n\r\n"
+	text := strings.Repeat("0123456789", 10)
+
+	// withPreview answers BODY.PEEK[HEADER] with the header and
+	// BODY.PEEK[TEXT]<0.N> with served
+	withPreview := func(served string) *imap.Message {
+		msg := newTestMessage(1, "Long", "<1@x>")
+		msg.Size = uint32(len(header) + len(text))
+		msg.Body = map[*imap.BodySectionName]imap.Literal{
+			{}: bytes.NewBufferString(header + text),
+			{BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier}}:                  bytes.NewBufferString(header),
+			{BodyPartName: imap.BodyPartName{Specifier: imap.TextSpecifier}, Partial: []int{0}}: bytes.NewBufferString(served),
+		}
+		return msg
+	}
+
+	tests := []struct {
+		name          string
+		served        string
+		limit         int
+		wantBody      string
+		wantTruncated bool
+	}{
+		{name: "cut at limit", served: text[:40], limit: 40, wantBody: text[:40], wantTruncated: true},
+		{name: "server ignores range", served: text, limit: 40, wantBody: text[:40], wantTruncated: true},
+		{name: "text exactly fits", served: text, limit: len(text), wantBody: text},
+		{name: "text shorter than limit", served: text, limit: 1000, wantBody: text},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {withPreview(tt.served)}}}
+			c := newTestClient(m)
+
+			email, err := c.FetchEmail(context.Background(), "INBOX", "1", FetchOptions{BodyMaxBytes: tt.limit})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if email.BodyPlain != tt.wantBody {
+				t.Errorf("BodyPlain = %q (%d bytes), want %d bytes", email.BodyPlain, len(email.BodyPlain), len(tt.wantBody))
+			}
+			if email.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v, want %v", email.Truncated, tt.wantTruncated)
+			}
+
+			var partial *imap.BodySectionName
+			for _, item := range m.LastFetchItems {
+				section, err := imap.ParseBodySectionName(item)
+				if err != nil {
+					continue
+				}
+				if section.Specifier == "" {
+					t.Errorf("fetched the full body %s for a preview", item)
+				}
+				if section.Specifier == imap.TextSpecifier {
+					partial = section
+				}
+			}
+			if partial == nil || !partial.Peek || len(partial.Partial) != 2 || partial.Partial[1] != tt.limit {
+				t.Errorf("fetch items = %v, want BODY.PEEK[TEXT]<0.%d>", m.LastFetchItems, tt.limit)
+			}
+		})
+	}
+
+	// Without a limit the whole message is fetched
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {withBody(newTestMessage(1, "Long", "<1@x>"), header+text)}}}
+	email, err := newTestClient(m).FetchEmail(context.Background(), "INBOX", "1", FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email.BodyPlain != text || email.Truncated {
+		t.Errorf("full fetch: body %d bytes, truncated %v; want %d bytes, not truncated", len(email.BodyPlain), email.Truncated, len(text))
+	}
+	full := false
+	for _, item := range m.LastFetchItems {
+		full = full || item == (&imap.BodySectionName{}).FetchItem()
+	}
+	if !full {
+		t.Errorf("fetch items = %v, want BODY[]", m.LastFetchItems)
+	}
+}
+
+func TestFetchEmailInlineAttachments(t *testing.T) {
+	small := []byte("tiny PNG bytes")
+	large := bytes.Repeat([]byte("x"), 300)
+	raw := "Subject: Files\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nSee attached.\r\n" +
+		"--b\r\nContent-Type: image/png\r\nContent-Disposition: attachment; filename=dot.png\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString(small) + "\r\n" +
+		"--b\r\nContent-Type: text/csv\r\nContent-Disposition: attachment; filename=data.csv\r\n\r\n" +
+		string(large) + "\r\n" +
+		"--b--\r\n"
+	newMock := func() *MockBackend {
+		return &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {withBody(newTestMessage(1, "Files", "<1@x>"), raw)}}}
+	}
+
+	email, err := newTestClient(newMock()).FetchEmail(context.Background(), "INBOX", "1", FetchOptions{InlineAttachmentMax: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(email.Attachments) != 2 {
+		t.Fatalf("got %d attachments, want 2", len(email.Attachments))
+	}
+	dot, data := email.Attachments[0], email.Attachments[1]
+	if decoded, err := base64.StdEncoding.DecodeString(dot.Content); err != nil || !bytes.Equal(decoded, small) {
+		t.Errorf("dot.png content = %q (%v), want %q", decoded, err, small)
+	}
+	if dot.MIMEType != "image/png" {
+		t.Errorf("dot.png MIMEType = %q, want image/png", dot.MIMEType)
+	}
+	if data.Filename != "data.csv" || data.Size != int64(len(large)) || data.Content != "" || data.MIMEType != "" {
+		t.Errorf("data.csv = %+v, want metadata only", data)
+	}
+	if email.BodyPlain != "See attached." {
+		t.Errorf("BodyPlain = %q", email.BodyPlain)
+	}
+
+	// Nothing is inlined unless asked
+	email, err = newTestClient(newMock()).FetchEmail(context.Background(), "INBOX", "1", FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, att := range email.Attachments {
+		if att.Content != "" {
+			t.Errorf("%s inlined without InlineAttachmentMax", att.Filename)
+		}
+	}
+}
+
+func TestParseMessageDataIsFromMe(t *testing.T) {
+	tests := []struct {
+		name string
+		from *imap.Address
+		want bool
+	}{
+		{name: "account address", from: &imap.Address{PersonalName: "Me", MailboxName: "Me", HostName: "iCloud.com"}, want: true},
+		{name: "alias", from: &imap.Address{MailboxName: "me", HostName: "example.org"}, want: true},
+		{name: "someone else", from: &imap.Address{MailboxName: "alice", HostName: "example.com"}, want: false},
+	}
+
+	c := newTestClient(&MockBackend{})
+	c.opts.Aliases = []string{"me@example.org"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := newTestMessage(1, "Hello", "<1@x>")
+			msg.Envelope.From = []*imap.Address{tt.from}
+
+			email := c.parseMessageData(msg, false)
+			if email.IsFromMe != tt.want {
+				t.Errorf("IsFromMe = %v, want %v", email.IsFromMe, tt.want)
+			}
+		})
+	}
+}
+
+func TestFolderStatus(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:   map[string][]*imap.Message{"INBOX": {newTestMessage(4, "a", ""), newTestMessage(9, "b", "")}},
+		UIDValidity: map[string]uint32{"INBOX": 1700000000},
+	}
+	c := newTestClient(m)
+
+	status, err := c.FolderStatus(context.Background(), "INBOX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Folder != "INBOX" || status.Messages != 2 || status.UIDNext != 10 || status.UIDValidity != 1700000000 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestUIDValidity(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes:   map[string][]*imap.Message{"INBOX": nil, "Archive": nil},
+		UIDValidity: map[string]uint32{"INBOX": 11, "Archive": 22},
+	}
+	c := newTestClient(m)
+
+	// A search selects the folder and reports its UIDVALIDITY
+	if _, _, v, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{}); err != nil || v != 11 {
+		t.Fatalf("SearchEmails uidvalidity = %d, %v; want 11", v, err)
+	}
+	if _, _, v, err := c.SearchUIDs(context.Background(), "Archive", "", EmailFilters{}); err != nil || v != 22 {
+		t.Fatalf("SearchUIDs uidvalidity = %d, %v; want 22", v, err)
+	}
+	if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	selects := len(m.Calls)
+	v, err := c.UIDValidity(context.Background(), "INBOX")
+	if err != nil || v != 11 {
+		t.Errorf("INBOX = %d, %v; want 11", v, err)
+	}
+	if len(m.Calls) != selects {
+		t.Errorf("calls = %v, want no command for the selected folder", m.Calls[selects:])
+	}
+
+	// Any other folder is examined
+	v, err = c.UIDValidity(context.Background(), "Archive")
+	if err != nil || v != 22 {
+		t.Errorf("Archive = %d, %v; want 22", v, err)
+	}
+	if m.Selected != "Archive" {
+		t.Errorf("selected %q, want Archive examined", m.Selected)
+	}
+
+	// A reset is seen on the next select
+	m.UIDValidity["INBOX"] = 12
+	if _, _, v, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{}); err != nil || v != 12 {
+		t.Fatalf("SearchEmails after reset = %d, %v; want 12", v, err)
+	}
+	if v, _ := c.UIDValidity(context.Background(), "INBOX"); v != 12 {
+		t.Errorf("after reset = %d, want 12", v)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	kept := newTestMessage(1, "keep", "")
+	gone1 := newTestMessage(2, "gone", "")
+	gone1.Flags = []string{imap.SeenFlag, imap.DeletedFlag}
+	flagged := newTestMessage(3, "flagged", "")
+	flagged.Flags = []string{imap.FlaggedFlag}
+	gone2 := newTestMessage(4, "gone too", "")
+	gone2.Flags = []string{imap.DeletedFlag}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {kept, gone1, flagged, gone2}}}
+	c := newTestClient(m)
+
+	n, err := c.PurgeDeleted(context.Background(), "INBOX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("purged %d, want 2", n)
+	}
+	var left []string
+	for _, msg := range m.Mailboxes["INBOX"] {
+		left = append(left, msg.Envelope.Subject)
+	}
+	if got := strings.Join(left, ","); got != "keep,flagged" {
+		t.Errorf("left %s, want keep,flagged", got)
+	}
+	if got := strings.Join(m.LastCriteria.WithFlags, ","); got != imap.DeletedFlag {
+		t.Errorf("searched for %s, want \\Deleted", got)
+	}
+}
+
+func TestPurgeDeletedProtected(t *testing.T) {
+	gone := newTestMessage(1, "gone", "")
+	gone.Flags = []string{imap.DeletedFlag}
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"Deleted Messages": {gone}}}
+	c := newTestClient(m)
+	c.opts.ProtectedFolders = []string{"trash"}
+
+	if _, err := c.PurgeDeleted(context.Background(), "Trash"); !errors.Is(err, ErrProtectedFolder) {
+		t.Fatalf("err = %v, want ErrProtectedFolder", err)
+	}
+	if m.Called("Expunge") != 0 || len(m.Mailboxes["Deleted Messages"]) != 1 {
+		t.Error("protected folder was purged")
+	}
+}
+
+func TestPurgeDeletedNothingMarked(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": {newTestMessage(1, "keep", "")}}}
+	c := newTestClient(m)
+
+	n, err := c.PurgeDeleted(context.Background(), "INBOX")
+	if err != nil || n != 0 {
+		t.Errorf("PurgeDeleted = %d, %v; want 0, nil", n, err)
+	}
+	if m.Called("Expunge") != 0 {
+		t.Error("expunged with nothing marked \\Deleted")
+	}
+	if len(m.Mailboxes["INBOX"]) != 1 {
+		t.Error("message removed")
+	}
+}
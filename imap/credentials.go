@@ -0,0 +1,83 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAuthFailed is wrapped by errors for a login the server refused, e.g.
+// because an app-specific password was revoked.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// authError marks a login failure as the server refusing the credentials,
+// keeping the server's wording while matching ErrAuthFailed.
+type authError struct{ error }
+
+func (e authError) Unwrap() error        { return e.error }
+func (e authError) Is(target error) bool { return target == ErrAuthFailed }
+
+// Outcomes of CheckCredentials
+const (
+	CredentialsValid    = "valid"         // the login succeeded
+	CredentialsRejected = "auth_failed"   // the server refused the credentials
+	CredentialsNetwork  = "network_error" // the server could not be reached or dropped the connection
+	CredentialsError    = "error"         // logged in, but the server failed otherwise
+)
+
+// CredentialCheck is the result of CheckCredentials
+type CredentialCheck struct {
+	Status    string `json:"status"` // one of the Credentials* outcomes
+	Valid     bool   `json:"valid"`
+	Error     string `json:"error,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// CheckCredentials logs in on a fresh connection, which is then closed, and
+// reports whether the configured credentials are accepted right now. The
+// pooled connections are not used: they stay logged in even after a password
+// is revoked, so only a new login tells.
+func (p *Pool) CheckCredentials(ctx context.Context) *CredentialCheck {
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		c, err := p.dial()
+		if err == nil {
+			_ = c.Close()
+		}
+		result <- err
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("no response from the IMAP server: %w", ctx.Err())
+	}
+	check := &CredentialCheck{
+		Status:    classifyLogin(err),
+		ElapsedMs: time.Since(start).Milliseconds(),
+	}
+	check.Valid = check.Status == CredentialsValid
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// classifyLogin maps the error from dialing and logging in to one of the
+// Credentials* outcomes. A connection lost mid-login is a network failure,
+// not a verdict on the credentials.
+func classifyLogin(err error) string {
+	switch {
+	case err == nil:
+		return CredentialsValid
+	case isConnectionError(err), errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return CredentialsNetwork
+	case errors.Is(err, ErrAuthFailed):
+		return CredentialsRejected
+	default:
+		return CredentialsError
+	}
+}
@@ -0,0 +1,89 @@
+package imap
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// debugWriter receives the raw IMAP conversation from client.SetDebug and
+// logs it one line at a time at debug level. Credentials are redacted:
+// the arguments of LOGIN and AUTHENTICATE, and every line the client sends
+// until the server's tagged reply, which covers literals and SASL responses.
+// Bcc header lines, such as those of an appended draft, are redacted too.
+type debugWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	log    func(line string)
+	secret string // tag of a LOGIN or AUTHENTICATE in progress
+	inBcc  bool   // the previous line was a Bcc header that may be folded
+}
+
+// newDebugWriter returns a debugWriter that logs through slog
+func newDebugWriter() *debugWriter {
+	return &debugWriter{log: func(line string) {
+		slog.Debug("imap wire", "line", line)
+	}}
+}
+
+// Write implements io.Writer. Partial lines are held until their newline
+// arrives, so a password split across writes is still redacted.
+func (w *debugWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		w.log(w.redact(line))
+	}
+	return len(p), nil
+}
+
+// redact returns line with any credentials it carries replaced
+func (w *debugWriter) redact(line string) string {
+	if w.secret != "" {
+		// The exchange ends with the server's tagged response; until then
+		// anything but a server continuation or untagged line is the client's
+		if strings.HasPrefix(line, w.secret+" ") {
+			w.secret = ""
+			return line
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "*") {
+			return line
+		}
+		return "[redacted]"
+	}
+
+	// Blind recipients, including folded continuation lines
+	if w.inBcc && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+		return "[redacted]"
+	}
+	w.inBcc = len(line) >= 4 && strings.EqualFold(line[:4], "bcc:")
+	if w.inBcc {
+		return line[:4] + " [redacted]"
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return line
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "LOGIN":
+		w.secret = fields[0]
+		return fields[0] + " LOGIN [redacted]"
+	case "AUTHENTICATE":
+		w.secret = fields[0]
+		if len(fields) > 3 {
+			// SASL initial response
+			return strings.Join(fields[:3], " ") + " [redacted]"
+		}
+	}
+	return line
+}
@@ -0,0 +1,87 @@
+package imap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDebugWriterRedactsCredentials(t *testing.T) {
+	const password = "s3cret-app-pass"
+	const token = "dXNlcj1tZUBpY2xvdWQuY29tAWF1dGg9QmVhcmVyIHRva2VuAQE="
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "quoted login",
+			writes: []string{"* OK ready\r\n", "a1 LOGIN \"me@icloud.com\" \"" + password + "\"\r\n", "a1 OK LOGIN completed\r\n"},
+			want:   []string{"* OK ready", "a1 LOGIN [redacted]", "a1 OK LOGIN completed"},
+		},
+		{
+			name:   "login split across writes",
+			writes: []string{"a1 LOGIN me@icloud.com s3cret", "-app-pass\r\na1 OK\r", "\na2 SELECT INBOX\r\n"},
+			want:   []string{"a1 LOGIN [redacted]", "a1 OK", "a2 SELECT INBOX"},
+		},
+		{
+			name:   "login with literals",
+			writes: []string{"a1 LOGIN {13}\r\n", "+ go ahead\r\n", "me@icloud.com {15}\r\n", "+ go ahead\r\n", password + "\r\n", "a1 OK\r\n"},
+			want:   []string{"a1 LOGIN [redacted]", "+ go ahead", "[redacted]", "+ go ahead", "[redacted]", "a1 OK"},
+		},
+		{
+			name:   "sasl initial response",
+			writes: []string{"a1 AUTHENTICATE XOAUTH2 " + token + "\r\n", "a1 OK authenticated\r\n", "a2 SELECT INBOX\r\n"},
+			want:   []string{"a1 AUTHENTICATE XOAUTH2 [redacted]", "a1 OK authenticated", "a2 SELECT INBOX"},
+		},
+		{
+			name:   "sasl continuation",
+			writes: []string{"a1 AUTHENTICATE XOAUTH2\r\n", "+ \r\n", token + "\r\n", "a1 OK\r\n"},
+			want:   []string{"a1 AUTHENTICATE XOAUTH2", "+ ", "[redacted]", "a1 OK"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			w := &debugWriter{log: func(line string) { lines = append(lines, line) }}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write = %d, %v", n, err)
+				}
+			}
+
+			captured := strings.Join(lines, "\n")
+			if strings.Contains(captured, password) || strings.Contains(captured, "s3cret") || strings.Contains(captured, token) {
+				t.Errorf("credentials leaked:\n%s", captured)
+			}
+			if captured != strings.Join(tt.want, "\n") {
+				t.Errorf("lines = %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugWriterRedactsBcc(t *testing.T) {
+	var lines []string
+	w := &debugWriter{log: func(line string) { lines = append(lines, line) }}
+	writes := "a1 APPEND Drafts (\\Draft) {120}\r\n" +
+		"+ go ahead\r\n" +
+		"From: me@icloud.com\r\n" +
+		"To: bob@example.com\r\n" +
+		"BCC: secret@example.com,\r\n" +
+		" other@example.com\r\n" +
+		"Subject: Hi\r\n"
+	if _, err := w.Write([]byte(writes)); err != nil {
+		t.Fatal(err)
+	}
+
+	captured := strings.Join(lines, "\n")
+	if strings.Contains(captured, "secret@example.com") || strings.Contains(captured, "other@example.com") {
+		t.Errorf("BCC leaked:\n%s", captured)
+	}
+	want := []string{"a1 APPEND Drafts (\\Draft) {120}", "+ go ahead", "From: me@icloud.com", "To: bob@example.com", "BCC: [redacted]", "[redacted]", "Subject: Hi"}
+	if captured != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,106 @@
+package imap
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DuplicateGroup is a set of messages in one folder that are copies of the
+// same email
+type DuplicateGroup struct {
+	MessageID string   `json:"messageId,omitempty"` // empty when matched by subject, date, and sender
+	Subject   string   `json:"subject"`
+	IDs       []string `json:"ids"` // oldest first; IDs[0] is the copy remove_duplicates keeps
+}
+
+// FindDuplicates returns the groups of messages in folder that share a
+// Message-ID. With matchHeaders, messages without a Message-ID are grouped
+// when their subject, date, and sender all match. A partial fetch returns
+// the groups found so far together with an ErrPartialResults error.
+func (c *Client) FindDuplicates(ctx context.Context, folder string, matchHeaders bool) ([]DuplicateGroup, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []Email
+	err = c.withRetry(ctx, func() error {
+		var err error
+		emails, _, err = c.searchEmails(ctx, folder, "", EmailFilters{})
+		return err
+	})
+	if emails == nil {
+		return nil, err
+	}
+
+	return groupDuplicates(emails, matchHeaders), err
+}
+
+// groupDuplicates groups emails by normalized Message-ID, or with
+// matchHeaders by subject, date, and lowercased sender when the ID is
+// missing. Each group lists its IDs oldest first (by date, then UID), and
+// groups come in the order their first copy appears in emails. Emails
+// without a copy are left out.
+func groupDuplicates(emails []Email, matchHeaders bool) []DuplicateGroup {
+	index := make(map[string]int)
+	var groups [][]Email
+	for _, email := range emails {
+		key := NormalizeMessageID(email.MessageID)
+		if key != "" {
+			key = "id:" + key
+		} else if matchHeaders {
+			key = "hdr:" + strings.Join([]string{email.Subject, email.Date.UTC().Format(time.RFC3339), strings.ToLower(email.From)}, "\x00")
+		} else {
+			continue
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], email)
+	}
+
+	duplicates := []DuplicateGroup{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return olderThan(group[i], group[j]) })
+
+		ids := make([]string, len(group))
+		for i, email := range group {
+			ids[i] = email.ID
+		}
+		duplicates = append(duplicates, DuplicateGroup{
+			MessageID: NormalizeMessageID(group[0].MessageID),
+			Subject:   group[0].Subject,
+			IDs:       ids,
+		})
+	}
+
+	return duplicates
+}
+
+// olderThan orders emails by date and then by UID, so that of two copies
+// with the same date the one stored first counts as older
+func olderThan(a, b Email) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	return uidOf(a.ID) < uidOf(b.ID)
+}
+
+// uidOf parses an email ID as a UID, treating a malformed one as 0
+func uidOf(id string) uint64 {
+	uid, _ := strconv.ParseUint(id, 10, 32)
+	return uid
+}
@@ -0,0 +1,66 @@
+package imap
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+
+	message "github.com/emersion/go-message/mail"
+)
+
+// parseEmbeddedMessage parses the body of a message/rfc822 part, such as an
+// email forwarded as an attachment, into an Email. Embedded messages have no
+// UID, so ID is left empty; their own attachments and embedded messages are
+// listed on the returned Email rather than on the one that carries it.
+func (c *Client) parseEmbeddedMessage(raw []byte) *Email {
+	mr, err := message.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		slog.Warn("failed to parse embedded message", "error", err)
+		return nil
+	}
+	h := mr.Header
+
+	email := &Email{
+		To:        formatAddressList(h, "To"),
+		CC:        formatAddressList(h, "Cc"),
+		BCC:       formatAddressList(h, "Bcc"),
+		MessageID: strings.TrimSpace(h.Get("Message-Id")),
+	}
+	if from, err := h.AddressList("From"); err == nil && len(from) > 0 {
+		email.From = formatMailAddress(from[0])
+		email.IsFromMe = c.isSelf(from[0].Address)
+	}
+	if subject, err := h.Subject(); err == nil {
+		email.Subject = subject
+	} else {
+		email.Subject = h.Get("Subject")
+	}
+	if date, err := h.Date(); err == nil {
+		email.Date = date
+	}
+
+	c.parseEmailBody(email, bytes.NewReader(raw))
+	return email
+}
+
+// formatAddressList returns the addresses in header key formatted like
+// formatAddress, skipping a header that does not parse
+func formatAddressList(h message.Header, key string) []string {
+	addrs, err := h.AddressList(key)
+	if err != nil {
+		return []string{}
+	}
+	list := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		list = append(list, formatMailAddress(a))
+	}
+	return list
+}
+
+// formatMailAddress formats a parsed header address like formatAddress
+func formatMailAddress(addr *message.Address) string {
+	if addr.Name != "" {
+		return addr.Name + " <" + addr.Address + ">"
+	}
+	return addr.Address
+}
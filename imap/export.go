@@ -0,0 +1,334 @@
+package imap
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	message "github.com/emersion/go-message/mail"
+)
+
+// Export formats accepted by ExportFolder
+const (
+	ExportMbox = "mbox"
+	ExportZip  = "zip"
+)
+
+// ExportFolder writes the raw source of every message in folder to destPath,
+// either as a single mboxrd file or as a zip holding one <uid>.eml entry per
+// message. Messages are written as they arrive from the server rather than
+// collected first. It returns the number of messages exported and the total
+// size of their raw source. The file is removed if the export fails.
+func (c *Client) ExportFolder(ctx context.Context, folder, destPath, format string) (int, int64, error) {
+	if format != ExportMbox && format != ExportZip {
+		return 0, 0, fmt.Errorf("unsupported export format %q (use %s or %s)", format, ExportMbox, ExportZip)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := c.selectFolder(folder, true); err != nil {
+		return 0, 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	uids, err := c.client.UidSearch(imap.NewSearchCriteria())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to search emails: %w", err)
+	}
+
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	count, total, err := c.exportMessages(ctx, uids, f, format)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to write export file: %w", cerr)
+	}
+	if err != nil {
+		os.Remove(destPath)
+		return 0, 0, err
+	}
+	return count, total, nil
+}
+
+// exportMessages streams the messages with the given UIDs into w in the
+// requested format (caller must hold c.mu and have selected the folder).
+func (c *Client) exportMessages(ctx context.Context, uids []uint32, w io.Writer, format string) (int, int64, error) {
+	buf := bufio.NewWriter(w)
+	var zw *zip.Writer
+	if format == ExportZip {
+		zw = zip.NewWriter(buf)
+	}
+
+	count := 0
+	var total int64
+	if len(uids) > 0 {
+		seqSet := new(imap.SeqSet)
+		seqSet.AddNum(uids...)
+
+		section := &imap.BodySectionName{}
+		items := []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, imap.FetchInternalDate, section.FetchItem()}
+
+		var writeErr error
+		err := c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+			return c.client.UidFetch(seqSet, items, messages)
+		}, func(msg *imap.Message) {
+			if writeErr != nil {
+				return // drain so the fetch can finish
+			}
+
+			var raw []byte
+			for _, literal := range msg.Body {
+				raw, writeErr = io.ReadAll(literal)
+				break
+			}
+			if writeErr != nil || raw == nil {
+				return
+			}
+
+			if zw != nil {
+				writeErr = writeZipEntry(zw, msg, raw)
+			} else {
+				writeErr = writeMboxEntry(buf, msg, raw)
+			}
+			count++
+			total += int64(len(raw))
+		})
+		if err != nil && ctx.Err() != nil {
+			return 0, 0, err
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch messages: %w", err)
+		}
+		if writeErr != nil {
+			return 0, 0, fmt.Errorf("failed to write export file: %w", writeErr)
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return 0, 0, fmt.Errorf("failed to write export file: %w", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	return count, total, nil
+}
+
+// ExportedEmail describes a zip written by ExportEmail
+type ExportedEmail struct {
+	Entries []string // zip entry names, the message source first
+	Skipped []string // attachments refused by the filter; still inside the .eml
+	Size    int64    // size of the message source
+}
+
+// ExportEmail writes one message to a zip at destPath: its raw source as
+// <uid>.eml, followed by each attachment as its own entry named by its
+// filename. Attachments are decoded straight into the zip rather than held
+// in memory. When filter is non-nil, an attachment it returns an error for
+// is left out and listed in Skipped. The message is peeked, so it stays
+// unread, and the file is removed if the export fails.
+func (c *Client) ExportEmail(ctx context.Context, folder, emailID, destPath string, filter func(filename, mimeType string) error) (*ExportedEmail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.fetchRaw(folder, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	exported, err := writeEmailZip(ctx, f, emailID, raw, filter)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to write export file: %w", cerr)
+	}
+	if err != nil {
+		os.Remove(destPath)
+		return nil, err
+	}
+	return exported, nil
+}
+
+// writeEmailZip writes the zip ExportEmail describes into w
+func writeEmailZip(ctx context.Context, w io.Writer, emailID string, raw []byte, filter func(filename, mimeType string) error) (*ExportedEmail, error) {
+	buf := bufio.NewWriter(w)
+	zw := zip.NewWriter(buf)
+	exported := &ExportedEmail{Entries: []string{}, Skipped: []string{}, Size: int64(len(raw))}
+
+	mr, err := message.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+	defer mr.Close()
+	modified, _ := mr.Header.Date()
+
+	emlName := emailID + ".eml"
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: emlName, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if _, err := entry.Write(raw); err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+	exported.Entries = append(exported.Entries, emlName)
+
+	used := map[string]bool{strings.ToLower(emlName): true}
+	unnamed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message part: %w", err)
+		}
+
+		h, ok := part.Header.(*message.AttachmentHeader)
+		if !ok {
+			continue
+		}
+		filename, _ := h.Filename()
+		mimeType, _, _ := h.ContentType()
+		if filter != nil {
+			if err := filter(filename, mimeType); err != nil {
+				exported.Skipped = append(exported.Skipped, filename)
+				continue
+			}
+		}
+
+		name := zipEntryName(filename)
+		if name == "" {
+			unnamed++
+			name = fmt.Sprintf("attachment-%d", unnamed)
+		}
+		name = uniqueEntryName(name, used)
+
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write export file: %w", err)
+		}
+		if _, err := io.Copy(entry, part.Body); err != nil {
+			return nil, fmt.Errorf("failed to write attachment %q: %w", name, err)
+		}
+		exported.Entries = append(exported.Entries, name)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := buf.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+	return exported, nil
+}
+
+// zipEntryName reduces an attachment filename to a flat entry name, so that
+// a name like "../../evil" or "a/b.pdf" cannot place a file outside the
+// folder it is extracted to. It returns "" when nothing usable is left.
+func zipEntryName(filename string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(filename))
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if strings.Trim(name, ".") == "" {
+		return ""
+	}
+	return name
+}
+
+// uniqueEntryName returns name, or name with " (2)", " (3)", ... before its
+// extension if that is already taken, and records the result in used.
+// Names are compared case-insensitively, as most filesystems do.
+func uniqueEntryName(name string, used map[string]bool) string {
+	candidate := name
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// writeZipEntry adds raw as <uid>.eml, stamped with the message's arrival time.
+func writeZipEntry(zw *zip.Writer, msg *imap.Message, raw []byte) error {
+	header := &zip.FileHeader{
+		Name:     fmt.Sprintf("%d.eml", msg.Uid),
+		Method:   zip.Deflate,
+		Modified: msg.InternalDate,
+	}
+	entry, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(raw)
+	return err
+}
+
+// writeMboxEntry appends raw in mboxrd form: a "From " separator line, the
+// message with CRLF line endings converted to LF and any body line matching
+// ">*From " quoted with an extra '>', then a blank line.
+func writeMboxEntry(w io.Writer, msg *imap.Message, raw []byte) error {
+	sender := "MAILER-DAEMON"
+	if msg.Envelope != nil && len(msg.Envelope.From) > 0 {
+		if addr := msg.Envelope.From[0].Address(); addr != "" {
+			sender = addr
+		}
+	}
+	date := msg.InternalDate
+	if date.IsZero() {
+		date = time.Unix(0, 0)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From %s %s\n", sender, date.UTC().Format(time.ANSIC))
+
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if isMboxFromLine(line) {
+			out.WriteByte('>')
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	out.WriteByte('\n')
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// isMboxFromLine reports whether line is "From " preceded by zero or more '>'.
+func isMboxFromLine(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From "))
+}
@@ -0,0 +1,50 @@
+package imap
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap"
+)
+
+// FlaggedFlag is the system flag set on every flagged email, whatever its
+// type or color
+const FlaggedFlag = imap.FlaggedFlag
+
+// flagTypeKeywords maps flag_email's flag types to the keywords Apple Mail
+// stores for them
+var flagTypeKeywords = map[string]string{
+	"follow-up": "$FollowUp",
+	"important": "$Important",
+	"deadline":  "$Deadline",
+}
+
+// flagColorKeywords maps flag colors to their keywords
+var flagColorKeywords = map[string]string{
+	"red":    "$FlagRed",
+	"orange": "$FlagOrange",
+	"yellow": "$FlagYellow",
+	"green":  "$FlagGreen",
+	"blue":   "$FlagBlue",
+	"purple": "$FlagPurple",
+}
+
+// FlagKeywords returns the keywords for a flag type and color, in that
+// order. Either may be empty, in which case it contributes no keyword.
+func FlagKeywords(flagType, color string) ([]string, error) {
+	var keywords []string
+	if flagType != "" {
+		keyword, ok := flagTypeKeywords[flagType]
+		if !ok {
+			return nil, fmt.Errorf("invalid flag type %q: must be one of follow-up, important, deadline", flagType)
+		}
+		keywords = append(keywords, keyword)
+	}
+	if color != "" {
+		keyword, ok := flagColorKeywords[color]
+		if !ok {
+			return nil, fmt.Errorf("invalid color %q: must be one of red, orange, yellow, green, blue, purple", color)
+		}
+		keywords = append(keywords, keyword)
+	}
+	return keywords, nil
+}
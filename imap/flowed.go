@@ -0,0 +1,76 @@
+package imap
+
+import (
+	"strings"
+)
+
+// UnfoldFlowed replaces a format=flowed (RFC 3676) plain text body with its
+// unfolded form, joining soft-wrapped lines into paragraphs. Bodies that are
+// not format=flowed are left alone.
+func (e *Email) UnfoldFlowed() {
+	if !e.Flowed {
+		return
+	}
+
+	unfolded := unfoldFlowed(e.BodyPlain, e.flowedDelSp)
+	if e.BestBody == strings.TrimSpace(e.BodyPlain) {
+		e.BestBody = strings.TrimSpace(unfolded)
+	}
+	e.BodyPlain = unfolded
+	e.Flowed = false
+}
+
+// unfoldFlowed joins format=flowed soft line breaks. A line ending in a space
+// continues onto the next line at the same quote depth; with delSp the
+// trailing space is a wrapping artifact and is removed. Quote markers are
+// normalized to "> " and space-stuffing is undone.
+func unfoldFlowed(text string, delSp bool) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var out []string
+	var para strings.Builder
+	paraDepth := -1 // quote depth of the paragraph in progress; -1 if none
+
+	flush := func() {
+		if paraDepth >= 0 {
+			out = append(out, quotePrefix(paraDepth)+para.String())
+			para.Reset()
+			paraDepth = -1
+		}
+	}
+
+	for _, line := range lines {
+		depth := 0
+		for depth < len(line) && line[depth] == '>' {
+			depth++
+		}
+		content := strings.TrimPrefix(line[depth:], " ")
+
+		// A change in quote depth always ends the paragraph
+		if paraDepth >= 0 && depth != paraDepth {
+			flush()
+		}
+
+		flowed := strings.HasSuffix(content, " ") && content != "-- "
+		if flowed && delSp {
+			content = strings.TrimSuffix(content, " ")
+		}
+
+		para.WriteString(content)
+		paraDepth = depth
+		if !flowed {
+			flush()
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// quotePrefix returns the quote marker for depth levels of quoting
+func quotePrefix(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return strings.Repeat(">", depth) + " "
+}
@@ -0,0 +1,235 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-imap"
+)
+
+// folderAlias describes how a friendly folder name maps to a server folder:
+// the SPECIAL-USE attribute to look for, then well-known names to try.
+type folderAlias struct {
+	attr      string
+	fallbacks []string
+}
+
+// folderAliases maps lowercase friendly names to their special-use folders.
+var folderAliases = map[string]folderAlias{
+	"sent":    {imap.SentAttr, []string{"Sent Messages", "Sent"}},
+	"drafts":  {imap.DraftsAttr, []string{"Drafts", "INBOX.Drafts", "[Gmail]/Drafts"}},
+	"trash":   {imap.TrashAttr, []string{"Deleted Messages", "Trash"}},
+	"junk":    {imap.JunkAttr, []string{"Junk", "Spam"}},
+	"archive": {imap.ArchiveAttr, []string{"Archive"}},
+}
+
+// ErrProtectedFolder is returned when a destructive operation targets one of
+// ClientOptions.ProtectedFolders.
+var ErrProtectedFolder = errors.New("folder is protected")
+
+// ErrFolderTooDeep is returned by CreateFolder when the new folder would be
+// nested deeper than ClientOptions.MaxFolderDepth.
+var ErrFolderTooDeep = errors.New("folder nested too deeply")
+
+// checkFolderDepth returns ErrFolderTooDeep if path has more levels than
+// ClientOptions.MaxFolderDepth. Levels are the segments between hierarchy
+// delimiters, so a namespace prefix such as "INBOX." counts as one.
+// Caller must hold c.mu.
+func (c *Client) checkFolderDepth(path string) error {
+	if c.opts.MaxFolderDepth <= 0 {
+		return nil
+	}
+	delimiter, err := c.folderDelimiter()
+	if err != nil {
+		return err
+	}
+	if depth := len(strings.Split(path, delimiter)); depth > c.opts.MaxFolderDepth {
+		return fmt.Errorf("%w: %s has %d levels, more than the %d allowed (see MAX_FOLDER_DEPTH)", ErrFolderTooDeep, path, depth, c.opts.MaxFolderDepth)
+	}
+	return nil
+}
+
+// checkProtected returns ErrProtectedFolder if name, or the folder it resolves
+// to, is protected. Protected entries may themselves be aliases such as
+// "trash", and are compared case-insensitively to err on the side of refusing.
+// Caller must hold c.mu.
+func (c *Client) checkProtected(name string) error {
+	if len(c.opts.ProtectedFolders) == 0 {
+		return nil
+	}
+
+	targets := []string{name}
+	if resolved, err := c.resolveFolder(name); err == nil && resolved != name {
+		targets = append(targets, resolved)
+	}
+
+	for _, p := range c.opts.ProtectedFolders {
+		candidates := []string{p}
+		if resolved, err := c.resolveFolder(p); err == nil && resolved != p {
+			candidates = append(candidates, resolved)
+		}
+		for _, t := range targets {
+			for _, cand := range candidates {
+				if strings.EqualFold(t, cand) {
+					return fmt.Errorf("%w: %s (see PROTECTED_FOLDERS)", ErrProtectedFolder, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckProtected returns ErrProtectedFolder if name is one of
+// ClientOptions.ProtectedFolders, for callers that remove mail from a folder
+// through several calls, e.g. one DeleteEmail per duplicate.
+func (c *Client) CheckProtected(ctx context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checkProtected(name)
+}
+
+// ResolveFolder maps a friendly alias (inbox, sent, drafts, trash, junk,
+// archive; case-insensitive) to the server's folder name. Any other name,
+// or an alias that is also the exact name of an existing folder, is
+// returned unchanged.
+func (c *Client) ResolveFolder(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resolveFolder(name)
+}
+
+// Subscribe adds a folder to the subscription list (IMAP SUBSCRIBE), which
+// some mail clients use to decide which folders to show
+func (c *Client) Subscribe(ctx context.Context, folder string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+	if err := c.client.Subscribe(folder); err != nil {
+		return fmt.Errorf("failed to subscribe to folder %s: %w", folder, err)
+	}
+	return nil
+}
+
+// Unsubscribe removes a folder from the subscription list (IMAP UNSUBSCRIBE).
+// The folder and its emails are kept.
+func (c *Client) Unsubscribe(ctx context.Context, folder string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+	if err := c.client.Unsubscribe(folder); err != nil {
+		return fmt.Errorf("failed to unsubscribe from folder %s: %w", folder, err)
+	}
+	return nil
+}
+
+// resolveFolder is the internal implementation (caller must hold c.mu)
+func (c *Client) resolveFolder(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "inbox" {
+		return "INBOX", nil
+	}
+	alias, ok := folderAliases[key]
+	if !ok {
+		return name, nil
+	}
+
+	mailboxes, err := c.listMailboxes()
+	if err != nil {
+		return "", err
+	}
+	for _, m := range mailboxes {
+		if m.Name == name {
+			return name, nil
+		}
+	}
+	if found := pickSpecialFolder(mailboxes, alias.attr, alias.fallbacks); found != "" {
+		return found, nil
+	}
+	return name, nil
+}
+
+// specialFolder returns the mailbox carrying the given SPECIAL-USE attribute
+// (e.g. imap.DraftsAttr). Servers that don't advertise special-use get the
+// first existing fallback name, or the first fallback if none exist.
+// Caller must hold c.mu.
+func (c *Client) specialFolder(attr string, fallbacks ...string) (string, error) {
+	mailboxes, err := c.listMailboxes()
+	if err != nil {
+		return "", err
+	}
+	if found := pickSpecialFolder(mailboxes, attr, fallbacks); found != "" {
+		return found, nil
+	}
+	return fallbacks[0], nil
+}
+
+// pickSpecialFolder returns the first mailbox with attr, else the first
+// fallback name that exists, else "".
+func pickSpecialFolder(mailboxes []*imap.MailboxInfo, attr string, fallbacks []string) string {
+	existing := make(map[string]bool, len(mailboxes))
+	for _, m := range mailboxes {
+		existing[m.Name] = true
+		for _, a := range m.Attributes {
+			if strings.EqualFold(a, attr) {
+				return m.Name
+			}
+		}
+	}
+	for _, name := range fallbacks {
+		if existing[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// listMailboxes returns every mailbox with its attributes (caller must hold c.mu)
+func (c *Client) listMailboxes() ([]*imap.MailboxInfo, error) {
+	ch := make(chan *imap.MailboxInfo, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.client.List("", "*", ch)
+	}()
+
+	var mailboxes []*imap.MailboxInfo
+	for m := range ch {
+		mailboxes = append(mailboxes, m)
+	}
+
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+
+	return mailboxes, nil
+}
+
+// FolderFlags returns the flags defined for folder and the subset the server
+// will store permanently. A "\*" in permanentFlags means clients may create
+// custom keywords.
+func (c *Client) FolderFlags(ctx context.Context, folder string) (flags []string, permanentFlags []string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err = c.resolveFolder(folder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, err := c.selectFolder(folder, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	return status.Flags, status.PermanentFlags, nil
+}
@@ -0,0 +1,145 @@
+package imap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/emersion/go-imap"
+)
+
+// GetHeaders fetches only the named header fields of an email, using
+// BODY.PEEK[HEADER.FIELDS (...)] so the body is never downloaded and the
+// message is not marked as read. The result maps each field present in the
+// message, in canonical form (e.g. "X-Mailer"), to its decoded values in
+// header order. Requested fields the message lacks are omitted.
+func (c *Client) GetHeaders(ctx context.Context, folder, emailID string, fields []string) (map[string][]string, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("at least one header field is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.selectFolder(folder, true); err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	var uid uint32
+	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
+		return nil, fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	section := &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier, Fields: fields},
+		Peek:         true,
+	}
+
+	messages := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+	}()
+
+	msg := <-messages
+	if msg == nil {
+		<-done
+		return nil, fmt.Errorf("email %w", ErrNotFound)
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to fetch headers: %w", err)
+	}
+
+	var raw []byte
+	for _, literal := range msg.Body {
+		if raw, err = io.ReadAll(literal); err != nil {
+			return nil, fmt.Errorf("failed to read headers: %w", err)
+		}
+		break
+	}
+
+	return selectHeaders(raw, fields)
+}
+
+// selectHeaders parses a raw header block and keeps only the given fields.
+func selectHeaders(raw []byte, fields []string) (map[string][]string, error) {
+	// Terminate the header block so it parses even without a body separator
+	m, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(raw), bytes.NewReader([]byte("\r\n"))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse headers: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	headers := map[string][]string{}
+	for _, field := range fields {
+		key := textproto.CanonicalMIMEHeaderKey(field)
+		for _, v := range m.Header[key] {
+			if decoded, err := dec.DecodeHeader(v); err == nil {
+				v = decoded
+			}
+			headers[key] = append(headers[key], v)
+		}
+	}
+	return headers, nil
+}
+
+// referencesSection fetches just the References header alongside a search
+// result's envelope, which carries In-Reply-To but not References
+var referencesSection = &imap.BodySectionName{
+	BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier, Fields: []string{"References"}},
+	Peek:         true,
+}
+
+// fetchedReferences returns the References header of msg if
+// referencesSection was fetched, or ""
+func fetchedReferences(msg *imap.Message) string {
+	literal := msg.GetBody(referencesSection)
+	if literal == nil {
+		return ""
+	}
+	raw, err := io.ReadAll(literal)
+	if err != nil {
+		return ""
+	}
+	headers, err := selectHeaders(raw, []string{"References"})
+	if err != nil {
+		return ""
+	}
+	return strings.Join(headers["References"], " ")
+}
+
+// referenceIDs returns the message IDs in a References header, oldest
+// first, followed by In-Reply-To if References does not already list it
+func referenceIDs(inReplyTo, references string) []string {
+	var ids []string
+	for rest := references; ; {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '>')
+		if end < 0 {
+			break
+		}
+		ids = append(ids, rest[start:start+end+1])
+		rest = rest[start+end+1:]
+	}
+	if inReplyTo = strings.TrimSpace(inReplyTo); inReplyTo != "" && !slices.Contains(ids, inReplyTo) {
+		ids = append(ids, inReplyTo)
+	}
+	return ids
+}
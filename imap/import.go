@@ -0,0 +1,100 @@
+package imap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"time"
+)
+
+// AppendMessage stores a raw RFC 5322 message in folder with the given flags.
+// The message's internal date is taken from its Date header; when that is
+// missing or unparseable the server assigns the current time.
+func (c *Client) AppendMessage(ctx context.Context, folder string, raw []byte, flags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.Append(folder, flags, messageDate(raw), bytes.NewReader(raw)); err != nil {
+		return fmt.Errorf("failed to append message to %s: %w", folder, err)
+	}
+	return nil
+}
+
+// messageDate returns the parsed Date header of raw, or the zero time.
+func messageDate(raw []byte) time.Time {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return time.Time{}
+	}
+	date, err := msg.Header.Date()
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
+
+// ReadMbox splits an mbox stream into messages and calls fn with each one,
+// in order, as soon as it has been read. A "From " line at the start of the
+// stream or after a blank line begins a new message and is not included in
+// it. Body lines escaped as ">From " (with any number of '>') lose one '>',
+// and line endings are normalized to CRLF. The slice passed to fn is reused
+// once fn returns. ReadMbox stops at the first error returned by fn.
+func ReadMbox(r io.Reader, fn func(raw []byte) error) error {
+	br := bufio.NewReader(r)
+	var msg bytes.Buffer
+	inMessage := false
+	prevBlank := true
+
+	flush := func() error {
+		if !inMessage {
+			return nil
+		}
+		// The blank line before the next separator belongs to the mbox
+		raw := msg.Bytes()
+		if bytes.HasSuffix(raw, []byte("\r\n\r\n")) {
+			raw = raw[:len(raw)-2]
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			return nil
+		}
+		return fn(raw)
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\r\n")
+			switch {
+			case prevBlank && bytes.HasPrefix(line, []byte("From ")):
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+				msg.Reset()
+				inMessage = true
+			case inMessage:
+				if bytes.HasPrefix(line, []byte(">")) && isMboxFromLine(line) {
+					line = line[1:]
+				}
+				msg.Write(line)
+				msg.WriteString("\r\n")
+			}
+			prevBlank = len(line) == 0
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mbox: %w", err)
+		}
+	}
+	return flush()
+}
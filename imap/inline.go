@@ -0,0 +1,68 @@
+package imap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/emersion/go-imap"
+	message "github.com/emersion/go-message/mail"
+)
+
+// bufferBody reads msg's body literal into memory and puts an equivalent
+// reader back, so the message can be parsed more than once
+func bufferBody(msg *imap.Message) ([]byte, error) {
+	for section, literal := range msg.Body {
+		if literal == nil {
+			return nil, nil
+		}
+		raw, err := io.ReadAll(literal)
+		if err != nil {
+			return nil, err
+		}
+		msg.Body[section] = bytes.NewReader(raw)
+		return raw, nil
+	}
+	return nil, nil
+}
+
+// inlineAttachments sets Content, base64-encoded, and MIMEType on each of
+// email.Attachments whose decoded size is at most limit bytes. raw is the
+// message email was parsed from; its attachments are matched to
+// email.Attachments in the order processMessagePart lists them.
+func inlineAttachments(email *Email, raw []byte, limit int64) {
+	if len(email.Attachments) == 0 {
+		return
+	}
+	mr, err := message.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		return
+	}
+
+	i := 0
+	for i < len(email.Attachments) {
+		part, err := mr.NextPart()
+		if err != nil {
+			return
+		}
+		h, ok := part.Header.(*message.AttachmentHeader)
+		if !ok {
+			continue
+		}
+		if filename, _ := h.Filename(); filename == "" {
+			continue
+		}
+
+		att := &email.Attachments[i]
+		i++
+		if att.Size > limit {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part.Body, limit+1))
+		if err != nil || int64(len(data)) > limit {
+			continue
+		}
+		att.MIMEType, _, _ = h.ContentType()
+		att.Content = base64.StdEncoding.EncodeToString(data)
+	}
+}
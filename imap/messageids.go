@@ -0,0 +1,202 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+)
+
+// MessageIDs returns the Message-ID of every message in folder that has
+// one. Unlike SearchEmails, a partial fetch is an error: callers use the set
+// to exclude messages, and a missing ID would silently let one through.
+func (c *Client) MessageIDs(ctx context.Context, folder string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	byUID, err := c.searchMessageIDs(ctx, folder, "", EmailFilters{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(byUID))
+	for _, id := range byUID {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SearchEmailsExcluding runs the same search as SearchEmails but leaves out
+// the messages whose Message-ID is in exclude before applying offset and
+// limit, so pages stay full. Only UIDs and Message-ID headers are read for
+// every match; envelopes are fetched for the page that remains. It returns
+// the page, the total after exclusion, how many matches were excluded, and
+// the folder's UIDVALIDITY. Messages without a Message-ID are always kept.
+func (c *Client) SearchEmailsExcluding(ctx context.Context, folder, query string, filters EmailFilters, exclude []string) (emails []Email, total, excluded int, uidValidity uint32, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err = c.resolveFolder(folder)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	all := filters
+	all.Offset, all.Limit = 0, 0
+	byUID, err := c.searchMessageIDs(ctx, folder, query, all)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	skip := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		skip[NormalizeMessageID(id)] = true
+	}
+	var kept []uint32
+	for _, uid := range slices.Sorted(maps.Keys(byUID)) {
+		if id := byUID[uid]; id != "" && skip[id] {
+			continue
+		}
+		kept = append(kept, uid)
+	}
+	excluded = len(byUID) - len(kept)
+
+	batch := c.opts.FetchBatchSize
+	if batch <= 0 {
+		batch = DefaultFetchBatchSize
+	}
+	emails = []Email{}
+	total, err = c.fetchPages(ctx, folder, pageUIDs(kept, filters.Offset, filters.Limit), len(kept), batch, func(page []Email) error {
+		emails = append(emails, page...)
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrPartialResults) {
+		return nil, 0, 0, 0, err
+	}
+	return emails, total, excluded, c.selectedUIDValidity(folder), err
+}
+
+// searchMessageIDs searches folder and maps each matching UID to its
+// normalized Message-ID, "" for a message without one. Only the Message-ID
+// header is fetched, FetchBatchSize messages at a time, and a partial fetch
+// is an error (caller must hold c.mu).
+func (c *Client) searchMessageIDs(ctx context.Context, folder, query string, filters EmailFilters) (map[uint32]string, error) {
+	var uids []uint32
+	err := c.withRetry(ctx, func() error {
+		var err error
+		uids, _, err = c.searchUIDs(folder, query, filters)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch := c.opts.FetchBatchSize
+	if batch <= 0 {
+		batch = DefaultFetchBatchSize
+	}
+	section := &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier, Fields: []string{"Message-Id"}},
+		Peek:         true,
+	}
+
+	byUID := make(map[uint32]string, len(uids))
+	for start := 0; start < len(uids); start += batch {
+		seqSet := new(imap.SeqSet)
+		seqSet.AddNum(uids[start:min(start+batch, len(uids))]...)
+		err := c.withRetry(ctx, func() error {
+			// A reconnect between attempts leaves no folder selected
+			if c.selected != folder {
+				if _, err := c.selectFolder(folder, false); err != nil {
+					return fmt.Errorf("failed to select folder %s: %w", folder, err)
+				}
+			}
+			return c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+				return c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+			}, func(msg *imap.Message) {
+				byUID[msg.Uid] = ""
+				literal := msg.GetBody(section)
+				if literal == nil {
+					return
+				}
+				raw, err := io.ReadAll(literal)
+				if err != nil {
+					return
+				}
+				if headers, err := selectHeaders(raw, []string{"Message-Id"}); err == nil && len(headers["Message-Id"]) > 0 {
+					byUID[msg.Uid] = NormalizeMessageID(headers["Message-Id"][0])
+				}
+			})
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to fetch Message-IDs: %w", err)
+		}
+	}
+	return byUID, nil
+}
+
+// FindByMessageID returns the UID of the message in folder with the given
+// Message-ID, taking the highest UID when there are several. It is how a
+// moved message is found again, since COPY gives no new UID without UIDPLUS.
+func (c *Client) FindByMessageID(ctx context.Context, folder, messageID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return "", err
+	}
+	if messageID == "" {
+		return "", fmt.Errorf("%w: empty Message-ID", ErrInvalidID)
+	}
+
+	if _, err := c.selectFolder(folder, true); err != nil {
+		return "", fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+	uid, err := c.newestByMessageID(messageID)
+	if err != nil {
+		return "", fmt.Errorf("failed to search folder %s: %w", folder, err)
+	}
+	if uid == 0 {
+		return "", fmt.Errorf("email %w in %s", ErrNotFound, folder)
+	}
+	return strconv.FormatUint(uint64(uid), 10), nil
+}
+
+// newestByMessageID returns the highest UID in the selected folder with the
+// given Message-ID, or 0 when there is none. Caller must hold c.mu.
+func (c *Client) newestByMessageID(messageID string) (uint32, error) {
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Set("Message-Id", messageID)
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return 0, err
+	}
+	var newest uint32
+	for _, u := range uids {
+		newest = max(newest, u)
+	}
+	return newest, nil
+}
+
+// NormalizeMessageID trims whitespace and angle brackets from a Message-ID
+// so IDs from envelopes and headers compare equal.
+func NormalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
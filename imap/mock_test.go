@@ -0,0 +1,407 @@
+package imap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/responses"
+)
+
+// MockBackend implements backend for testing. Messages are held per folder
+// and served by UidFetch; UidSearch returns every UID in the selected folder
+// unless SearchResults overrides it.
+type MockBackend struct {
+	// Folder contents
+	Mailboxes     map[string][]*imap.Message
+	SearchResults map[string][]uint32
+	Attributes    map[string][]string // LIST attributes, e.g. special-use
+
+	// Server capabilities and NAMESPACE/LIST hierarchy details. Delimiter
+	// defaults to "/"; a nil Namespace means NAMESPACE returns NIL.
+	Capabilities []string
+	Namespace    []interface{}
+	Delimiter    string
+
+	// CopyUID is the destination UID reported in COPYUID by a UID MOVE or
+	// UID COPY sent through Execute; 0 sends no COPYUID
+	CopyUID uint32
+
+	// Reported by every Select. UIDValidity defaults to 1 for folders
+	// missing from it.
+	Flags          []string
+	PermanentFlags []string
+	UIDValidity    map[string]uint32
+
+	// Error injection, keyed by method name. Transient errors are returned
+	// by successive calls, one each, before the method succeeds.
+	Errs      map[string]error
+	Transient map[string][]error
+
+	// Hooks run at the start of the named method, e.g. to hold a call
+	// in flight while a test checks what else is running
+	Hooks map[string]func()
+
+	// Call tracking
+	Calls          []string
+	Selected       string
+	LastCriteria   *imap.SearchCriteria
+	LastFetchItems []imap.FetchItem
+	LastDest       string
+	LastSeqSet     *imap.SeqSet
+	LastStoreItem  imap.StoreItem
+	LastStoreValue interface{}
+	LastCommand    *imap.Command
+	Subscribed     []string
+	Unsubscribed   []string
+	Appended       []string
+	AppendedBodies []string
+	AppendedDates  []time.Time
+}
+
+func (m *MockBackend) call(method string) error {
+	if hook := m.Hooks[method]; hook != nil {
+		hook()
+	}
+	m.Calls = append(m.Calls, method)
+	if errs := m.Transient[method]; len(errs) > 0 {
+		m.Transient[method] = errs[1:]
+		return errs[0]
+	}
+	return m.Errs[method]
+}
+
+// Called reports how many times method was invoked.
+func (m *MockBackend) Called(method string) int {
+	n := 0
+	for _, c := range m.Calls {
+		if c == method {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *MockBackend) Select(name string, readOnly bool) (*imap.MailboxStatus, error) {
+	if err := m.call("Select"); err != nil {
+		return nil, err
+	}
+	if _, ok := m.Mailboxes[name]; !ok {
+		return nil, fmt.Errorf("no such mailbox: %s", name)
+	}
+	m.Selected = name
+	status := imap.NewMailboxStatus(name, nil)
+	status.Messages = uint32(len(m.Mailboxes[name]))
+	status.UidValidity = 1
+	if v, ok := m.UIDValidity[name]; ok {
+		status.UidValidity = v
+	}
+	for _, msg := range m.Mailboxes[name] {
+		if msg.Uid >= status.UidNext {
+			status.UidNext = msg.Uid + 1
+		}
+	}
+	status.Flags = m.Flags
+	status.PermanentFlags = m.PermanentFlags
+	return status, nil
+}
+
+func (m *MockBackend) List(ref, name string, ch chan *imap.MailboxInfo) error {
+	defer close(ch)
+	if err := m.call("List"); err != nil {
+		return err
+	}
+	// LIST "" "" only reports the hierarchy delimiter
+	if name == "" {
+		ch <- &imap.MailboxInfo{Delimiter: m.delimiter(), Attributes: []string{imap.NoSelectAttr}}
+		return nil
+	}
+	for folder := range m.Mailboxes {
+		ch <- &imap.MailboxInfo{Name: folder, Delimiter: m.delimiter(), Attributes: m.Attributes[folder]}
+	}
+	return nil
+}
+
+func (m *MockBackend) delimiter() string {
+	if m.Delimiter == "" {
+		return "/"
+	}
+	return m.Delimiter
+}
+
+func (m *MockBackend) UidSearch(criteria *imap.SearchCriteria) ([]uint32, error) {
+	m.LastCriteria = criteria
+	if err := m.call("UidSearch"); err != nil {
+		return nil, err
+	}
+	if uids, ok := m.SearchResults[m.Selected]; ok {
+		return uids, nil
+	}
+	var uids []uint32
+	for _, msg := range m.Mailboxes[m.Selected] {
+		if criteria.Larger > 0 && msg.Size <= criteria.Larger {
+			continue
+		}
+		if hasAnyFlag(msg.Flags, criteria.WithoutFlags) {
+			continue
+		}
+		if !hasAllFlags(msg.Flags, criteria.WithFlags) {
+			continue
+		}
+		uids = append(uids, msg.Uid)
+	}
+	return uids, nil
+}
+
+// hasAllFlags reports whether flags contains every one of want
+func hasAllFlags(flags, want []string) bool {
+	for _, w := range want {
+		if !hasAnyFlag(flags, []string{w}) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyFlag reports whether flags contains one of want
+func hasAnyFlag(flags, want []string) bool {
+	for _, f := range flags {
+		for _, w := range want {
+			if f == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Fetch addresses messages by their 1-based position in the selected mailbox
+func (m *MockBackend) Fetch(seqset *imap.SeqSet, items []imap.FetchItem, ch chan *imap.Message) error {
+	defer close(ch)
+	m.LastFetchItems = items
+	err := m.call("Fetch")
+	for i, msg := range m.Mailboxes[m.Selected] {
+		if seqset.Contains(uint32(i + 1)) {
+			ch <- msg
+		}
+	}
+	return err
+}
+
+// UidFetch delivers the matching messages and then returns any injected
+// error, mimicking a server that fails partway through a response.
+func (m *MockBackend) UidFetch(seqset *imap.SeqSet, items []imap.FetchItem, ch chan *imap.Message) error {
+	defer close(ch)
+	m.LastFetchItems = items
+	err := m.call("UidFetch")
+	for _, msg := range m.Mailboxes[m.Selected] {
+		if seqset.Contains(msg.Uid) {
+			ch <- withMessageIDHeader(msg, items)
+		}
+	}
+	return err
+}
+
+// withMessageIDHeader answers a BODY.PEEK[HEADER.FIELDS (Message-Id)] fetch
+// item from the envelope, returning a copy of msg that carries the section
+func withMessageIDHeader(msg *imap.Message, items []imap.FetchItem) *imap.Message {
+	for _, item := range items {
+		section, err := imap.ParseBodySectionName(item)
+		if err != nil || section.Specifier != imap.HeaderSpecifier || len(section.Fields) != 1 || !strings.EqualFold(section.Fields[0], "Message-Id") {
+			continue
+		}
+		header := "\r\n"
+		if msg.Envelope != nil && msg.Envelope.MessageId != "" {
+			header = "Message-Id: " + msg.Envelope.MessageId + "\r\n\r\n"
+		}
+		section.Peek = false // responses never echo .PEEK
+		cp := *msg
+		cp.Body = map[*imap.BodySectionName]imap.Literal{section: bytes.NewBufferString(header)}
+		return &cp
+	}
+	return msg
+}
+
+func (m *MockBackend) UidStore(seqset *imap.SeqSet, item imap.StoreItem, value interface{}, ch chan *imap.Message) error {
+	m.LastSeqSet = seqset
+	m.LastStoreItem = item
+	m.LastStoreValue = value
+	return m.call("UidStore")
+}
+
+func (m *MockBackend) UidCopy(seqset *imap.SeqSet, dest string) error {
+	m.LastDest = dest
+	m.LastSeqSet = seqset
+	return m.call("UidCopy")
+}
+
+func (m *MockBackend) UidMove(seqset *imap.SeqSet, dest string) error {
+	m.LastDest = dest
+	m.LastSeqSet = seqset
+	return m.call("UidMove")
+}
+
+// Expunge removes the selected mailbox's \Deleted messages, reporting each
+// one's sequence number as it stands at the time of its removal
+func (m *MockBackend) Expunge(ch chan uint32) error {
+	if ch != nil {
+		defer close(ch)
+	}
+	if err := m.call("Expunge"); err != nil {
+		return err
+	}
+	var kept []*imap.Message
+	for _, msg := range m.Mailboxes[m.Selected] {
+		if hasAnyFlag(msg.Flags, []string{imap.DeletedFlag}) {
+			if ch != nil {
+				ch <- uint32(len(kept) + 1)
+			}
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	if m.Mailboxes[m.Selected] != nil {
+		m.Mailboxes[m.Selected] = kept
+	}
+	return nil
+}
+
+func (m *MockBackend) Append(mbox string, flags []string, date time.Time, msg imap.Literal) error {
+	if err := m.call("Append"); err != nil {
+		return err
+	}
+	m.Appended = append(m.Appended, mbox)
+	m.AppendedDates = append(m.AppendedDates, date)
+	body, err := io.ReadAll(msg)
+	if err != nil {
+		return err
+	}
+	m.AppendedBodies = append(m.AppendedBodies, string(body))
+	return nil
+}
+
+func (m *MockBackend) Create(name string) error {
+	if err := m.call("Create"); err != nil {
+		return err
+	}
+	if m.Mailboxes != nil {
+		m.Mailboxes[name] = nil
+	}
+	return nil
+}
+
+func (m *MockBackend) Subscribe(name string) error {
+	if err := m.call("Subscribe"); err != nil {
+		return err
+	}
+	m.Subscribed = append(m.Subscribed, name)
+	return nil
+}
+
+func (m *MockBackend) Unsubscribe(name string) error {
+	if err := m.call("Unsubscribe"); err != nil {
+		return err
+	}
+	m.Unsubscribed = append(m.Unsubscribed, name)
+	return nil
+}
+
+func (m *MockBackend) Delete(name string) error {
+	if err := m.call("Delete"); err != nil {
+		return err
+	}
+	delete(m.Mailboxes, name)
+	return nil
+}
+
+func (m *MockBackend) Support(capability string) (bool, error) {
+	if err := m.call("Support"); err != nil {
+		return false, err
+	}
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Execute answers the NAMESPACE command with the configured personal
+// namespace, creates the mailbox named by a CREATE, and acknowledges UID
+// MOVE and UID COPY with CopyUID; other commands are not supported.
+func (m *MockBackend) Execute(cmd imap.Commander, h responses.Handler) (*imap.StatusResp, error) {
+	m.LastCommand = cmd.Command()
+	name := m.LastCommand.Name
+	if name == "UID" {
+		name += " " + fmt.Sprint(m.LastCommand.Arguments[0])
+	}
+	if err := m.call(name); err != nil {
+		return nil, err
+	}
+	if name == "UID MOVE" || name == "UID COPY" {
+		m.LastDest = fmt.Sprint(m.LastCommand.Arguments[2])
+		status := &imap.StatusResp{Type: imap.StatusRespOk}
+		if m.CopyUID == 0 {
+			return status, nil
+		}
+		copyUID := &imap.StatusResp{Type: imap.StatusRespOk, Code: "COPYUID", Arguments: []interface{}{"1", fmt.Sprint(m.LastCommand.Arguments[1]), fmt.Sprint(m.CopyUID)}}
+		if name == "UID COPY" {
+			return copyUID, nil
+		}
+		if err := h.Handle(copyUID); err != nil {
+			return nil, err
+		}
+		return status, nil
+	}
+	if name == "CREATE" {
+		if mailbox, ok := m.LastCommand.Arguments[0].(string); ok && m.Mailboxes != nil {
+			m.Mailboxes[mailbox] = nil
+		}
+		return &imap.StatusResp{Type: imap.StatusRespOk}, nil
+	}
+	if name != "NAMESPACE" {
+		return &imap.StatusResp{Type: imap.StatusRespBad, Info: "unknown command"}, nil
+	}
+	var personal interface{}
+	if m.Namespace != nil {
+		personal = []interface{}{m.Namespace}
+	}
+	resp := &imap.DataResp{Fields: []interface{}{"NAMESPACE", personal, nil, nil}}
+	if err := h.Handle(resp); err != nil {
+		return nil, err
+	}
+	return &imap.StatusResp{Type: imap.StatusRespOk}, nil
+}
+
+func (m *MockBackend) Logout() error {
+	return m.call("Logout")
+}
+
+// newTestClient returns a Client backed by m.
+func newTestClient(m *MockBackend) *Client {
+	return &Client{client: m, username: "me@icloud.com"}
+}
+
+// newTestMessage builds a fetched message with a minimal envelope.
+func newTestMessage(uid uint32, subject, messageID string) *imap.Message {
+	msg := imap.NewMessage(uid, nil)
+	msg.Uid = uid
+	msg.Envelope = &imap.Envelope{
+		Subject:   subject,
+		MessageId: messageID,
+		Date:      time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		From:      []*imap.Address{{MailboxName: "alice", HostName: "example.com"}},
+	}
+	return msg
+}
+
+// withBody attaches a full RFC 5322 message as the fetched body.
+func withBody(msg *imap.Message, raw string) *imap.Message {
+	msg.Body = map[*imap.BodySectionName]imap.Literal{
+		{}: bytes.NewBufferString(raw),
+	}
+	return msg
+}
@@ -0,0 +1,145 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/responses"
+)
+
+// namespaceCommand is the NAMESPACE command (RFC 2342)
+type namespaceCommand struct{}
+
+func (namespaceCommand) Command() *imap.Command {
+	return &imap.Command{Name: "NAMESPACE"}
+}
+
+// namespaceResponse captures the first personal namespace from an untagged
+// NAMESPACE response
+type namespaceResponse struct {
+	prefix    string
+	delimiter string
+	found     bool
+}
+
+// Handle implements responses.Handler. The response carries three lists
+// (personal, other users, shared), each NIL or a list of (prefix delimiter)
+// pairs; only the first personal entry is kept.
+func (r *namespaceResponse) Handle(resp imap.Resp) error {
+	name, fields, ok := imap.ParseNamedResp(resp)
+	if !ok || name != "NAMESPACE" {
+		return responses.ErrUnhandled
+	}
+	if len(fields) != 3 {
+		return errors.New("invalid NAMESPACE response")
+	}
+
+	personal, ok := fields[0].([]interface{})
+	if !ok || len(personal) == 0 {
+		return nil // NIL: no personal namespace
+	}
+	entry, ok := personal[0].([]interface{})
+	if !ok || len(entry) < 2 {
+		return errors.New("invalid NAMESPACE entry")
+	}
+	prefix, err := imap.ParseString(entry[0])
+	if err != nil {
+		return fmt.Errorf("invalid NAMESPACE prefix: %w", err)
+	}
+	// A NIL delimiter means the namespace is flat
+	var delimiter string
+	if entry[1] != nil {
+		if delimiter, err = imap.ParseString(entry[1]); err != nil {
+			return fmt.Errorf("invalid NAMESPACE delimiter: %w", err)
+		}
+	}
+
+	r.prefix, r.delimiter, r.found = prefix, delimiter, true
+	return nil
+}
+
+// Namespace returns the personal namespace prefix (e.g. "" on iCloud,
+// "INBOX." on some servers) and the hierarchy delimiter used to build nested
+// folder paths. Servers without the NAMESPACE extension get an empty prefix
+// and the delimiter reported by LIST.
+func (c *Client) Namespace(ctx context.Context) (prefix, delimiter string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.namespace()
+}
+
+// namespace is the internal implementation (caller must hold c.mu)
+func (c *Client) namespace() (prefix, delimiter string, err error) {
+	supported, err := c.client.Support("NAMESPACE")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check capabilities: %w", err)
+	}
+	if supported {
+		res := &namespaceResponse{}
+		status, err := c.client.Execute(namespaceCommand{}, res)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get namespace: %w", err)
+		}
+		if err := status.Err(); err != nil {
+			return "", "", fmt.Errorf("failed to get namespace: %w", err)
+		}
+		if res.found {
+			return res.prefix, res.delimiter, nil
+		}
+	}
+
+	delimiter, err = c.hierarchyDelimiter()
+	return "", delimiter, err
+}
+
+// hierarchyDelimiter asks for the delimiter with LIST "" "", which returns
+// it without listing any mailboxes (caller must hold c.mu)
+func (c *Client) hierarchyDelimiter() (string, error) {
+	ch := make(chan *imap.MailboxInfo, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.List("", "", ch)
+	}()
+
+	var delimiter string
+	for info := range ch {
+		if delimiter == "" {
+			delimiter = info.Delimiter
+		}
+	}
+	if err := <-done; err != nil {
+		return "", fmt.Errorf("failed to get hierarchy delimiter: %w", err)
+	}
+	return delimiter, nil
+}
+
+// folderPath joins a parent folder and a child name with the server's
+// hierarchy delimiter (caller must hold c.mu)
+func (c *Client) folderPath(parent, name string) (string, error) {
+	if parent == "" {
+		return name, nil
+	}
+	delimiter, err := c.folderDelimiter()
+	if err != nil {
+		return "", err
+	}
+	return parent + delimiter + name, nil
+}
+
+// folderDelimiter returns the server's hierarchy delimiter, discovered once
+// per connection and defaulting to "/" (caller must hold c.mu)
+func (c *Client) folderDelimiter() (string, error) {
+	if c.delimiter == "" {
+		_, delimiter, err := c.namespace()
+		if err != nil {
+			return "", err
+		}
+		if delimiter == "" {
+			delimiter = "/"
+		}
+		c.delimiter = delimiter
+	}
+	return c.delimiter, nil
+}
@@ -0,0 +1,112 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-imap"
+)
+
+// DefaultFetchBatchSize is how many messages SearchEmails fetches per UID
+// FETCH unless ClientOptions.FetchBatchSize says otherwise. One fetch of a
+// large window makes a response some servers truncate.
+const DefaultFetchBatchSize = 50
+
+// SearchEmailsPaged runs the same search as SearchEmails but fetches the
+// matching messages pageSize UIDs at a time, oldest first, passing each page
+// to page as soon as it arrives rather than collecting them all. A pageSize
+// of 0 or less fetches everything as one page. It returns the total before
+// offset and limit. An error from page stops the search and is returned as
+// is; a fetch that fails after some emails were passed to page returns
+// ErrPartialResults.
+func (c *Client) SearchEmailsPaged(ctx context.Context, folder, query string, filters EmailFilters, pageSize int, page func([]Email) error) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, err
+	}
+	return c.searchPages(ctx, folder, query, filters, pageSize, page)
+}
+
+// searchPages is the internal implementation of SearchEmailsPaged. The
+// search and each page's fetch are retried on their own, so a retry never
+// passes a page to page twice (caller must hold c.mu).
+func (c *Client) searchPages(ctx context.Context, folder, query string, filters EmailFilters, pageSize int, page func([]Email) error) (int, error) {
+	var uids []uint32
+	var total int
+	err := c.withRetry(ctx, func() error {
+		var err error
+		uids, total, err = c.searchUIDs(folder, query, filters)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return c.fetchPages(ctx, folder, uids, total, pageSize, page)
+}
+
+// fetchPages fetches the summaries of uids in folder pageSize at a time and
+// passes each page to page, returning total on success. It is the fetching
+// half of searchPages (caller must hold c.mu).
+func (c *Client) fetchPages(ctx context.Context, folder string, uids []uint32, total, pageSize int, page func([]Email) error) (int, error) {
+	if pageSize <= 0 {
+		pageSize = len(uids)
+	}
+
+	fetched := 0
+	for start := 0; start < len(uids); start += pageSize {
+		chunk := uids[start:min(start+pageSize, len(uids))]
+
+		var emails []Email
+		err := c.withRetry(ctx, func() error {
+			// A reconnect between attempts leaves no folder selected
+			if c.selected != folder {
+				if _, err := c.selectFolder(folder, false); err != nil {
+					return fmt.Errorf("failed to select folder %s: %w", folder, err)
+				}
+			}
+			var err error
+			emails, err = c.fetchSummaries(ctx, chunk)
+			return err
+		})
+		if err != nil && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		// Pass on whatever arrived, even from a fetch that then failed
+		if len(emails) > 0 {
+			if err := page(emails); err != nil {
+				return 0, err
+			}
+			fetched += len(emails)
+		}
+		if err != nil {
+			if fetched > 0 {
+				return total, fmt.Errorf("%w: fetched %d of %d messages: %v", ErrPartialResults, fetched, len(uids), err)
+			}
+			return 0, fmt.Errorf("failed to fetch messages: %w", err)
+		}
+	}
+	return total, nil
+}
+
+// fetchSummaries fetches the envelope, date, flags, and References header
+// of the messages with the given UIDs in the selected folder. The emails
+// that arrived are returned even when the fetch fails part way (caller must
+// hold c.mu).
+func (c *Client) fetchSummaries(ctx context.Context, uids []uint32) ([]Email, error) {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	emails := []Email{}
+	err := c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchInternalDate, imap.FetchFlags, imap.FetchUid, referencesSection.FetchItem()}, messages)
+	}, func(msg *imap.Message) {
+		if email := c.parseMessageData(msg, false); email != nil {
+			emails = append(emails, *email)
+		}
+	})
+	return emails, err
+}
@@ -0,0 +1,437 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Pool.Get after Close
+var ErrPoolClosed = errors.New("connection pool closed")
+
+// Pool hands out authenticated IMAP connections so that independent tool
+// calls run in parallel instead of queueing behind one Client's mutex. It
+// opens connections lazily up to its size; when all are busy, Get blocks
+// until one is returned. Pool exposes the same operations as Client, each
+// running on a connection checked out for the duration of the call.
+type Pool struct {
+	dial     func() (*Client, error)
+	idle     chan *Client  // connections ready for use
+	slots    chan struct{} // one token per open connection, capacity = size
+	username string
+
+	// Held by BlockSender, which reads the block list note, changes it, and
+	// writes it back; two updates on different connections would otherwise
+	// each drop the other's sender
+	blockList chan struct{}
+
+	mu     sync.Mutex
+	conns  []*Client
+	closed bool
+}
+
+// NewPool creates a pool of up to size connections made by dial. The first
+// connection is opened immediately so configuration errors surface at
+// startup. A size below 1 is treated as 1.
+func NewPool(size int, dial func() (*Client, error)) (*Pool, error) {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		dial:  dial,
+		idle:  make(chan *Client, size),
+		slots: make(chan struct{}, size),
+
+		blockList: make(chan struct{}, 1),
+	}
+
+	p.slots <- struct{}{}
+	c, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	p.username = c.GetUsername()
+	p.idle <- c
+
+	return p, nil
+}
+
+// open dials a connection for a slot the caller already holds
+func (p *Pool) open() (*Client, error) {
+	c, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	p.mu.Lock()
+	p.conns = append(p.conns, c)
+	p.mu.Unlock()
+	return c, nil
+}
+
+// Get checks out a connection, reusing an idle one when possible, opening a
+// new one while under the size limit, and otherwise waiting for a Put or for
+// ctx to end. Every successful Get must be paired with a Put.
+func (p *Pool) Get(ctx context.Context) (*Client, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+
+	// Prefer an idle connection over opening a new one
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	case p.slots <- struct{}{}:
+		return p.open()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Put returns a connection obtained from Get to the pool. A connection
+// still draining a cancelled fetch is held back until it is done, so the
+// caller is not kept waiting.
+func (p *Pool) Put(c *Client) {
+	if busy := c.busyUntil(); busy != nil {
+		go func() {
+			<-busy
+			p.idle <- c
+		}()
+		return
+	}
+	p.idle <- c
+}
+
+// Close logs out every connection the pool has opened
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	var errs []error
+	for _, c := range p.conns {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.conns = nil
+	return errors.Join(errs...)
+}
+
+// GetUsername returns the account username
+func (p *Pool) GetUsername() string {
+	return p.username
+}
+
+// withConn runs fn on a checked-out connection
+func withConn[T any](ctx context.Context, p *Pool, fn func(*Client) (T, error)) (T, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer p.Put(c)
+	return fn(c)
+}
+
+// do runs fn on a checked-out connection
+func (p *Pool) do(ctx context.Context, fn func(*Client) error) error {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(c)
+	return fn(c)
+}
+
+// ListFolders lists all available mailboxes/folders
+func (p *Pool) ListFolders(ctx context.Context) ([]string, error) {
+	return withConn(ctx, p, func(c *Client) ([]string, error) { return c.ListFolders(ctx) })
+}
+
+// SearchEmails searches for emails in a folder with filters
+func (p *Pool) SearchEmails(ctx context.Context, folder, query string, filters EmailFilters) ([]Email, int, uint32, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer p.Put(c)
+	return c.SearchEmails(ctx, folder, query, filters)
+}
+
+// SearchEmailsPaged searches a folder and passes the matches to page a
+// batch at a time
+func (p *Pool) SearchEmailsPaged(ctx context.Context, folder, query string, filters EmailFilters, pageSize int, page func([]Email) error) (int, error) {
+	return withConn(ctx, p, func(c *Client) (int, error) {
+		return c.SearchEmailsPaged(ctx, folder, query, filters, pageSize, page)
+	})
+}
+
+// SearchUIDs searches a folder and returns only the matching UIDs
+func (p *Pool) SearchUIDs(ctx context.Context, folder, query string, filters EmailFilters) ([]string, int, uint32, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer p.Put(c)
+	return c.SearchUIDs(ctx, folder, query, filters)
+}
+
+// SearchEmailsExcluding searches a folder, leaving out the given
+// Message-IDs before offset and limit
+func (p *Pool) SearchEmailsExcluding(ctx context.Context, folder, query string, filters EmailFilters, exclude []string) ([]Email, int, int, uint32, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	defer p.Put(c)
+	return c.SearchEmailsExcluding(ctx, folder, query, filters, exclude)
+}
+
+// GetEmail retrieves a full email by UID
+func (p *Pool) GetEmail(ctx context.Context, folder, emailID string) (*Email, error) {
+	return withConn(ctx, p, func(c *Client) (*Email, error) { return c.GetEmail(ctx, folder, emailID) })
+}
+
+// PeekEmail retrieves a full email without marking it as read
+func (p *Pool) PeekEmail(ctx context.Context, folder, emailID string) (*Email, error) {
+	return withConn(ctx, p, func(c *Client) (*Email, error) { return c.PeekEmail(ctx, folder, emailID) })
+}
+
+// FetchEmail retrieves a full email by UID or sequence number
+func (p *Pool) FetchEmail(ctx context.Context, folder, emailID string, opts FetchOptions) (*Email, error) {
+	return withConn(ctx, p, func(c *Client) (*Email, error) { return c.FetchEmail(ctx, folder, emailID, opts) })
+}
+
+// GetStoredMessage reconstructs the send parameters of a stored email
+func (p *Pool) GetStoredMessage(ctx context.Context, folder, emailID string) (*StoredMessage, error) {
+	return withConn(ctx, p, func(c *Client) (*StoredMessage, error) { return c.GetStoredMessage(ctx, folder, emailID) })
+}
+
+// GetHeaders fetches only the named header fields of an email
+func (p *Pool) GetHeaders(ctx context.Context, folder, emailID string, fields []string) (map[string][]string, error) {
+	return withConn(ctx, p, func(c *Client) (map[string][]string, error) { return c.GetHeaders(ctx, folder, emailID, fields) })
+}
+
+// CountEmails counts emails matching filters
+func (p *Pool) CountEmails(ctx context.Context, folder string, filters EmailFilters) (int, error) {
+	return withConn(ctx, p, func(c *Client) (int, error) { return c.CountEmails(ctx, folder, filters) })
+}
+
+// MessageIDs returns the Message-IDs of all messages in a folder
+func (p *Pool) MessageIDs(ctx context.Context, folder string) ([]string, error) {
+	return withConn(ctx, p, func(c *Client) ([]string, error) { return c.MessageIDs(ctx, folder) })
+}
+
+// FindByMessageID returns the UID of the message in a folder with a Message-ID
+func (p *Pool) FindByMessageID(ctx context.Context, folder, messageID string) (string, error) {
+	return withConn(ctx, p, func(c *Client) (string, error) { return c.FindByMessageID(ctx, folder, messageID) })
+}
+
+// GetAttachment downloads a specific attachment from an email
+func (p *Pool) GetAttachment(ctx context.Context, folder, emailID, filename string) (*AttachmentData, error) {
+	return withConn(ctx, p, func(c *Client) (*AttachmentData, error) { return c.GetAttachment(ctx, folder, emailID, filename) })
+}
+
+// MailboxStats summarizes message counts, unread counts, and dates per folder
+func (p *Pool) MailboxStats(ctx context.Context, opts StatsOptions) (*Stats, error) {
+	return withConn(ctx, p, func(c *Client) (*Stats, error) { return c.MailboxStats(ctx, opts) })
+}
+
+// FindLargeAttachments lists emails with big attachments across folders
+func (p *Pool) FindLargeAttachments(ctx context.Context, folders []string, minSize uint32, limit int) ([]LargeAttachment, error) {
+	return withConn(ctx, p, func(c *Client) ([]LargeAttachment, error) {
+		return c.FindLargeAttachments(ctx, folders, minSize, limit)
+	})
+}
+
+// ListDrafts returns the envelopes of all messages in the Drafts folder
+func (p *Pool) ListDrafts(ctx context.Context) ([]Email, error) {
+	return withConn(ctx, p, func(c *Client) ([]Email, error) { return c.ListDrafts(ctx) })
+}
+
+// CountBySender tallies messages in a folder by sender address
+func (p *Pool) CountBySender(ctx context.Context, folder string, lastDays, limit int) ([]SenderCount, error) {
+	return withConn(ctx, p, func(c *Client) ([]SenderCount, error) { return c.CountBySender(ctx, folder, lastDays, limit) })
+}
+
+// FindDuplicates groups the messages in a folder that are copies of each other
+func (p *Pool) FindDuplicates(ctx context.Context, folder string, matchHeaders bool) ([]DuplicateGroup, error) {
+	return withConn(ctx, p, func(c *Client) ([]DuplicateGroup, error) { return c.FindDuplicates(ctx, folder, matchHeaders) })
+}
+
+// CountByDay counts messages in a folder per day
+func (p *Pool) CountByDay(ctx context.Context, folder string, lastDays int) (map[string]int, error) {
+	return withConn(ctx, p, func(c *Client) (map[string]int, error) { return c.CountByDay(ctx, folder, lastDays) })
+}
+
+// RecentRecipients lists the addresses most recently sent to
+func (p *Pool) RecentRecipients(ctx context.Context, lastDays, limit int) ([]Correspondent, error) {
+	return withConn(ctx, p, func(c *Client) ([]Correspondent, error) { return c.RecentRecipients(ctx, lastDays, limit) })
+}
+
+// FolderStatus returns a folder's message count, next UID, and UIDVALIDITY
+func (p *Pool) FolderStatus(ctx context.Context, folder string) (*FolderStatus, error) {
+	return withConn(ctx, p, func(c *Client) (*FolderStatus, error) { return c.FolderStatus(ctx, folder) })
+}
+
+// UIDValidity returns a folder's UIDVALIDITY
+func (p *Pool) UIDValidity(ctx context.Context, folder string) (uint32, error) {
+	return withConn(ctx, p, func(c *Client) (uint32, error) { return c.UIDValidity(ctx, folder) })
+}
+
+// FolderFlags returns a folder's flags and permanent flags
+func (p *Pool) FolderFlags(ctx context.Context, folder string) (flags []string, permanentFlags []string, err error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer p.Put(c)
+	return c.FolderFlags(ctx, folder)
+}
+
+// ExportFolder writes every message in a folder to an mbox or zip file
+func (p *Pool) ExportFolder(ctx context.Context, folder, destPath, format string) (count int, size int64, err error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer p.Put(c)
+	return c.ExportFolder(ctx, folder, destPath, format)
+}
+
+// ExportEmail writes one message and its attachments to a zip file
+func (p *Pool) ExportEmail(ctx context.Context, folder, emailID, destPath string, filter func(filename, mimeType string) error) (*ExportedEmail, error) {
+	return withConn(ctx, p, func(c *Client) (*ExportedEmail, error) { return c.ExportEmail(ctx, folder, emailID, destPath, filter) })
+}
+
+// Namespace returns the personal namespace prefix and hierarchy delimiter
+func (p *Pool) Namespace(ctx context.Context) (prefix, delimiter string, err error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer p.Put(c)
+	return c.Namespace(ctx)
+}
+
+// ResolveFolder maps a friendly folder alias to the server's folder name
+func (p *Pool) ResolveFolder(ctx context.Context, name string) (string, error) {
+	return withConn(ctx, p, func(c *Client) (string, error) { return c.ResolveFolder(ctx, name) })
+}
+
+// CheckProtected returns ErrProtectedFolder if name is a protected folder
+func (p *Pool) CheckProtected(ctx context.Context, name string) error {
+	return p.do(ctx, func(c *Client) error { return c.CheckProtected(ctx, name) })
+}
+
+// MarkRead marks an email as read or unread
+func (p *Pool) MarkRead(ctx context.Context, folder, emailID string, read bool) error {
+	return p.do(ctx, func(c *Client) error { return c.MarkRead(ctx, folder, emailID, read) })
+}
+
+// MarkReadBulk marks several emails as read or unread in one command
+func (p *Pool) MarkReadBulk(ctx context.Context, folder string, emailIDs []string, read bool) (int, error) {
+	return withConn(ctx, p, func(c *Client) (int, error) { return c.MarkReadBulk(ctx, folder, emailIDs, read) })
+}
+
+// MoveEmail moves an email from one folder to another
+func (p *Pool) MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string, opts MoveOptions) (bool, error) {
+	return withConn(ctx, p, func(c *Client) (bool, error) { return c.MoveEmail(ctx, fromFolder, toFolder, emailID, opts) })
+}
+
+// MoveEmailBulk moves several emails between folders in one command
+func (p *Pool) MoveEmailBulk(ctx context.Context, fromFolder, toFolder string, emailIDs []string) (int, error) {
+	return withConn(ctx, p, func(c *Client) (int, error) { return c.MoveEmailBulk(ctx, fromFolder, toFolder, emailIDs) })
+}
+
+// SnoozeEmail re-files an email under Snoozed until the given time
+func (p *Pool) SnoozeEmail(ctx context.Context, folder, emailID string, until time.Time) (*SnoozedEmail, error) {
+	return withConn(ctx, p, func(c *Client) (*SnoozedEmail, error) { return c.SnoozeEmail(ctx, folder, emailID, until) })
+}
+
+// FlushSnoozed moves snoozed emails that are due back to INBOX
+func (p *Pool) FlushSnoozed(ctx context.Context, now time.Time) (*FlushResult, error) {
+	return withConn(ctx, p, func(c *Client) (*FlushResult, error) { return c.FlushSnoozed(ctx, now) })
+}
+
+// DeleteEmail deletes an email (moves to trash or permanently deletes)
+func (p *Pool) DeleteEmail(ctx context.Context, folder, emailID string, permanent bool) error {
+	return p.do(ctx, func(c *Client) error { return c.DeleteEmail(ctx, folder, emailID, permanent) })
+}
+
+// PurgeDeleted expunges the messages in a folder already flagged \Deleted
+func (p *Pool) PurgeDeleted(ctx context.Context, folder string) (int, error) {
+	return withConn(ctx, p, func(c *Client) (int, error) { return c.PurgeDeleted(ctx, folder) })
+}
+
+// FlagEmail sets or removes flags on an email
+func (p *Pool) FlagEmail(ctx context.Context, folder, emailID, flagType, color string) error {
+	return p.do(ctx, func(c *Client) error { return c.FlagEmail(ctx, folder, emailID, flagType, color) })
+}
+
+// FlagEmailBulk sets or removes flags on several emails in one command
+func (p *Pool) FlagEmailBulk(ctx context.Context, folder string, emailIDs []string, flagType, color string) error {
+	return p.do(ctx, func(c *Client) error { return c.FlagEmailBulk(ctx, folder, emailIDs, flagType, color) })
+}
+
+// SaveDraft saves an email as a draft in the Drafts folder
+func (p *Pool) SaveDraft(ctx context.Context, from string, to []string, subject, body string, opts DraftOptions) (string, error) {
+	return withConn(ctx, p, func(c *Client) (string, error) { return c.SaveDraft(ctx, from, to, subject, body, opts) })
+}
+
+// DeleteDraft permanently removes a draft from the Drafts folder
+func (p *Pool) DeleteDraft(ctx context.Context, emailID string) error {
+	return p.do(ctx, func(c *Client) error { return c.DeleteDraft(ctx, emailID) })
+}
+
+// BlockSender moves a message to Junk and blocks its sender
+func (p *Pool) BlockSender(ctx context.Context, folder, emailID string) (*BlockResult, error) {
+	select {
+	case p.blockList <- struct{}{}:
+		defer func() { <-p.blockList }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return withConn(ctx, p, func(c *Client) (*BlockResult, error) { return c.BlockSender(ctx, folder, emailID) })
+}
+
+// AppendMessage stores a raw message in a folder
+func (p *Pool) AppendMessage(ctx context.Context, folder string, raw []byte, flags []string) error {
+	return p.do(ctx, func(c *Client) error { return c.AppendMessage(ctx, folder, raw, flags) })
+}
+
+// CreateFolder creates a new mailbox folder
+func (p *Pool) CreateFolder(ctx context.Context, name, parent string, opts CreateFolderOptions) (string, error) {
+	return withConn(ctx, p, func(c *Client) (string, error) { return c.CreateFolder(ctx, name, parent, opts) })
+}
+
+// Subscribe adds a folder to the subscription list
+func (p *Pool) Subscribe(ctx context.Context, folder string) error {
+	return p.do(ctx, func(c *Client) error { return c.Subscribe(ctx, folder) })
+}
+
+// Unsubscribe removes a folder from the subscription list
+func (p *Pool) Unsubscribe(ctx context.Context, folder string) error {
+	return p.do(ctx, func(c *Client) error { return c.Unsubscribe(ctx, folder) })
+}
+
+// DeleteFolder deletes a mailbox folder
+func (p *Pool) DeleteFolder(ctx context.Context, name string, force bool) (wasEmpty bool, emailCount int, err error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer p.Put(c)
+	return c.DeleteFolder(ctx, name, force)
+}
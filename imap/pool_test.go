@@ -0,0 +1,293 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// newTestPool returns a pool whose connections are mock-backed clients, and
+// a counter of how many connections were dialed.
+func newTestPool(t *testing.T, size int) (*Pool, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	dialed := 0
+	p, err := NewPool(size, func() (*Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dialed++
+		return newTestClient(&MockBackend{Mailboxes: map[string][]*imap.Message{
+			"INBOX":   {newTestMessage(1, "Hi", "<1@x>")},
+			"Archive": nil,
+		}}), nil
+	})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	return p, &dialed
+}
+
+func TestPoolCheckoutCheckin(t *testing.T) {
+	p, dialed := newTestPool(t, 2)
+	if *dialed != 1 {
+		t.Fatalf("dialed %d connections at startup, want 1", *dialed)
+	}
+
+	ctx := context.Background()
+	a, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if a == b {
+		t.Error("concurrent checkouts returned the same connection")
+	}
+	if *dialed != 2 {
+		t.Errorf("dialed %d, want 2", *dialed)
+	}
+
+	p.Put(a)
+	p.Put(b)
+	if len(p.idle) != 2 {
+		t.Errorf("idle = %d, want 2", len(p.idle))
+	}
+}
+
+func TestPoolReusesConnections(t *testing.T) {
+	p, dialed := newTestPool(t, 3)
+	ctx := context.Background()
+
+	// Sequential calls keep using the one open connection
+	for i := 0; i < 5; i++ {
+		if _, _, _, err := p.SearchEmails(ctx, "Archive", "", EmailFilters{}); err != nil {
+			t.Fatalf("SearchEmails: %v", err)
+		}
+	}
+	if *dialed != 1 {
+		t.Errorf("dialed %d, want 1", *dialed)
+	}
+
+	// The connection remembers what it last selected
+	c, _ := p.Get(ctx)
+	defer p.Put(c)
+	if got := c.SelectedFolder(); got != "Archive" {
+		t.Errorf("SelectedFolder = %q, want Archive", got)
+	}
+}
+
+func TestPoolBlocksAtMaxSize(t *testing.T) {
+	p, dialed := newTestPool(t, 1)
+	held, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	// A second checkout waits rather than opening a new connection
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get at max size = %v, want deadline exceeded", err)
+	}
+
+	// ...and proceeds once the held connection is returned
+	got := make(chan *Client, 1)
+	go func() {
+		c, err := p.Get(context.Background())
+		if err != nil {
+			t.Errorf("Get: %v", err)
+		}
+		got <- c
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Put(held)
+
+	select {
+	case c := <-got:
+		if c != held {
+			t.Error("waiter did not receive the returned connection")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter never received a connection")
+	}
+	if *dialed != 1 {
+		t.Errorf("dialed %d, want 1", *dialed)
+	}
+}
+
+func TestPoolDialFailureFreesSlot(t *testing.T) {
+	fail := false
+	p, err := NewPool(2, func() (*Client, error) {
+		if fail {
+			return nil, errors.New("login failed")
+		}
+		return newTestClient(&MockBackend{}), nil
+	})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	held, _ := p.Get(context.Background())
+	fail = true
+	if _, err := p.Get(context.Background()); err == nil {
+		t.Fatal("expected dial error")
+	}
+	fail = false
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Errorf("slot not released after failed dial: %v", err)
+	}
+	p.Put(held)
+}
+
+func TestPoolBlockSenderSerialized(t *testing.T) {
+	spam := func(from string) *imap.Message {
+		msg := newTestMessage(42, "You won!", "<spam@x>")
+		return withBody(msg, "From: "+from+"\r\nSubject: You won!\r\n\r\nClaim now\r\n")
+	}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var stall sync.Once
+	backends := []*MockBackend{
+		{
+			Mailboxes:  map[string][]*imap.Message{"INBOX": {spam("a@spam.example")}, "Junk": nil},
+			Attributes: map[string][]string{"Junk": {imap.JunkAttr}},
+			// The first update stalls while writing the new note
+			Hooks: map[string]func(){"Append": func() {
+				stall.Do(func() {
+					close(entered)
+					<-release
+				})
+			}},
+		},
+		{
+			Mailboxes:  map[string][]*imap.Message{"INBOX": {spam("b@spam.example")}, "Junk": nil},
+			Attributes: map[string][]string{"Junk": {imap.JunkAttr}},
+		},
+	}
+	var mu sync.Mutex
+	dialed := 0
+	p, err := NewPool(2, func() (*Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c := newTestClient(backends[dialed])
+		dialed++
+		return c, nil
+	})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := p.BlockSender(context.Background(), "INBOX", "42")
+		errs <- err
+	}()
+	<-entered
+
+	// The second update must wait for the first rather than reading the
+	// block list on the pool's other connection
+	go func() {
+		_, err := p.BlockSender(context.Background(), "INBOX", "42")
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	during := dialed
+	mu.Unlock()
+	if during != 1 {
+		t.Errorf("second BlockSender ran on a new connection while the first was writing")
+	}
+
+	close(release)
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Errorf("BlockSender: %v", err)
+		}
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	p, _ := newTestPool(t, 2)
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := p.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get after Close = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolCheckCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialErr    error
+		wantStatus string
+	}{
+		{
+			name:       "valid",
+			wantStatus: CredentialsValid,
+		},
+		{
+			name:       "auth failed",
+			dialErr:    fmt.Errorf("failed to login: %w", authError{errors.New("Authentication failed.")}),
+			wantStatus: CredentialsRejected,
+		},
+		{
+			name:       "network failed",
+			dialErr:    fmt.Errorf("failed to connect to IMAP server: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			wantStatus: CredentialsNetwork,
+		},
+		{
+			name:       "connection dropped during login",
+			dialErr:    fmt.Errorf("failed to login: %w", authError{io.EOF}),
+			wantStatus: CredentialsNetwork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dialed := newTestPool(t, 1)
+			if tt.dialErr != nil {
+				p.dial = func() (*Client, error) { return nil, tt.dialErr }
+			}
+
+			check := p.CheckCredentials(context.Background())
+			if check.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s (error %q)", check.Status, tt.wantStatus, check.Error)
+			}
+			if check.Valid != (tt.wantStatus == CredentialsValid) {
+				t.Errorf("valid = %v for status %s", check.Valid, check.Status)
+			}
+			if (check.Error != "") != (tt.dialErr != nil) {
+				t.Errorf("error = %q, want error %v", check.Error, tt.dialErr)
+			}
+			if tt.dialErr == nil && *dialed != 2 {
+				t.Errorf("dialed %d, want a fresh connection for the check", *dialed)
+			}
+		})
+	}
+}
+
+func TestPoolCheckCredentialsCancelled(t *testing.T) {
+	p, _ := newTestPool(t, 1)
+	release := make(chan struct{})
+	defer close(release)
+	p.dial = func() (*Client, error) {
+		<-release
+		return nil, errors.New("too late")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if check := p.CheckCredentials(ctx); check.Status != CredentialsNetwork {
+		t.Errorf("status = %s, want %s", check.Status, CredentialsNetwork)
+	}
+}
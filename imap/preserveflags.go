@@ -0,0 +1,190 @@
+package imap
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
+)
+
+// moveEmailWithFlags moves one message and then stores the flags it had in
+// the source onto the copy in the destination. With UIDPLUS the server
+// names the copy's UID in its COPYUID response; otherwise the copy is found
+// by Message-ID, taking the highest UID when there are several. Caller must
+// hold c.mu.
+func (c *Client) moveEmailWithFlags(fromFolder, toFolder, emailID string) error {
+	var uid uint32
+	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
+		return fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	flags, messageID, err := c.sourceFlags(fromFolder, uid)
+	if err != nil {
+		return err
+	}
+
+	uidPlus, err := c.client.Support("UIDPLUS")
+	if err != nil {
+		return fmt.Errorf("failed to check capabilities: %w", err)
+	}
+	var newUID uint32
+	if uidPlus {
+		newUID, err = c.moveEmailCopyUID(fromFolder, toFolder, uid)
+	} else {
+		err = c.moveEmails(fromFolder, toFolder, []uint32{uid})
+	}
+	if err != nil {
+		return err
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+
+	if newUID == 0 {
+		if messageID == "" {
+			slog.Warn("moved email has no Message-ID; flags not restored", "folder", toFolder)
+			return nil
+		}
+		if _, err := c.selectFolder(toFolder, false); err != nil {
+			return fmt.Errorf("failed to select folder %s: %w", toFolder, err)
+		}
+		newUID, err = c.newestByMessageID(messageID)
+		if err != nil {
+			return fmt.Errorf("failed to find moved email in %s: %w", toFolder, err)
+		}
+		if newUID == 0 {
+			slog.Warn("moved email not found by Message-ID; flags not restored", "folder", toFolder)
+			return nil
+		}
+	} else if _, err := c.selectFolder(toFolder, false); err != nil {
+		return fmt.Errorf("failed to select folder %s: %w", toFolder, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(newUID)
+	values := make([]interface{}, len(flags))
+	for i, f := range flags {
+		values[i] = f
+	}
+	if err := c.client.UidStore(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), values, nil); err != nil {
+		return fmt.Errorf("email moved but flags were not restored: %w", err)
+	}
+	return nil
+}
+
+// sourceFlags returns a message's storable flags and its Message-ID. \Recent
+// is set by the server and \Deleted is being applied by the move itself, so
+// neither is carried over. Caller must hold c.mu.
+func (c *Client) sourceFlags(folder string, uid uint32) ([]string, string, error) {
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return nil, "", fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+	messages := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, imap.FetchFlags, imap.FetchEnvelope}, messages)
+	}()
+
+	msg := <-messages
+	if msg == nil {
+		<-done
+		return nil, "", fmt.Errorf("email %w", ErrNotFound)
+	}
+	if err := <-done; err != nil {
+		return nil, "", fmt.Errorf("failed to fetch flags: %w", err)
+	}
+
+	var flags []string
+	for _, f := range msg.Flags {
+		if f != imap.RecentFlag && f != imap.DeletedFlag {
+			flags = append(flags, f)
+		}
+	}
+	var messageID string
+	if msg.Envelope != nil {
+		messageID = msg.Envelope.MessageId
+	}
+	return flags, messageID, nil
+}
+
+// moveEmailCopyUID moves one message like moveEmails, but runs UID MOVE or
+// UID COPY itself so that the COPYUID response code (RFC 4315) can be read.
+// It returns the message's UID in toFolder, or 0 if the server named none.
+// Caller must hold c.mu.
+func (c *Client) moveEmailCopyUID(fromFolder, toFolder string, uid uint32) (uint32, error) {
+	if _, err := c.selectFolder(fromFolder, false); err != nil {
+		return 0, fmt.Errorf("failed to select folder %s: %w", fromFolder, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	move, err := c.client.Support("MOVE")
+	if err != nil {
+		return 0, fmt.Errorf("failed to check capabilities: %w", err)
+	}
+
+	// MOVE reports COPYUID untagged, COPY in its tagged OK
+	res := &copyUIDResponse{}
+	if move {
+		status, err := c.client.Execute(&commands.Uid{Cmd: &commands.Move{SeqSet: seqSet, Mailbox: toFolder}}, res)
+		if err == nil {
+			err = status.Err()
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to move email: %w", err)
+		}
+		return res.uid, nil
+	}
+
+	status, err := c.client.Execute(&commands.Uid{Cmd: &commands.Copy{SeqSet: seqSet, Mailbox: toFolder}}, res)
+	if err == nil {
+		err = status.Err()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy email: %w", err)
+	}
+	_ = res.Handle(status)
+
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.client.UidStore(seqSet, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+		return 0, fmt.Errorf("failed to mark email as deleted: %w", err)
+	}
+	if err := c.client.Expunge(nil); err != nil {
+		return 0, fmt.Errorf("failed to expunge: %w", err)
+	}
+	return res.uid, nil
+}
+
+// copyUIDResponse captures the destination UID from a COPYUID response
+// code, "[COPYUID <uidvalidity> <source uids> <destination uids>]", for a
+// command that copied a single message
+type copyUIDResponse struct {
+	uid uint32
+}
+
+// Handle implements responses.Handler
+func (r *copyUIDResponse) Handle(resp imap.Resp) error {
+	status, ok := resp.(*imap.StatusResp)
+	if !ok || status.Code != "COPYUID" {
+		return responses.ErrUnhandled
+	}
+	if len(status.Arguments) != 3 {
+		return nil
+	}
+	dest, err := imap.ParseString(status.Arguments[2])
+	if err != nil {
+		return nil
+	}
+	set, err := imap.ParseSeqSet(dest)
+	if err != nil || len(set.Set) != 1 || set.Set[0].Start != set.Set[0].Stop {
+		return nil
+	}
+	r.uid = set.Set[0].Start
+	return nil
+}
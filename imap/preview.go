@@ -0,0 +1,58 @@
+package imap
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/emersion/go-imap"
+)
+
+// previewSections returns the sections fetched instead of BODY[] for a
+// preview: the full header and the first limit bytes of the text. Both peek,
+// so a preview never marks the message as read.
+func previewSections(limit int) (header, text *imap.BodySectionName) {
+	header = &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier},
+		Peek:         true,
+	}
+	text = &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.TextSpecifier},
+		Peek:         true,
+		Partial:      []int{0, limit},
+	}
+	return header, text
+}
+
+// joinPreview replaces msg.Body with the fetched header followed by at most
+// limit bytes of text, so the result parses like a full message, and reports
+// whether text was cut off. A text section shorter than limit is complete;
+// one that fills it is complete only if RFC822.SIZE says nothing is left.
+func joinPreview(msg *imap.Message, header, text *imap.BodySectionName, limit int) (bool, error) {
+	var head, body []byte
+	if literal := msg.GetBody(header); literal != nil {
+		var err error
+		if head, err = io.ReadAll(literal); err != nil {
+			return false, err
+		}
+	}
+	if literal := msg.GetBody(text); literal != nil {
+		var err error
+		if body, err = io.ReadAll(literal); err != nil {
+			return false, err
+		}
+	}
+
+	truncated := false
+	switch {
+	case len(body) > limit:
+		// The server ignored the partial range
+		body, truncated = body[:limit], true
+	case len(body) == limit:
+		truncated = msg.Size == 0 || uint32(len(head)+len(body)) < msg.Size
+	}
+
+	msg.Body = map[*imap.BodySectionName]imap.Literal{
+		{}: bytes.NewReader(append(head, body...)),
+	}
+	return truncated, nil
+}
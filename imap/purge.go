@@ -0,0 +1,58 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-imap"
+)
+
+// PurgeDeleted permanently removes the messages in folder that already carry
+// the \Deleted flag, e.g. left behind by another client, and returns how
+// many the server expunged. Messages without the flag are untouched. A
+// protected folder returns ErrProtectedFolder.
+func (c *Client) PurgeDeleted(ctx context.Context, folder string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkProtected(folder); err != nil {
+		return 0, err
+	}
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	// Nothing to expunge unless something is already marked
+	criteria := imap.NewSearchCriteria()
+	criteria.WithFlags = []string{imap.DeletedFlag}
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search deleted emails: %w", err)
+	}
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	// EXPUNGE only removes \Deleted messages; count the server's reports
+	expunged := make(chan uint32)
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range expunged {
+			n++
+		}
+		done <- n
+	}()
+	err = c.client.Expunge(expunged)
+	n := <-done
+	if err != nil {
+		return n, fmt.Errorf("failed to expunge: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,47 @@
+package imap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-imap"
+)
+
+// ErrInvalidQuery is wrapped by errors for a raw search query that is not
+// valid IMAP SEARCH syntax.
+var ErrInvalidQuery = errors.New("invalid raw query")
+
+// ParseRawQuery parses raw IMAP SEARCH keys such as
+// `OR FROM a@b.com SUBJECT "weekly report"` into search criteria. The
+// criteria are formatted afresh when the search is sent, so nothing in raw
+// reaches the server verbatim; line breaks, other control characters, and
+// literals are refused outright so that a query cannot smuggle in a second
+// command.
+func ParseRawQuery(raw string) (*imap.SearchCriteria, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidQuery)
+	}
+	for _, r := range raw {
+		if r < ' ' || r == 0x7f {
+			return nil, fmt.Errorf("%w: control characters are not allowed", ErrInvalidQuery)
+		}
+	}
+	if strings.Contains(raw, "{") {
+		return nil, fmt.Errorf("%w: literals are not allowed", ErrInvalidQuery)
+	}
+
+	r := imap.NewReader(bufio.NewReader(strings.NewReader(raw + "\r\n")))
+	fields, err := r.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	if err := criteria.ParseWithCharset(fields, nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return criteria, nil
+}
@@ -0,0 +1,84 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestParseRawQuery(t *testing.T) {
+	t.Run("or of from and subject", func(t *testing.T) {
+		c, err := ParseRawQuery(`OR FROM a@b.com SUBJECT "weekly report"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(c.Or) != 1 {
+			t.Fatalf("Or = %v, want one pair", c.Or)
+		}
+		if got := c.Or[0][0].Header.Get("From"); got != "a@b.com" {
+			t.Errorf("first alternative FROM = %q", got)
+		}
+		if got := c.Or[0][1].Header.Get("Subject"); got != "weekly report" {
+			t.Errorf("second alternative SUBJECT = %q", got)
+		}
+	})
+
+	t.Run("flags dates and negation", func(t *testing.T) {
+		c, err := ParseRawQuery("unseen since 1-Feb-2024 NOT (FROM noreply@x.com) LARGER 1000")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(c.WithoutFlags) != 1 || c.WithoutFlags[0] != imap.SeenFlag {
+			t.Errorf("WithoutFlags = %v", c.WithoutFlags)
+		}
+		if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !c.Since.Equal(want) {
+			t.Errorf("Since = %v, want %v", c.Since, want)
+		}
+		if len(c.Not) != 1 || c.Not[0].Header.Get("From") != "noreply@x.com" {
+			t.Errorf("Not = %+v", c.Not)
+		}
+		if c.Larger != 1000 {
+			t.Errorf("Larger = %d", c.Larger)
+		}
+	})
+
+	for _, raw := range []string{
+		"",
+		"FROM",
+		"BOGUS foo",
+		"SINCE yesterday",
+		"(FROM a@b.com",
+		"FROM a@b.com) SUBJECT x",
+		"FROM a@b.com\r\nA1 DELETE INBOX",
+		"SUBJECT {5}\r\nhello",
+	} {
+		if _, err := ParseRawQuery(raw); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParseRawQuery(%q) error = %v, want ErrInvalidQuery", raw, err)
+		}
+	}
+}
+
+func TestSearchEmailsRawQuery(t *testing.T) {
+	m := &MockBackend{Mailboxes: map[string][]*imap.Message{"INBOX": nil}}
+	c := newTestClient(m)
+
+	filters := EmailFilters{LastDays: 30, UnreadOnly: true, RawQuery: "OR FROM a@b.com SUBJECT foo"}
+	if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "ignored", filters); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := m.LastCriteria
+	if len(got.Or) != 1 || got.Or[0][0].Header.Get("From") != "a@b.com" || got.Or[0][1].Header.Get("Subject") != "foo" {
+		t.Errorf("criteria = %+v, want the raw OR", got)
+	}
+	if !got.Since.IsZero() || len(got.WithoutFlags) != 0 || len(got.Text) != 0 {
+		t.Errorf("criteria = %+v, want the other filters bypassed", got)
+	}
+
+	filters.RawQuery = "NOPE"
+	if _, _, _, err := c.SearchEmails(context.Background(), "INBOX", "", filters); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("error = %v, want ErrInvalidQuery", err)
+	}
+}
@@ -0,0 +1,160 @@
+package imap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	message "github.com/emersion/go-message/mail"
+)
+
+// StoredMessage holds the send parameters recovered from a stored message,
+// such as a sent email that bounced, so it can be sent again.
+type StoredMessage struct {
+	From       string
+	To         []string
+	CC         []string
+	BCC        []string
+	Subject    string
+	Body       string
+	HTML       bool // Body is the HTML part
+	InReplyTo  string
+	References []string
+}
+
+// GetStoredMessage fetches an email's raw source without marking it as read
+// and reconstructs the parameters it was sent with. Attachments are not
+// recovered.
+func (c *Client) GetStoredMessage(ctx context.Context, folder, emailID string) (*StoredMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.fetchRaw(folder, emailID)
+	if err != nil {
+		return nil, err
+	}
+	return parseStoredMessage(bytes.NewReader(raw))
+}
+
+// fetchRaw returns the full RFC 5322 source of one message using
+// BODY.PEEK[] (caller must hold c.mu)
+func (c *Client) fetchRaw(folder, emailID string) ([]byte, error) {
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	var uid uint32
+	if _, err := fmt.Sscanf(emailID, "%d", &uid); err != nil {
+		return nil, fmt.Errorf("%w format: %w", ErrInvalidID, err)
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+	}()
+
+	msg := <-messages
+	if msg == nil {
+		<-done
+		return nil, fmt.Errorf("email %w", ErrNotFound)
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to fetch message: %w", err)
+	}
+
+	for _, literal := range msg.Body {
+		raw, err := io.ReadAll(literal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		return raw, nil
+	}
+	return nil, fmt.Errorf("failed to get message body")
+}
+
+// parseStoredMessage reads the headers and text body of a raw message. The
+// HTML part wins over the plain part, since messages sent as HTML carry a
+// plain alternative generated from it.
+func parseStoredMessage(r io.Reader) (*StoredMessage, error) {
+	mr, err := message.CreateReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+	defer mr.Close()
+
+	stored := &StoredMessage{
+		To:         addressList(mr.Header, "To"),
+		CC:         addressList(mr.Header, "Cc"),
+		BCC:        addressList(mr.Header, "Bcc"),
+		InReplyTo:  strings.TrimSpace(mr.Header.Get("In-Reply-To")),
+		References: strings.Fields(mr.Header.Get("References")),
+	}
+	if from := addressList(mr.Header, "From"); len(from) > 0 {
+		stored.From = from[0]
+	}
+	if subject, err := mr.Header.Subject(); err == nil {
+		stored.Subject = subject
+	} else {
+		stored.Subject = mr.Header.Get("Subject")
+	}
+
+	var plain, html string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message part: %w", err)
+		}
+		h, ok := part.Header.(*message.InlineHeader)
+		if !ok {
+			continue // attachments are not resent
+		}
+		contentType, _, _ := h.ContentType()
+		body, err := io.ReadAll(part.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message part: %w", err)
+		}
+		switch {
+		case contentType == "text/plain" && plain == "":
+			plain = string(body)
+		case contentType == "text/html" && html == "":
+			html = string(body)
+		}
+	}
+
+	if html != "" {
+		stored.Body, stored.HTML = html, true
+	} else {
+		stored.Body = plain
+	}
+	return stored, nil
+}
+
+// addressList returns the bare addresses in header key, skipping a header
+// that does not parse.
+func addressList(h message.Header, key string) []string {
+	addrs, err := h.AddressList(key)
+	if err != nil {
+		return nil
+	}
+	list := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		list = append(list, a.Address)
+	}
+	return list
+}
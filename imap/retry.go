@@ -0,0 +1,116 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// RetryOptions controls how searches and fetches retry transient failures
+type RetryOptions struct {
+	// Attempts is how many times a failed operation is retried; 0 disables
+	// retries
+	Attempts int
+	// Backoff is the wait before the first retry, doubled for each retry
+	// after it
+	Backoff time.Duration
+}
+
+// withRetry runs op and retries it while it fails with a transient error,
+// up to c.opts.Retry.Attempts times. When the connection itself was lost it
+// is re-established before the next attempt, so op must select its own
+// folder. An op is not started once ctx has ended. Caller must hold c.mu.
+func (c *Client) withRetry(ctx context.Context, op func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	backoff := c.opts.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt > c.opts.Retry.Attempts || !isTransient(err) {
+			return err
+		}
+		slog.Warn("transient IMAP error, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if isConnectionError(err) {
+			if rerr := c.reconnect(); rerr != nil {
+				return fmt.Errorf("%w (reconnect failed: %v)", err, rerr)
+			}
+		}
+	}
+}
+
+// reconnect replaces a lost connection with a freshly authenticated one
+// (caller must hold c.mu)
+func (c *Client) reconnect() error {
+	if c.redial == nil {
+		return errors.New("reconnecting is not supported")
+	}
+	b, err := c.redial()
+	if err != nil {
+		return err
+	}
+	_ = c.client.Logout()
+	c.client = b
+	c.selected = ""
+	slog.Info("reconnected to IMAP server")
+	return nil
+}
+
+// isTransient reports whether an operation that failed with err may succeed
+// if tried again: the connection dropped, or the server asked the client to
+// come back later. Missing mailboxes and messages, bad IDs, and other
+// refusals are permanent.
+func isTransient(err error) bool {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidID) || errors.Is(err, ErrInvalidQuery) || errors.Is(err, ErrPartialResults) || errors.Is(err, ErrAuthFailed) {
+		return false
+	}
+	// A cancelled or timed-out request is over, whatever the connection's
+	// state; context.DeadlineExceeded would otherwise pass for a net.Error
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isConnectionError(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, phrase := range []string{"try again", "temporar", "unavailable", "server busy", "too many", "throttl"} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConnectionError reports whether err means the connection to the server
+// was lost, after which no command on it can succeed
+func isConnectionError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, client.ErrNotLoggedIn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, phrase := range []string{"connection closed", "connection reset", "broken pipe"} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
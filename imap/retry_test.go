@@ -0,0 +1,131 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestSearchEmailsRetriesTransientError(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{"INBOX": {newTestMessage(1, "Hi", "<1@x>")}},
+		Transient: map[string][]error{"UidSearch": {errors.New("Server busy, please try again later")}},
+	}
+	c := newTestClient(m)
+	c.opts.Retry = RetryOptions{Attempts: 2}
+
+	emails, total, _, err := c.SearchEmails(context.Background(), "INBOX", "", EmailFilters{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 1 || len(emails) != 1 {
+		t.Errorf("got %d of %d emails, want 1", len(emails), total)
+	}
+	if n := m.Called("UidSearch"); n != 2 {
+		t.Errorf("UidSearch called %d times, want 2", n)
+	}
+}
+
+func TestGetEmailReconnectsAfterConnectionLoss(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{"INBOX": {withBody(newTestMessage(1, "Hi", "<1@x>"), "Subject: Hi\r\n\r\nBody\r\n")}},
+		Transient: map[string][]error{"UidFetch": {fmt.Errorf("read tcp: %w", io.ErrUnexpectedEOF)}},
+	}
+	c := newTestClient(m)
+	c.opts.Retry = RetryOptions{Attempts: 1}
+	redials := 0
+	c.redial = func() (backend, error) {
+		redials++
+		return m, nil
+	}
+
+	email, err := c.GetEmail(context.Background(), "INBOX", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email.Subject != "Hi" {
+		t.Errorf("Subject = %q, want Hi", email.Subject)
+	}
+	if redials != 1 || m.Called("Logout") != 1 {
+		t.Errorf("redialed %d times, logged out %d times; want 1 each", redials, m.Called("Logout"))
+	}
+	if n := m.Called("Select"); n != 2 {
+		t.Errorf("Select called %d times, want the folder selected again after reconnecting", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	busy := errors.New("Mailbox temporarily unavailable")
+	tests := []struct {
+		name      string
+		transient []error
+		attempts  int
+		wantCalls int
+	}{
+		{name: "retries disabled", transient: []error{busy}, attempts: 0, wantCalls: 1},
+		{name: "attempts exhausted", transient: []error{busy, busy, busy}, attempts: 2, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MockBackend{
+				Mailboxes: map[string][]*imap.Message{"INBOX": nil},
+				Transient: map[string][]error{"UidSearch": tt.transient},
+			}
+			c := newTestClient(m)
+			c.opts.Retry = RetryOptions{Attempts: tt.attempts}
+
+			if _, err := c.CountEmails(context.Background(), "INBOX", EmailFilters{}); !errors.Is(err, busy) {
+				t.Errorf("error = %v, want %v", err, busy)
+			}
+			if n := m.Called("UidSearch"); n != tt.wantCalls {
+				t.Errorf("UidSearch called %d times, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetrySkipsPermanentError(t *testing.T) {
+	m := &MockBackend{
+		Mailboxes: map[string][]*imap.Message{"INBOX": nil},
+		Errs:      map[string]error{"Select": errors.New("Mailbox does not exist")},
+	}
+	c := newTestClient(m)
+	c.opts.Retry = RetryOptions{Attempts: 3}
+
+	if _, err := c.CountEmails(context.Background(), "Archive", EmailFilters{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+	if n := m.Called("Select"); n != 1 {
+		t.Errorf("Select called %d times, want no retries", n)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{io.EOF, true},
+		{fmt.Errorf("failed to fetch message: %w", io.ErrUnexpectedEOF), true},
+		{errors.New("imap: connection closed"), true},
+		{errors.New("write tcp: broken pipe"), true},
+		{errors.New("Server unavailable, try again later"), true},
+		{errors.New("Too many simultaneous connections"), true},
+		{fmt.Errorf("failed to select folder X: %w", missingMailboxError{errors.New("Mailbox does not exist")}), false},
+		{fmt.Errorf("%w format: bad", ErrInvalidID), false},
+		{errors.New("Invalid search criteria"), false},
+		{context.Canceled, false},
+		{fmt.Errorf("failed to fetch messages: %w", context.DeadlineExceeded), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package imap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// SnoozeHeader records when a snoozed message is due back in the inbox.
+const SnoozeHeader = "X-Snooze-Until"
+
+// snoozeFolder holds snoozed mail in one subfolder per due date
+const snoozeFolder = "Snoozed"
+
+// SnoozedEmail describes a message after SnoozeEmail re-filed it.
+type SnoozedEmail struct {
+	Folder string    `json:"folder"` // e.g. Snoozed/2024-01-20
+	Until  time.Time `json:"until"`
+}
+
+// FlushResult reports what FlushSnoozed moved back to the inbox.
+type FlushResult struct {
+	Returned  int `json:"returned"`  // messages moved back to INBOX
+	Remaining int `json:"remaining"` // snoozed messages not yet due
+}
+
+// SnoozeEmail hides an email until a given time. IMAP cannot add a header to
+// a stored message, so the message is re-appended with an X-Snooze-Until
+// header to Snoozed/<date> (created if needed) and the original is removed.
+// The snoozed copy is stored unread so it stands out when it comes back.
+func (c *Client) SnoozeEmail(ctx context.Context, folder, emailID string, until time.Time) (*SnoozedEmail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.fetchRaw(folder, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Snoozed and the dated subfolder; existing ones are fine
+	target, err := c.folderPath(snoozeFolder, until.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{snoozeFolder, target} {
+		if err := c.client.Create(name); err != nil && !isExistingMailbox(err) {
+			return nil, fmt.Errorf("failed to create folder %s: %w", name, err)
+		}
+	}
+
+	// Keep the original date so the message sorts where it did before
+	if err := c.client.Append(target, nil, messageDate(raw), bytes.NewReader(withSnoozeHeader(raw, until))); err != nil {
+		return nil, fmt.Errorf("failed to append message to %s: %w", target, err)
+	}
+	if err := c.expungeEmail(folder, emailID); err != nil {
+		return nil, fmt.Errorf("snoozed copy saved to %s but the original was not removed: %w", target, err)
+	}
+
+	return &SnoozedEmail{Folder: target, Until: until}, nil
+}
+
+// FlushSnoozed moves every snoozed message that is due at now back to INBOX.
+// Dated subfolders left empty are deleted.
+func (c *Client) FlushSnoozed(ctx context.Context, now time.Time) (*FlushResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delimiter, err := c.folderDelimiter()
+	if err != nil {
+		return nil, err
+	}
+	folders, err := c.listFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FlushResult{}
+	for _, folder := range folders {
+		day, ok := strings.CutPrefix(folder, snoozeFolder+delimiter)
+		if !ok {
+			continue
+		}
+
+		until, err := c.snoozedUntil(ctx, folder)
+		if err != nil {
+			return result, err
+		}
+
+		var due []uint32
+		for uid, header := range until {
+			if snoozeDue(header, day, now) {
+				due = append(due, uid)
+			}
+		}
+		result.Remaining += len(until) - len(due)
+		if len(due) == 0 {
+			continue
+		}
+
+		if err := c.moveEmails(folder, "INBOX", due); err != nil {
+			return result, err
+		}
+		result.Returned += len(due)
+
+		if len(due) == len(until) {
+			// Deselect first: a mailbox cannot be deleted while selected
+			if _, err := c.selectFolder("INBOX", false); err == nil {
+				if err := c.client.Delete(folder); err != nil {
+					slog.Warn("failed to delete empty snooze folder", "folder", folder, "error", err)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// snoozedUntil returns the X-Snooze-Until value of every message in a snooze
+// folder, keyed by UID, fetching only that header (caller must hold c.mu)
+func (c *Client) snoozedUntil(ctx context.Context, folder string) (map[uint32]string, error) {
+	if _, err := c.selectFolder(folder, false); err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	uids, err := c.client.UidSearch(imap.NewSearchCriteria())
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", folder, err)
+	}
+	until := make(map[uint32]string, len(uids))
+	if len(uids) == 0 {
+		return until, nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+	section := &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.HeaderSpecifier, Fields: []string{SnoozeHeader}},
+		Peek:         true,
+	}
+
+	err = c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages)
+	}, func(msg *imap.Message) {
+		until[msg.Uid] = ""
+		for _, literal := range msg.Body {
+			raw, err := io.ReadAll(literal)
+			if err != nil {
+				continue
+			}
+			if headers, err := selectHeaders(raw, []string{SnoozeHeader}); err == nil && len(headers[SnoozeHeader]) > 0 {
+				until[msg.Uid] = headers[SnoozeHeader][0]
+			}
+			break
+		}
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch snooze headers: %w", err)
+	}
+	return until, nil
+}
+
+// snoozeDue reports whether a snoozed message is due at now. The
+// X-Snooze-Until header decides; without a readable one, the message is due
+// from the start of the day its folder is named after (local time), and
+// messages in a folder without a date are never returned.
+func snoozeDue(header, day string, now time.Time) bool {
+	if until, err := time.Parse(time.RFC3339, strings.TrimSpace(header)); err == nil {
+		return !now.Before(until)
+	}
+	if until, err := time.ParseInLocation("2006-01-02", day, now.Location()); err == nil {
+		return !now.Before(until)
+	}
+	return false
+}
+
+// withSnoozeHeader returns raw with an X-Snooze-Until header set to until,
+// replacing any existing one
+func withSnoozeHeader(raw []byte, until time.Time) []byte {
+	var header []byte
+	body := raw
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		header, body = raw[:i+2], raw[i+2:]
+	} else if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		header, body = raw[:i+1], raw[i+1:]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(SnoozeHeader + ": " + until.Format(time.RFC3339) + "\r\n")
+
+	// Drop old snooze header lines, including folded continuations
+	prefix := strings.ToLower(SnoozeHeader) + ":"
+	skipping := false
+	for _, line := range bytes.SplitAfter(header, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if skipping && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+		skipping = strings.HasPrefix(strings.ToLower(string(line)), prefix)
+		if !skipping {
+			buf.Write(line)
+		}
+	}
+	buf.Write(body)
+	return buf.Bytes()
+}
@@ -0,0 +1,66 @@
+package imap
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/utf7"
+)
+
+// specialUseCreateCommand is CREATE with the USE parameter of the
+// CREATE-SPECIAL-USE extension (RFC 6154), e.g. CREATE Archive (USE (\Archive))
+type specialUseCreateCommand struct {
+	mailbox string
+	attr    string
+}
+
+func (cmd specialUseCreateCommand) Command() *imap.Command {
+	mailbox, _ := utf7.Encoding.NewEncoder().String(cmd.mailbox)
+	use := []interface{}{imap.RawString("USE"), []interface{}{imap.RawString(cmd.attr)}}
+	return &imap.Command{
+		Name:      "CREATE",
+		Arguments: []interface{}{mailbox, use},
+	}
+}
+
+// SpecialUses lists the values CreateFolderOptions.Use accepts
+func SpecialUses() []string {
+	uses := make([]string, 0, len(folderAliases))
+	for use := range folderAliases {
+		uses = append(uses, use)
+	}
+	sort.Strings(uses)
+	return uses
+}
+
+// createMailbox creates path, tagged with the special-use attribute for use
+// (a folderAliases key such as "archive") when one is given. Servers without
+// CREATE-SPECIAL-USE get a plain CREATE, and the folder is untagged.
+// Caller must hold c.mu.
+func (c *Client) createMailbox(path, use string) error {
+	if use == "" {
+		return c.client.Create(path)
+	}
+	alias, ok := folderAliases[strings.ToLower(use)]
+	if !ok {
+		return fmt.Errorf("invalid special use %q: must be one of %s", use, strings.Join(SpecialUses(), ", "))
+	}
+
+	supported, err := c.client.Support("CREATE-SPECIAL-USE")
+	if err != nil {
+		return fmt.Errorf("failed to check capabilities: %w", err)
+	}
+	if !supported {
+		slog.Warn("server does not support CREATE-SPECIAL-USE; folder created without its special-use attribute", "folder", path, "use", alias.attr)
+		return c.client.Create(path)
+	}
+
+	status, err := c.client.Execute(specialUseCreateCommand{mailbox: path, attr: alias.attr}, nil)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
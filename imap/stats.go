@@ -0,0 +1,321 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// SenderCount is the number of messages received from one sender
+type SenderCount struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// Correspondent is an address mail was sent to, with when it was last used
+type Correspondent struct {
+	Address       string    `json:"address"`
+	Name          string    `json:"name,omitempty"`
+	LastContacted time.Time `json:"last_contacted"`
+}
+
+// FolderStats summarizes one folder for MailboxStats
+type FolderStats struct {
+	Folder string    `json:"folder"`
+	Total  int       `json:"total"`
+	Unread int       `json:"unread"`
+	Size   int64     `json:"size,omitempty"` // bytes; only with StatsOptions.IncludeSize
+	Oldest time.Time `json:"oldest,omitzero"`
+	Newest time.Time `json:"newest,omitzero"`
+	Error  string    `json:"error,omitempty"` // set when the folder could not be read
+}
+
+// Stats aggregates FolderStats across folders
+type Stats struct {
+	Total   int           `json:"total"`
+	Unread  int           `json:"unread"`
+	Size    int64         `json:"size,omitempty"`
+	Folders []FolderStats `json:"folders"`
+}
+
+// StatsOptions controls what MailboxStats reads
+type StatsOptions struct {
+	// Folders to summarize; all folders when empty
+	Folders []string
+	// IncludeSize sums RFC822.SIZE over every message, which fetches one
+	// item per message and is slow on large folders. Without it only the
+	// first and last messages are fetched for the date range.
+	IncludeSize bool
+}
+
+// CountBySender tallies messages in folder from the last lastDays days (all
+// messages if lastDays is 0) by sender address and returns the top limit
+// senders (all if limit is 0), busiest first. Addresses are compared
+// case-insensitively. A partial fetch returns the tally so far together with
+// an ErrPartialResults error.
+func (c *Client) CountBySender(ctx context.Context, folder string, lastDays, limit int) ([]SenderCount, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, _, err := c.searchEmails(ctx, folder, "", EmailFilters{LastDays: lastDays})
+	if emails == nil {
+		return nil, err
+	}
+
+	return tallySenders(emails, limit), err
+}
+
+// tallySenders counts emails per sender address, sorted by count descending
+// and then by address, truncated to limit entries when limit > 0.
+func tallySenders(emails []Email, limit int) []SenderCount {
+	index := make(map[string]int)
+	counts := []SenderCount{}
+	for _, email := range emails {
+		address, name := strings.ToLower(email.From), ""
+		if parsed, err := mail.ParseAddress(email.From); err == nil {
+			address, name = strings.ToLower(parsed.Address), parsed.Name
+		}
+		if address == "" {
+			continue
+		}
+
+		i, ok := index[address]
+		if !ok {
+			i = len(counts)
+			index[address] = i
+			counts = append(counts, SenderCount{Address: address})
+		}
+		counts[i].Count++
+		if counts[i].Name == "" {
+			counts[i].Name = name
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Address < counts[j].Address
+	})
+
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
+
+// dayLayout formats the day keys of CountByDay
+const dayLayout = "2006-01-02"
+
+// CountByDay counts messages in folder from the last lastDays days (all
+// messages if lastDays is 0) by the day of their date, as YYYY-MM-DD in the
+// local timezone of this server. Days without mail are absent. A partial
+// fetch returns the counts so far together with an ErrPartialResults error.
+func (c *Client) CountByDay(ctx context.Context, folder string, lastDays int) (map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []Email
+	err = c.withRetry(ctx, func() error {
+		var err error
+		emails, _, err = c.searchEmails(ctx, folder, "", EmailFilters{LastDays: lastDays})
+		return err
+	})
+	if emails == nil {
+		return nil, err
+	}
+
+	return countByDay(emails, time.Local), err
+}
+
+// countByDay buckets emails by the day of their date in loc, skipping emails
+// with no known date
+func countByDay(emails []Email, loc *time.Location) map[string]int {
+	counts := make(map[string]int)
+	for _, email := range emails {
+		if email.Date.IsZero() {
+			continue
+		}
+		counts[email.Date.In(loc).Format(dayLayout)]++
+	}
+	return counts
+}
+
+// RecentRecipients scans the Sent folder (found by its \Sent special-use
+// attribute) over the last lastDays days (all messages if 0) and returns the
+// distinct To addresses, most recently contacted first, truncated to limit
+// entries when limit > 0. A partial fetch returns what was collected with an
+// ErrPartialResults error.
+func (c *Client) RecentRecipients(ctx context.Context, lastDays, limit int) ([]Correspondent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sent := folderAliases["sent"]
+	folder, err := c.specialFolder(sent.attr, sent.fallbacks...)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, _, err := c.searchEmails(ctx, folder, "", EmailFilters{LastDays: lastDays})
+	if emails == nil {
+		return nil, err
+	}
+
+	return recentRecipients(emails, limit), err
+}
+
+// recentRecipients collects the To addresses of emails, keeping the latest
+// date and first display name seen for each, sorted by date descending and
+// then by address.
+func recentRecipients(emails []Email, limit int) []Correspondent {
+	index := make(map[string]int)
+	recipients := []Correspondent{}
+	for _, email := range emails {
+		for _, to := range email.To {
+			address, name := strings.ToLower(to), ""
+			if parsed, err := mail.ParseAddress(to); err == nil {
+				address, name = strings.ToLower(parsed.Address), parsed.Name
+			}
+			if address == "" {
+				continue
+			}
+
+			i, ok := index[address]
+			if !ok {
+				i = len(recipients)
+				index[address] = i
+				recipients = append(recipients, Correspondent{Address: address})
+			}
+			if email.Date.After(recipients[i].LastContacted) {
+				recipients[i].LastContacted = email.Date
+			}
+			if recipients[i].Name == "" {
+				recipients[i].Name = name
+			}
+		}
+	}
+
+	sort.Slice(recipients, func(i, j int) bool {
+		if !recipients[i].LastContacted.Equal(recipients[j].LastContacted) {
+			return recipients[i].LastContacted.After(recipients[j].LastContacted)
+		}
+		return recipients[i].Address < recipients[j].Address
+	})
+
+	if limit > 0 && len(recipients) > limit {
+		recipients = recipients[:limit]
+	}
+	return recipients
+}
+
+// MailboxStats reports message and unread counts and the oldest and newest
+// arrival dates for each folder, with totals. Folders are opened read-only.
+// A folder that cannot be read (such as a \Noselect parent) is reported with
+// its error instead of failing the whole call.
+func (c *Client) MailboxStats(ctx context.Context, opts StatsOptions) (*Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folders := opts.Folders
+	if len(folders) == 0 {
+		var err error
+		if folders, err = c.listFolders(); err != nil {
+			return nil, err
+		}
+		sort.Strings(folders)
+	}
+
+	stats := &Stats{Folders: make([]FolderStats, 0, len(folders))}
+	for _, folder := range folders {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resolved, err := c.resolveFolder(folder)
+		if err != nil {
+			return nil, err
+		}
+
+		fs, err := c.folderStats(ctx, resolved, opts.IncludeSize)
+		if err != nil {
+			fs = FolderStats{Folder: resolved, Error: err.Error()}
+		}
+		stats.Total += fs.Total
+		stats.Unread += fs.Unread
+		stats.Size += fs.Size
+		stats.Folders = append(stats.Folders, fs)
+	}
+	return stats, nil
+}
+
+// folderStats summarizes one folder (caller must hold c.mu)
+func (c *Client) folderStats(ctx context.Context, folder string, includeSize bool) (FolderStats, error) {
+	fs := FolderStats{Folder: folder}
+
+	status, err := c.selectFolder(folder, true)
+	if err != nil {
+		return fs, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+	fs.Total = int(status.Messages)
+	if fs.Total == 0 {
+		return fs, nil
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	unread, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return fs, fmt.Errorf("failed to search folder %s: %w", folder, err)
+	}
+	fs.Unread = len(unread)
+
+	// Messages are numbered in arrival order, so the ends give the range
+	// unless every message is fetched anyway
+	seqSet := new(imap.SeqSet)
+	items := []imap.FetchItem{imap.FetchInternalDate}
+	if includeSize {
+		seqSet.AddRange(1, status.Messages)
+		items = append(items, imap.FetchRFC822Size)
+	} else {
+		seqSet.AddNum(1, status.Messages)
+	}
+
+	err = c.fetchMessages(ctx, func(messages chan *imap.Message) error {
+		return c.client.Fetch(seqSet, items, messages)
+	}, func(msg *imap.Message) {
+		if includeSize {
+			fs.Size += int64(msg.Size)
+		}
+		if msg.InternalDate.IsZero() {
+			return
+		}
+		if fs.Oldest.IsZero() || msg.InternalDate.Before(fs.Oldest) {
+			fs.Oldest = msg.InternalDate
+		}
+		if msg.InternalDate.After(fs.Newest) {
+			fs.Newest = msg.InternalDate
+		}
+	})
+	if err != nil && ctx.Err() != nil {
+		return fs, err
+	}
+	if err != nil {
+		return fs, fmt.Errorf("failed to fetch message dates: %w", err)
+	}
+	return fs, nil
+}
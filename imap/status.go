@@ -0,0 +1,63 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+)
+
+// FolderStatus describes a folder as the server reports it on SELECT
+type FolderStatus struct {
+	Folder   string `json:"folder"`
+	Messages uint32 `json:"messages"`
+	UIDNext  uint32 `json:"uidNext"` // UID the next message added will get
+	// UIDValidity changes when the server renumbers the folder; UIDs
+	// cached under an earlier value no longer name the same messages
+	UIDValidity uint32 `json:"uidValidity"`
+}
+
+// FolderStatus returns the message count, next UID, and UIDVALIDITY of
+// folder, examining it read-only
+func (c *Client) FolderStatus(ctx context.Context, folder string) (*FolderStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.selectFolder(folder, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+
+	return &FolderStatus{
+		Folder:      folder,
+		Messages:    status.Messages,
+		UIDNext:     status.UidNext,
+		UIDValidity: status.UidValidity,
+	}, nil
+}
+
+// UIDValidity returns the UIDVALIDITY of folder. While folder is the
+// selected one, the value the server sent when it was selected still holds
+// and no command is needed; otherwise the folder is examined read-only.
+func (c *Client) UIDValidity(ctx context.Context, folder string) (uint32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, err := c.resolveFolder(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.selected == folder && c.uidValidity != 0 {
+		return c.uidValidity, nil
+	}
+
+	status, err := c.selectFolder(folder, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select folder %s: %w", folder, err)
+	}
+	return status.UidValidity, nil
+}
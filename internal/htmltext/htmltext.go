@@ -0,0 +1,38 @@
+// Package htmltext converts HTML email bodies to plain text.
+package htmltext
+
+import (
+	"html"
+	"strings"
+)
+
+// ToText strips tags from an HTML body, turning line breaks and block ends
+// into newlines and decoding entities. It is a lightweight conversion meant
+// for plain text alternatives and previews, not a full HTML renderer.
+func ToText(s string) string {
+	// Replace common tags with newlines
+	text := strings.ReplaceAll(s, "<br>", "\n")
+	text = strings.ReplaceAll(text, "<br/>", "\n")
+	text = strings.ReplaceAll(text, "<br />", "\n")
+	text = strings.ReplaceAll(text, "</p>", "\n\n")
+	text = strings.ReplaceAll(text, "</div>", "\n")
+
+	// Remove remaining tags
+	inTag := false
+	var result strings.Builder
+	for _, char := range text {
+		if char == '<' {
+			inTag = true
+			continue
+		}
+		if char == '>' {
+			inTag = false
+			continue
+		}
+		if !inTag {
+			result.WriteRune(char)
+		}
+	}
+
+	return strings.TrimSpace(html.UnescapeString(result.String()))
+}
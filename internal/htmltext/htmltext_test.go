@@ -0,0 +1,22 @@
+package htmltext
+
+import "testing"
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"<p>Hello</p><p>World</p>", "Hello\n\nWorld"},
+		{"Line one<br>Line two<br />Line three", "Line one\nLine two\nLine three"},
+		{"<div><b>Bold</b> text</div>", "Bold text"},
+		{"Fish &amp; chips &lt;3", "Fish & chips <3"},
+		{"  plain  ", "plain"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ToText(tt.in); got != tt.want {
+			t.Errorf("ToText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
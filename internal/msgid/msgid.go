@@ -0,0 +1,21 @@
+// Package msgid generates RFC 5322 Message-ID values.
+package msgid
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// New returns a unique Message-ID in angle brackets under domain.
+func New(domain string) string {
+	return "<" + uuid.New().String() + "@" + domain + ">"
+}
+
+// DomainOf returns the domain part of an email address, or "" if it has none.
+func DomainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package msgid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	id := New("example.com")
+	if !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("New = %q, want <...@example.com>", id)
+	}
+	if strings.Count(id, "@") != 1 {
+		t.Errorf("New = %q, want exactly one @", id)
+	}
+	if New("example.com") == id {
+		t.Error("expected unique IDs")
+	}
+}
+
+func TestDomainOf(t *testing.T) {
+	tests := map[string]string{
+		"me@icloud.com":   "icloud.com",
+		"a@b@example.org": "example.org",
+		"nodomain":        "",
+	}
+	for in, want := range tests {
+		if got := DomainOf(in); got != want {
+			t.Errorf("DomainOf(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
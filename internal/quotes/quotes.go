@@ -0,0 +1,63 @@
+// Package quotes trims quoted history and signatures from plain text
+// replies, leaving the text the sender actually wrote.
+package quotes
+
+import (
+	"regexp"
+	"strings"
+)
+
+// attributionRe matches the line a mail client puts above a quoted
+// original, such as "On Mon, 1 Jan 2024, Alice <a@example.com> wrote:",
+// including German and French forms like "Am 01.01.2024 schrieb Alice:".
+var attributionRe = regexp.MustCompile(`(?i)\b(wrote|schrieb|a écrit)\b[^:]*:$`)
+
+// Trim returns the new content of a plain text reply. Everything from the
+// first signature delimiter ("-- " on a line of its own) is dropped, then
+// quoted blocks (lines starting with ">") at the end of the text along with
+// the "On ... wrote:" line introducing them. Quotes interleaved with
+// replies are kept, since the replies refer to them. When nothing would be
+// left, as in a bare forward, text is returned unchanged.
+func Trim(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// Many clients strip the delimiter's trailing space, so "--" counts too
+	for i, line := range lines {
+		if line == "-- " || line == "--" {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	for {
+		end := len(lines)
+		quoted := false
+		for end > 0 {
+			line := strings.TrimSpace(lines[end-1])
+			if line != "" && !strings.HasPrefix(line, ">") {
+				break
+			}
+			quoted = quoted || line != ""
+			end--
+		}
+		lines = lines[:end]
+
+		// An attribution may be wrapped onto a second line, as in
+		// "On Mon, 1 Jan 2024 at 10:00, Alice <\na@example.com> wrote:"
+		if quoted && end > 0 && attributionRe.MatchString(strings.TrimSpace(lines[end-1])) {
+			end--
+			if end > 0 && !strings.HasPrefix(lines[end], "On ") && strings.HasPrefix(lines[end-1], "On ") {
+				end--
+			}
+			lines = lines[:end]
+			continue
+		}
+		break
+	}
+
+	trimmed := strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+	if strings.TrimSpace(trimmed) == "" {
+		return text
+	}
+	return trimmed
+}
@@ -0,0 +1,61 @@
+package quotes
+
+import "testing"
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "reply with quoted original and signature",
+			in: "Sounds good, see you Tuesday.\r\n\r\n-- \r\nBob\r\nSent from my phone\r\n\r\n" +
+				"On Mon, 1 Jan 2024 at 10:00, Alice <alice@example.com> wrote:\r\n> Are we still on for Tuesday?\r\n>\r\n> Alice\r\n",
+			want: "Sounds good, see you Tuesday.",
+		},
+		{
+			name: "bottom-posted reply keeps the text above the signature",
+			in:   "> Are we still on?\n\nYes.\n--\nBob",
+			want: "> Are we still on?\n\nYes.",
+		},
+		{
+			name: "wrapped attribution",
+			in:   "Thanks!\n\nOn Mon, 1 Jan 2024 at 10:00, Alice Example <\nalice@example.com> wrote:\n\n> Here is the file.\n",
+			want: "Thanks!",
+		},
+		{
+			name: "nested quotes",
+			in:   "Agreed.\n\nAm 01.01.2024 schrieb Carol:\n> Fine by me.\n>> Shall we meet?\n",
+			want: "Agreed.",
+		},
+		{
+			name: "interleaved quotes are kept",
+			in:   "> First question?\nFirst answer.\n> Second question?\nSecond answer.\n",
+			want: "> First question?\nFirst answer.\n> Second question?\nSecond answer.",
+		},
+		{
+			name: "wrote without a quote below is not an attribution",
+			in:   "Here is what she wrote:\n\n",
+			want: "Here is what she wrote:",
+		},
+		{
+			name: "nothing but a quote is left alone",
+			in:   "> forwarded text\n",
+			want: "> forwarded text\n",
+		},
+		{
+			name: "plain message",
+			in:   "Hello",
+			want: "Hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Trim(tt.in); got != tt.want {
+				t.Errorf("Trim() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
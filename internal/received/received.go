@@ -0,0 +1,102 @@
+// Package received parses chains of Received headers (RFC 5321 section 4.4)
+// into delivery hops.
+package received
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Hop is one relay a message passed through.
+type Hop struct {
+	From  string        // host the relay received the message from
+	By    string        // host of the relay itself
+	With  string        // protocol, e.g. ESMTPS
+	ID    string        // the relay's queue ID
+	Time  time.Time     // when the relay received the message; zero if unreadable
+	Delay time.Duration // since the previous hop; 0 for the first hop or a missing time
+	Raw   string        // the header value as written
+}
+
+// Parse turns Received header values, in header order (newest first, as each
+// relay prepends its own), into hops ordered from the sender's side to the
+// mailbox, each with the delay since the hop before it.
+func Parse(values []string) []Hop {
+	hops := make([]Hop, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		hop := parseHop(values[i])
+		if n := len(hops); n > 0 && !hop.Time.IsZero() && !hops[n-1].Time.IsZero() {
+			hop.Delay = hop.Time.Sub(hops[n-1].Time)
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseHop reads the from/by/with/id clauses and the timestamp after the
+// last semicolon. Comments such as "(mail.example.com [192.0.2.1])" are
+// skipped, so each clause yields its first word.
+func parseHop(value string) Hop {
+	hop := Hop{Raw: value}
+
+	clauses := value
+	if i := strings.LastIndex(value, ";"); i >= 0 {
+		clauses = value[:i]
+		if t, err := mail.ParseDate(strings.TrimSpace(value[i+1:])); err == nil {
+			hop.Time = t
+		}
+	}
+
+	words := words(clauses)
+	for i := 0; i+1 < len(words); i++ {
+		var field *string
+		switch strings.ToLower(words[i]) {
+		case "from":
+			field = &hop.From
+		case "by":
+			field = &hop.By
+		case "with":
+			field = &hop.With
+		case "id":
+			field = &hop.ID
+		default:
+			continue
+		}
+		if *field == "" {
+			*field = words[i+1]
+			i++
+		}
+	}
+	return hop
+}
+
+// words splits s on whitespace, dropping parenthesized comments (which may
+// nest)
+func words(s string) []string {
+	var out []string
+	var word strings.Builder
+	depth := 0
+	flush := func() {
+		if word.Len() > 0 {
+			out = append(out, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '(':
+			flush()
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth > 0:
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			flush()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,62 @@
+package received
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseChain(t *testing.T) {
+	// Header order: the mailbox server's hop first
+	values := []string{
+		"from mx.icloud.com (mx.icloud.com [17.57.0.1]) by p00-icloudmta.icloud.com with ESMTPS id AB12 for <me@icloud.com>; Mon, 15 Jan 2024 10:02:30 +0000",
+		"from relay.example.net (relay.example.net [198.51.100.7]) by mx.icloud.com (Postfix) with ESMTPS id 4TD9; Mon, 15 Jan 2024 10:00:30 +0000 (UTC)",
+		"from [192.0.2.10] (helo=laptop) by relay.example.net with ESMTPSA id 1rP; Mon, 15 Jan 2024 11:00:00 +0100",
+	}
+
+	hops := Parse(values)
+	if len(hops) != 3 {
+		t.Fatalf("got %d hops, want 3", len(hops))
+	}
+
+	want := []struct {
+		from, by, with, id string
+		delay              time.Duration
+	}{
+		{from: "[192.0.2.10]", by: "relay.example.net", with: "ESMTPSA", id: "1rP"},
+		{from: "relay.example.net", by: "mx.icloud.com", with: "ESMTPS", id: "4TD9", delay: 30 * time.Second},
+		{from: "mx.icloud.com", by: "p00-icloudmta.icloud.com", with: "ESMTPS", id: "AB12", delay: 2 * time.Minute},
+	}
+	for i, w := range want {
+		hop := hops[i]
+		if hop.From != w.from || hop.By != w.by || hop.With != w.with || hop.ID != w.id {
+			t.Errorf("hop %d = from %q by %q with %q id %q; want %q %q %q %q", i, hop.From, hop.By, hop.With, hop.ID, w.from, w.by, w.with, w.id)
+		}
+		if hop.Delay != w.delay {
+			t.Errorf("hop %d delay = %v, want %v", i, hop.Delay, w.delay)
+		}
+		if hop.Raw != values[len(values)-1-i] {
+			t.Errorf("hop %d raw = %q", i, hop.Raw)
+		}
+	}
+	if !hops[0].Time.Equal(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("first hop time = %v", hops[0].Time)
+	}
+}
+
+func TestParseMissingTime(t *testing.T) {
+	hops := Parse([]string{
+		"from b.example.com by c.example.com; Mon, 15 Jan 2024 10:05:00 +0000",
+		"from a.example.com by b.example.com; not a date",
+		"by a.example.com with local; Mon, 15 Jan 2024 10:00:00 +0000",
+	})
+	if len(hops) != 3 {
+		t.Fatalf("got %d hops, want 3", len(hops))
+	}
+	if hops[0].From != "" || hops[0].By != "a.example.com" || hops[0].With != "local" {
+		t.Errorf("first hop = %+v", hops[0])
+	}
+	// Delays next to an unreadable time are unknown, not negative or huge
+	if !hops[1].Time.IsZero() || hops[1].Delay != 0 || hops[2].Delay != 0 {
+		t.Errorf("delays = %v, %v; want 0 around a missing time", hops[1].Delay, hops[2].Delay)
+	}
+}
@@ -0,0 +1,51 @@
+// Package subject normalizes email subject lines for reply composition and
+// thread matching.
+package subject
+
+import (
+	"regexp"
+	"strings"
+)
+
+// prefixRe matches one leading reply or forward marker, including localized
+// forms (AW, SV, VS, WG, TR, Antw), an optional counter like "Re[2]:", and
+// stray whitespace before the colon.
+var prefixRe = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|sv|vs|wg|tr|antw)\s*(\[\d+\])?\s*:\s*`)
+
+// Normalize strips all leading reply/forward prefixes and surrounding
+// whitespace, so "RE: Fwd: Re : Hello" becomes "Hello".
+func Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		loc := prefixRe.FindStringIndex(s)
+		if loc == nil {
+			return s
+		}
+		s = strings.TrimSpace(s[loc[1]:])
+	}
+}
+
+// DefaultReplyPrefix is the marker Reply puts in front of a subject.
+const DefaultReplyPrefix = "Re:"
+
+// Reply returns the subject for a reply: the normalized subject with a single
+// "Re: " prefix.
+func Reply(s string) string {
+	return ReplyWith(DefaultReplyPrefix, s)
+}
+
+// ReplyWith is Reply with a custom (e.g. localized) prefix such as "Odp:".
+// Existing copies of prefix are stripped along with the standard markers, so
+// repeated replies never stack. An empty prefix means DefaultReplyPrefix.
+func ReplyWith(prefix, s string) string {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		prefix = DefaultReplyPrefix
+	}
+
+	s = Normalize(s)
+	for len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		s = Normalize(s[len(prefix):])
+	}
+	return prefix + " " + s
+}
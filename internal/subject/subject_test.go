@@ -0,0 +1,69 @@
+package subject
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hello", "Hello"},
+		{"Re: Hello", "Hello"},
+		{"RE: Hello", "Hello"},
+		{"Re : Hello", "Hello"},
+		{"Fwd: Hello", "Hello"},
+		{"FW: Hello", "Hello"},
+		{"Re: Re: Hello", "Hello"},
+		{"Re: Fwd: RE: Hello", "Hello"},
+		{"AW: Hello", "Hello"},
+		{"SV: Hello", "Hello"},
+		{"Re[2]: Hello", "Hello"},
+		{"   Re: Hello  ", "Hello"},
+		{"Regarding: Hello", "Regarding: Hello"},
+		{"Return policy", "Return policy"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReply(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Hello", "Re: Hello"},
+		{"RE: Hello", "Re: Hello"},
+		{"Re: Re: Hello", "Re: Hello"},
+		{"AW: Hello", "Re: Hello"},
+		{"  Fwd: Hello", "Re: Hello"},
+	}
+	for _, tt := range tests {
+		if got := Reply(tt.in); got != tt.want {
+			t.Errorf("Reply(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReplyWith(t *testing.T) {
+	tests := []struct {
+		prefix string
+		in     string
+		want   string
+	}{
+		{"Odp:", "Hello", "Odp: Hello"},
+		{"Odp:", "Odp: Hello", "Odp: Hello"},
+		{"Odp:", "ODP: Re: Odp: Hello", "Odp: Hello"},
+		{"AW:", "Re: Hello", "AW: Hello"},
+		{"回复:", "回复: Hello", "回复: Hello"},
+		{"", "Re: Hello", "Re: Hello"},
+	}
+	for _, tt := range tests {
+		if got := ReplyWith(tt.prefix, tt.in); got != tt.want {
+			t.Errorf("ReplyWith(%q, %q) = %q, want %q", tt.prefix, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package tlsconf builds the TLS settings shared by the IMAP and SMTP clients.
+package tlsconf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPinMismatch is returned from the handshake when no certificate presented
+// by the server matches a pinned fingerprint.
+var ErrPinMismatch = errors.New("server certificate does not match TLS_PIN")
+
+// ParseVersion parses a minimum TLS version such as "1.2" or "1.3". An empty
+// string means TLS 1.2; older versions are refused.
+func ParseVersion(s string) (uint16, error) {
+	switch strings.TrimSpace(s) {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q (use 1.2 or 1.3)", s)
+}
+
+// ParsePins parses comma-separated SHA-256 certificate fingerprints written
+// in hex, with or without colons (as printed by openssl x509 -fingerprint).
+func ParsePins(s string) ([][]byte, error) {
+	var pins [][]byte
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		pin, err := hex.DecodeString(strings.ReplaceAll(field, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate fingerprint %q: want a SHA-256 fingerprint in hex", field)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// New returns a client TLS config requiring at least minVersion. With pins,
+// the handshake also requires one of the certificates the server presents
+// (its own or an intermediate) to have a pinned SHA-256 fingerprint; normal
+// chain and hostname verification still applies.
+func New(minVersion uint16, pins [][]byte) *tls.Config {
+	config := &tls.Config{MinVersion: minVersion}
+	if len(pins) > 0 {
+		config.VerifyPeerCertificate = verifyPins(pins)
+	}
+	return config
+}
+
+// verifyPins returns a VerifyPeerCertificate callback accepting any chain
+// that contains a pinned certificate
+func verifyPins(pins [][]byte) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for _, raw := range rawCerts {
+			sum := sha256.Sum256(raw)
+			for _, pin := range pins {
+				if bytes.Equal(sum[:], pin) {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+}
@@ -0,0 +1,187 @@
+package tlsconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{in: "", want: tls.VersionTLS12},
+		{in: "1.2", want: tls.VersionTLS12},
+		{in: " 1.3 ", want: tls.VersionTLS13},
+		{in: "1.1", wantErr: true},
+		{in: "tls13", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseVersion(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %x, %v; want %x", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	sum := sha256.Sum256([]byte("cert"))
+	plain := hex.EncodeToString(sum[:])
+	colons := strings.ToUpper(strings.Join(splitPairs(plain), ":"))
+
+	pins, err := ParsePins(plain + ", " + colons)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pins) != 2 || string(pins[0]) != string(sum[:]) || string(pins[1]) != string(sum[:]) {
+		t.Errorf("pins = %x", pins)
+	}
+
+	if pins, err := ParsePins(""); err != nil || len(pins) != 0 {
+		t.Errorf("ParsePins(\"\") = %x, %v; want none", pins, err)
+	}
+	for _, bad := range []string{"zz", "abcd", plain + "00"} {
+		if _, err := ParsePins(bad); err == nil {
+			t.Errorf("ParsePins(%q): expected error", bad)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	config := New(tls.VersionTLS13, nil)
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x, want TLS 1.3", config.MinVersion)
+	}
+	if config.VerifyPeerCertificate != nil {
+		t.Error("expected no pin check without pins")
+	}
+	if New(tls.VersionTLS12, [][]byte{make([]byte, sha256.Size)}).VerifyPeerCertificate == nil {
+		t.Error("expected a pin check with pins")
+	}
+}
+
+func TestHandshakePinning(t *testing.T) {
+	cert, der := selfSigned(t)
+	sum := sha256.Sum256(der)
+	other := sha256.Sum256([]byte("some other certificate"))
+
+	tests := []struct {
+		name       string
+		pins       [][]byte
+		minVersion uint16
+		serverMax  uint16
+		wantErr    error
+	}{
+		{name: "no pin", minVersion: tls.VersionTLS12},
+		{name: "pin matches", pins: [][]byte{other[:], sum[:]}, minVersion: tls.VersionTLS12},
+		{name: "pin mismatch", pins: [][]byte{other[:]}, minVersion: tls.VersionTLS12, wantErr: ErrPinMismatch},
+		{name: "version too old", minVersion: tls.VersionTLS13, serverMax: tls.VersionTLS12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serve(t, &tls.Config{Certificates: []tls.Certificate{cert}, MaxVersion: tt.serverMax})
+
+			roots := x509.NewCertPool()
+			roots.AddCert(cert.Leaf)
+			config := New(tt.minVersion, tt.pins)
+			config.RootCAs = roots
+			config.ServerName = "localhost"
+
+			conn, err := tls.Dial("tcp", addr, config)
+			if conn != nil {
+				conn.Close()
+			}
+			switch {
+			case tt.serverMax != 0:
+				if err == nil {
+					t.Error("expected handshake to fail below the minimum version")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// selfSigned returns a certificate for localhost and its DER encoding
+func selfSigned(t *testing.T) (tls.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, der
+}
+
+// serve accepts TLS connections on a local port until the test ends
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// splitPairs splits a hex string into two-character groups
+func splitPairs(s string) []string {
+	var pairs []string
+	for i := 0; i+2 <= len(s); i += 2 {
+		pairs = append(pairs, s[i:i+2])
+	}
+	return pairs
+}
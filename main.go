@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rgabriel/mcp-icloud-email/config"
 	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/tlsconf"
 	"github.com/rgabriel/mcp-icloud-email/smtp"
 	"github.com/rgabriel/mcp-icloud-email/tools"
 )
@@ -22,6 +24,23 @@ import (
 // version is set at build time via ldflags
 var version = "dev"
 
+// toolTimeouts overrides the default per-call deadline for tools whose
+// expected duration differs substantially from the norm.
+var toolTimeouts = map[string]time.Duration{
+	"get_attachment":         180 * time.Second,
+	"export_folder":          600 * time.Second,
+	"import_mbox":            600 * time.Second,
+	"count_emails":           15 * time.Second,
+	"fetch_unread":           120 * time.Second,
+	"find_large_attachments": 180 * time.Second,
+	"mailbox_stats":          180 * time.Second,
+	"find_duplicates":        180 * time.Second,
+	"remove_duplicates":      180 * time.Second,
+	"count_by_day":           120 * time.Second,
+	"flush_snoozed":          120 * time.Second,
+	"diff_folders":           180 * time.Second,
+}
+
 func main() {
 	// Initialize structured logging
 	logLevel := new(slog.LevelVar)
@@ -45,21 +64,47 @@ func main() {
 		slog.Error("configuration error", "error", err)
 		os.Exit(1)
 	}
-
-	// Create IMAP client
-	imapClient, err := imap.NewClient(cfg.ICloudEmail, cfg.ICloudPassword)
-	if err != nil {
-		slog.Error("failed to create IMAP client", "error", err)
-		os.Exit(1)
+	// The wire log is written at debug level; asking for it implies that level
+	if cfg.IMAPDebug {
+		logLevel.Set(slog.LevelDebug)
 	}
-	// Test IMAP connection by listing folders
-	_, err = imapClient.ListFolders(context.Background())
-	if err != nil {
-		_ = imapClient.Close()
-		slog.Error("failed to connect to iCloud IMAP (check credentials)", "error", err)
-		os.Exit(1)
+
+	// All connections share the TLS_MIN_VERSION and TLS_PIN settings
+	tlsConfig := tlsconf.New(cfg.TLSMinVersion, cfg.TLSPins)
+
+	// Create an IMAP connection pool and an SMTP client for each account
+	accounts := make([]*account, 0, len(cfg.Accounts))
+	defer func() {
+		for _, a := range accounts {
+			_ = a.imapClient.Close()
+		}
+	}()
+	for _, acct := range cfg.Accounts {
+		pool, err := connectAccount(cfg, acct, tlsConfig)
+		if err != nil {
+			slog.Error("failed to connect to iCloud IMAP (check credentials)", "account", acct.ID, "error", err)
+			for _, a := range accounts {
+				_ = a.imapClient.Close()
+			}
+			os.Exit(1)
+		}
+		accounts = append(accounts, &account{
+			Account:    acct,
+			imapClient: pool,
+			smtpClient: smtp.NewClient(acct.Email, acct.Password, smtp.ClientOptions{
+				OAuthToken:          acct.OAuthToken,
+				MessageIDDomain:     acct.MessageIDDomain,
+				AutoBCC:             acct.AutoBCC,
+				ReplyPrefix:         cfg.ReplyPrefix,
+				AttributionTemplate: cfg.AttributionTemplate,
+				TLSConfig:           tlsConfig,
+				Aliases:             acct.AllowedFrom,
+				SendRatePerMinute:   cfg.SendRate,
+				Charset:             cfg.BodyCharset,
+				HeloHost:            cfg.SMTPHeloHost,
+			}),
+		})
 	}
-	defer func() { _ = imapClient.Close() }()
 
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -73,19 +118,23 @@ func main() {
 		cancel()
 	}()
 
-	// Create SMTP client
-	smtpClient := smtp.NewClient(cfg.ICloudEmail, cfg.ICloudPassword)
-
 	// Create MCP server with middleware (applied in reverse: logging wraps timeout wraps handler)
 	s := server.NewMCPServer(
 		"iCloud Email Server",
 		version,
 		server.WithToolCapabilities(false),
 		server.WithRecovery(),
-		server.WithToolHandlerMiddleware(timeoutMiddleware(60*time.Second)),
+		server.WithToolHandlerMiddleware(timeoutMiddleware(60*time.Second, toolTimeouts)),
 		server.WithToolHandlerMiddleware(loggingMiddleware()),
 	)
 
+	// In read-only mode, tools that send, move, flag, or delete anything are
+	// never registered, so a client cannot even see them
+	addTool := toolRegistrar(s, cfg.ReadOnly, accounts)
+	if cfg.ReadOnly {
+		slog.Info("read-only mode: mutating tools are not registered")
+	}
+
 	// Register search_emails tool
 	searchEmailsTool := mcp.NewTool("search_emails",
 		mcp.WithDescription("Search and list emails with optional filters. Use list_folders first to discover valid folder names. Returns each email's id (use with get_email), from, to, subject, date, and unread status."),
@@ -97,7 +146,7 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder to search in. Use list_folders to discover valid names."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithNumber("last_days",
 			mcp.Description("Only return emails from the last N days. Ignored if 'since' is provided."),
@@ -119,14 +168,42 @@ func main() {
 			mcp.Description("Only return unread (unseen) emails."),
 			mcp.DefaultBool(false),
 		),
+		mcp.WithString("delivered_to",
+			mcp.Description("Only return emails delivered to this address (Delivered-To or X-Original-To header), e.g. a plus-address like you+shopping@icloud.com."),
+		),
 		mcp.WithString("since",
-			mcp.Description("Start date filter in RFC 3339 format (e.g., '2024-01-15T14:30:00Z'). Overrides last_days."),
+			mcp.Description("Start date filter. Accepts RFC 3339 (e.g., '2024-01-15T14:30:00Z'), a date ('2024-01-15'), or today, yesterday, this_week, last_week, this_month, last_month. Overrides last_days."),
 		),
 		mcp.WithString("before",
-			mcp.Description("End date filter in RFC 3339 format (e.g., '2024-01-15T14:30:00Z')."),
+			mcp.Description("End date filter (exclusive). Accepts the same formats as 'since'; dates and keywords mean the start of that day, week, or month."),
+		),
+		mcp.WithString("exclude_folder",
+			mcp.Description("Leave out emails whose Message-ID also appears in this folder, e.g. a 'Done' folder holding copies of handled mail."),
+		),
+		mcp.WithString("raw_query",
+			mcp.Description("IMAP SEARCH keys used as-is for the server-side search, e.g. 'OR FROM a@b.com SUBJECT invoice' or 'UNSEEN SINCE 1-Feb-2024'. Replaces query, last_days, since, before, unread_only, and delivered_to; limit and offset still apply."),
+		),
+		mcp.WithBoolean("group_by_thread",
+			mcp.Description("Group results into conversations by normalized subject and References. Returns 'conversations' (each with the latest message, count, and email_ids) instead of 'emails'."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("format",
+			mcp.Enum("json", "compact"),
+			mcp.Description("Output format. 'compact' returns a summary line and one 'id | date | from | subject | unread' row per email, which uses far fewer tokens than JSON. Cannot be combined with group_by_thread."),
+			mcp.DefaultString("json"),
+		),
+		mcp.WithBoolean("ids_only",
+			mcp.Description("Return only the matching email IDs ('ids') and total, without fetching any headers. Fast input for bulk moves and deletes. Cannot be combined with group_by_thread, exclude_folder, or format=compact."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("explain_empty",
+			mcp.Description("Add 'found' (true or false) to JSON responses; when nothing matched, also add a 'message' explaining why and the 'filters' that were applied. An empty result is not an error."),
+			mcp.DefaultBool(cfg.ExplainEmptyResults),
 		),
 	)
-	s.AddTool(searchEmailsTool, tools.SearchEmailsHandler(imapClient))
+	addTool(searchEmailsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.SearchEmailsHandler(a.imapClient, cfg.DefaultFolder, cfg.ExplainEmptyResults)
+	})
 
 	// Register get_email tool
 	getEmailTool := mcp.NewTool("get_email",
@@ -134,6 +211,99 @@ func main() {
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID from search_emails results, or a sequence number with id_type=seq."),
+		),
+		mcp.WithString("id_type",
+			mcp.Description("How email_id is interpreted: uid (stable) or seq (message sequence number, which shifts when earlier messages are removed)."),
+			mcp.Enum("uid", "seq"),
+			mcp.DefaultString("uid"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email. Use list_folders to discover valid names."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithBoolean("unfold_flowed",
+			mcp.Description("For format=flowed (RFC 3676) plain text bodies, join soft-wrapped lines into paragraphs while keeping hard line breaks."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("trim_quotes",
+			mcp.Description("Return only the new content of the plain text body, cutting the signature and the quoted message it replies to. Useful when reading a whole thread."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithArray("headers",
+			mcp.Description("Header field names to fetch (e.g. [\"List-Id\", \"X-Mailer\"]). When set, only these headers are downloaded and returned instead of the full email."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("body_max_bytes",
+			mcp.Description("Preview long emails: download only the first this many bytes of the message text and set truncated=true if it was cut off. Previews do not mark the email as read."),
+			mcp.Min(1),
+		),
+		mcp.WithBoolean("include_small_attachments",
+			mcp.Description(fmt.Sprintf("Return the content of attachments up to %d KB inline as base64 in each attachment's content field, saving get_attachment calls. Larger attachments are listed by metadata only.", cfg.InlineAttachmentMax/1024)),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(getEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.GetEmailHandler(a.imapClient, cfg.DefaultFolder, cfg.InlineAttachmentMax, tools.AttachmentPolicy{
+			Allowed: cfg.AllowedAttachmentTypes,
+			Blocked: cfg.BlockedAttachmentTypes,
+		})
+	})
+
+	// Register triage_email tool
+	triageEmailTool := mcp.NewTool("triage_email",
+		mcp.WithDescription("Get a compact summary of one email for deciding what to do with it: from, subject, date, unread/answered/flagged status, size, attachment count, a short body preview, and whether it belongs to a thread. Cheaper than get_email and never marks the email as read."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID from search_emails results, or a sequence number with id_type=seq."),
+		),
+		mcp.WithString("id_type",
+			mcp.Description("How email_id is interpreted: uid (stable) or seq (message sequence number, which shifts when earlier messages are removed)."),
+			mcp.Enum("uid", "seq"),
+			mcp.DefaultString("uid"),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(triageEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.TriageEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register get_invite tool
+	getInviteTool := mcp.NewTool("get_invite",
+		mcp.WithDescription("Extract the calendar invitation (text/calendar VEVENT) from an email. Returns summary, start, end, organizer, and location. Errors if the email has no invitation."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID from search_emails results."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email. Use list_folders to discover valid names."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(getInviteTool, func(a *account) server.ToolHandlerFunc {
+		return tools.GetInviteHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register delivery_trace tool
+	deliveryTraceTool := mcp.NewTool("delivery_trace",
+		mcp.WithDescription("Trace how an email was delivered: parses its Received headers into hops ordered from the sender to the mailbox, each with from/by hosts, protocol, timestamp, and the delay since the previous hop. Use it to find where a late email was held up. Only headers are fetched; the email is not marked as read."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
 		mcp.WithString("email_id",
 			mcp.Required(),
 			mcp.MinLength(1),
@@ -141,14 +311,16 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder containing the email. Use list_folders to discover valid names."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 	)
-	s.AddTool(getEmailTool, tools.GetEmailHandler(imapClient))
+	addTool(deliveryTraceTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DeliveryTraceHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
 	// Register send_email tool
 	sendEmailTool := mcp.NewTool("send_email",
-		mcp.WithDescription("Compose and send a new email via SMTP. Returns success status and subject. Calling twice will send duplicate emails."),
+		mcp.WithDescription("Compose and send a new email via SMTP. Returns success status and subject. Calling twice sends a duplicate email unless both calls pass the same idempotency_key within an hour, in which case the second replays the first result without sending."),
 		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(false),
@@ -177,8 +349,48 @@ func main() {
 			mcp.Description("Set true if body contains HTML. A plain text version is auto-generated."),
 			mcp.DefaultBool(false),
 		),
+		mcp.WithBoolean("request_read_receipt",
+			mcp.Description("Request a read receipt (Disposition-Notification-To header). Recipients may ignore it."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("request_delivery_receipt",
+			mcp.Description("Request a delivery receipt (Return-Receipt-To header). Recipients may ignore it."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("priority",
+			mcp.Enum("high", "normal", "low"),
+			mcp.Description("Message priority. Sets X-Priority, Importance, and X-MSMail-Priority headers. Omit to send without priority headers."),
+		),
+		mcp.WithString("from",
+			mcp.Description("Send from this address instead of the account address. Must be listed in ALLOWED_FROM."),
+		),
+		mcp.WithString("idempotency_key",
+			mcp.Description("Optional client-chosen key. Repeating a key within an hour returns the first result without sending again."),
+		),
+		mcp.WithBoolean("auto_bcc",
+			mcp.Description("Blind-copy the addresses configured in AUTO_BCC. Set false to skip them for this message."),
+			mcp.DefaultBool(true),
+		),
+	)
+	addTool(sendEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.SendEmailHandler(a.smtpClient, a.Email, a.AllowedFrom)
+	})
+
+	// Register preview_plaintext tool
+	previewPlaintextTool := mcp.NewTool("preview_plaintext",
+		mcp.WithDescription("Render an HTML body to the plain-text alternative that send_email would generate for it, to check the fallback is readable before sending. Nothing is sent."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("html",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("HTML email body to render."),
+		),
 	)
-	s.AddTool(sendEmailTool, tools.SendEmailHandler(smtpClient, cfg.ICloudEmail))
+	addTool(previewPlaintextTool, func(*account) server.ToolHandlerFunc {
+		return tools.PreviewPlaintextHandler()
+	})
 
 	// Register reply_email tool
 	replyEmailTool := mcp.NewTool("reply_email",
@@ -198,18 +410,120 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder containing the original email."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithBoolean("reply_all",
-			mcp.Description("Reply to all original recipients (To + CC) instead of just the sender."),
-			mcp.DefaultBool(false),
+			mcp.Description("Reply to all original recipients (To + CC) instead of just the sender. Your own addresses are never included."),
+			mcp.DefaultBool(cfg.ReplyAllDefault),
 		),
 		mcp.WithBoolean("html",
 			mcp.Description("Set true if body contains HTML."),
 			mcp.DefaultBool(false),
 		),
+		mcp.WithBoolean("quote_original",
+			mcp.Description("Append an attribution line (REPLY_ATTRIBUTION) and the quoted original message below the reply."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("auto_bcc",
+			mcp.Description("Blind-copy the addresses configured in AUTO_BCC. Set false to skip them for this message."),
+			mcp.DefaultBool(true),
+		),
+	)
+	addTool(replyEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ReplyEmailHandler(a.imapClient, a.smtpClient, cfg.DefaultFolder, cfg.ReplyAllDefault)
+	})
+
+	// Register preview_reply tool
+	previewReplyTool := mcp.NewTool("preview_reply",
+		mcp.WithDescription("Show the To, CC, subject, and In-Reply-To/References headers that reply_email would use for an email, without sending anything."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID of the message being replied to (from search_emails or get_email)."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the original email."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithBoolean("reply_all",
+			mcp.Description("Preview a reply to all original recipients (To + CC) instead of just the sender."),
+			mcp.DefaultBool(cfg.ReplyAllDefault),
+		),
+	)
+	addTool(previewReplyTool, func(a *account) server.ToolHandlerFunc {
+		return tools.PreviewReplyHandler(a.imapClient, a.smtpClient, cfg.DefaultFolder, cfg.ReplyAllDefault)
+	})
+
+	// Register resend tool
+	resendTool := mcp.NewTool("resend",
+		mcp.WithDescription("Send a stored message again, e.g. one in Sent that bounced or one in a failures folder. Rebuilds recipients, subject, body, and thread headers (In-Reply-To/References) from the stored message; any of them can be overridden. Attachments are not resent. Calling twice sends duplicate emails."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID of the stored message to send again."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the stored message."),
+			mcp.DefaultString("sent"),
+		),
+		mcp.WithString("to",
+			mcp.Description("Replace the original To recipients. Email address (string) or JSON array of addresses."),
+		),
+		mcp.WithString("cc",
+			mcp.Description("Replace the original CC recipients. An empty array removes them."),
+		),
+		mcp.WithString("bcc",
+			mcp.Description("Replace the original BCC recipients. An empty array removes them."),
+		),
+		mcp.WithString("subject",
+			mcp.Description("Replace the original subject."),
+		),
+		mcp.WithString("body",
+			mcp.Description("Replace the original body. Plain text unless html=true."),
+		),
+		mcp.WithBoolean("html",
+			mcp.Description("Set true if body contains HTML. Defaults to the original message's format, or plain text when body is replaced."),
+		),
+		mcp.WithString("from",
+			mcp.Description("Send from this address instead of the original sender. Must be the account address or listed in ALLOWED_FROM."),
+		),
+	)
+	addTool(resendTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ResendHandler(a.imapClient, a.smtpClient, a.Email, a.AllowedFrom)
+	})
+
+	// Register verify_recipient tool
+	verifyRecipientTool := mcp.NewTool("verify_recipient",
+		mcp.WithDescription("Best-effort check that an address can receive mail. Looks up the recipient's MX and issues MAIL FROM / RCPT TO without sending a message. Many servers accept every recipient (catch-all), so acceptance is not proof of delivery."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email address to verify."),
+		),
+	)
+	addTool(verifyRecipientTool, func(a *account) server.ToolHandlerFunc {
+		return tools.VerifyRecipientHandler(a.smtpClient)
+	})
+
+	// Register check_credentials tool
+	checkCredentialsTool := mcp.NewTool("check_credentials",
+		mcp.WithDescription("Check whether the iCloud credentials are currently accepted by performing a fresh IMAP login on a separate connection. Reports valid, auth_failed (e.g. a revoked app-specific password), or network_error (server unreachable, so the credentials could not be checked)."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
 	)
-	s.AddTool(replyEmailTool, tools.ReplyEmailHandler(imapClient, smtpClient))
+	addTool(checkCredentialsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.CheckCredentialsHandler(a.imapClient)
+	})
 
 	// Register delete_email tool
 	deleteEmailTool := mcp.NewTool("delete_email",
@@ -224,14 +538,34 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder containing the email."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithBoolean("permanent",
-			mcp.Description("Permanently expunge the email instead of moving to trash. This cannot be undone."),
+			mcp.Description("Permanently expunge the email instead of moving to trash. This cannot be undone. The first such call only returns a confirm token; repeat it with the token to delete."),
 			mcp.DefaultBool(false),
 		),
+		mcp.WithString("confirm",
+			mcp.Description("Confirmation token returned by a first permanent=true call for this email. Valid once, for 5 minutes."),
+		),
+	)
+	addTool(deleteEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DeleteEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register purge_deleted tool
+	purgeDeletedTool := mcp.NewTool("purge_deleted",
+		mcp.WithDescription("Permanently remove the emails in a folder that are already marked \\Deleted but were never expunged, e.g. by another mail client. Emails without the flag are not touched. This cannot be undone, and protected folders such as INBOX are refused."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to purge."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
 	)
-	s.AddTool(deleteEmailTool, tools.DeleteEmailHandler(imapClient))
+	addTool(purgeDeletedTool, func(a *account) server.ToolHandlerFunc {
+		return tools.PurgeDeletedHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
 	// Register move_email tool
 	moveEmailTool := mcp.NewTool("move_email",
@@ -246,143 +580,579 @@ func main() {
 		),
 		mcp.WithString("from_folder",
 			mcp.Description("Source mailbox folder."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithString("to_folder",
 			mcp.Required(),
 			mcp.MinLength(1),
 			mcp.Description("Destination mailbox folder (from list_folders)."),
 		),
+		mcp.WithBoolean("skip_if_duplicate",
+			mcp.Description("Skip the move if a message with the same Message-ID already exists in the destination. Useful when re-running archiving workflows."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("preserve_flags",
+			mcp.Description("Re-apply the email's read, answered, and flagged state in the destination after the move, for servers that drop flags when moving."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("return_email",
+			mcp.Description("Include the moved email, fetched from the destination, in the response. The email gets a new ID there, returned as new_email_id."),
+			mcp.DefaultBool(false),
+		),
 	)
-	s.AddTool(moveEmailTool, tools.MoveEmailHandler(imapClient))
-
-	// Register list_folders tool
-	listFoldersTool := mcp.NewTool("list_folders",
-		mcp.WithDescription("List all available mailbox folders. Returns folder names that can be used as the 'folder' parameter in other tools. Call this first to discover valid folder names."),
-		mcp.WithReadOnlyHintAnnotation(true),
-		mcp.WithDestructiveHintAnnotation(false),
-		mcp.WithIdempotentHintAnnotation(true),
-	)
-	s.AddTool(listFoldersTool, tools.ListFoldersHandler(imapClient))
+	addTool(moveEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.MoveEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
-	// Register create_folder tool
-	createFolderTool := mcp.NewTool("create_folder",
-		mcp.WithDescription("Create a new mailbox folder. Optionally nest under a parent folder. Calling twice with the same name may fail if the folder already exists."),
+	// Register file_email tool
+	fileEmailTool := mcp.NewTool("file_email",
+		mcp.WithDescription("File an email into a folder, creating the folder first if it does not exist, e.g. \"create 'Project X' and move this email there\" in one step. Reports whether the folder was created."),
 		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(false),
-		mcp.WithString("name",
+		mcp.WithString("email_id",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Name of the new folder."),
-		),
-		mcp.WithString("parent",
-			mcp.Description("Parent folder path for nesting (from list_folders). Omit for top-level folder."),
+			mcp.Description("Email UID to file (from search_emails)."),
 		),
-	)
-	s.AddTool(createFolderTool, tools.CreateFolderHandler(imapClient))
-
-	// Register delete_folder tool
-	deleteFolderTool := mcp.NewTool("delete_folder",
-		mcp.WithDescription("Delete a mailbox folder. Refuses if the folder contains emails unless force=true. Use list_folders to discover valid names."),
-		mcp.WithReadOnlyHintAnnotation(false),
-		mcp.WithDestructiveHintAnnotation(true),
-		mcp.WithIdempotentHintAnnotation(false),
-		mcp.WithString("name",
+		mcp.WithString("folder",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Folder name to delete (from list_folders)."),
+			mcp.Description("Destination folder name; created at the top level if missing."),
 		),
-		mcp.WithBoolean("force",
-			mcp.Description("Delete even if the folder contains emails. All contained emails will be lost."),
-			mcp.DefaultBool(false),
+		mcp.WithString("from_folder",
+			mcp.Description("Folder the email is currently in."),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 	)
-	s.AddTool(deleteFolderTool, tools.DeleteFolderHandler(imapClient))
+	addTool(fileEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FileEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
-	// Register mark_read tool
-	markReadTool := mcp.NewTool("mark_read",
-		mcp.WithDescription("Mark an email as read (seen) or unread (unseen). Use search_emails to find email IDs."),
+	// Register mark_for_review tool
+	markForReviewTool := mcp.NewTool("mark_for_review",
+		mcp.WithDescription("Set an email aside to review later: flag it for follow-up and move it to a review folder, creating the folder if it does not exist. The flag is set first, so if the move fails the email is still flagged and the error says so. The moved email gets a new email ID."),
 		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(false),
-		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(false),
 		mcp.WithString("email_id",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Email UID to mark (from search_emails)."),
+			mcp.Description("Email UID to set aside (from search_emails)."),
 		),
 		mcp.WithString("folder",
-			mcp.Description("Mailbox folder containing the email."),
-			mcp.DefaultString("INBOX"),
+			mcp.Description("Folder the email is currently in."),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
-		mcp.WithBoolean("read",
-			mcp.Description("true to mark as read, false to mark as unread."),
-			mcp.DefaultBool(true),
+		mcp.WithString("review_folder",
+			mcp.Description("Folder to move the email to; created at the top level if missing."),
+			mcp.DefaultString("Review"),
 		),
 	)
-	s.AddTool(markReadTool, tools.MarkReadHandler(imapClient))
+	addTool(markForReviewTool, func(a *account) server.ToolHandlerFunc {
+		return tools.MarkForReviewHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
-	// Register count_emails tool
-	countEmailsTool := mcp.NewTool("count_emails",
-		mcp.WithDescription("Count emails matching filters without fetching content. Lightweight alternative to search_emails when you only need a count."),
-		mcp.WithReadOnlyHintAnnotation(true),
+	// Register file_and_read tool
+	fileAndReadTool := mcp.NewTool("file_and_read",
+		mcp.WithDescription("Mark an email read and move it to another folder in one call, so it arrives there already read. The email is marked first, so if the move fails it is still read in its original folder and the error says so. The moved email gets a new email ID."),
+		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(true),
-		mcp.WithString("folder",
-			mcp.Description("Mailbox folder to count in."),
-			mcp.DefaultString("INBOX"),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID to file (from search_emails)."),
 		),
-		mcp.WithNumber("last_days",
-			mcp.Description("Only count emails from the last N days."),
-			mcp.Min(1),
+		mcp.WithString("from_folder",
+			mcp.Description("Folder the email is currently in."),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
-		mcp.WithBoolean("unread_only",
-			mcp.Description("Only count unread (unseen) emails."),
-			mcp.DefaultBool(false),
+		mcp.WithString("to_folder",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Destination mailbox folder (from list_folders)."),
 		),
 	)
-	s.AddTool(countEmailsTool, tools.CountEmailsHandler(imapClient))
+	addTool(fileAndReadTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FileAndReadHandler(a.imapClient, cfg.DefaultFolder)
+	})
 
-	// Register draft_email tool
-	draftEmailTool := mcp.NewTool("draft_email",
-		mcp.WithDescription("Save an email as a draft in the Drafts folder for later review and sending. Returns a draft_id. Calling twice creates duplicate drafts."),
+	// Register snooze_email tool
+	snoozeEmailTool := mcp.NewTool("snooze_email",
+		mcp.WithDescription("Snooze an email: hide it in a Snoozed/<date> folder until a given time, stamped with an X-Snooze-Until header. The email comes back to INBOX, unread, the next time flush_snoozed runs after that time. The message is re-appended, so it gets a new email ID."),
 		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(false),
-		mcp.WithString("to",
+		mcp.WithString("email_id",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Recipient email address (string) or JSON array of addresses."),
+			mcp.Description("Email UID to snooze (from search_emails)."),
 		),
-		mcp.WithString("subject",
+		mcp.WithString("until",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Email subject line."),
+			mcp.Description("When the email should come back: an RFC 3339 timestamp like '2024-01-20T09:00:00Z', or a date like '2024-01-20' (start of that day, server time). Must be in the future."),
 		),
-		mcp.WithString("body",
+		mcp.WithString("folder",
+			mcp.Description("Folder the email is currently in."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(snoozeEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.SnoozeEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register flush_snoozed tool
+	flushSnoozedTool := mcp.NewTool("flush_snoozed",
+		mcp.WithDescription("Move every snoozed email whose X-Snooze-Until time has passed back to INBOX. Run it periodically, e.g. at the start of a session. Returns how many came back and how many are still snoozed."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	addTool(flushSnoozedTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FlushSnoozedHandler(a.imapClient)
+	})
+
+	// Register move_by_sender tool
+	moveBySenderTool := mcp.NewTool("move_by_sender",
+		mcp.WithDescription("Move every email from one sender address into a folder in a single bulk move, e.g. filing all newsletters into Shopping. Only exact address matches are moved. Returns the number moved, or a 'nothing to move' message when there are no matches."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("sender",
 			mcp.Required(),
 			mcp.MinLength(1),
-			mcp.Description("Email body content. Plain text by default; set html=true for HTML."),
+			mcp.Description("Sender email address to match, e.g. newsletters@store.com."),
 		),
-		mcp.WithString("cc",
-			mcp.Description("CC email address (string) or JSON array of addresses."),
+		mcp.WithString("to_folder",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Destination folder name."),
 		),
-		mcp.WithString("bcc",
-			mcp.Description("BCC email address (string) or JSON array of addresses."),
+		mcp.WithString("from_folder",
+			mcp.Description("Folder to search for the sender's emails."),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
-		mcp.WithBoolean("html",
-			mcp.Description("Set true if body contains HTML."),
+		mcp.WithBoolean("create_folder",
+			mcp.Description("Create to_folder first if it does not exist."),
 			mcp.DefaultBool(false),
 		),
-		mcp.WithString("reply_to_id",
-			mcp.Description("Email UID of the original message if creating a reply draft. Sets In-Reply-To and References headers."),
+	)
+	addTool(moveBySenderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.MoveBySenderHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register block_sender tool
+	blockSenderTool := mcp.NewTool("block_sender",
+		mcp.WithDescription("Move an email to Junk and add its sender to a blocked senders list kept as a note in the 'Blocked Senders' folder (iCloud rules aren't reachable over IMAP). Returns the updated block list. Blocking an already-blocked sender leaves the list unchanged."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID from search_emails results."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(blockSenderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.BlockSenderHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register list_folders tool
+	listFoldersTool := mcp.NewTool("list_folders",
+		mcp.WithDescription("List all available mailbox folders. Returns folder names that can be used as the 'folder' parameter in other tools. Call this first to discover valid folder names."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	addTool(listFoldersTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ListFoldersHandler(a.imapClient, cfg.FolderFilter)
+	})
+
+	// Register folder_flags tool
+	folderFlagsTool := mcp.NewTool("folder_flags",
+		mcp.WithDescription("Show the flags a folder defines and which ones the server stores permanently (PERMANENTFLAGS). Check custom_keywords_allowed before setting custom keywords."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to inspect."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(folderFlagsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FolderFlagsHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register folder_status tool
+	folderStatusTool := mcp.NewTool("folder_status",
+		mcp.WithDescription("Show a folder's message count, next UID, and UIDVALIDITY. Email IDs are UIDs, which stay valid only while UIDVALIDITY is unchanged; if it changes, discard any cached IDs for the folder."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to inspect."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(folderStatusTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FolderStatusHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register get_namespace tool
+	getNamespaceTool := mcp.NewTool("get_namespace",
+		mcp.WithDescription("Get the server's personal namespace prefix (e.g. \"\" on iCloud, \"INBOX.\" on some servers) and hierarchy delimiter. Use them to build nested folder paths: prefix + parent + delimiter + child."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	addTool(getNamespaceTool, func(a *account) server.ToolHandlerFunc {
+		return tools.GetNamespaceHandler(a.imapClient)
+	})
+
+	// Register create_folder tool
+	createFolderTool := mcp.NewTool("create_folder",
+		mcp.WithDescription("Create a new mailbox folder. Optionally nest under a parent folder. Calling twice with the same name may fail if the folder already exists."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Name of the new folder."),
+		),
+		mcp.WithString("parent",
+			mcp.Description("Parent folder path for nesting (from list_folders). Omit for top-level folder."),
+		),
+		mcp.WithBoolean("subscribe",
+			mcp.Description("Subscribe to the new folder so mail clients that only show subscribed folders list it."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithString("use",
+			mcp.Enum(imap.SpecialUses()...),
+			mcp.Description("Special role for the folder, tagged with its SPECIAL-USE attribute (e.g. \\Archive) so mail clients and the folder aliases find it. Needs server support for CREATE-SPECIAL-USE; otherwise the folder is created without the tag."),
+		),
+	)
+	addTool(createFolderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.CreateFolderHandler(a.imapClient)
+	})
+
+	// Register subscribe_folder tool
+	subscribeFolderTool := mcp.NewTool("subscribe_folder",
+		mcp.WithDescription("Subscribe to a folder (IMAP SUBSCRIBE). Some mail clients only show subscribed folders."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Folder name to subscribe to (from list_folders)."),
+		),
+	)
+	addTool(subscribeFolderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.SubscribeFolderHandler(a.imapClient)
+	})
+
+	// Register unsubscribe_folder tool
+	unsubscribeFolderTool := mcp.NewTool("unsubscribe_folder",
+		mcp.WithDescription("Unsubscribe from a folder (IMAP UNSUBSCRIBE). Hides it in clients that only show subscribed folders; the folder and its emails are kept."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Folder name to unsubscribe from (from list_folders)."),
+		),
+	)
+	addTool(unsubscribeFolderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.UnsubscribeFolderHandler(a.imapClient)
+	})
+
+	// Register delete_folder tool
+	deleteFolderTool := mcp.NewTool("delete_folder",
+		mcp.WithDescription("Delete a mailbox folder. Refuses if the folder contains emails unless force=true, and always refuses protected folders such as INBOX and Sent Messages. Use list_folders to discover valid names."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Folder name to delete (from list_folders)."),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Delete even if the folder contains emails. All contained emails will be lost. The first such call only returns a confirm token; repeat it with the token to delete."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("confirm",
+			mcp.Description("Confirmation token returned by a first force=true call for this folder. Valid once, for 5 minutes."),
+		),
+	)
+	addTool(deleteFolderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DeleteFolderHandler(a.imapClient)
+	})
+
+	// Register mark_read tool
+	markReadTool := mcp.NewTool("mark_read",
+		mcp.WithDescription("Mark an email as read (seen) or unread (unseen). Use search_emails to find email IDs."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID to mark (from search_emails)."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithBoolean("read",
+			mcp.Description("true to mark as read, false to mark as unread."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithBoolean("return_email",
+			mcp.Description("Include the updated email in the response, saving a separate get_email call."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(markReadTool, func(a *account) server.ToolHandlerFunc {
+		return tools.MarkReadHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register fetch_unread tool
+	fetchUnreadTool := mcp.NewTool("fetch_unread",
+		mcp.WithDescription("Fetch the unread emails in a folder with their bodies, optionally marking them all read once retrieved. Use for an inbox sweep; only the returned emails are marked."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to sweep."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of unread emails to fetch (max 50)."),
+			mcp.DefaultNumber(20),
+			mcp.Min(1),
+			mcp.Max(50),
+		),
+		mcp.WithBoolean("mark_read_after",
+			mcp.Description("Mark the fetched emails as read in one command after all were retrieved successfully."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(fetchUnreadTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FetchUnreadHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register count_emails tool
+	countEmailsTool := mcp.NewTool("count_emails",
+		mcp.WithDescription("Count emails matching filters without fetching content. Lightweight alternative to search_emails when you only need a count."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to count in."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("last_days",
+			mcp.Description("Only count emails from the last N days."),
+			mcp.Min(1),
+		),
+		mcp.WithBoolean("unread_only",
+			mcp.Description("Only count unread (unseen) emails."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(countEmailsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.CountEmailsHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register count_by_sender tool
+	countBySenderTool := mcp.NewTool("count_by_sender",
+		mcp.WithDescription("Tally received emails in a folder by sender address and return the busiest senders first. Useful for finding who clutters the inbox."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to analyze."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("last_days",
+			mcp.Description("Only count emails from the last N days."),
+			mcp.Min(1),
+			mcp.DefaultNumber(30),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of senders to return."),
+			mcp.Min(1),
+			mcp.DefaultNumber(10),
+		),
+	)
+	addTool(countBySenderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.CountBySenderHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register count_by_day tool
+	countByDayTool := mcp.NewTool("count_by_day",
+		mcp.WithDescription("Count the emails in a folder per day over a window, for charting how much mail arrives. Returns a map of YYYY-MM-DD to count with every day of the window present, zero for days without mail."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to analyze."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("last_days",
+			mcp.Description("Count emails from the last N days."),
+			mcp.Min(1),
+			mcp.DefaultNumber(30),
+		),
+	)
+	addTool(countByDayTool, func(a *account) server.ToolHandlerFunc {
+		return tools.CountByDayHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register find_duplicates tool
+	findDuplicatesTool := mcp.NewTool("find_duplicates",
+		mcp.WithDescription("Find duplicate emails in a folder, such as copies left behind by a migration, grouped by Message-ID. Each group lists its email IDs oldest first. Nothing is changed; use remove_duplicates to move the extra copies to trash."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to check."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithBoolean("match_headers",
+			mcp.Description("Also group emails without a Message-ID whose subject, date, and sender match."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(findDuplicatesTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FindDuplicatesHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register remove_duplicates tool
+	removeDuplicatesTool := mcp.NewTool("remove_duplicates",
+		mcp.WithDescription("Move all but the oldest copy of each find_duplicates group to trash. The first call only lists the groups and returns a confirm token; repeat it with the token to move them. Refused if the folder could not be scanned completely, and for protected folders such as INBOX."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to dedupe."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithBoolean("match_headers",
+			mcp.Description("Also group emails without a Message-ID whose subject, date, and sender match."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("confirm",
+			mcp.Description("Confirmation token returned by a first call for this folder. Valid once, for 5 minutes, and only while the folder holds the same duplicates."),
+		),
+	)
+	addTool(removeDuplicatesTool, func(a *account) server.ToolHandlerFunc {
+		return tools.RemoveDuplicatesHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register recent_senders tool
+	recentSendersTool := mcp.NewTool("recent_senders",
+		mcp.WithDescription("List the addresses you recently sent mail to, most recently contacted first, with the last-contacted date. Scans the Sent folder; useful for completing recipient addresses."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithNumber("last_days",
+			mcp.Description("Only scan mail sent in the last N days."),
+			mcp.Min(1),
+			mcp.DefaultNumber(90),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of addresses to return."),
+			mcp.Min(1),
+			mcp.DefaultNumber(20),
+		),
+	)
+	addTool(recentSendersTool, func(a *account) server.ToolHandlerFunc {
+		return tools.RecentSendersHandler(a.imapClient)
+	})
+
+	// Register draft_email tool
+	draftEmailTool := mcp.NewTool("draft_email",
+		mcp.WithDescription("Save an email as a draft in the Drafts folder for later review and sending. Returns a draft_id. Calling twice creates duplicate drafts."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("to",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Recipient email address (string) or JSON array of addresses."),
+		),
+		mcp.WithString("subject",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email subject line."),
+		),
+		mcp.WithString("body",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email body content. Plain text by default; set html=true for HTML."),
+		),
+		mcp.WithString("cc",
+			mcp.Description("CC email address (string) or JSON array of addresses."),
+		),
+		mcp.WithString("bcc",
+			mcp.Description("BCC email address (string) or JSON array of addresses."),
+		),
+		mcp.WithBoolean("html",
+			mcp.Description("Set true if body contains HTML."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("priority",
+			mcp.Enum("high", "normal", "low"),
+			mcp.Description("Message priority. Sets X-Priority, Importance, and X-MSMail-Priority headers. Omit to send without priority headers."),
+		),
+		mcp.WithString("reply_to_id",
+			mcp.Description("Email UID of the original message if creating a reply draft. Sets In-Reply-To and References headers."),
 		),
 		mcp.WithString("folder",
 			mcp.Description("Folder containing the original email for reply drafts."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 	)
-	s.AddTool(draftEmailTool, tools.DraftEmailHandler(imapClient, cfg.ICloudEmail))
+	addTool(draftEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DraftEmailHandler(a.imapClient, a.Email, cfg.DefaultFolder)
+	})
+
+	// Register list_drafts tool
+	listDraftsTool := mcp.NewTool("list_drafts",
+		mcp.WithDescription("List saved drafts in the Drafts folder with their UIDs, subjects, recipients, and dates. Use delete_draft to discard one."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	addTool(listDraftsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ListDraftsHandler(a.imapClient)
+	})
+
+	// Register delete_draft tool
+	deleteDraftTool := mcp.NewTool("delete_draft",
+		mcp.WithDescription("Permanently delete a draft from the Drafts folder. Cannot be undone. Use list_drafts to find draft UIDs."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Draft UID from list_drafts results."),
+		),
+	)
+	addTool(deleteDraftTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DeleteDraftHandler(a.imapClient)
+	})
 
 	// Register get_attachment tool
 	getAttachmentTool := mcp.NewTool("get_attachment",
@@ -402,13 +1172,139 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder containing the email."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithString("save_path",
 			mcp.Description("Absolute file path to save the attachment to disk. Must not contain '..'. If omitted, returns base64-encoded content in the response."),
 		),
+		mcp.WithBoolean("compress",
+			mcp.Description("Gzip the content before base64-encoding it; the response's encoding is then 'gzip+base64'. Saves tokens on text, CSV, and other compressible files. Ignored with save_path."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(getAttachmentTool, func(a *account) server.ToolHandlerFunc {
+		return tools.GetAttachmentHandler(a.imapClient, cfg.DefaultFolder, tools.AttachmentPolicy{
+			Allowed: cfg.AllowedAttachmentTypes,
+			Blocked: cfg.BlockedAttachmentTypes,
+		})
+	})
+
+	// Register find_large_attachments tool
+	findLargeAttachmentsTool := mcp.NewTool("find_large_attachments",
+		mcp.WithDescription("Find emails with large attachments to reclaim storage. Searches the given folders for messages over a size threshold and returns each one's largest attachment (name and size), biggest first. Only metadata is fetched."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithArray("folders",
+			mcp.Description("Folders to search (default: DEFAULT_FOLDER)."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("min_size_mb",
+			mcp.Description("Only consider messages larger than this many megabytes."),
+			mcp.DefaultNumber(5),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of emails to return."),
+			mcp.Min(1),
+			mcp.DefaultNumber(20),
+		),
 	)
-	s.AddTool(getAttachmentTool, tools.GetAttachmentHandler(imapClient))
+	addTool(findLargeAttachmentsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FindLargeAttachmentsHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register mailbox_stats tool
+	mailboxStatsTool := mcp.NewTool("mailbox_stats",
+		mcp.WithDescription("Summarize the mailbox across folders: total and unread message counts and the oldest and newest message dates per folder, plus totals. Set include_size to also sum message sizes, which reads every message's size and is slower on large folders."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithArray("folders",
+			mcp.Description("Folders to summarize (default: all folders)."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("include_size",
+			mcp.Description("Also report the approximate total size in bytes (sum of message sizes)."),
+			mcp.DefaultBool(false),
+		),
+	)
+	addTool(mailboxStatsTool, func(a *account) server.ToolHandlerFunc {
+		return tools.MailboxStatsHandler(a.imapClient)
+	})
+
+	// Register export_folder tool
+	exportFolderTool := mcp.NewTool("export_folder",
+		mcp.WithDescription("Back up every message in a folder to disk, either as a single mbox file or as a zip of .eml files. Returns the number of messages and their total size. Overwrites any existing file at save_path."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("save_path",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Absolute file path to write the export to. Must not contain '..' and its directory must already exist."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to export."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithString("format",
+			mcp.Description("Export format: 'mbox' for a single mbox file, 'zip' for one .eml file per message."),
+			mcp.Enum("mbox", "zip"),
+			mcp.DefaultString("mbox"),
+		),
+	)
+	addTool(exportFolderTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ExportFolderHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register export_email tool
+	exportEmailTool := mcp.NewTool("export_email",
+		mcp.WithDescription("Archive one email to a zip on disk: its full source as <email_id>.eml plus each attachment as its own file. Returns the path and the zip's entries. Overwrites any existing file at save_path. Does not mark the email read."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID to export (from search_emails)."),
+		),
+		mcp.WithString("save_path",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Absolute path of the zip file to write. Must not contain '..' and its directory must already exist."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+	)
+	addTool(exportEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ExportEmailHandler(a.imapClient, cfg.DefaultFolder, tools.AttachmentPolicy{
+			Allowed: cfg.AllowedAttachmentTypes,
+			Blocked: cfg.BlockedAttachmentTypes,
+		})
+	})
+
+	// Register import_mbox tool
+	importMboxTool := mcp.NewTool("import_mbox",
+		mcp.WithDescription("Import every message from an mbox file (such as one written by export_folder) into a folder. Each message keeps its Date header as its received date. Importing the same file twice creates duplicates."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithString("mbox_path",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Absolute path of the mbox file to read. Must not contain '..'."),
+		),
+		mcp.WithString("folder",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Existing folder to import the messages into."),
+		),
+	)
+	addTool(importMboxTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ImportMboxHandler(a.imapClient)
+	})
 
 	// Register flag_email tool
 	flagEmailTool := mcp.NewTool("flag_email",
@@ -428,19 +1324,214 @@ func main() {
 		),
 		mcp.WithString("folder",
 			mcp.Description("Mailbox folder containing the email."),
-			mcp.DefaultString("INBOX"),
+			mcp.DefaultString(cfg.DefaultFolder),
 		),
 		mcp.WithString("color",
 			mcp.Enum("red", "orange", "yellow", "green", "blue", "purple"),
 			mcp.Description("Optional flag color. Only applies when flag is not 'none'."),
 		),
+		mcp.WithBoolean("return_email",
+			mcp.Description("Include the updated email in the response, saving a separate get_email call."),
+			mcp.DefaultBool(false),
+		),
 	)
-	s.AddTool(flagEmailTool, tools.FlagEmailHandler(imapClient))
+	addTool(flagEmailTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FlagEmailHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register flag_search tool
+	flagSearchTool := mcp.NewTool("flag_search",
+		mcp.WithDescription("Flag every email matching a search at once, e.g. all mail from one project, with a single bulk store. Takes the same filters as search_emails. Use 'none' to clear flags instead. Returns the number flagged and their IDs."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("flag",
+			mcp.Required(),
+			mcp.Enum("follow-up", "important", "deadline", "none"),
+			mcp.Description("Flag type to set. Use 'none' to remove all flags."),
+		),
+		mcp.WithString("color",
+			mcp.Enum("red", "orange", "yellow", "green", "blue", "purple"),
+			mcp.Description("Optional flag color. Only applies when flag is not 'none'."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to search and flag in."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithString("query",
+			mcp.Description("Search term to find in subject and body text"),
+		),
+		mcp.WithString("from",
+			mcp.Description("Only flag emails whose sender contains this text, e.g. an address or domain."),
+		),
+		mcp.WithNumber("last_days",
+			mcp.Description("Only flag emails from the last N days. Ignored if 'since' is provided."),
+			mcp.DefaultNumber(30),
+			mcp.Min(1),
+		),
+		mcp.WithString("since",
+			mcp.Description("Start date filter, in the formats search_emails accepts. Overrides last_days."),
+		),
+		mcp.WithString("before",
+			mcp.Description("End date filter (exclusive), in the formats search_emails accepts."),
+		),
+		mcp.WithBoolean("unread_only",
+			mcp.Description("Only flag unread emails."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("raw_query",
+			mcp.Description("IMAP SEARCH keys used as-is, replacing query, from, last_days, since, before, and unread_only."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of emails to flag, most recent first."),
+			mcp.DefaultNumber(200),
+			mcp.Min(1),
+			mcp.Max(1000),
+		),
+	)
+	addTool(flagSearchTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FlagSearchHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register list_flagged tool
+	listFlaggedTool := mcp.NewTool("list_flagged",
+		mcp.WithDescription("List the emails in a folder carrying a flag set by flag_email, by flag type, color, or both. With neither, lists every flagged email. Not limited to recent mail."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("flag",
+			mcp.Enum("follow-up", "important", "deadline"),
+			mcp.Description("Only list emails with this flag type."),
+		),
+		mcp.WithString("color",
+			mcp.Enum("red", "orange", "yellow", "green", "blue", "purple"),
+			mcp.Description("Only list emails with this flag color."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder to search."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of emails to return, most recent first."),
+			mcp.Min(1),
+			mcp.Max(200),
+			mcp.DefaultNumber(50),
+		),
+	)
+	addTool(listFlaggedTool, func(a *account) server.ToolHandlerFunc {
+		return tools.ListFlaggedHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register sent_to tool
+	sentToTool := mcp.NewTool("sent_to",
+		mcp.WithDescription("Search the Sent folder for messages sent to an address (in To or CC), e.g. to check whether you already replied to someone."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.Description("Recipient email address to look for"),
+		),
+		mcp.WithNumber("last_days",
+			mcp.Description("Only search messages sent in the last N days."),
+			mcp.Min(1),
+			mcp.DefaultNumber(30),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of emails to return, most recent first."),
+			mcp.Min(1),
+			mcp.Max(200),
+			mcp.DefaultNumber(50),
+		),
+	)
+	addTool(sentToTool, func(a *account) server.ToolHandlerFunc {
+		return tools.SentToHandler(a.imapClient)
+	})
+
+	// Register find_related tool
+	findRelatedTool := mcp.NewTool("find_related",
+		mcp.WithDescription("Find the other emails in a folder on the same conversation as a given email, matched by subject with Re:/Fwd: prefixes stripped, within a window of days around it. Works when References and In-Reply-To headers are missing, which header-based threading needs."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("email_id",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Email UID to find related emails for (from search_emails)."),
+		),
+		mcp.WithString("folder",
+			mcp.Description("Mailbox folder containing the email; related emails are searched for there."),
+			mcp.DefaultString(cfg.DefaultFolder),
+		),
+		mcp.WithNumber("days",
+			mcp.Description("Search this many days before and after the email's date."),
+			mcp.Min(1),
+			mcp.DefaultNumber(30),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of related emails to return, most recent first."),
+			mcp.Min(1),
+			mcp.Max(200),
+			mcp.DefaultNumber(50),
+		),
+	)
+	addTool(findRelatedTool, func(a *account) server.ToolHandlerFunc {
+		return tools.FindRelatedHandler(a.imapClient, cfg.DefaultFolder)
+	})
+
+	// Register diff_folders tool
+	diffFoldersTool := mcp.NewTool("diff_folders",
+		mcp.WithDescription("Compare two folders by Message-ID and report which messages are only in folder_a, only in folder_b, and in both. Use it to verify that a migration or manual move completed."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("folder_a",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("First folder, e.g. the source of a move. Use list_folders to discover valid names."),
+		),
+		mcp.WithString("folder_b",
+			mcp.Required(),
+			mcp.MinLength(1),
+			mcp.Description("Second folder, e.g. the destination of a move."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum Message-IDs listed in each of only_in_a, only_in_b, and in_both. The counts always cover every message."),
+			mcp.Min(1),
+			mcp.Max(1000),
+			mcp.DefaultNumber(100),
+		),
+	)
+	addTool(diffFoldersTool, func(a *account) server.ToolHandlerFunc {
+		return tools.DiffFoldersHandler(a.imapClient)
+	})
+
+	// Register describe_tools last; it reads the server's tool registry at
+	// call time, so it always reflects every tool above
+	describeToolsTool := mcp.NewTool("describe_tools",
+		mcp.WithDescription("Describe every tool this server offers in one stable JSON document: each parameter's type, whether it is required, its default (e.g. the configured default folder), limits such as maximum counts, and allowed enum values, plus read-only/destructive/idempotent hints. Use it to plan calls without trial and error."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithString("tool",
+			mcp.Description("Describe only this tool, e.g. search_emails. Omit to describe all tools."),
+		),
+	)
+	addTool(describeToolsTool, func(*account) server.ToolHandlerFunc {
+		return tools.DescribeToolsHandler(func() []mcp.Tool {
+			registered := s.ListTools()
+			defs := make([]mcp.Tool, 0, len(registered))
+			for _, t := range registered {
+				defs = append(defs, t.Tool)
+			}
+			return defs
+		})
+	})
 
 	// Log startup
 	slog.Info("server starting",
 		"version", version,
-		"email", cfg.ICloudEmail,
+		"accounts", accountIDs(accounts),
 		"imap_server", fmt.Sprintf("imap.mail.me.com:%d", 993),
 		"smtp_server", fmt.Sprintf("smtp.mail.me.com:%d", 587),
 	)
@@ -455,10 +1546,150 @@ func main() {
 	slog.Info("server stopped")
 }
 
-// timeoutMiddleware wraps each tool handler with a context deadline.
-func timeoutMiddleware(timeout time.Duration) server.ToolHandlerMiddleware {
+// connectWithRetry runs check, the startup connection test, and retries it up
+// to retries times while it fails, waiting backoff before the first retry and
+// doubling the wait for each one after it. It returns the last error once the
+// retries run out or ctx is done.
+func connectWithRetry(ctx context.Context, retries int, backoff time.Duration, check func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt > retries {
+			return err
+		}
+		slog.Warn("IMAP connection test failed, retrying", "attempt", attempt, "retries", retries, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// account is one configured iCloud account with its own connections
+type account struct {
+	config.Account
+	imapClient *imap.Pool
+	smtpClient *smtp.Client
+}
+
+// connectAccount creates the IMAP pool for acct and tests it by listing
+// folders, retrying so that a transient iCloud failure during startup does
+// not stop the server
+func connectAccount(cfg *config.Config, acct config.Account, tlsConfig *tls.Config) (*imap.Pool, error) {
+	imapOpts := imap.ClientOptions{
+		OAuthToken:       acct.OAuthToken,
+		MessageIDDomain:  acct.MessageIDDomain,
+		ReplyPrefix:      cfg.ReplyPrefix,
+		ProtectedFolders: cfg.ProtectedFolders,
+		MaxFolderDepth:   cfg.MaxFolderDepth,
+		Aliases:          acct.AllowedFrom,
+		Debug:            cfg.IMAPDebug,
+		TLSConfig:        tlsConfig,
+		AutoPlaintext:    cfg.AutoPlaintext,
+		FetchBatchSize:   cfg.FetchBatchSize,
+		Retry: imap.RetryOptions{
+			Attempts: cfg.IMAPRetries,
+			Backoff:  cfg.IMAPRetryBackoff,
+		},
+	}
+	var imapClient *imap.Pool
+	err := connectWithRetry(context.Background(), cfg.StartupRetries, cfg.StartupRetryBackoff, func(ctx context.Context) error {
+		if imapClient == nil {
+			pool, err := imap.NewPool(cfg.IMAPPoolSize, func() (*imap.Client, error) {
+				return imap.NewClient(acct.Email, acct.Password, imapOpts)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create IMAP client: %w", err)
+			}
+			imapClient = pool
+		}
+		_, err := imapClient.ListFolders(ctx)
+		return err
+	})
+	if err != nil {
+		if imapClient != nil {
+			_ = imapClient.Close()
+		}
+		return nil, err
+	}
+	return imapClient, nil
+}
+
+// accountIDs returns the ids of accounts in order
+func accountIDs(accounts []*account) []string {
+	ids := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+// toolRegistrar returns a function that adds a tool to s, building its
+// handler once per account with build and routing each call by its account
+// argument (see routeAccount). With more than one account the tool gains an
+// optional account parameter. With readOnly set, tools not annotated
+// read-only are skipped instead.
+func toolRegistrar(s *server.MCPServer, readOnly bool, accounts []*account) func(mcp.Tool, func(*account) server.ToolHandlerFunc) {
+	return func(tool mcp.Tool, build func(*account) server.ToolHandlerFunc) {
+		if readOnly && !isReadOnlyTool(tool) {
+			return
+		}
+		if len(accounts) > 1 {
+			if tool.InputSchema.Properties == nil {
+				tool.InputSchema.Properties = make(map[string]any)
+			}
+			tool.InputSchema.Properties["account"] = map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("Account to use, by id. Defaults to %q.", accounts[0].ID),
+				"enum":        accountIDs(accounts),
+			}
+		}
+		s.AddTool(tool, routeAccount(accounts, build))
+	}
+}
+
+// routeAccount builds a handler for each account and returns one that passes
+// every call to the handler of the account its account argument names, or
+// of the first account when there is none. An unknown account is an
+// invalid_argument error.
+func routeAccount(accounts []*account, build func(*account) server.ToolHandlerFunc) server.ToolHandlerFunc {
+	handlers := make(map[string]server.ToolHandlerFunc, len(accounts))
+	for _, a := range accounts {
+		handlers[a.ID] = build(a)
+	}
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, ok := req.GetArguments()["account"].(string)
+		if !ok || id == "" {
+			id = accounts[0].ID
+		}
+		handler, ok := handlers[id]
+		if !ok {
+			return tools.InvalidArgument(fmt.Sprintf("unknown account %q; configured accounts: %s", id, strings.Join(accountIDs(accounts), ", "))), nil
+		}
+		return handler(ctx, req)
+	}
+}
+
+// isReadOnlyTool reports whether tool carries readOnlyHint: true. A tool
+// without the annotation is treated as mutating.
+func isReadOnlyTool(tool mcp.Tool) bool {
+	return tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint
+}
+
+// timeoutMiddleware wraps each tool handler with a context deadline. Tools
+// listed in overrides get their own deadline; all others use defaultTimeout.
+func timeoutMiddleware(defaultTimeout time.Duration, overrides map[string]time.Duration) server.ToolHandlerMiddleware {
 	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			timeout := defaultTimeout
+			if d, ok := overrides[req.Params.Name]; ok {
+				timeout = d
+			}
 			ctx, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 			return next(ctx, req)
@@ -467,6 +1698,7 @@ func timeoutMiddleware(timeout time.Duration) server.ToolHandlerMiddleware {
 }
 
 // loggingMiddleware logs each tool call with a unique request ID, tool name, duration, and outcome.
+// Arguments and results are never logged: they can hold BCC addresses and message bodies.
 func loggingMiddleware() server.ToolHandlerMiddleware {
 	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rgabriel/mcp-icloud-email/config"
+)
+
+// slowTool returns a handler that takes d to finish unless ctx expires first.
+func slowTool(d time.Duration) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		select {
+		case <-time.After(d):
+			return mcp.NewToolResultText("done"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func toolReq(name string) mcp.CallToolRequest {
+	return mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name}}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	overrides := map[string]time.Duration{
+		"slow_tool": time.Second,
+		"fast_tool": 5 * time.Millisecond,
+	}
+	mw := timeoutMiddleware(20*time.Millisecond, overrides)
+
+	tests := []struct {
+		name    string
+		tool    string
+		work    time.Duration
+		wantErr bool
+	}{
+		{name: "override allows slow work", tool: "slow_tool", work: 50 * time.Millisecond},
+		{name: "default rejects same work", tool: "other_tool", work: 50 * time.Millisecond, wantErr: true},
+		{name: "default allows quick work", tool: "other_tool", work: time.Millisecond},
+		{name: "tighter override fails fast", tool: "fast_tool", work: 15 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := mw(slowTool(tt.work))
+			_, err := handler(context.Background(), toolReq(tt.tool))
+			if tt.wantErr {
+				if err != context.DeadlineExceeded {
+					t.Errorf("err = %v, want context.DeadlineExceeded", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	calls := 0
+	check := func(ctx context.Context) error {
+		calls++
+		if calls <= 2 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}
+
+	if err := connectWithRetry(context.Background(), 3, time.Millisecond, check); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("check called %d times, want 3", calls)
+	}
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	calls := 0
+	check := func(ctx context.Context) error {
+		calls++
+		return errors.New("authentication failed")
+	}
+
+	err := connectWithRetry(context.Background(), 2, time.Millisecond, check)
+	if err == nil || err.Error() != "authentication failed" {
+		t.Errorf("err = %v, want the last check error", err)
+	}
+	if calls != 3 {
+		t.Errorf("check called %d times, want 3", calls)
+	}
+}
+
+func TestToolRegistrarReadOnly(t *testing.T) {
+	defs := []mcp.Tool{
+		mcp.NewTool("search_emails", mcp.WithReadOnlyHintAnnotation(true)),
+		mcp.NewTool("get_attachment", mcp.WithReadOnlyHintAnnotation(true)),
+		mcp.NewTool("send_email", mcp.WithReadOnlyHintAnnotation(false)),
+		mcp.NewTool("delete_email", mcp.WithReadOnlyHintAnnotation(false), mcp.WithDestructiveHintAnnotation(true)),
+		mcp.NewTool("unannotated"),
+	}
+	handler := slowTool(0)
+
+	tests := []struct {
+		name     string
+		readOnly bool
+		want     []string
+	}{
+		{name: "all tools", want: []string{"delete_email", "get_attachment", "search_emails", "send_email", "unannotated"}},
+		{name: "read-only mode", readOnly: true, want: []string{"get_attachment", "search_emails"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "dev")
+			add := toolRegistrar(s, tt.readOnly, []*account{{Account: config.Account{ID: "default"}}})
+			for _, def := range defs {
+				add(def, func(*account) server.ToolHandlerFunc { return handler })
+			}
+
+			var got []string
+			for name := range s.ListTools() {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("registered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// echoAccount builds a handler that answers with the id of its account
+func echoAccount(a *account) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(a.ID), nil
+	}
+}
+
+func TestRouteAccount(t *testing.T) {
+	accounts := []*account{
+		{Account: config.Account{ID: "personal", Email: "me@icloud.com"}},
+		{Account: config.Account{ID: "work", Email: "work@icloud.com"}},
+	}
+	handler := routeAccount(accounts, echoAccount)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "omitted uses first account", args: map[string]interface{}{"folder": "INBOX"}, want: "personal"},
+		{name: "empty uses first account", args: map[string]interface{}{"account": ""}, want: "personal"},
+		{name: "first account", args: map[string]interface{}{"account": "personal"}, want: "personal"},
+		{name: "second account", args: map[string]interface{}{"account": "work"}, want: "work"},
+		{name: "unknown account", args: map[string]interface{}{"account": "Work"}, want: "unknown account", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := toolReq("search_emails")
+			req.Params.Arguments = tt.args
+			result, err := handler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if result.IsError != tt.wantErr {
+				t.Errorf("IsError = %v, want %v", result.IsError, tt.wantErr)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.Contains(text, tt.want) {
+				t.Errorf("result = %q, want containing %q", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolRegistrarAccountParam(t *testing.T) {
+	personal := &account{Account: config.Account{ID: "personal"}}
+	work := &account{Account: config.Account{ID: "work"}}
+
+	tests := []struct {
+		name     string
+		accounts []*account
+		wantEnum []string
+	}{
+		{name: "single account", accounts: []*account{personal}},
+		{name: "two accounts", accounts: []*account{personal, work}, wantEnum: []string{"personal", "work"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "dev")
+			add := toolRegistrar(s, false, tt.accounts)
+			add(mcp.NewTool("search_emails", mcp.WithString("query")), echoAccount)
+
+			props := s.ListTools()["search_emails"].Tool.InputSchema.Properties
+			if props["query"] == nil {
+				t.Error("tool lost its own parameters")
+			}
+			param, ok := props["account"].(map[string]any)
+			if tt.wantEnum == nil {
+				if ok {
+					t.Errorf("unexpected account parameter %v", param)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("missing account parameter")
+			}
+			if got := strings.Join(param["enum"].([]string), ","); got != strings.Join(tt.wantEnum, ",") {
+				t.Errorf("enum = %s, want %v", got, tt.wantEnum)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package smtp
+
+import (
+	"errors"
+	"net/smtp"
+)
+
+// xoauth2Auth implements smtp.Auth for the XOAUTH2 mechanism.
+type xoauth2Auth struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Like PlainAuth, never send a bearer token over an unencrypted connection
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	resp := []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01")
+	return "XOAUTH2", resp, nil
+}
+
+// Next answers the server's error challenge with an empty response so the
+// server can complete the exchange with a failure reply.
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,72 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultCharset is the charset of outgoing text parts when none is configured
+const DefaultCharset = "utf-8"
+
+// ErrCharset is returned by SendEmail when the body holds characters the
+// configured charset cannot represent
+var ErrCharset = errors.New("body cannot be encoded in the configured charset")
+
+// Charsets are the values ClientOptions.Charset accepts: the charsets
+// go-message can write text parts in.
+var Charsets = []string{"utf-8", "us-ascii"}
+
+// ValidCharset reports whether name is one of Charsets, ignoring case
+func ValidCharset(name string) bool {
+	for _, charset := range Charsets {
+		if strings.EqualFold(name, charset) {
+			return true
+		}
+	}
+	return false
+}
+
+// charset returns the configured charset for text parts
+func (c *Client) charset() string {
+	if c.opts.Charset == "" {
+		return DefaultCharset
+	}
+	return strings.ToLower(c.opts.Charset)
+}
+
+// encodeText returns s ready to write as a text part, with the charset to
+// declare for it. Invalid UTF-8, which a quoted original or an HTML-to-text
+// conversion can carry, is replaced with U+FFFD. Text that is not plain
+// ASCII fails with ErrCharset when us-ascii is configured, rather than
+// going out in a charset the sender did not choose.
+func (c *Client) encodeText(s string) ([]byte, string, error) {
+	s = strings.ToValidUTF8(s, "�")
+
+	charset := c.charset()
+	if charset == "us-ascii" && !isASCII(s) {
+		return nil, "", fmt.Errorf("%w: %s has no %q (see BODY_CHARSET)", ErrCharset, charset, firstNonASCII(s))
+	}
+	return []byte(s), charset, nil
+}
+
+// isASCII reports whether s contains only 7-bit characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// firstNonASCII returns the first character of s outside 7-bit ASCII
+func firstNonASCII(s string) rune {
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			return r
+		}
+	}
+	return utf8.RuneError
+}
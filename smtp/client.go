@@ -3,14 +3,18 @@ package smtp
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
+	"html"
+	"net"
 	"net/smtp"
 	"strings"
 	"time"
 
 	"github.com/emersion/go-message/mail"
-	"github.com/google/uuid"
 	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/htmltext"
+	"github.com/rgabriel/mcp-icloud-email/internal/msgid"
 )
 
 const (
@@ -22,29 +26,105 @@ const (
 type Client struct {
 	username string
 	password string
+	opts     ClientOptions
+
+	// sendMail delivers a composed message; replaced in tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	// lookupMX and probe back VerifyRecipient; replaced in tests
+	lookupMX func(ctx context.Context, domain string) ([]*net.MX, error)
+	probe    prober
+	// limiter caps outgoing messages; nil when unlimited
+	limiter *rateLimiter
+}
+
+// ClientOptions contains optional settings for the SMTP client
+type ClientOptions struct {
+	// OAuthToken, when set, authenticates with XOAUTH2 instead of the password
+	OAuthToken string
+	// MessageIDDomain is the domain used in generated Message-IDs. Defaults to
+	// the domain of the account address.
+	MessageIDDomain string
+	// AutoBCC addresses are added to the envelope of every message sent
+	AutoBCC []string
+	// ReplyPrefix replaces "Re:" in reply subjects (e.g. "AW:", "Odp:")
+	ReplyPrefix string
+	// AttributionTemplate is the line introducing a quoted original. It may
+	// use {date}, {from}, and {subject}. Defaults to DefaultAttribution.
+	AttributionTemplate string
+	// TLSConfig, when set, is used for STARTTLS instead of the default
+	// settings (e.g. to raise the minimum version or pin certificates)
+	TLSConfig *tls.Config
+	// Aliases are other addresses of this account (e.g. ALLOWED_FROM); like
+	// the account address, they are left out of reply-all recipients
+	Aliases []string
+	// SendRatePerMinute caps how many messages may be sent per minute; sends
+	// beyond it fail with *RateLimitError. Zero means unlimited.
+	SendRatePerMinute int
+	// Charset is declared on outgoing text parts; one of Charsets. Defaults
+	// to DefaultCharset; text it cannot represent fails with ErrCharset.
+	Charset string
+	// HeloHost is the hostname sent in EHLO. Defaults to net/smtp's
+	// "localhost".
+	HeloHost string
 }
 
+// DefaultAttribution is the attribution line used when none is configured.
+const DefaultAttribution = "On {date}, {from} wrote:"
+
+// attributionDateLayout formats {date} in attribution lines.
+const attributionDateLayout = "Mon, Jan 2, 2006 at 3:04 PM"
+
 // SendOptions contains optional parameters for sending emails
 type SendOptions struct {
 	CC      []string
 	BCC     []string
 	HTML    bool
 	Headers map[string]string
+	// SkipAutoBCC leaves the client's AutoBCC addresses off this message
+	SkipAutoBCC bool
+	// QuoteOriginal appends an attribution line and the quoted original
+	// message to a reply
+	QuoteOriginal bool
 }
 
 // NewClient creates a new SMTP client
-func NewClient(username, password string) *Client {
-	return &Client{
+func NewClient(username, password string, opts ClientOptions) *Client {
+	c := &Client{
 		username: username,
 		password: password,
+		opts:     opts,
+		sendMail: newSendMail(opts.TLSConfig, opts.HeloHost),
+		lookupMX: net.DefaultResolver.LookupMX,
+		probe:    probeRecipient,
+	}
+	if opts.SendRatePerMinute > 0 {
+		c.limiter = newRateLimiter(opts.SendRatePerMinute)
 	}
+	return c
 }
 
-// SendEmail sends an email via SMTP
+// auth returns the SMTP authentication mechanism for the configured credentials
+func (c *Client) auth() smtp.Auth {
+	if c.opts.OAuthToken != "" {
+		return &xoauth2Auth{username: c.username, token: c.opts.OAuthToken}
+	}
+	return smtp.PlainAuth("", c.username, c.password, smtpServer)
+}
+
+// SendEmail sends an email via SMTP. An address repeated across To, CC,
+// BCC, and AutoBCC is sent to once, kept in the most visible of them.
 func (c *Client) SendEmail(ctx context.Context, from string, to []string, subject, body string, opts SendOptions) error {
 	// Create message buffer
 	var buf bytes.Buffer
 
+	// Send one copy per address, in the most visible list that names it
+	var autoBCC []string
+	if !opts.SkipAutoBCC {
+		autoBCC = c.opts.AutoBCC
+	}
+	lists := dedupeRecipients(to, opts.CC, opts.BCC, autoBCC)
+	to, opts.CC, opts.BCC, autoBCC = lists[0], lists[1], lists[2], lists[3]
+
 	// Create message header
 	var h mail.Header
 	h.SetDate(time.Now())
@@ -73,8 +153,11 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 	h.SetSubject(subject)
 
 	// Generate Message-ID
-	messageID := fmt.Sprintf("<%s.%s@%s>", uuid.New().String(), c.username, smtpServer)
-	h.Set("Message-ID", messageID)
+	domain := c.opts.MessageIDDomain
+	if domain == "" {
+		domain = msgid.DomainOf(c.username)
+	}
+	h.Set("Message-ID", msgid.New(domain))
 
 	// Set custom headers
 	for key, value := range opts.Headers {
@@ -94,29 +177,38 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 		}
 
 		// Plain text part
+		plainBody, charset, err := c.encodeText(htmltext.ToText(body))
+		if err != nil {
+			_ = mw.Close()
+			return err
+		}
 		var textHeader mail.InlineHeader
-		textHeader.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+		textHeader.SetContentType("text/plain", map[string]string{"charset": charset})
 		textPart, err := mw.CreateSingleInline(textHeader)
 		if err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to create text part: %w", err)
 		}
-		plainBody := stripHTML(body)
-		if _, err := textPart.Write([]byte(plainBody)); err != nil {
+		if _, err := textPart.Write(plainBody); err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to write text part: %w", err)
 		}
 		_ = textPart.Close()
 
 		// HTML part
+		htmlBody, charset, err := c.encodeText(body)
+		if err != nil {
+			_ = mw.Close()
+			return err
+		}
 		var htmlHeader mail.InlineHeader
-		htmlHeader.SetContentType("text/html", map[string]string{"charset": "utf-8"})
+		htmlHeader.SetContentType("text/html", map[string]string{"charset": charset})
 		htmlPart, err := mw.CreateSingleInline(htmlHeader)
 		if err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to create HTML part: %w", err)
 		}
-		if _, err := htmlPart.Write([]byte(body)); err != nil {
+		if _, err := htmlPart.Write(htmlBody); err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to write HTML part: %w", err)
 		}
@@ -125,7 +217,11 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 		_ = mw.Close()
 	} else {
 		// Plain text only
-		h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+		textBody, charset, err := c.encodeText(body)
+		if err != nil {
+			return err
+		}
+		h.SetContentType("text/plain", map[string]string{"charset": charset})
 		mw, err = mail.CreateWriter(&buf, h)
 		if err != nil {
 			return fmt.Errorf("failed to create message writer: %w", err)
@@ -133,13 +229,13 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 		
 		// Create inline part for plain text
 		var textHeader mail.InlineHeader
-		textHeader.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+		textHeader.SetContentType("text/plain", map[string]string{"charset": charset})
 		textPart, err := mw.CreateSingleInline(textHeader)
 		if err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to create text part: %w", err)
 		}
-		if _, err := textPart.Write([]byte(body)); err != nil {
+		if _, err := textPart.Write(textBody); err != nil {
 			_ = mw.Close()
 			return fmt.Errorf("failed to write body: %w", err)
 		}
@@ -147,19 +243,26 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 		_ = mw.Close()
 	}
 
-	// Build recipient list (To + CC + BCC)
-	recipients := make([]string, 0, len(to)+len(opts.CC)+len(opts.BCC))
+	// Build recipient list (To + CC + BCC + auto BCC)
+	recipients := make([]string, 0, len(to)+len(opts.CC)+len(opts.BCC)+len(autoBCC))
 	recipients = append(recipients, to...)
 	recipients = append(recipients, opts.CC...)
 	recipients = append(recipients, opts.BCC...)
+	recipients = append(recipients, autoBCC...)
+
+	// Stay under the send rate rather than let iCloud block the account
+	if c.limiter != nil {
+		if err := c.limiter.take(); err != nil {
+			return err
+		}
+	}
 
 	// Send via SMTP
 	addr := fmt.Sprintf("%s:%d", smtpServer, smtpPort)
-	auth := smtp.PlainAuth("", c.username, c.password, smtpServer)
-
-	err = smtp.SendMail(addr, auth, from, recipients, buf.Bytes())
+	err = c.sendMail(addr, c.auth(), from, recipients, buf.Bytes())
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		// Blind recipients must not show up in a rejection's message
+		return fmt.Errorf("failed to send email: %w", redactAddresses(err, opts.BCC, autoBCC))
 	}
 
 	return nil
@@ -167,91 +270,87 @@ func (c *Client) SendEmail(ctx context.Context, from string, to []string, subjec
 
 // ReplyToEmail replies to an existing email
 func (c *Client) ReplyToEmail(ctx context.Context, original *imap.Email, body string, replyAll bool, opts SendOptions) error {
-	// Build recipient list
-	to := []string{original.From}
-	
-	var cc []string
-	if replyAll {
-		// Add all To recipients except ourselves
-		for _, addr := range original.To {
-			if !strings.Contains(addr, c.username) {
-				cc = append(cc, addr)
-			}
-		}
-		// Add all CC recipients except ourselves
-		for _, addr := range original.CC {
-			if !strings.Contains(addr, c.username) {
-				cc = append(cc, addr)
-			}
-		}
-	}
+	to, cc, replySubject, headers := computeReply(original, replyAll, c.username, c.opts.Aliases, c.opts.ReplyPrefix, opts)
 
-	// Merge with provided CC
-	if len(opts.CC) > 0 {
-		cc = append(cc, opts.CC...)
+	// Quote the original below the reply
+	if opts.QuoteOriginal {
+		body = c.quoteOriginal(original, body, opts.HTML)
 	}
 
-	// Build subject with Re: prefix
-	subject := original.Subject
-	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
-		subject = "Re: " + subject
+	// Send the reply
+	sendOpts := SendOptions{
+		CC:          cc,
+		BCC:         opts.BCC,
+		HTML:        opts.HTML,
+		Headers:     headers,
+		SkipAutoBCC: opts.SkipAutoBCC,
 	}
 
-	// Build reply headers
-	headers := make(map[string]string)
-	if original.MessageID != "" {
-		headers["In-Reply-To"] = original.MessageID
-		
-		// Build References header
-		refs := []string{}
-		if len(original.References) > 0 {
-			refs = append(refs, original.References...)
-		}
-		refs = append(refs, original.MessageID)
-		headers["References"] = strings.Join(refs, " ")
-	}
+	return c.SendEmail(ctx, c.username, to, replySubject, body, sendOpts)
+}
 
-	// Merge with provided headers
-	for key, value := range opts.Headers {
-		headers[key] = value
-	}
+// ReplyPreview is what ReplyToEmail would send for an email, minus the body
+type ReplyPreview struct {
+	To         []string `json:"to"`
+	CC         []string `json:"cc"`
+	Subject    string   `json:"subject"`
+	InReplyTo  string   `json:"inReplyTo,omitempty"`
+	References string   `json:"references,omitempty"`
+}
 
-	// Send the reply
-	sendOpts := SendOptions{
-		CC:      cc,
-		BCC:     opts.BCC,
-		HTML:    opts.HTML,
-		Headers: headers,
+// PreviewReply returns the recipients, subject, and threading headers that
+// ReplyToEmail would use for the same arguments, without sending anything.
+// Recipients are deduplicated as SendEmail does; BCC is left out.
+func (c *Client) PreviewReply(original *imap.Email, replyAll bool, opts SendOptions) ReplyPreview {
+	to, cc, replySubject, headers := computeReply(original, replyAll, c.username, c.opts.Aliases, c.opts.ReplyPrefix, opts)
+	lists := dedupeRecipients(to, cc)
+	preview := ReplyPreview{
+		To:         lists[0],
+		CC:         lists[1],
+		Subject:    replySubject,
+		InReplyTo:  headers["In-Reply-To"],
+		References: headers["References"],
+	}
+	if preview.CC == nil {
+		preview.CC = []string{}
 	}
+	return preview
+}
 
-	return c.SendEmail(ctx, c.username, to, subject, body, sendOpts)
+// isSelf reports whether addr, bare or with a display name, is the account
+// address or one of its aliases. Addresses are compared case-insensitively.
+func (c *Client) isSelf(addr string) bool {
+	return isSelfAddress(addr, c.username, c.opts.Aliases)
 }
 
-// stripHTML removes HTML tags for plain text version (basic implementation)
-func stripHTML(html string) string {
-	// Simple HTML stripping - replace common tags with newlines
-	text := strings.ReplaceAll(html, "<br>", "\n")
-	text = strings.ReplaceAll(text, "<br/>", "\n")
-	text = strings.ReplaceAll(text, "<br />", "\n")
-	text = strings.ReplaceAll(text, "</p>", "\n\n")
-	text = strings.ReplaceAll(text, "</div>", "\n")
-	
-	// Remove remaining tags
-	inTag := false
-	var result strings.Builder
-	for _, char := range text {
-		if char == '<' {
-			inTag = true
-			continue
-		}
-		if char == '>' {
-			inTag = false
-			continue
-		}
-		if !inTag {
-			result.WriteRune(char)
-		}
+// attribution renders the configured attribution template for original.
+func (c *Client) attribution(original *imap.Email) string {
+	tmpl := c.opts.AttributionTemplate
+	if tmpl == "" {
+		tmpl = DefaultAttribution
+	}
+	return strings.NewReplacer(
+		"{date}", original.Date.Format(attributionDateLayout),
+		"{from}", original.From,
+		"{subject}", original.Subject,
+	).Replace(tmpl)
+}
+
+// quoteOriginal appends the attribution line and the original plain text body,
+// prefixed with "> " (or wrapped in a blockquote for HTML replies).
+func (c *Client) quoteOriginal(original *imap.Email, body string, isHTML bool) string {
+	attribution := c.attribution(original)
+	quoted := strings.TrimRight(original.BodyPlain, "\n")
+
+	if isHTML {
+		return fmt.Sprintf("%s<br><br>%s<blockquote>%s</blockquote>",
+			body, html.EscapeString(attribution),
+			strings.ReplaceAll(html.EscapeString(quoted), "\n", "<br>"))
+	}
+
+	lines := strings.Split(quoted, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
 	}
-	
-	return strings.TrimSpace(result.String())
+	return body + "\n\n" + attribution + "\n" + strings.Join(lines, "\n")
 }
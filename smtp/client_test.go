@@ -0,0 +1,407 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// sentMessage records one delivery through Client.sendMail.
+type sentMessage struct {
+	addr string
+	from string
+	to   []string
+	msg  []byte
+}
+
+// newTestClient returns a client whose deliveries are captured in sent.
+func newTestClient(opts ClientOptions, sent *[]sentMessage) *Client {
+	c := NewClient("me@icloud.com", "app-pass", opts)
+	c.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		*sent = append(*sent, sentMessage{addr: addr, from: from, to: to, msg: msg})
+		return nil
+	}
+	return c
+}
+
+// header parses a captured message and returns the named header.
+func (s sentMessage) header(t *testing.T, key string) string {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(string(s.msg)))
+	if err != nil {
+		t.Fatalf("failed to parse sent message: %v", err)
+	}
+	return m.Header.Get(key)
+}
+
+func TestClientAuth(t *testing.T) {
+	server := &smtp.ServerInfo{Name: smtpServer, TLS: true, Auth: []string{"PLAIN", "XOAUTH2"}}
+
+	tests := []struct {
+		name     string
+		client   *Client
+		wantMech string
+	}{
+		{name: "password", client: NewClient("me@icloud.com", "app-pass", ClientOptions{}), wantMech: "PLAIN"},
+		{name: "oauth token", client: NewClient("me@icloud.com", "", ClientOptions{OAuthToken: "tok123"}), wantMech: "XOAUTH2"},
+		{name: "token wins", client: NewClient("me@icloud.com", "app-pass", ClientOptions{OAuthToken: "tok123"}), wantMech: "XOAUTH2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mech, _, err := tt.client.auth().Start(server)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mech != tt.wantMech {
+				t.Errorf("mech = %q, want %q", mech, tt.wantMech)
+			}
+		})
+	}
+}
+
+func TestXoauth2AuthRequiresTLS(t *testing.T) {
+	a := &xoauth2Auth{username: "me@icloud.com", token: "tok123"}
+	if _, _, err := a.Start(&smtp.ServerInfo{Name: smtpServer}); err == nil {
+		t.Error("expected error on unencrypted connection")
+	}
+}
+
+func TestSendEmailMessageIDDomain(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ClientOptions
+		wantSuffix string
+	}{
+		{name: "defaults to account domain", wantSuffix: "@icloud.com>"},
+		{name: "configured domain", opts: ClientOptions{MessageIDDomain: "example.org"}, wantSuffix: "@example.org>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []sentMessage
+			c := newTestClient(tt.opts, &sent)
+			if err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", SendOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			id := sent[0].header(t, "Message-Id")
+			if !strings.HasSuffix(id, tt.wantSuffix) {
+				t.Errorf("Message-ID = %q, want suffix %q", id, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestSendEmailAutoBCC(t *testing.T) {
+	original := &imap.Email{From: "alice@example.com", Subject: "Hi", MessageID: "<1@example.com>"}
+
+	tests := []struct {
+		name     string
+		send     func(c *Client) error
+		wantAuto bool
+	}{
+		{
+			name: "send",
+			send: func(c *Client) error {
+				return c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", SendOptions{})
+			},
+			wantAuto: true,
+		},
+		{
+			name: "reply",
+			send: func(c *Client) error {
+				return c.ReplyToEmail(context.Background(), original, "Thanks", false, SendOptions{})
+			},
+			wantAuto: true,
+		},
+		{
+			name: "skipped per call",
+			send: func(c *Client) error {
+				return c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", SendOptions{SkipAutoBCC: true})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []sentMessage
+			c := newTestClient(ClientOptions{AutoBCC: []string{"archive@example.com"}}, &sent)
+			if err := tt.send(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+
+			inEnvelope := false
+			for _, rcpt := range sent[0].to {
+				if rcpt == "archive@example.com" {
+					inEnvelope = true
+				}
+			}
+			if inEnvelope != tt.wantAuto {
+				t.Errorf("envelope %v contains auto BCC = %v, want %v", sent[0].to, inEnvelope, tt.wantAuto)
+			}
+			if strings.Contains(string(sent[0].msg), "archive@example.com") {
+				t.Error("auto BCC address leaked into message headers")
+			}
+		})
+	}
+}
+
+func TestSendEmailDedupesRecipients(t *testing.T) {
+	var sent []sentMessage
+	c := newTestClient(ClientOptions{AutoBCC: []string{"carol@example.com", "archive@example.com"}}, &sent)
+
+	opts := SendOptions{
+		CC:  []string{"Bob@Example.com", "carol@example.com", "dave@example.com"},
+		BCC: []string{"bob@example.com", "dave@example.com", "erin@example.com"},
+	}
+	if err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com", "Bob <BOB@example.com>"}, "Hi", "Hello", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "bob@example.com,carol@example.com,dave@example.com,erin@example.com,archive@example.com"
+	if got := strings.Join(sent[0].to, ","); got != want {
+		t.Errorf("envelope = %s, want %s", got, want)
+	}
+	if got := sent[0].header(t, "To"); got != "<bob@example.com>" {
+		t.Errorf("To = %q, want bob only once", got)
+	}
+	if got := sent[0].header(t, "Cc"); got != "<carol@example.com>, <dave@example.com>" {
+		t.Errorf("Cc = %q, want bob dropped", got)
+	}
+}
+
+func TestSendEmailRedactsBCCFromErrors(t *testing.T) {
+	rejected := &textproto.Error{Code: 550, Msg: "5.1.1 <Secret@Example.com>: Recipient address rejected"}
+	c := NewClient("me@icloud.com", "app-pass", ClientOptions{AutoBCC: []string{"archive@example.com"}})
+	c.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return rejected
+	}
+
+	opts := SendOptions{BCC: []string{"secret@example.com"}}
+	err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", opts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(strings.ToLower(err.Error()), "secret@example.com") {
+		t.Errorf("error leaks BCC address: %v", err)
+	}
+	if !strings.Contains(err.Error(), "[bcc]") {
+		t.Errorf("error = %v, want the address replaced by [bcc]", err)
+	}
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) || tpErr.Code != 550 {
+		t.Errorf("error = %v, want the server's 550 reachable with errors.As", err)
+	}
+}
+
+func TestReplyToEmailLocalization(t *testing.T) {
+	original := &imap.Email{
+		From:      "alice@example.com",
+		Subject:   "AW: Plan",
+		Date:      time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC),
+		BodyPlain: "Line one\nLine two\n",
+		MessageID: "<1@example.com>",
+	}
+
+	tests := []struct {
+		name        string
+		opts        ClientOptions
+		quote       bool
+		wantSubject string
+		wantBody    []string
+		wantAbsent  string
+	}{
+		{
+			name:        "defaults",
+			quote:       true,
+			wantSubject: "Re: Plan",
+			wantBody:    []string{"Thanks\n\nOn Mon, Jan 15, 2024 at 2:30 PM, alice@example.com wrote:\n> Line one\n> Line two"},
+		},
+		{
+			name:        "custom template and prefix",
+			opts:        ClientOptions{ReplyPrefix: "AW:", AttributionTemplate: "Am {date} schrieb {from} ({subject}):"},
+			quote:       true,
+			wantSubject: "AW: Plan",
+			wantBody:    []string{"Am Mon, Jan 15, 2024 at 2:30 PM schrieb alice@example.com (AW: Plan):\n> Line one"},
+		},
+		{
+			name:        "no quote",
+			opts:        ClientOptions{ReplyPrefix: "Odp:"},
+			wantSubject: "Odp: Plan",
+			wantAbsent:  "> Line one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []sentMessage
+			c := newTestClient(tt.opts, &sent)
+			if err := c.ReplyToEmail(context.Background(), original, "Thanks", false, SendOptions{QuoteOriginal: tt.quote}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sent[0].header(t, "Subject"); got != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", got, tt.wantSubject)
+			}
+			msg := strings.ReplaceAll(string(sent[0].msg), "\r\n", "\n")
+			for _, want := range tt.wantBody {
+				if !strings.Contains(msg, want) {
+					t.Errorf("message missing %q:\n%s", want, msg)
+				}
+			}
+			if tt.wantAbsent != "" && strings.Contains(msg, tt.wantAbsent) {
+				t.Errorf("message unexpectedly contains %q", tt.wantAbsent)
+			}
+		})
+	}
+}
+
+func TestReplyToEmailAllExcludesSelf(t *testing.T) {
+	original := &imap.Email{
+		From:      "alice@example.com",
+		To:        []string{"Me <ME@iCloud.com>", "notme@icloud.com"},
+		CC:        []string{"Work Me <me@example.com>", "bob@example.com"},
+		Subject:   "Plan",
+		MessageID: "<1@example.com>",
+	}
+
+	var sent []sentMessage
+	c := newTestClient(ClientOptions{Aliases: []string{"Me@Example.com"}}, &sent)
+	if err := c.ReplyToEmail(context.Background(), original, "Thanks", true, SendOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// notme@icloud.com contains the account address but is someone else;
+	// the display-name and alias forms are the account itself
+	got := strings.Join(sent[0].to, ",")
+	if got != "alice@example.com,notme@icloud.com,bob@example.com" {
+		t.Errorf("recipients = %s", got)
+	}
+}
+
+func TestPreviewReplyMatchesReply(t *testing.T) {
+	original := &imap.Email{
+		From:       "Alice <alice@example.com>",
+		To:         []string{"me@icloud.com", "bob@example.com"},
+		CC:         []string{"carol@example.com", "Alice <alice@example.com>"},
+		Subject:    "Re: Plan",
+		MessageID:  "<2@example.com>",
+		References: []string{"<1@example.com>"},
+	}
+
+	tests := []struct {
+		name     string
+		replyAll bool
+		wantTo   string
+		wantCC   string
+	}{
+		{name: "reply to sender", wantTo: "Alice <alice@example.com>"},
+		{name: "reply all", replyAll: true, wantTo: "Alice <alice@example.com>", wantCC: "bob@example.com,carol@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []sentMessage
+			c := newTestClient(ClientOptions{}, &sent)
+
+			preview := c.PreviewReply(original, tt.replyAll, SendOptions{})
+			if err := c.ReplyToEmail(context.Background(), original, "Thanks", tt.replyAll, SendOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msg := sent[0]
+
+			if got := strings.Join(preview.To, ","); got != tt.wantTo {
+				t.Errorf("preview To = %s, want %s", got, tt.wantTo)
+			}
+			if got := strings.Join(preview.CC, ","); got != tt.wantCC {
+				t.Errorf("preview CC = %s, want %s", got, tt.wantCC)
+			}
+
+			// The envelope of the real reply holds exactly the previewed recipients
+			want := append(append([]string{}, preview.To...), preview.CC...)
+			if got := strings.Join(msg.to, ","); got != strings.Join(want, ",") {
+				t.Errorf("sent to %s, preview promised %s", got, strings.Join(want, ","))
+			}
+			if got := msg.header(t, "Subject"); got != preview.Subject || preview.Subject != "Re: Plan" {
+				t.Errorf("Subject = %q, preview %q", got, preview.Subject)
+			}
+			if got := msg.header(t, "In-Reply-To"); got != preview.InReplyTo || preview.InReplyTo != "<2@example.com>" {
+				t.Errorf("In-Reply-To = %q, preview %q", got, preview.InReplyTo)
+			}
+			if got := msg.header(t, "References"); got != preview.References || preview.References != "<1@example.com> <2@example.com>" {
+				t.Errorf("References = %q, preview %q", got, preview.References)
+			}
+		})
+	}
+}
+
+func TestSendEmailCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		charset     string
+		body        string
+		html        bool
+		wantCharset string
+		wantText    string
+		notText     string
+	}{
+		{name: "default utf-8", body: "Hello", wantCharset: "charset=utf-8", wantText: "Hello"},
+		{name: "custom charset", charset: "US-ASCII", body: "Hello", wantCharset: "charset=us-ascii", wantText: "Hello"},
+		{name: "custom charset html", charset: "us-ascii", body: "<p>Hello</p>", html: true, wantCharset: "charset=us-ascii", wantText: "<p>Hello</p>"},
+		{name: "invalid utf-8 replaced", body: "Caf\xff\xfe", wantCharset: "charset=utf-8", wantText: "Caf=EF=BF=BD", notText: "=FF"},
+		{name: "invalid utf-8 from html", body: "<p>Caf\xff</p>", html: true, wantCharset: "charset=utf-8", wantText: "Caf=EF=BF=BD", notText: "=FF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []sentMessage
+			c := newTestClient(ClientOptions{Charset: tt.charset}, &sent)
+			if err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", tt.body, SendOptions{HTML: tt.html}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			msg := string(sent[0].msg)
+			if !strings.Contains(msg, tt.wantCharset) {
+				t.Errorf("message does not declare %s:\n%s", tt.wantCharset, msg)
+			}
+			if !strings.Contains(msg, tt.wantText) {
+				t.Errorf("message does not contain %q:\n%s", tt.wantText, msg)
+			}
+			if tt.notText != "" && strings.Contains(msg, tt.notText) {
+				t.Errorf("message contains %q:\n%s", tt.notText, msg)
+			}
+		})
+	}
+}
+
+func TestSendEmailCharsetUnencodable(t *testing.T) {
+	for _, html := range []bool{false, true} {
+		var sent []sentMessage
+		c := newTestClient(ClientOptions{Charset: "us-ascii"}, &sent)
+		err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Caf\u00e9", SendOptions{HTML: html})
+		if !errors.Is(err, ErrCharset) {
+			t.Errorf("html=%v: err = %v, want ErrCharset", html, err)
+		}
+		if len(sent) != 0 {
+			t.Errorf("html=%v: message sent in a charset other than us-ascii", html)
+		}
+	}
+
+	// HTML entities count once converted for the plain text part
+	var sent []sentMessage
+	c := newTestClient(ClientOptions{Charset: "us-ascii"}, &sent)
+	if err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "<p>Caf&eacute;</p>", SendOptions{HTML: true}); !errors.Is(err, ErrCharset) {
+		t.Errorf("entity: err = %v, want ErrCharset", err)
+	}
+}
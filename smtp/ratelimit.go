@@ -0,0 +1,61 @@
+package smtp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitError is returned when a send would exceed the configured rate.
+// The message is not sent; it can be retried after RetryAfter.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// rateLimiter is a token bucket holding up to perMinute sends that refills
+// continuously at perMinute per minute, so a burst of the full allowance is
+// possible after an idle minute but the sustained rate never exceeds it.
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	interval time.Duration // time to earn one token
+	last     time.Time
+	now      func() time.Time // replaced in tests
+}
+
+// newRateLimiter returns a limiter allowing perMinute sends per minute
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		interval: time.Minute / time.Duration(perMinute),
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// take consumes one token, or returns a *RateLimitError saying how long
+// until one is available
+func (l *rateLimiter) take() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		wait := time.Duration((1 - l.tokens) * float64(l.interval))
+		return &RateLimitError{RetryAfter: wait}
+	}
+	l.tokens--
+	return nil
+}
@@ -0,0 +1,83 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	l := newRateLimiter(3)
+	l.last = now
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if err := l.take(); err != nil {
+			t.Fatalf("send %d: unexpected error: %v", i+1, err)
+		}
+	}
+
+	// The bucket is empty; one token takes 20s to earn at 3 per minute
+	var rateErr *RateLimitError
+	if err := l.take(); !errors.As(err, &rateErr) {
+		t.Fatalf("error = %v, want *RateLimitError", err)
+	}
+	if rateErr.RetryAfter != 20*time.Second {
+		t.Errorf("RetryAfter = %v, want 20s", rateErr.RetryAfter)
+	}
+
+	now = now.Add(5 * time.Second)
+	if err := l.take(); !errors.As(err, &rateErr) || rateErr.RetryAfter != 15*time.Second {
+		t.Errorf("after 5s: error = %v, want retry after 15s", err)
+	}
+
+	now = now.Add(15 * time.Second)
+	if err := l.take(); err != nil {
+		t.Errorf("after a token was earned: unexpected error: %v", err)
+	}
+
+	// Idle time never banks more than one minute's allowance
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := l.take(); err != nil {
+			t.Fatalf("burst send %d: unexpected error: %v", i+1, err)
+		}
+	}
+	if err := l.take(); err == nil {
+		t.Error("expected the fourth burst send to be limited")
+	}
+}
+
+func TestSendEmailRateLimit(t *testing.T) {
+	var sent []sentMessage
+	c := newTestClient(ClientOptions{SendRatePerMinute: 2}, &sent)
+	original := &imap.Email{From: "alice@example.com", Subject: "Hi", MessageID: "<1@example.com>"}
+
+	if err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", SendOptions{}); err != nil {
+		t.Fatalf("first send: unexpected error: %v", err)
+	}
+	if err := c.ReplyToEmail(context.Background(), original, "Thanks", false, SendOptions{}); err != nil {
+		t.Fatalf("reply: unexpected error: %v", err)
+	}
+
+	err := c.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi again", "Hello", SendOptions{})
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("error = %v, want *RateLimitError", err)
+	}
+	if len(sent) != 2 {
+		t.Errorf("delivered %d messages, want 2", len(sent))
+	}
+
+	// Unlimited by default
+	unlimited := newTestClient(ClientOptions{}, &sent)
+	for i := 0; i < 10; i++ {
+		if err := unlimited.SendEmail(context.Background(), "me@icloud.com", []string{"bob@example.com"}, "Hi", "Hello", SendOptions{}); err != nil {
+			t.Fatalf("unlimited send %d: unexpected error: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package smtp
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// bareAddress returns the address part of addr, which may carry a display
+// name, lowercased for comparison
+func bareAddress(addr string) string {
+	bare := strings.TrimSpace(addr)
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		bare = parsed.Address
+	}
+	return strings.ToLower(bare)
+}
+
+// dedupeRecipients drops repeated addresses so that nobody gets more than
+// one copy of a message. Lists are given from most to least visible (To,
+// CC, BCC, ...) and an address is kept only where it first appears, so one
+// listed in both To and CC stays in To. Addresses compare case-insensitively
+// and ignore display names; the first spelling is kept. Empty lists stay nil.
+func dedupeRecipients(lists ...[]string) [][]string {
+	seen := make(map[string]bool)
+	deduped := make([][]string, len(lists))
+	for i, list := range lists {
+		for _, addr := range list {
+			key := bareAddress(addr)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			deduped[i] = append(deduped[i], addr)
+		}
+	}
+	return deduped
+}
+
+// redactedError is an error whose message has had addresses removed; it
+// unwraps to the original so errors.Is and errors.As still see it
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactAddresses replaces every address in the hidden lists with "[bcc]"
+// in err's message, so that a server rejecting a blind recipient does not
+// reveal it to the caller or the logs
+func redactAddresses(err error, hidden ...[]string) error {
+	msg := err.Error()
+	for _, list := range hidden {
+		for _, addr := range list {
+			bare := bareAddress(addr)
+			if bare == "" {
+				continue
+			}
+			msg = regexp.MustCompile(`(?i)`+regexp.QuoteMeta(bare)).ReplaceAllString(msg, "[bcc]")
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
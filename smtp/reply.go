@@ -0,0 +1,70 @@
+package smtp
+
+import (
+	"strings"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/subject"
+)
+
+// computeReply returns the recipients, subject, and headers of a reply to
+// original sent by self. The sender goes in To; with replyAll every other
+// original recipient follows in CC, except self and its aliases, and then
+// opts.CC. The subject gets a single prefix (prefix, or "Re:" when empty),
+// and In-Reply-To and References thread the reply under original, with
+// opts.Headers applied last. It sends nothing, so PreviewReply shares it
+// with ReplyToEmail.
+func computeReply(original *imap.Email, replyAll bool, self string, aliases []string, prefix string, opts SendOptions) (to, cc []string, replySubject string, headers map[string]string) {
+	// Build recipient list
+	to = []string{original.From}
+
+	if replyAll {
+		// Add all To and CC recipients except ourselves
+		for _, list := range [][]string{original.To, original.CC} {
+			for _, addr := range list {
+				if !isSelfAddress(addr, self, aliases) {
+					cc = append(cc, addr)
+				}
+			}
+		}
+	}
+
+	// Merge with provided CC
+	cc = append(cc, opts.CC...)
+
+	// Build subject with a single reply prefix
+	replySubject = subject.ReplyWith(prefix, original.Subject)
+
+	// Build reply headers
+	headers = make(map[string]string)
+	if original.MessageID != "" {
+		headers["In-Reply-To"] = original.MessageID
+
+		// Build References header
+		refs := append([]string{}, original.References...)
+		refs = append(refs, original.MessageID)
+		headers["References"] = strings.Join(refs, " ")
+	}
+
+	// Merge with provided headers
+	for key, value := range opts.Headers {
+		headers[key] = value
+	}
+
+	return to, cc, replySubject, headers
+}
+
+// isSelfAddress reports whether addr, bare or with a display name, is self
+// or one of aliases. Addresses are compared case-insensitively.
+func isSelfAddress(addr, self string, aliases []string) bool {
+	bare := bareAddress(addr)
+	if strings.EqualFold(bare, self) {
+		return true
+	}
+	for _, alias := range aliases {
+		if strings.EqualFold(bare, alias) {
+			return true
+		}
+	}
+	return false
+}
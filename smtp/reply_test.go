@@ -0,0 +1,119 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+func TestComputeReply(t *testing.T) {
+	original := &imap.Email{
+		From:       "Alice <alice@example.com>",
+		To:         []string{"Me <ME@iCloud.com>", "notme@icloud.com"},
+		CC:         []string{"Work Me <me@example.com>", "bob@example.com"},
+		Subject:    "RE: Re: Plan",
+		MessageID:  "<2@example.com>",
+		References: []string{"<0@example.com>", "<1@example.com>"},
+	}
+
+	tests := []struct {
+		name        string
+		original    *imap.Email
+		replyAll    bool
+		prefix      string
+		opts        SendOptions
+		wantCC      string
+		wantSubject string
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "sender only",
+			original:    original,
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<2@example.com>", "References": "<0@example.com> <1@example.com> <2@example.com>"},
+		},
+		{
+			// notme@icloud.com contains the account address but is someone
+			// else; the display-name and alias forms are the account itself
+			name:        "reply all drops self and aliases",
+			original:    original,
+			replyAll:    true,
+			wantCC:      "notme@icloud.com,bob@example.com",
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<2@example.com>", "References": "<0@example.com> <1@example.com> <2@example.com>"},
+		},
+		{
+			name:        "extra cc follows original recipients",
+			original:    original,
+			replyAll:    true,
+			opts:        SendOptions{CC: []string{"carol@example.com"}},
+			wantCC:      "notme@icloud.com,bob@example.com,carol@example.com",
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<2@example.com>", "References": "<0@example.com> <1@example.com> <2@example.com>"},
+		},
+		{
+			name:        "extra cc without reply all",
+			original:    original,
+			opts:        SendOptions{CC: []string{"carol@example.com"}},
+			wantCC:      "carol@example.com",
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<2@example.com>", "References": "<0@example.com> <1@example.com> <2@example.com>"},
+		},
+		{
+			name:        "localized prefix is not stacked",
+			original:    &imap.Email{From: "alice@example.com", Subject: "AW: Plan"},
+			prefix:      "AW:",
+			wantSubject: "AW: Plan",
+			wantHeaders: map[string]string{},
+		},
+		{
+			name:        "no message id means no threading headers",
+			original:    &imap.Email{From: "alice@example.com", Subject: "Plan", References: []string{"<0@example.com>"}},
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{},
+		},
+		{
+			name:        "first reply starts references",
+			original:    &imap.Email{From: "alice@example.com", Subject: "Plan", MessageID: "<1@example.com>"},
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<1@example.com>", "References": "<1@example.com>"},
+		},
+		{
+			name:        "provided headers win",
+			original:    &imap.Email{From: "alice@example.com", Subject: "Plan", MessageID: "<1@example.com>"},
+			opts:        SendOptions{Headers: map[string]string{"References": "<x@example.com>", "X-Priority": "1"}},
+			wantSubject: "Re: Plan",
+			wantHeaders: map[string]string{"In-Reply-To": "<1@example.com>", "References": "<x@example.com>", "X-Priority": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to, cc, replySubject, headers := computeReply(tt.original, tt.replyAll, "me@icloud.com", []string{"Me@Example.com"}, tt.prefix, tt.opts)
+
+			if got := strings.Join(to, ","); got != tt.original.From {
+				t.Errorf("to = %s, want %s", got, tt.original.From)
+			}
+			if got := strings.Join(cc, ","); got != tt.wantCC {
+				t.Errorf("cc = %s, want %s", got, tt.wantCC)
+			}
+			if replySubject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", replySubject, tt.wantSubject)
+			}
+			if len(headers) != len(tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", headers, tt.wantHeaders)
+			}
+			for key, want := range tt.wantHeaders {
+				if headers[key] != want {
+					t.Errorf("%s = %q, want %q", key, headers[key], want)
+				}
+			}
+		})
+	}
+
+	// The original's reference chain is left untouched
+	if got := strings.Join(original.References, " "); got != "<0@example.com> <1@example.com>" {
+		t.Errorf("original References changed to %s", got)
+	}
+}
@@ -0,0 +1,84 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// newSendMail returns the production sendMail. It replaces smtp.SendMail so
+// that the EHLO hostname can be set (heloHost; net/smtp's "localhost" when
+// empty) and STARTTLS negotiated with config (the default settings when
+// nil). Like smtp.SendMail it refuses to send over a connection that cannot
+// be upgraded.
+func newSendMail(config *tls.Config, heloHost string) func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	return func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+
+		c, err := smtp.Dial(addr)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		return deliver(c, host, config, heloHost, a, from, to, msg)
+	}
+}
+
+// deliver runs one mail transaction on c, a fresh connection to host:
+// EHLO, STARTTLS, AUTH, then the message itself.
+func deliver(c *smtp.Client, host string, config *tls.Config, heloHost string, a smtp.Auth, from string, to []string, msg []byte) error {
+	if heloHost != "" {
+		if err := c.Hello(heloHost); err != nil {
+			return fmt.Errorf("EHLO %s failed: %w", heloHost, err)
+		}
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return errors.New("smtp: server doesn't support STARTTLS")
+	}
+	tlsConfig := &tls.Config{}
+	if config != nil {
+		tlsConfig = config.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	if err := c.StartTLS(tlsConfig); err != nil {
+		return fmt.Errorf("STARTTLS failed: %w", err)
+	}
+
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,97 @@
+package smtp
+
+import (
+	"bufio"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers an SMTP session on conn without STARTTLS, recording
+// every command it receives. The returned channel yields the commands once
+// the client hangs up.
+func fakeServer(conn net.Conn) <-chan []string {
+	done := make(chan []string, 1)
+	go func() {
+		defer conn.Close()
+		var commands []string
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("220 fake ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- commands
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			commands = append(commands, line)
+
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				_, _ = conn.Write([]byte("250-fake\r\n250 AUTH PLAIN\r\n"))
+			case "QUIT":
+				_, _ = conn.Write([]byte("221 bye\r\n"))
+			default:
+				_, _ = conn.Write([]byte("502 not implemented\r\n"))
+			}
+		}
+	}()
+	return done
+}
+
+func TestDeliverHeloHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		heloHost string
+		want     string
+	}{
+		{name: "configured", heloHost: "mail.example.com", want: "EHLO mail.example.com"},
+		{name: "default", want: "EHLO localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			done := fakeServer(serverConn)
+
+			c, err := smtp.NewClient(clientConn, "smtp.example.com")
+			if err != nil {
+				t.Fatalf("failed to greet: %v", err)
+			}
+			err = deliver(c, "smtp.example.com", nil, tt.heloHost, nil, "me@icloud.com", []string{"bob@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n"))
+			c.Close()
+
+			// The fake server never offers STARTTLS, so nothing is sent
+			if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+				t.Errorf("error = %v, want a missing STARTTLS error", err)
+			}
+			commands := <-done
+			if len(commands) == 0 || commands[0] != tt.want {
+				t.Errorf("commands = %q, want %q first", commands, tt.want)
+			}
+			for _, cmd := range commands {
+				if strings.HasPrefix(cmd, "MAIL") {
+					t.Errorf("sent %q without STARTTLS", cmd)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverHeloRejected(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	done := fakeServer(serverConn)
+
+	c, err := smtp.NewClient(clientConn, "smtp.example.com")
+	if err != nil {
+		t.Fatalf("failed to greet: %v", err)
+	}
+	err = deliver(c, "smtp.example.com", nil, "bad\r\nhost", nil, "me@icloud.com", []string{"bob@example.com"}, nil)
+	c.Close()
+	<-done
+
+	if err == nil || !strings.Contains(err.Error(), "EHLO") {
+		t.Errorf("error = %v, want an EHLO error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rgabriel/mcp-icloud-email/internal/msgid"
+)
+
+// probeTimeout bounds a single RCPT probe against one MX host.
+const probeTimeout = 20 * time.Second
+
+// prober runs MAIL FROM / RCPT TO against host and returns the server's reply
+// to RCPT. A non-nil error means the conversation failed before RCPT.
+type prober func(ctx context.Context, host, from, rcpt string) (code int, msg string, err error)
+
+// VerifyRecipient asks the recipient's mail exchanger whether it would accept
+// mail for addr, without sending any message. It reports whether the server
+// accepted the recipient along with the server's reply. Temporary failures
+// such as greylisting are reported as not accepted with a 4xx reply.
+func (c *Client) VerifyRecipient(ctx context.Context, addr string) (bool, string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	domain := msgid.DomainOf(parsed.Address)
+
+	hosts, err := c.mxHosts(ctx, domain)
+	if err != nil {
+		return false, "", err
+	}
+
+	var lastErr error
+	for _, host := range hosts {
+		code, msg, err := c.probe(ctx, host, c.username, parsed.Address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reply := fmt.Sprintf("%d %s", code, msg)
+		return code >= 200 && code < 300, reply, nil
+	}
+
+	return false, "", fmt.Errorf("failed to probe mail servers for %s: %w", domain, lastErr)
+}
+
+// mxHosts returns the mail exchangers for domain in preference order, falling
+// back to the domain itself when it publishes no MX records.
+func (c *Client) mxHosts(ctx context.Context, domain string) ([]string, error) {
+	records, err := c.lookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return []string{domain}, nil
+		}
+		return nil, fmt.Errorf("failed to look up MX for %s: %w", domain, err)
+	}
+	if len(records) == 0 {
+		return []string{domain}, nil
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Pref < records[j].Pref })
+	hosts := make([]string, 0, len(records))
+	for _, mx := range records {
+		hosts = append(hosts, strings.TrimSuffix(mx.Host, "."))
+	}
+	return hosts, nil
+}
+
+// probeRecipient is the default prober. It connects to port 25 on host and
+// stops after RCPT TO, so no message is ever transferred.
+func probeRecipient(ctx context.Context, host, from, rcpt string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, "25"))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return 0, "", fmt.Errorf("failed to greet %s: %w", host, err)
+	}
+	defer client.Close()
+
+	if err := client.Hello(msgid.DomainOf(from)); err != nil {
+		return 0, "", fmt.Errorf("HELO rejected by %s: %w", host, err)
+	}
+	if err := client.Mail(from); err != nil {
+		return 0, "", fmt.Errorf("MAIL FROM rejected by %s: %w", host, err)
+	}
+
+	code, msg := 250, "OK"
+	if err := client.Rcpt(rcpt); err != nil {
+		var tpErr *textproto.Error
+		if !errors.As(err, &tpErr) {
+			return 0, "", fmt.Errorf("RCPT TO failed on %s: %w", host, err)
+		}
+		code, msg = tpErr.Code, tpErr.Msg
+	}
+	_ = client.Quit()
+
+	return code, msg, nil
+}
@@ -0,0 +1,119 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestVerifyRecipient(t *testing.T) {
+	tests := []struct {
+		name         string
+		mx           []*net.MX
+		mxErr        error
+		replies      map[string]int // host -> RCPT reply code; missing hosts fail to connect
+		wantAccepted bool
+		wantReply    string
+		wantHosts    []string
+		wantErr      bool
+	}{
+		{
+			name:         "accepted",
+			mx:           []*net.MX{{Host: "mx1.example.com.", Pref: 10}},
+			replies:      map[string]int{"mx1.example.com": 250},
+			wantAccepted: true,
+			wantReply:    "250 ok",
+			wantHosts:    []string{"mx1.example.com"},
+		},
+		{
+			name:      "rejected",
+			mx:        []*net.MX{{Host: "mx1.example.com.", Pref: 10}},
+			replies:   map[string]int{"mx1.example.com": 550},
+			wantReply: "550 ok",
+			wantHosts: []string{"mx1.example.com"},
+		},
+		{
+			name:      "greylisted",
+			mx:        []*net.MX{{Host: "mx1.example.com.", Pref: 10}},
+			replies:   map[string]int{"mx1.example.com": 451},
+			wantReply: "451 ok",
+			wantHosts: []string{"mx1.example.com"},
+		},
+		{
+			name:         "falls back to next MX in preference order",
+			mx:           []*net.MX{{Host: "mx2.example.com.", Pref: 20}, {Host: "mx1.example.com.", Pref: 10}},
+			replies:      map[string]int{"mx2.example.com": 250},
+			wantAccepted: true,
+			wantReply:    "250 ok",
+			wantHosts:    []string{"mx1.example.com", "mx2.example.com"},
+		},
+		{
+			name:         "no MX uses domain",
+			mxErr:        &net.DNSError{Err: "no such host", IsNotFound: true},
+			replies:      map[string]int{"example.com": 250},
+			wantAccepted: true,
+			wantReply:    "250 ok",
+			wantHosts:    []string{"example.com"},
+		},
+		{
+			name:    "lookup failure",
+			mxErr:   errors.New("dns timeout"),
+			wantErr: true,
+		},
+		{
+			name:      "all hosts unreachable",
+			mx:        []*net.MX{{Host: "mx1.example.com.", Pref: 10}},
+			wantHosts: []string{"mx1.example.com"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed []string
+			c := NewClient("me@icloud.com", "app-pass", ClientOptions{})
+			c.lookupMX = func(ctx context.Context, domain string) ([]*net.MX, error) {
+				return tt.mx, tt.mxErr
+			}
+			c.probe = func(ctx context.Context, host, from, rcpt string) (int, string, error) {
+				probed = append(probed, host)
+				if from != "me@icloud.com" || rcpt != "bob@example.com" {
+					t.Errorf("probe(%q, %q), want me@icloud.com -> bob@example.com", from, rcpt)
+				}
+				code, ok := tt.replies[host]
+				if !ok {
+					return 0, "", errors.New("connection refused")
+				}
+				return code, "ok", nil
+			}
+
+			accepted, reply, err := c.VerifyRecipient(context.Background(), "Bob <bob@example.com>")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if accepted != tt.wantAccepted || reply != tt.wantReply {
+				t.Errorf("got (%v, %q), want (%v, %q)", accepted, reply, tt.wantAccepted, tt.wantReply)
+			}
+			if len(probed) != len(tt.wantHosts) {
+				t.Fatalf("probed %v, want %v", probed, tt.wantHosts)
+			}
+			for i := range probed {
+				if probed[i] != tt.wantHosts[i] {
+					t.Errorf("probed %v, want %v", probed, tt.wantHosts)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRecipientInvalidAddress(t *testing.T) {
+	c := NewClient("me@icloud.com", "app-pass", ClientOptions{})
+	if _, _, err := c.VerifyRecipient(context.Background(), "not-an-address"); err == nil {
+		t.Error("expected error")
+	}
+}
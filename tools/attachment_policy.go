@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// AttachmentPolicy restricts which attachments get_attachment returns or
+// saves. Entries are MIME types ("application/pdf", or "image/*" for a whole
+// family) or filename extensions (".exe"), lowercase. An empty Allowed list
+// allows every type that is not Blocked.
+type AttachmentPolicy struct {
+	Allowed []string
+	Blocked []string
+}
+
+// genericMIMETypes say nothing about the content, so the extension's type
+// is checked as well
+var genericMIMETypes = map[string]bool{
+	"":                         true,
+	"application/octet-stream": true,
+	"application/binary":       true,
+}
+
+// check returns an error naming the policy that rejects an attachment with
+// this filename and declared MIME type, or nil if it may be downloaded.
+// Blocked entries match either the declared type or the extension, so a
+// renamed executable is still caught; when the declared type is generic,
+// the type implied by the extension is matched too.
+func (p AttachmentPolicy) check(filename, mimeType string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	types := []string{strings.ToLower(strings.TrimSpace(mimeType))}
+	if genericMIMETypes[types[0]] && ext != "" {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+			types = append(types, byExt)
+		}
+	}
+
+	if entry, ok := matchAttachmentType(p.Blocked, ext, types); ok {
+		return fmt.Errorf("attachment type %s is blocked by BLOCKED_ATTACHMENT_TYPES", entry)
+	}
+	if len(p.Allowed) > 0 {
+		if _, ok := matchAttachmentType(p.Allowed, ext, types); !ok {
+			return fmt.Errorf("attachment %s (%s) is not in ALLOWED_ATTACHMENT_TYPES", filename, mimeType)
+		}
+	}
+	return nil
+}
+
+// matchAttachmentType returns the first entry matching the extension or any
+// of the MIME types
+func matchAttachmentType(entries []string, ext string, types []string) (string, bool) {
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, ".") {
+			if ext != "" && entry == ext {
+				return entry, true
+			}
+			continue
+		}
+		for _, t := range types {
+			if t == "" {
+				continue
+			}
+			if entry == t || (strings.HasSuffix(entry, "/*") && strings.HasPrefix(t, strings.TrimSuffix(entry, "*"))) {
+				return entry, true
+			}
+		}
+	}
+	return "", false
+}
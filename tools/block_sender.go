@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// BlockSenderHandler creates a handler for moving an email to Junk and
+// blocking its sender
+func BlockSenderHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Block sender
+		result, err := client.BlockSender(ctx, folder, emailID)
+		if err != nil {
+			return operationError("failed to block sender", err), nil
+		}
+
+		// Format response
+		message := fmt.Sprintf("Moved to %s and blocked %s", result.JunkFolder, result.Sender)
+		if result.AlreadyBlocked {
+			message = fmt.Sprintf("Moved to %s; %s was already blocked", result.JunkFolder, result.Sender)
+		}
+
+		response := map[string]interface{}{
+			"success":         true,
+			"email_id":        emailID,
+			"message":         message,
+			"sender":          result.Sender,
+			"already_blocked": result.AlreadyBlocked,
+			"blocked_senders": result.Blocked,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// credentialMessages explain each CheckCredentials outcome and what to do
+var credentialMessages = map[string]string{
+	imap.CredentialsValid:    "The credentials are valid: a fresh IMAP login succeeded.",
+	imap.CredentialsRejected: "The server rejected the credentials. The app-specific password may have been revoked (or the OAuth token expired); generate a new one at appleid.apple.com, update ICLOUD_PASSWORD, and restart the server.",
+	imap.CredentialsNetwork:  "The IMAP server could not be reached, so the credentials could not be checked. This says nothing about the password; check the network and try again.",
+	imap.CredentialsError:    "The login succeeded but the server then failed; the credentials are not the problem.",
+}
+
+// CheckCredentialsHandler creates a handler that reports whether the
+// account credentials are currently accepted, telling a refused login apart
+// from a server that cannot be reached
+func CheckCredentialsHandler(checker CredentialChecker) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		check := checker.CheckCredentials(ctx)
+
+		// Format response
+		response := map[string]interface{}{
+			"status":     check.Status,
+			"valid":      check.Valid,
+			"elapsed_ms": check.ElapsedMs,
+			"message":    credentialMessages[check.Status],
+		}
+		if check.Error != "" {
+			response["error"] = check.Error
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
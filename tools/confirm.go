@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// confirmTTL is how long a confirmation token stays valid.
+const confirmTTL = 5 * time.Minute
+
+// confirmStore issues the single-use tokens that destructive operations must
+// echo back before they run. Each token is bound to the operation it was
+// issued for, so it cannot confirm a different email or folder.
+type confirmStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]confirmEntry
+}
+
+type confirmEntry struct {
+	operation string
+	expires   time.Time
+}
+
+func newConfirmStore(ttl time.Duration) *confirmStore {
+	return &confirmStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]confirmEntry),
+	}
+}
+
+// issue returns a new token for operation, a key naming exactly what will
+// be done such as "delete_email INBOX 42".
+func (s *confirmStore) issue(operation string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[token] = confirmEntry{operation: operation, expires: now.Add(s.ttl)}
+	return token
+}
+
+// redeem reports whether token was issued for operation and has not
+// expired. A token is used up by its first redeem, whatever the outcome, so
+// a mistyped operation needs a fresh token.
+func (s *confirmStore) redeem(token, operation string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[token]
+	if !ok {
+		return false
+	}
+	delete(s.entries, token)
+	return e.operation == operation && !s.now().After(e.expires)
+}
+
+// confirmationRequired is the result of a destructive call made without a
+// confirm token: nothing has been done. It adds a new token for operation,
+// and action saying what would happen and whether it can be undone, to
+// response, which describes the emails or folder at stake.
+func confirmationRequired(store *confirmStore, operation, action string, response map[string]interface{}) *mcp.CallToolResult {
+	token := store.issue(operation)
+	response["success"] = false
+	response["confirmation_required"] = true
+	response["confirm"] = token
+	response["expires_in_seconds"] = int(store.ttl.Seconds())
+	response["message"] = fmt.Sprintf("%s. To proceed, repeat the call with the same arguments and confirm=%q within %s.", action, token, store.ttl)
+
+	jsonData, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err))
+	}
+	return mcp.NewToolResultText(string(jsonData))
+}
+
+// errInvalidConfirm is the message for a confirm token that does not
+// authorize the call it came with
+const errInvalidConfirm = "confirm token is invalid, expired, or was issued for a different operation; repeat the call without confirm for a new one"
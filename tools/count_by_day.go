@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// CountByDayHandler creates a handler for counting emails per day, e.g. to
+// chart how much mail arrives
+func CountByDayHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Parse last_days (default 30)
+		lastDays := 30
+		if ld, ok := args["last_days"].(float64); ok && ld > 0 {
+			lastDays = int(ld)
+		}
+
+		// Count per day. A partial result still carries usable counts.
+		days, err := client.CountByDay(ctx, folder, lastDays)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to count by day", err), nil
+		}
+
+		// Every day of the window gets an entry so charts have no gaps
+		total := 0
+		for _, n := range days {
+			total += n
+		}
+		if days == nil {
+			days = make(map[string]int)
+		}
+		now := time.Now()
+		for d := now.AddDate(0, 0, -lastDays); !d.After(now); d = d.AddDate(0, 0, 1) {
+			day := d.Format("2006-01-02")
+			if _, ok := days[day]; !ok {
+				days[day] = 0
+			}
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folder":    folder,
+			"last_days": lastDays,
+			"days":      days,
+			"total":     total,
+		}
+
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
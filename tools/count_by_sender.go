@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// CountBySenderHandler creates a handler for tallying received emails by sender
+func CountBySenderHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Parse last_days (default 30)
+		lastDays := 30
+		if ld, ok := args["last_days"].(float64); ok && ld > 0 {
+			lastDays = int(ld)
+		}
+
+		// Parse limit (default 10)
+		limit := 10
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+		}
+
+		// Count senders. A partial result still carries usable counts.
+		senders, err := client.CountBySender(ctx, folder, lastDays, limit)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to count by sender", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folder":    folder,
+			"last_days": lastDays,
+			"senders":   senders,
+		}
+
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
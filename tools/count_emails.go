@@ -10,14 +10,14 @@ import (
 )
 
 // CountEmailsHandler creates a handler for counting emails
-func CountEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func CountEmailsHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Build filters
@@ -36,7 +36,7 @@ func CountEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolRe
 		// Count emails
 		count, err := client.CountEmails(ctx, folder, filters)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to count emails: %v", err)), nil
+			return operationError("failed to count emails", err), nil
 		}
 
 		// Format response
@@ -54,7 +54,7 @@ func CountEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolRe
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
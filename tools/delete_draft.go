@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DeleteDraftHandler creates a handler for permanently deleting a draft
+func DeleteDraftHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Delete draft
+		if err := client.DeleteDraft(ctx, emailID); err != nil {
+			return operationError("failed to delete draft", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":  true,
+			"email_id": emailID,
+			"message":  "Draft permanently deleted",
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -4,25 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// DeleteEmailHandler creates a handler for deleting emails
-func DeleteEmailHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// DeleteEmailHandler creates a handler for deleting emails. A permanent
+// delete takes two calls: the first describes the email and returns a
+// confirmation token, and only a repeat carrying that token deletes it.
+func DeleteEmailHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	confirms := newConfirmStore(confirmTTL)
+
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required email_id
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Get permanent flag (default to false)
@@ -31,10 +36,36 @@ func DeleteEmailHandler(client EmailWriter) func(context.Context, mcp.CallToolRe
 			permanent = perm
 		}
 
+		// A permanent delete must be confirmed with a token from a first call
+		if permanent {
+			operation := fmt.Sprintf("delete_email %s %s", folder, emailID)
+			token, _ := args["confirm"].(string)
+			if token == "" {
+				headers, err := client.GetHeaders(ctx, folder, emailID, []string{"From", "Subject", "Date"})
+				if err != nil {
+					return operationError("failed to read email", err), nil
+				}
+				response := map[string]interface{}{
+					"email_id": emailID,
+					"folder":   folder,
+				}
+				for _, field := range []string{"From", "Subject", "Date"} {
+					if values := headers[field]; len(values) > 0 {
+						response[strings.ToLower(field)] = values[0]
+					}
+				}
+				action := fmt.Sprintf("Email %s in '%s' will be permanently deleted. This cannot be undone", emailID, folder)
+				return confirmationRequired(confirms, operation, action, response), nil
+			}
+			if !confirms.redeem(token, operation) {
+				return invalidArgument(errInvalidConfirm), nil
+			}
+		}
+
 		// Delete email
-		err := client.DeleteEmail(ctx, folder, emailID, permanent)
+		err = client.DeleteEmail(ctx, folder, emailID, permanent)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to delete email: %v", err)), nil
+			return operationError("failed to delete email", err), nil
 		}
 
 		// Format response
@@ -51,7 +82,7 @@ func DeleteEmailHandler(client EmailWriter) func(context.Context, mcp.CallToolRe
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
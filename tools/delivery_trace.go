@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/internal/received"
+)
+
+// DeliveryTraceHandler creates a handler that reconstructs the relays an
+// email passed through from its Received headers, to diagnose delivery delays
+func DeliveryTraceHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get email ID (required)
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		headers, err := client.GetHeaders(ctx, folder, emailID, []string{"Received"})
+		if err != nil {
+			return operationError("failed to get headers", err), nil
+		}
+
+		// Hops run from the sender's side to the mailbox
+		parsed := received.Parse(headers["Received"])
+		hops := make([]map[string]interface{}, 0, len(parsed))
+		var first, last time.Time
+		for i, hop := range parsed {
+			entry := map[string]interface{}{
+				"hop": i + 1,
+				"raw": hop.Raw,
+			}
+			if hop.From != "" {
+				entry["from"] = hop.From
+			}
+			if hop.By != "" {
+				entry["by"] = hop.By
+			}
+			if hop.With != "" {
+				entry["with"] = hop.With
+			}
+			if hop.ID != "" {
+				entry["id"] = hop.ID
+			}
+			if !hop.Time.IsZero() {
+				entry["time"] = hop.Time.Format(time.RFC3339)
+				if first.IsZero() {
+					first = hop.Time
+				}
+				last = hop.Time
+				if i > 0 && !parsed[i-1].Time.IsZero() {
+					entry["delay_seconds"] = hop.Delay.Seconds()
+				}
+			}
+			hops = append(hops, entry)
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"email_id": emailID,
+			"folder":   folder,
+			"count":    len(hops),
+			"hops":     hops,
+		}
+		if !first.IsZero() {
+			response["total_delay_seconds"] = last.Sub(first).Seconds()
+		}
+		if len(hops) == 0 {
+			response["message"] = "No Received headers; the message may have been stored without passing through a mail server"
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
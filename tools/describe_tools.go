@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DescribeToolsHandler creates a handler that summarizes every registered
+// tool in one stable JSON document: parameters with their types, defaults,
+// limits, and enum values, plus the read-only/destructive/idempotent hints.
+// list returns the tool definitions as registered with the server.
+func DescribeToolsHandler(list func() []mcp.Tool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Optional single tool to describe
+		only, _ := args["tool"].(string)
+
+		described := []map[string]interface{}{}
+		for _, tool := range list() {
+			if only != "" && tool.Name != only {
+				continue
+			}
+			described = append(described, describeTool(tool))
+		}
+		if only != "" && len(described) == 0 {
+			return toolError(CodeNotFound, fmt.Sprintf("no tool named %q", only)), nil
+		}
+		sort.Slice(described, func(i, j int) bool {
+			return described[i]["name"].(string) < described[j]["name"].(string)
+		})
+
+		// Format response
+		response := map[string]interface{}{
+			"count": len(described),
+			"tools": described,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
+
+// describeTool flattens a tool definition. Each parameter keeps its JSON
+// Schema keywords (type, default, enum, minimum, maximum, minLength, items)
+// and gains its name and whether it is required; parameters are sorted by
+// name so the output is stable.
+func describeTool(tool mcp.Tool) map[string]interface{} {
+	required := map[string]bool{}
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+
+	params := make([]map[string]interface{}, 0, len(tool.InputSchema.Properties))
+	for name, schema := range tool.InputSchema.Properties {
+		param := map[string]interface{}{}
+		if props, ok := schema.(map[string]interface{}); ok {
+			for k, v := range props {
+				param[k] = v
+			}
+		}
+		param["name"] = name
+		param["required"] = required[name]
+		params = append(params, param)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i]["name"].(string) < params[j]["name"].(string)
+	})
+
+	return map[string]interface{}{
+		"name":        tool.Name,
+		"description": tool.Description,
+		"read_only":   hint(tool.Annotations.ReadOnlyHint, false),
+		"destructive": hint(tool.Annotations.DestructiveHint, true),
+		"idempotent":  hint(tool.Annotations.IdempotentHint, false),
+		"parameters":  params,
+	}
+}
+
+// hint reads an optional annotation, using the MCP default when it is unset
+func hint(b *bool, def bool) bool {
+	if b == nil {
+		return def
+	}
+	return *b
+}
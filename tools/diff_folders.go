@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DiffFoldersHandler creates a handler that compares two folders by
+// Message-ID, e.g. to verify that a migration or manual move is complete
+func DiffFoldersHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required folders
+		for _, key := range []string{"folder_a", "folder_b"} {
+			if name, _ := args[key].(string); name == "" {
+				return invalidArgument(key + " is required"), nil
+			}
+		}
+		folderA, err := resolveFolderArg(ctx, client, args, "folder_a", "")
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+		folderB, err := resolveFolderArg(ctx, client, args, "folder_b", "")
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+		if folderA == folderB {
+			return invalidArgument(fmt.Sprintf("folder_a and folder_b are both %s", folderA)), nil
+		}
+
+		// Parse limit (default 100), which caps each list but not the counts
+		limit := 100
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+			if limit > 1000 {
+				limit = 1000 // Max limit
+			}
+		}
+
+		idsA, err := client.MessageIDs(ctx, folderA)
+		if err != nil {
+			return operationError(fmt.Sprintf("failed to read Message-IDs in %s", folderA), err), nil
+		}
+		idsB, err := client.MessageIDs(ctx, folderB)
+		if err != nil {
+			return operationError(fmt.Sprintf("failed to read Message-IDs in %s", folderB), err), nil
+		}
+		onlyA, onlyB, both := diffMessageIDs(idsA, idsB)
+
+		// Format response
+		response := map[string]interface{}{
+			"folder_a":        folderA,
+			"folder_b":        folderB,
+			"only_in_a_count": len(onlyA),
+			"only_in_b_count": len(onlyB),
+			"in_both_count":   len(both),
+			"only_in_a":       capList(onlyA, limit),
+			"only_in_b":       capList(onlyB, limit),
+			"in_both":         capList(both, limit),
+			"in_sync":         len(onlyA) == 0 && len(onlyB) == 0,
+		}
+		if len(onlyA) > limit || len(onlyB) > limit || len(both) > limit {
+			response["truncated"] = true
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
+
+// diffMessageIDs splits the Message-IDs of two folders into those only in
+// a, only in b, and in both. Each list keeps the order of its folder and
+// holds every ID once, however many copies a folder has.
+func diffMessageIDs(a, b []string) (onlyA, onlyB, both []string) {
+	inA := make(map[string]bool, len(a))
+	for _, id := range a {
+		inA[id] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	onlyA, both, onlyB = []string{}, []string{}, []string{}
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, id := range a {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if inB[id] {
+			both = append(both, id)
+		} else {
+			onlyA = append(onlyA, id)
+		}
+	}
+	for _, id := range b {
+		if !seen[id] {
+			seen[id] = true
+			onlyB = append(onlyB, id)
+		}
+	}
+	return onlyA, onlyB, both
+}
+
+// capList returns at most the first n entries of ids
+func capList(ids []string, n int) []string {
+	if len(ids) > n {
+		return ids[:n]
+	}
+	return ids
+}
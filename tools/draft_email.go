@@ -11,31 +11,31 @@ import (
 )
 
 // DraftEmailHandler creates a handler for saving email drafts
-func DraftEmailHandler(imapClient EmailWriter, fromEmail string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func DraftEmailHandler(imapClient EmailWriter, fromEmail string, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required parameters
 		subject, ok := args["subject"].(string)
 		if !ok || subject == "" {
-			return mcp.NewToolResultError("subject is required"), nil
+			return invalidArgument("subject is required"), nil
 		}
 		if err := validateSubjectSize(subject); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		body, ok := args["body"].(string)
 		if !ok || body == "" {
-			return mcp.NewToolResultError("body is required"), nil
+			return invalidArgument("body is required"), nil
 		}
 		if err := validateBodySize(body); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse and validate To addresses
 		to, err := requireAddressList(args, "to")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Build draft options
@@ -44,13 +44,13 @@ func DraftEmailHandler(imapClient EmailWriter, fromEmail string) func(context.Co
 		// Parse CC addresses
 		opts.CC, err = parseAddressList(args, "cc")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse BCC addresses
 		opts.BCC, err = parseAddressList(args, "bcc")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse HTML flag
@@ -58,23 +58,32 @@ func DraftEmailHandler(imapClient EmailWriter, fromEmail string) func(context.Co
 			opts.HTML = html
 		}
 
+		// Parse priority
+		priority, _ := args["priority"].(string)
+		opts.Headers, err = priorityHeaders(priority)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
 		// Parse reply_to_id
 		if replyToID, ok := args["reply_to_id"].(string); ok && replyToID != "" {
 			opts.ReplyToID = replyToID
 
 			// Parse folder for reply source
-			if folder, ok := args["folder"].(string); ok && folder != "" {
-				opts.Folder = folder
+			folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+			if err != nil {
+				return argumentResult("failed to resolve folder", err), nil
 			}
+			opts.Folder = folder
 		}
 
 		// Save draft
 		draftID, err := imapClient.SaveDraft(ctx, fromEmail, to, subject, body, opts)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to save draft: %v", err)), nil
+			return operationError("failed to save draft", err), nil
 		}
 
-		// Build preview string
+		// Build preview string (BCC is left out, as in a sent message)
 		var preview strings.Builder
 		preview.WriteString(fmt.Sprintf("To: %s\n", strings.Join(to, ", ")))
 		if len(opts.CC) > 0 {
@@ -102,7 +111,7 @@ func DraftEmailHandler(imapClient EmailWriter, fromEmail string) func(context.Co
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/textproto"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/smtp"
+)
+
+// Error codes reported in the "code" field of tool error responses. They are
+// part of the tool contract: clients may branch on them, so never rename one.
+const (
+	CodeInvalidArgument = "invalid_argument" // bad or missing parameter
+	CodeNotFound        = "not_found"        // email, attachment, folder, or file does not exist
+	CodeProtected       = "protected_folder" // folder is in PROTECTED_FOLDERS
+	CodeConflict        = "conflict"         // an identical request is already in progress, or the folder exists
+	CodePermission      = "permission_denied"
+	CodeRejected        = "rejected"     // mail server permanently refused (5xx)
+	CodeUnavailable     = "unavailable"  // temporary server refusal (4xx) or shutting down
+	CodeRateLimited     = "rate_limited" // SEND_RATE_PER_MINUTE reached; retry later
+	CodeTimeout         = "timeout"
+	CodeCanceled        = "canceled"
+	CodeNetwork         = "network_error"
+	CodeBackend         = "backend_error" // any other mail server failure
+	CodeInternal        = "internal_error"
+)
+
+// toolErrorPayload is the JSON body of every tool error response.
+type toolErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// toolError builds an error result whose text is {"code": ..., "message": ...}.
+func toolError(code, message string) *mcp.CallToolResult {
+	data, err := json.Marshal(toolErrorPayload{Code: code, Message: message})
+	if err != nil {
+		return mcp.NewToolResultError(message)
+	}
+	return mcp.NewToolResultError(string(data))
+}
+
+// invalidArgument reports a parameter validation failure.
+func invalidArgument(message string) *mcp.CallToolResult {
+	return toolError(CodeInvalidArgument, message)
+}
+
+// InvalidArgument is invalidArgument for callers outside this package, such
+// as main routing a call to an account that is not configured.
+func InvalidArgument(message string) *mcp.CallToolResult {
+	return invalidArgument(message)
+}
+
+// operationError reports a failed operation as "<action>: <err>", with the
+// code derived from err by classifyError.
+func operationError(action string, err error) *mcp.CallToolResult {
+	return toolError(classifyError(err), fmt.Sprintf("%s: %v", action, err))
+}
+
+// argumentError is a parameter validation failure reported by a helper that
+// also calls the server, such as resolveFolderArg.
+type argumentError struct {
+	msg string
+}
+
+func (e *argumentError) Error() string { return e.msg }
+
+// argumentResult reports an error from such a helper: validation failures
+// become invalid_argument with their own message, anything else is an
+// operationError for action.
+func argumentResult(action string, err error) *mcp.CallToolResult {
+	var argErr *argumentError
+	if errors.As(err, &argErr) {
+		return invalidArgument(argErr.msg)
+	}
+	return operationError(action, err)
+}
+
+// classifyError maps an error from the IMAP/SMTP clients or the filesystem to
+// an error code.
+func classifyError(err error) string {
+	var protoErr *textproto.Error
+	var netErr net.Error
+	var rateErr *smtp.RateLimitError
+
+	switch {
+	case errors.Is(err, imap.ErrProtectedFolder):
+		return CodeProtected
+	case errors.Is(err, imap.ErrInvalidID), errors.Is(err, imap.ErrInvalidQuery), errors.Is(err, imap.ErrFolderTooDeep), errors.Is(err, smtp.ErrCharset):
+		return CodeInvalidArgument
+	case errors.Is(err, imap.ErrAlreadyExists):
+		return CodeConflict
+	case errors.Is(err, imap.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return CodePermission
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, imap.ErrPoolClosed):
+		return CodeUnavailable
+	case errors.As(err, &rateErr):
+		return CodeRateLimited
+	case errors.As(err, &protoErr):
+		if protoErr.Code >= 500 {
+			return CodeRejected
+		}
+		return CodeUnavailable
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return CodeTimeout
+		}
+		return CodeNetwork
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return CodeNetwork
+	}
+	return CodeBackend
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+
+	imappkg "github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/smtp"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "missing email", err: fmt.Errorf("email %w", imappkg.ErrNotFound), want: CodeNotFound},
+		{name: "missing file", err: &fs.PathError{Op: "open", Path: "/x", Err: fs.ErrNotExist}, want: CodeNotFound},
+		{name: "bad uid", err: fmt.Errorf("%w format: expected integer", imappkg.ErrInvalidID), want: CodeInvalidArgument},
+		{name: "folder too deep", err: fmt.Errorf("%w: a/b/c has 3 levels", imappkg.ErrFolderTooDeep), want: CodeInvalidArgument},
+		{name: "protected folder", err: fmt.Errorf("%w: INBOX", imappkg.ErrProtectedFolder), want: CodeProtected},
+		{name: "deadline", err: fmt.Errorf("failed to fetch: %w", context.DeadlineExceeded), want: CodeTimeout},
+		{name: "canceled", err: context.Canceled, want: CodeCanceled},
+		{name: "pool closed", err: imappkg.ErrPoolClosed, want: CodeUnavailable},
+		{name: "body charset", err: fmt.Errorf("%w: us-ascii has no 'é'", smtp.ErrCharset), want: CodeInvalidArgument},
+		{name: "send rate", err: fmt.Errorf("failed to send: %w", &smtp.RateLimitError{RetryAfter: time.Second}), want: CodeRateLimited},
+		{name: "smtp permanent", err: &textproto.Error{Code: 550, Msg: "mailbox unavailable"}, want: CodeRejected},
+		{name: "smtp temporary", err: &textproto.Error{Code: 451, Msg: "try again later"}, want: CodeUnavailable},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: CodeNetwork},
+		{name: "anything else", err: errors.New("NO [SERVERBUG] oops"), want: CodeBackend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mock     *MockEmailService
+		args     map[string]interface{}
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name:     "missing parameter",
+			mock:     &MockEmailService{},
+			args:     map[string]interface{}{},
+			wantCode: CodeInvalidArgument,
+			wantMsg:  "email_id is required",
+		},
+		{
+			name:     "email not found",
+			mock:     &MockEmailService{Err: fmt.Errorf("email %w", imappkg.ErrNotFound)},
+			args:     map[string]interface{}{"email_id": "42"},
+			wantCode: CodeNotFound,
+			wantMsg:  "failed to get email: email not found",
+		},
+		{
+			name:     "invalid id from server side",
+			mock:     &MockEmailService{Err: fmt.Errorf("%w format: expected integer", imappkg.ErrInvalidID)},
+			args:     map[string]interface{}{"email_id": "abc"},
+			wantCode: CodeInvalidArgument,
+		},
+		{
+			name:     "network failure",
+			mock:     &MockEmailService{Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}},
+			args:     map[string]interface{}{"email_id": "42"},
+			wantCode: CodeNetwork,
+		},
+		{
+			name:     "other backend failure",
+			mock:     newErrMock("server said no"),
+			args:     map[string]interface{}{"email_id": "42"},
+			wantCode: CodeBackend,
+			wantMsg:  "failed to get email: server said no",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GetEmailHandler(tt.mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			payload := resultErr(t, result)
+			if payload.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", payload.Code, tt.wantCode)
+			}
+			if tt.wantMsg != "" && payload.Message != tt.wantMsg {
+				t.Errorf("message = %q, want %q", payload.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestDeleteFolderProtectedCode(t *testing.T) {
+	mock := &MockEmailService{Err: fmt.Errorf("%w: INBOX (see PROTECTED_FOLDERS)", imappkg.ErrProtectedFolder)}
+	result, err := DeleteFolderHandler(mock)(context.Background(), req(map[string]interface{}{"name": "INBOX", "force": true}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if code := resultErrCode(t, result); code != CodeProtected {
+		t.Errorf("code = %q, want %q", code, CodeProtected)
+	}
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ExportEmailHandler creates a handler for archiving one email, with its
+// attachments as separate files, into a zip on disk. Attachments the policy
+// rejects are not extracted, though they remain inside the .eml.
+func ExportEmailHandler(imapClient EmailReader, defaultFolder string, policy AttachmentPolicy) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get required save_path and validate against path traversal
+		savePath, ok := args["save_path"].(string)
+		if !ok || savePath == "" {
+			return invalidArgument("save_path is required"), nil
+		}
+		if err := validateSavePath(savePath); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		parentDir := filepath.Dir(savePath)
+		if _, err := os.Stat(parentDir); os.IsNotExist(err) {
+			return invalidArgument(fmt.Sprintf("save path directory does not exist: %s", parentDir)), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		exported, err := imapClient.ExportEmail(ctx, folder, emailID, savePath, policy.check)
+		if err != nil {
+			return operationError("failed to export email", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":     true,
+			"email_id":    emailID,
+			"folder":      folder,
+			"path":        savePath,
+			"entry_count": len(exported.Entries),
+			"entries":     exported.Entries,
+			"total_bytes": exported.Size,
+		}
+		if len(exported.Skipped) > 0 {
+			response["skipped"] = exported.Skipped
+			response["warning"] = fmt.Sprintf("%d attachments were not extracted because of the attachment policy; they are still inside the .eml", len(exported.Skipped))
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// ExportFolderHandler creates a handler for backing up a folder to disk
+func ExportFolderHandler(imapClient EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required save_path and validate against path traversal
+		savePath, ok := args["save_path"].(string)
+		if !ok || savePath == "" {
+			return invalidArgument("save_path is required"), nil
+		}
+		if err := validateSavePath(savePath); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		parentDir := filepath.Dir(savePath)
+		if _, err := os.Stat(parentDir); os.IsNotExist(err) {
+			return invalidArgument(fmt.Sprintf("save path directory does not exist: %s", parentDir)), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Get format (default to mbox)
+		format, _ := args["format"].(string)
+		if format == "" {
+			format = imap.ExportMbox
+		}
+		if format != imap.ExportMbox && format != imap.ExportZip {
+			return invalidArgument("format must be one of: mbox, zip"), nil
+		}
+
+		count, size, err := imapClient.ExportFolder(ctx, folder, savePath, format)
+		if err != nil {
+			return operationError("failed to export folder", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":     true,
+			"folder":      folder,
+			"format":      format,
+			"path":        savePath,
+			"count":       count,
+			"total_bytes": size,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// FetchUnreadHandler creates a handler for an inbox sweep: it returns the
+// unread emails in a folder and, optionally, marks exactly those as read
+func FetchUnreadHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Parse limit (default 20, max 50)
+		limit := 20
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+			if limit > 50 {
+				limit = 50
+			}
+		}
+
+		markRead, _ := args["mark_read_after"].(bool)
+
+		// No date window: a sweep should see every unread message
+		unread, total, _, err := client.SearchEmails(ctx, folder, "", imap.EmailFilters{
+			UnreadOnly: true,
+			Limit:      limit,
+		})
+		if err != nil {
+			return operationError("failed to search unread emails", err), nil
+		}
+
+		// Peek so nothing is marked read until every message was retrieved
+		emails := make([]map[string]interface{}, 0, len(unread))
+		ids := make([]string, 0, len(unread))
+		for _, summary := range unread {
+			email, err := client.PeekEmail(ctx, folder, summary.ID)
+			if err != nil {
+				return operationError(fmt.Sprintf("failed to get email %s", summary.ID), err), nil
+			}
+			emails = append(emails, map[string]interface{}{
+				"id":          summary.ID,
+				"from":        summary.From,
+				"subject":     summary.Subject,
+				"date":        summary.Date,
+				"body":        email.BestBody,
+				"attachments": email.Attachments,
+			})
+			ids = append(ids, summary.ID)
+		}
+
+		// Mark the whole batch read in one store
+		marked := 0
+		if markRead && len(ids) > 0 {
+			marked, err = client.MarkReadBulk(ctx, folder, ids, true)
+			if err != nil {
+				return operationError("failed to mark emails as read", err), nil
+			}
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folder":      folder,
+			"count":       len(emails),
+			"total":       total,
+			"emails":      emails,
+			"marked_read": marked,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// FileAndReadHandler creates a handler that marks an email read and moves it
+// to a folder. The email is marked in the source first, while its UID there
+// is still valid, so it arrives in the destination already read; a failed
+// move leaves it read in the source folder.
+func FileAndReadHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required parameters
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		toFolder, ok := args["to_folder"].(string)
+		if !ok || toFolder == "" {
+			return invalidArgument("to_folder is required"), nil
+		}
+
+		// Get from_folder (default to DEFAULT_FOLDER)
+		fromFolder, err := resolveFolderArg(ctx, client, args, "from_folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		if err := client.MarkRead(ctx, fromFolder, emailID, true); err != nil {
+			return operationError("failed to mark email read", err), nil
+		}
+
+		// From here on the email stays read whatever else fails
+		if _, err := client.MoveEmail(ctx, fromFolder, toFolder, emailID, imap.MoveOptions{}); err != nil {
+			return operationError(fmt.Sprintf("email marked read but not moved to '%s'", toFolder), err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":     true,
+			"email_id":    emailID,
+			"from_folder": fromFolder,
+			"to_folder":   toFolder,
+			"marked_read": true,
+			"moved":       true,
+			"message":     fmt.Sprintf("Email marked read and moved from '%s' to '%s'", fromFolder, toFolder),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// FileEmailHandler creates a handler that files an email into a folder,
+// creating the folder first when it does not exist yet
+func FileEmailHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required parameters
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		folderName, ok := args["folder"].(string)
+		if !ok || folderName == "" {
+			return invalidArgument("folder is required"), nil
+		}
+		if err := validateFolderName(folderName); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get from_folder (default to DEFAULT_FOLDER)
+		fromFolder, err := resolveFolderArg(ctx, client, args, "from_folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Create the destination; one that already exists is used as is
+		created := true
+		toFolder, err := client.CreateFolder(ctx, folderName, "", imap.CreateFolderOptions{})
+		if errors.Is(err, imap.ErrAlreadyExists) {
+			created, toFolder = false, folderName
+		} else if err != nil {
+			return operationError("failed to create folder", err), nil
+		}
+
+		if _, err := client.MoveEmail(ctx, fromFolder, toFolder, emailID, imap.MoveOptions{}); err != nil {
+			return operationError("failed to move email", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":        true,
+			"email_id":       emailID,
+			"from_folder":    fromFolder,
+			"to_folder":      toFolder,
+			"created_folder": created,
+			"message":        fmt.Sprintf("Email filed in existing folder '%s'", toFolder),
+		}
+		if created {
+			response["message"] = fmt.Sprintf("Created folder '%s' and filed the email in it", toFolder)
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// FindDuplicatesHandler creates a handler that reports groups of duplicate
+// emails in a folder without changing anything; remove_duplicates acts on
+// the same groups
+func FindDuplicatesHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		matchHeaders := false
+		if v, ok := args["match_headers"].(bool); ok {
+			matchHeaders = v
+		}
+
+		groups, err := client.FindDuplicates(ctx, folder, matchHeaders)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to find duplicates", err), nil
+		}
+
+		duplicates := 0
+		for _, group := range groups {
+			duplicates += len(group.IDs) - 1
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folder":     folder,
+			"groups":     groups,
+			"duplicates": duplicates,
+			"message":    fmt.Sprintf("Found %d duplicate emails in %d groups", duplicates, len(groups)),
+		}
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
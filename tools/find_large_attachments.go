@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// FindLargeAttachmentsHandler creates a handler for finding the emails whose
+// attachments use the most storage
+func FindLargeAttachmentsHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folders (default to DEFAULT_FOLDER)
+		folders, err := parseStringList(args, "folders")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		if len(folders) == 0 {
+			folders = []string{defaultFolder}
+		}
+		for _, folder := range folders {
+			if err := validateFolderName(folder); err != nil {
+				return invalidArgument(fmt.Sprintf("invalid folders: %v", err)), nil
+			}
+		}
+
+		// Parse min_size_mb (default 5)
+		minSizeMB := 5.0
+		if v, ok := args["min_size_mb"].(float64); ok && v > 0 {
+			minSizeMB = v
+		}
+		if minSizeMB > 4000 {
+			return invalidArgument("min_size_mb must be at most 4000"), nil
+		}
+		minSize := uint32(minSizeMB * 1024 * 1024)
+
+		// Parse limit (default 20)
+		limit := 20
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+		}
+
+		emails, err := client.FindLargeAttachments(ctx, folders, minSize, limit)
+		if err != nil {
+			return operationError("failed to find large attachments", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folders":     folders,
+			"min_size_mb": minSizeMB,
+			"count":       len(emails),
+			"emails":      emails,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/subject"
+)
+
+// relatedCandidates is how many emails find_related examines in the window
+const relatedCandidates = 200
+
+// FindRelatedHandler creates a handler that finds the other emails in a
+// folder whose normalized subject matches a given email's, sent within a
+// window of days around it. Unlike threading by References, this still links
+// replies from clients that drop the threading headers.
+func FindRelatedHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Parse days (default 30) and limit (default 50)
+		days := 30
+		if d, ok := args["days"].(float64); ok && d > 0 {
+			days = int(d)
+		}
+		limit := 50
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+			if limit > 200 {
+				limit = 200 // Max limit
+			}
+		}
+
+		// Peek so that looking up the email does not mark it read
+		original, err := client.PeekEmail(ctx, folder, emailID)
+		if err != nil {
+			return operationError("failed to get email", err), nil
+		}
+		normalized := subject.Normalize(original.Subject)
+		if normalized == "" {
+			return invalidArgument("email has no subject to match related emails by"), nil
+		}
+
+		// Search the window around the email; the subject narrows the
+		// search server-side and is matched exactly below
+		center := original.Date
+		if center.IsZero() {
+			center = time.Now()
+		}
+		since, before := center.AddDate(0, 0, -days), center.AddDate(0, 0, days+1)
+		filters := imap.EmailFilters{Since: &since, Before: &before, Limit: relatedCandidates}
+		candidates, _, _, err := client.SearchEmails(ctx, folder, normalized, filters)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to search related emails", err), nil
+		}
+
+		related := []imap.Email{}
+		for _, e := range candidates {
+			if e.ID == original.ID || !strings.EqualFold(subject.Normalize(e.Subject), normalized) {
+				continue
+			}
+			related = append(related, e)
+			if len(related) == limit {
+				break
+			}
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"email_id": emailID,
+			"folder":   folder,
+			"subject":  normalized,
+			"days":     days,
+			"count":    len(related),
+			"emails":   related,
+		}
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
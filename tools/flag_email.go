@@ -3,66 +3,45 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // FlagEmailHandler creates a handler for flagging emails
-func FlagEmailHandler(imapClient EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func FlagEmailHandler(imapClient EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required email_id
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 
 		// Get required flag type
 		flagType, ok := args["flag"].(string)
 		if !ok || flagType == "" {
-			return mcp.NewToolResultError("flag is required"), nil
+			return invalidArgument("flag is required"), nil
 		}
 
-		// Validate flag type
-		validFlags := map[string]bool{
-			"follow-up": true,
-			"important": true,
-			"deadline":  true,
-			"none":      true,
-		}
-		if !validFlags[flagType] {
-			return mcp.NewToolResultError("flag must be one of: follow-up, important, deadline, none"), nil
-		}
-
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get optional color, then validate both
+		color, _ := args["color"].(string)
+		if err := validateFlag(flagType, color); err != nil {
+			return invalidArgument(err.Error()), nil
 		}
 
-		// Get optional color
-		color, _ := args["color"].(string)
-		if color != "" {
-			// Validate color
-			validColors := map[string]bool{
-				"red":    true,
-				"orange": true,
-				"yellow": true,
-				"green":  true,
-				"blue":   true,
-				"purple": true,
-			}
-			if !validColors[color] {
-				return mcp.NewToolResultError("color must be one of: red, orange, yellow, green, blue, purple"), nil
-			}
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Flag the email
-		err := imapClient.FlagEmail(ctx, folder, emailID, flagType, color)
+		err = imapClient.FlagEmail(ctx, folder, emailID, flagType, color)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to flag email: %v", err)), nil
+			return operationError("failed to flag email", err), nil
 		}
 
 		// Format response
@@ -88,11 +67,44 @@ func FlagEmailHandler(imapClient EmailWriter) func(context.Context, mcp.CallTool
 		}
 		response["message"] = message
 
+		if returnEmail, _ := args["return_email"].(bool); returnEmail {
+			attachUpdatedEmail(ctx, imapClient, folder, emailID, response)
+		}
+
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
 	}
 }
+
+// validateFlag checks a flag type and optional color as flag_email and
+// flag_search accept them
+func validateFlag(flagType, color string) error {
+	validFlags := map[string]bool{
+		"follow-up": true,
+		"important": true,
+		"deadline":  true,
+		"none":      true,
+	}
+	if !validFlags[flagType] {
+		return errors.New("flag must be one of: follow-up, important, deadline, none")
+	}
+
+	if color != "" {
+		validColors := map[string]bool{
+			"red":    true,
+			"orange": true,
+			"yellow": true,
+			"green":  true,
+			"blue":   true,
+			"purple": true,
+		}
+		if !validColors[color] {
+			return errors.New("color must be one of: red, orange, yellow, green, blue, purple")
+		}
+	}
+	return nil
+}
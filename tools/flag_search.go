@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// FlagSearchHandler creates a handler that flags every email matching a
+// search, e.g. all mail about one project, with a single bulk store
+func FlagSearchHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required flag type and optional color
+		flagType, ok := args["flag"].(string)
+		if !ok || flagType == "" {
+			return invalidArgument("flag is required"), nil
+		}
+		color, _ := args["color"].(string)
+		if err := validateFlag(flagType, color); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Build filters like search_emails, with a larger limit
+		query, _ := args["query"].(string)
+		filters := imap.EmailFilters{
+			LastDays: 30,
+			Limit:    200,
+		}
+		if lastDays, ok := args["last_days"].(float64); ok && lastDays > 0 {
+			filters.LastDays = int(lastDays)
+		}
+		if limit, ok := args["limit"].(float64); ok && limit > 0 {
+			filters.Limit = int(limit)
+			if filters.Limit > 1000 {
+				filters.Limit = 1000 // Max limit
+			}
+		}
+		if unreadOnly, ok := args["unread_only"].(bool); ok {
+			filters.UnreadOnly = unreadOnly
+		}
+		if from, ok := args["from"].(string); ok && from != "" {
+			filters.From = from
+		}
+		now := time.Now()
+		if sinceStr, ok := args["since"].(string); ok && sinceStr != "" {
+			t, err := parseDateArg(sinceStr, now)
+			if err != nil {
+				return invalidArgument(fmt.Sprintf("invalid since format: %v", err)), nil
+			}
+			filters.Since = &t
+			filters.LastDays = 0 // Clear last_days when since is provided
+		}
+		if beforeStr, ok := args["before"].(string); ok && beforeStr != "" {
+			t, err := parseDateArg(beforeStr, now)
+			if err != nil {
+				return invalidArgument(fmt.Sprintf("invalid before format: %v", err)), nil
+			}
+			filters.Before = &t
+		}
+		if raw, ok := args["raw_query"].(string); ok && raw != "" {
+			if _, err := imap.ParseRawQuery(raw); err != nil {
+				return invalidArgument(err.Error()), nil
+			}
+			filters.RawQuery = raw
+		}
+
+		// Only UIDs are needed, so nothing is fetched
+		ids, total, _, err := client.SearchUIDs(ctx, folder, query, filters)
+		if err != nil {
+			return operationError("failed to search emails", err), nil
+		}
+
+		response := map[string]interface{}{
+			"success": true,
+			"folder":  folder,
+			"flag":    flagType,
+			"flagged": 0,
+			"total":   total,
+		}
+		if color != "" {
+			response["color"] = color
+		}
+
+		if len(ids) == 0 {
+			response["message"] = fmt.Sprintf("Nothing to flag: no emails in '%s' match the search", folder)
+			jsonData, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(jsonData)), nil
+		}
+
+		if err := client.FlagEmailBulk(ctx, folder, ids, flagType, color); err != nil {
+			return operationError("failed to flag emails", err), nil
+		}
+
+		response["flagged"] = len(ids)
+		response["email_ids"] = ids
+		if flagType == "none" {
+			response["message"] = fmt.Sprintf("Removed flags from %d emails", len(ids))
+		} else {
+			response["message"] = fmt.Sprintf("Flagged %d emails as %s", len(ids), flagType)
+		}
+		if total > len(ids) {
+			response["warning"] = fmt.Sprintf("%d emails matched but only the most recent %d were flagged; raise limit or narrow the search", total, len(ids))
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
 )
 
 // CreateFolderHandler creates a handler for creating a new folder
@@ -16,29 +19,38 @@ func CreateFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 		// Get folder name (required)
 		name, ok := args["name"].(string)
 		if !ok || name == "" {
-			return mcp.NewToolResultError("name parameter is required"), nil
+			return invalidArgument("name parameter is required"), nil
 		}
 		if err := validateFolderName(name); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Get parent folder (optional)
 		parent, _ := args["parent"].(string)
 		if parent != "" {
 			if err := validateFolderName(parent); err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("invalid parent: %v", err)), nil
+				return invalidArgument(fmt.Sprintf("invalid parent: %v", err)), nil
 			}
 		}
 
-		// Construct full folder path
-		folderPath := name
-		if parent != "" {
-			folderPath = parent + "/" + name
+		// Subscribe the new folder unless asked not to
+		opts := imap.CreateFolderOptions{}
+		if subscribe, ok := args["subscribe"].(bool); ok {
+			opts.SkipSubscribe = !subscribe
+		}
+
+		// Optional special-use role, e.g. archive
+		if use, ok := args["use"].(string); ok && use != "" {
+			if !slices.Contains(imap.SpecialUses(), use) {
+				return invalidArgument(fmt.Sprintf("use must be one of: %s", strings.Join(imap.SpecialUses(), ", "))), nil
+			}
+			opts.Use = use
 		}
 
-		// Create the folder
-		if err := client.CreateFolder(ctx, name, parent); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to create folder: %v", err)), nil
+		// Create the folder; the server's delimiter decides the full path
+		folderPath, err := client.CreateFolder(ctx, name, parent, opts)
+		if err != nil {
+			return operationError("failed to create folder", err), nil
 		}
 
 		// Format response
@@ -46,12 +58,16 @@ func CreateFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 			"success":     true,
 			"folder_name": name,
 			"path":        folderPath,
+			"subscribed":  !opts.SkipSubscribe,
 			"message":     fmt.Sprintf("Folder '%s' created successfully", folderPath),
 		}
+		if opts.Use != "" {
+			response["use"] = opts.Use
+		}
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
@@ -59,17 +75,19 @@ func CreateFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 }
 
 // DeleteFolderHandler creates a handler for deleting a folder
-func DeleteFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func DeleteFolderHandler(client EmailService) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	confirms := newConfirmStore(confirmTTL)
+
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get folder name (required)
 		name, ok := args["name"].(string)
 		if !ok || name == "" {
-			return mcp.NewToolResultError("name parameter is required"), nil
+			return invalidArgument("name parameter is required"), nil
 		}
 		if err := validateFolderName(name); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Get force flag (optional, default false)
@@ -78,6 +96,27 @@ func DeleteFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 			force = forceArg
 		}
 
+		// A forced delete must be confirmed with a token from a first call
+		if force {
+			operation := "delete_folder " + name
+			token, _ := args["confirm"].(string)
+			if token == "" {
+				status, err := client.FolderStatus(ctx, name)
+				if err != nil {
+					return operationError("failed to examine folder", err), nil
+				}
+				response := map[string]interface{}{
+					"folder_name": name,
+					"email_count": status.Messages,
+				}
+				action := fmt.Sprintf("Folder '%s' and the %d emails in it will be deleted. This cannot be undone", name, status.Messages)
+				return confirmationRequired(confirms, operation, action, response), nil
+			}
+			if !confirms.redeem(token, operation) {
+				return invalidArgument(errInvalidConfirm), nil
+			}
+		}
+
 		// Delete the folder
 		wasEmpty, emailCount, err := client.DeleteFolder(ctx, name, force)
 		if err != nil {
@@ -93,7 +132,7 @@ func DeleteFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 				jsonData, _ := json.MarshalIndent(response, "", "  ")
 				return mcp.NewToolResultText(string(jsonData)), nil
 			}
-			return mcp.NewToolResultError(fmt.Sprintf("failed to delete folder: %v", err)), nil
+			return operationError("failed to delete folder", err), nil
 		}
 
 		// Format success response
@@ -110,7 +149,58 @@ func DeleteFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolR
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
+
+// SubscribeFolderHandler creates a handler for subscribing to a folder
+func SubscribeFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return subscriptionHandler(client.Subscribe, true)
+}
+
+// UnsubscribeFolderHandler creates a handler for unsubscribing from a folder
+func UnsubscribeFolderHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return subscriptionHandler(client.Unsubscribe, false)
+}
+
+// subscriptionHandler is shared by subscribe_folder and unsubscribe_folder,
+// which differ only in the call made and the wording of the result
+func subscriptionHandler(change func(ctx context.Context, folder string) error, subscribe bool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	action, done := "unsubscribe from", "Unsubscribed from"
+	if subscribe {
+		action, done = "subscribe to", "Subscribed to"
+	}
+
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder name (required)
+		name, ok := args["name"].(string)
+		if !ok || name == "" {
+			return invalidArgument("name parameter is required"), nil
+		}
+		if err := validateFolderName(name); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		if err := change(ctx, name); err != nil {
+			return operationError("failed to "+action+" folder", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":     true,
+			"folder_name": name,
+			"subscribed":  subscribe,
+			"message":     fmt.Sprintf("%s folder '%s'", done, name),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
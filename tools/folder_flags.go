@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// FolderFlagsHandler creates a handler for reporting a folder's flag vocabulary
+func FolderFlagsHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		flags, permanentFlags, err := client.FolderFlags(ctx, folder)
+		if err != nil {
+			return operationError("failed to get folder flags", err), nil
+		}
+
+		// "\*" means the server keeps keywords it hasn't seen before
+		customKeywords := false
+		for _, f := range permanentFlags {
+			if f == `\*` {
+				customKeywords = true
+				break
+			}
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"folder":                  folder,
+			"flags":                   flags,
+			"permanent_flags":         permanentFlags,
+			"custom_keywords_allowed": customKeywords,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
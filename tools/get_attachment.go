@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -11,45 +13,52 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// GetAttachmentHandler creates a handler for downloading email attachments
-func GetAttachmentHandler(imapClient EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// GetAttachmentHandler creates a handler for downloading email attachments.
+// Attachments rejected by policy are neither returned nor saved.
+func GetAttachmentHandler(imapClient EmailReader, defaultFolder string, policy AttachmentPolicy) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required email_id
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 		if err := validateEmailID(emailID); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Get required filename
 		filename, ok := args["filename"].(string)
 		if !ok || filename == "" {
-			return mcp.NewToolResultError("filename is required"), nil
+			return invalidArgument("filename is required"), nil
 		}
 		if err := validateFilename(filename); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Get optional save_path and validate against path traversal
 		savePath, _ := args["save_path"].(string)
 		if err := validateSavePath(savePath); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
+		// Gzip inline content before encoding (ignored with save_path)
+		compress, _ := args["compress"].(bool)
+
 		// Get attachment from IMAP
 		attachment, err := imapClient.GetAttachment(ctx, folder, emailID, filename)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get attachment: %v", err)), nil
+			return operationError("failed to get attachment", err), nil
+		}
+		if err := policy.check(attachment.Filename, attachment.MIMEType); err != nil {
+			return toolError(CodePermission, err.Error()), nil
 		}
 
 		// Build response
@@ -64,28 +73,48 @@ func GetAttachmentHandler(imapClient EmailReader) func(context.Context, mcp.Call
 			// Validate save path - check parent directory exists
 			parentDir := filepath.Dir(savePath)
 			if _, err := os.Stat(parentDir); os.IsNotExist(err) {
-				return mcp.NewToolResultError(fmt.Sprintf("save path directory does not exist: %s", parentDir)), nil
+				return invalidArgument(fmt.Sprintf("save path directory does not exist: %s", parentDir)), nil
 			}
 
 			// Write file
 			if err := os.WriteFile(savePath, attachment.Content, 0600); err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("failed to save attachment: %v", err)), nil
+				return operationError("failed to save attachment", err), nil
 			}
 
 			response["path"] = savePath
 			response["saved"] = true
 		} else {
-			// Return base64 encoded content
-			encoded := base64.StdEncoding.EncodeToString(attachment.Content)
-			response["data"] = encoded
+			// Return base64 encoded content, optionally gzipped first
+			content, encoding := attachment.Content, "base64"
+			if compress {
+				if content, err = gzipBytes(content); err != nil {
+					return toolError(CodeInternal, fmt.Sprintf("failed to compress attachment: %v", err)), nil
+				}
+				encoding = "gzip+base64"
+			}
+			response["data"] = base64.StdEncoding.EncodeToString(content)
+			response["encoding"] = encoding
 			response["saved"] = false
 		}
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
 	}
 }
+
+// gzipBytes compresses data with gzip at the default level
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -6,35 +6,120 @@ import (
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/quotes"
 )
 
-// GetEmailHandler creates a handler for getting full email content
-func GetEmailHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// GetEmailHandler creates a handler for getting full email content. On
+// request, attachments of at most inlineMax bytes that policy allows are
+// returned inline.
+func GetEmailHandler(client EmailReader, defaultFolder string, inlineMax int64, policy AttachmentPolicy) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required email_id
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
+		bySeq, err := bySequenceArg(args)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Optional preview length for the body
+		bodyMaxBytes := 0
+		if v, ok := args["body_max_bytes"].(float64); ok {
+			if v < 1 || v != float64(int(v)) {
+				return invalidArgument("body_max_bytes must be a positive integer"), nil
+			}
+			bodyMaxBytes = int(v)
+		}
+
+		// Optionally return small attachments inline
+		inline, _ := args["include_small_attachments"].(bool)
+		if inline && bodyMaxBytes > 0 {
+			return invalidArgument("include_small_attachments cannot be combined with body_max_bytes"), nil
+		}
+
+		// With headers, fetch just those fields and skip the body
+		fields, err := parseStringList(args, "headers")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		if len(fields) > 0 {
+			if bySeq {
+				return invalidArgument("headers cannot be combined with id_type=seq"), nil
+			}
+			if bodyMaxBytes > 0 || inline {
+				return invalidArgument("headers cannot be combined with body_max_bytes or include_small_attachments"), nil
+			}
+			for _, field := range fields {
+				if err := validateHeaderName(field); err != nil {
+					return invalidArgument(err.Error()), nil
+				}
+			}
+
+			headers, err := client.GetHeaders(ctx, folder, emailID, fields)
+			if err != nil {
+				return operationError("failed to get email headers", err), nil
+			}
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+			response := map[string]interface{}{
+				"id":      emailID,
+				"folder":  folder,
+				"headers": headers,
+			}
+			jsonData, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(jsonData)), nil
 		}
 
-		// Get full email
-		email, err := client.GetEmail(ctx, folder, emailID)
+		// Get full email; a sequence number is resolved to the message's UID
+		var email *imap.Email
+		if bySeq || bodyMaxBytes > 0 || inline {
+			opts := imap.FetchOptions{BySequence: bySeq, BodyMaxBytes: bodyMaxBytes}
+			if inline {
+				opts.InlineAttachmentMax = inlineMax
+			}
+			email, err = client.FetchEmail(ctx, folder, emailID, opts)
+		} else {
+			email, err = client.GetEmail(ctx, folder, emailID)
+		}
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get email: %v", err)), nil
+			return operationError("failed to get email", err), nil
+		}
+
+		// Attachment restrictions apply to inline content too
+		for i, att := range email.Attachments {
+			if att.Content != "" && policy.check(att.Filename, att.MIMEType) != nil {
+				email.Attachments[i].Content = ""
+			}
+		}
+
+		// Optionally join format=flowed soft line breaks
+		if unfold, ok := args["unfold_flowed"].(bool); ok && unfold {
+			email.UnfoldFlowed()
+		}
+
+		// Optionally cut the quoted history and signature from the text
+		if trim, ok := args["trim_quotes"].(bool); ok && trim {
+			email.BodyPlain = quotes.Trim(email.BodyPlain)
+			email.BestBody = quotes.Trim(email.BestBody)
 		}
 
 		// Format response
 		jsonData, err := json.MarshalIndent(email, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
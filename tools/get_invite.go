@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GetInviteHandler creates a handler for extracting a calendar invitation from an email
+func GetInviteHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Get full email
+		email, err := client.GetEmail(ctx, folder, emailID)
+		if err != nil {
+			return operationError("failed to get email", err), nil
+		}
+
+		if email.CalendarEvent == nil {
+			return toolError(CodeNotFound, "email does not contain a calendar invitation"), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"email_id": emailID,
+			"subject":  email.Subject,
+			"event":    email.CalendarEvent,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
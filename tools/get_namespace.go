@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GetNamespaceHandler creates a handler for reporting the personal namespace
+// prefix and hierarchy delimiter used to build nested folder paths
+func GetNamespaceHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		prefix, delimiter, err := client.Namespace(ctx)
+		if err != nil {
+			return operationError("failed to get namespace", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"prefix":    prefix,
+			"delimiter": delimiter,
+		}
+		if delimiter != "" {
+			response["example"] = prefix + "Parent" + delimiter + "Child"
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -1,15 +1,22 @@
 package tools
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	imappkg "github.com/rgabriel/mcp-icloud-email/imap"
+	smtppkg "github.com/rgabriel/mcp-icloud-email/smtp"
 )
 
 // req builds a mcp.CallToolRequest with the given arguments.
@@ -41,20 +48,55 @@ func resultJSON(t *testing.T, result *mcp.CallToolResult) map[string]interface{}
 	return m
 }
 
+// resultText returns the raw text content of a successful result.
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	if result.IsError {
+		t.Fatalf("expected success but got error: %+v", result.Content)
+	}
+	if len(result.Content) == 0 {
+		t.Fatal("expected content but got none")
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("expected TextContent, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
 // resultErrText extracts the error message from an error result.
 func resultErrText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	return resultErr(t, result).Message
+}
+
+// resultErrCode returns the code of a structured error result.
+func resultErrCode(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	return resultErr(t, result).Code
+}
+
+// resultErr decodes the {code, message} payload of an error result.
+func resultErr(t *testing.T, result *mcp.CallToolResult) toolErrorPayload {
 	t.Helper()
 	if !result.IsError {
 		t.Fatalf("expected error result but got success: %+v", result.Content)
 	}
 	if len(result.Content) == 0 {
-		return ""
+		t.Fatal("error result has no content")
 	}
 	text, ok := result.Content[0].(mcp.TextContent)
 	if !ok {
 		t.Fatalf("expected TextContent, got %T", result.Content[0])
 	}
-	return text.Text
+	var payload toolErrorPayload
+	if err := json.Unmarshal([]byte(text.Text), &payload); err != nil {
+		t.Fatalf("error result is not a structured payload: %q", text.Text)
+	}
+	if payload.Code == "" {
+		t.Fatalf("error result has no code: %q", text.Text)
+	}
+	return payload
 }
 
 // --- ListFolders ---
@@ -80,7 +122,7 @@ func TestListFoldersHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := ListFoldersHandler(tt.mock)
+			handler := ListFoldersHandler(tt.mock, nil)
 			result, err := handler(context.Background(), req(nil))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -99,6 +141,42 @@ func TestListFoldersHandler(t *testing.T) {
 	}
 }
 
+func TestListFoldersHandlerFilter(t *testing.T) {
+	folders := []string{"INBOX", "Sent Messages", "Archive", "Archive/2023", "Archive/2024", "Notes"}
+
+	tests := []struct {
+		name   string
+		filter []string
+		want   string
+	}{
+		{name: "include patterns", filter: []string{"INBOX", "Archive*"}, want: "INBOX,Archive"},
+		{name: "exclude patterns", filter: []string{"!Archive/*", "!Notes"}, want: "INBOX,Sent Messages,Archive"},
+		{name: "include then exclude", filter: []string{"Archive*", "Archive/*", "!Archive/2023"}, want: "Archive,Archive/2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Folders: folders}
+			result, err := ListFoldersHandler(mock, tt.filter)(context.Background(), req(nil))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+
+			var got []string
+			for _, f := range data["folders"].([]interface{}) {
+				got = append(got, f.(string))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("folders = %v, want %s", got, tt.want)
+			}
+			if int(data["count"].(float64)) != len(got) {
+				t.Errorf("count = %v, want %d", data["count"], len(got))
+			}
+		})
+	}
+}
+
 // --- GetEmail ---
 
 func TestGetEmailHandler(t *testing.T) {
@@ -146,11 +224,30 @@ func TestGetEmailHandler(t *testing.T) {
 			wantErr: true,
 			errMsg:  "failed to get email",
 		},
+		{
+			name: "by sequence number",
+			args: map[string]interface{}{"email_id": "7", "id_type": "seq"},
+			mock: &MockEmailService{Email: sampleEmail},
+		},
+		{
+			name:    "invalid id_type",
+			args:    map[string]interface{}{"email_id": "123", "id_type": "msn"},
+			mock:    &MockEmailService{},
+			wantErr: true,
+			errMsg:  "id_type must be one of",
+		},
+		{
+			name:    "sequence number with headers",
+			args:    map[string]interface{}{"email_id": "7", "id_type": "seq", "headers": "Subject"},
+			mock:    &MockEmailService{},
+			wantErr: true,
+			errMsg:  "headers cannot be combined",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := GetEmailHandler(tt.mock)
+			handler := GetEmailHandler(tt.mock, "INBOX", 0, AttachmentPolicy{})
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -166,6 +263,13 @@ func TestGetEmailHandler(t *testing.T) {
 			if data["id"] != "123" {
 				t.Errorf("id = %v, want 123", data["id"])
 			}
+			wantMethod := "GetEmail"
+			if tt.args["id_type"] == "seq" {
+				wantMethod = "FetchEmail"
+			}
+			if tt.mock.LastMethod != wantMethod || tt.mock.LastFetchOpts.BySequence != (wantMethod == "FetchEmail") {
+				t.Errorf("called %s with %+v, want %s", tt.mock.LastMethod, tt.mock.LastFetchOpts, wantMethod)
+			}
 			if tt.args["folder"] != nil {
 				if tt.mock.LastFolder != tt.args["folder"].(string) {
 					t.Errorf("folder = %q, want %q", tt.mock.LastFolder, tt.args["folder"])
@@ -179,6 +283,383 @@ func TestGetEmailHandler(t *testing.T) {
 	}
 }
 
+func TestGetEmailHandlerUnfoldFlowed(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{name: "default keeps wraps", args: map[string]interface{}{"email_id": "123"}, want: "Soft \nwrap\n"},
+		{name: "unfold", args: map[string]interface{}{"email_id": "123", "unfold_flowed": true}, want: "Soft wrap\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Email: &imappkg.Email{ID: "123", BodyPlain: "Soft \nwrap\n", Flowed: true}}
+			result, err := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+			if data["bodyPlain"] != tt.want {
+				t.Errorf("bodyPlain = %q, want %q", data["bodyPlain"], tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEmailHandlerTrimQuotes(t *testing.T) {
+	body := "Sounds good, see you Tuesday.\n\n-- \nBob\n\nOn Mon, 1 Jan 2024, Alice <alice@example.com> wrote:\n> Are we still on?\n"
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "123", BodyPlain: body, BestBody: body}}
+	args := map[string]interface{}{"email_id": "123", "trim_quotes": true}
+	result, err := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	data := resultJSON(t, result)
+	for _, key := range []string{"bodyPlain", "bestBody"} {
+		if data[key] != "Sounds good, see you Tuesday." {
+			t.Errorf("%s = %q, want only the new text", key, data[key])
+		}
+	}
+}
+
+func TestGetEmailHandlerBodyMaxBytes(t *testing.T) {
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "123", BodyPlain: "Long", Truncated: true}}
+	handler := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})
+
+	result, err := handler(context.Background(), req(map[string]interface{}{"email_id": "123", "body_max_bytes": 512.0}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if data := resultJSON(t, result); data["truncated"] != true {
+		t.Errorf("truncated = %v, want true", data["truncated"])
+	}
+	if mock.LastMethod != "FetchEmail" || mock.LastFetchOpts.BodyMaxBytes != 512 || mock.LastFetchOpts.BySequence {
+		t.Errorf("called %s with %+v, want FetchEmail with BodyMaxBytes 512", mock.LastMethod, mock.LastFetchOpts)
+	}
+
+	// Without it the full email is fetched
+	mock.Email.Truncated = false
+	result, _ = handler(context.Background(), req(map[string]interface{}{"email_id": "123"}))
+	if data := resultJSON(t, result); data["truncated"] != nil {
+		t.Errorf("truncated = %v on a full fetch", data["truncated"])
+	}
+	if mock.LastMethod != "GetEmail" {
+		t.Errorf("called %s, want GetEmail", mock.LastMethod)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"email_id": "123", "body_max_bytes": 0.0},
+		{"email_id": "123", "body_max_bytes": 10.5},
+		{"email_id": "123", "body_max_bytes": 100.0, "headers": []interface{}{"List-Id"}},
+	} {
+		result, _ := handler(context.Background(), req(args))
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("%v: code = %q, want %q", args, code, CodeInvalidArgument)
+		}
+	}
+}
+
+func TestGetEmailHandlerIncludeSmallAttachments(t *testing.T) {
+	newMock := func() *MockEmailService {
+		return &MockEmailService{Email: &imappkg.Email{ID: "123", Attachments: []imappkg.Attachment{
+			{Filename: "dot.png", Size: 14, MIMEType: "image/png", Content: "dGlueSBQTkcgYnl0ZXM="},
+			{Filename: "run.exe", Size: 20, MIMEType: "application/octet-stream", Content: "TVqQAAMAAAAEAAAA"},
+			{Filename: "big.pdf", Size: 5_000_000},
+		}}}
+	}
+	policy := AttachmentPolicy{Blocked: []string{".exe"}}
+
+	mock := newMock()
+	result, err := GetEmailHandler(mock, "INBOX", 100*1024, policy)(context.Background(), req(map[string]interface{}{
+		"email_id":                  "123",
+		"include_small_attachments": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if mock.LastMethod != "FetchEmail" || mock.LastFetchOpts.InlineAttachmentMax != 100*1024 {
+		t.Errorf("called %s with %+v, want FetchEmail with InlineAttachmentMax 102400", mock.LastMethod, mock.LastFetchOpts)
+	}
+	attachments := resultJSON(t, result)["attachments"].([]interface{})
+	content := func(i int) interface{} { return attachments[i].(map[string]interface{})["content"] }
+	if content(0) != "dGlueSBQTkcgYnl0ZXM=" {
+		t.Errorf("dot.png content = %v, want it inlined", content(0))
+	}
+	if content(1) != nil {
+		t.Errorf("run.exe content = %v, want it withheld by the attachment policy", content(1))
+	}
+	if content(2) != nil {
+		t.Errorf("big.pdf content = %v, want metadata only", content(2))
+	}
+
+	// Off by default
+	mock = newMock()
+	mock.Email.Attachments = mock.Email.Attachments[2:]
+	if _, err := GetEmailHandler(mock, "INBOX", 100*1024, policy)(context.Background(), req(map[string]interface{}{"email_id": "123"})); err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if mock.LastMethod != "GetEmail" {
+		t.Errorf("called %s, want GetEmail", mock.LastMethod)
+	}
+
+	result, _ = GetEmailHandler(newMock(), "INBOX", 100*1024, policy)(context.Background(), req(map[string]interface{}{
+		"email_id":                  "123",
+		"include_small_attachments": true,
+		"body_max_bytes":            100.0,
+	}))
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("with body_max_bytes: code = %q, want %q", code, CodeInvalidArgument)
+	}
+}
+
+func TestGetEmailHandlerHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		wantFields []string
+		wantErr    string
+	}{
+		{
+			name:       "array of fields",
+			args:       map[string]interface{}{"email_id": "123", "headers": []interface{}{"List-Id", "X-Mailer"}},
+			wantFields: []string{"List-Id", "X-Mailer"},
+		},
+		{
+			name:       "single field as string",
+			args:       map[string]interface{}{"email_id": "123", "headers": "List-Id"},
+			wantFields: []string{"List-Id"},
+		},
+		{
+			name:    "invalid field name",
+			args:    map[string]interface{}{"email_id": "123", "headers": []interface{}{"List Id"}},
+			wantErr: "invalid header name",
+		},
+		{
+			name:    "wrong type",
+			args:    map[string]interface{}{"email_id": "123", "headers": 5.0},
+			wantErr: "headers must be a string or array of strings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Headers: map[string][]string{"List-Id": {"<dev.example.com>"}}}
+			result, err := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want it to contain %q", msg, tt.wantErr)
+				}
+				if mock.CallCount != 0 {
+					t.Errorf("backend called %d times", mock.CallCount)
+				}
+				return
+			}
+			if mock.LastMethod != "GetHeaders" {
+				t.Errorf("called %s, want GetHeaders only", mock.LastMethod)
+			}
+			if strings.Join(mock.LastFields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("fields = %v, want %v", mock.LastFields, tt.wantFields)
+			}
+			data := resultJSON(t, result)
+			headers, _ := data["headers"].(map[string]interface{})
+			if _, ok := headers["List-Id"]; !ok || len(headers) != 1 {
+				t.Errorf("headers = %v", data["headers"])
+			}
+			if _, ok := data["bodyPlain"]; ok {
+				t.Error("body returned with headers")
+			}
+		})
+	}
+}
+
+// --- DeliveryTrace ---
+
+func TestDeliveryTraceHandler(t *testing.T) {
+	mock := &MockEmailService{Headers: map[string][]string{"Received": {
+		"from relay.example.net by mx.icloud.com with ESMTPS id 4TD9; Mon, 15 Jan 2024 10:05:00 +0000",
+		"from [192.0.2.10] by relay.example.net with ESMTPSA; Mon, 15 Jan 2024 10:00:00 +0000",
+	}}}
+	result, err := DeliveryTraceHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "9"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if len(mock.LastFields) != 1 || mock.LastFields[0] != "Received" {
+		t.Errorf("fetched headers %v, want only Received", mock.LastFields)
+	}
+
+	data := resultJSON(t, result)
+	hops := data["hops"].([]interface{})
+	if len(hops) != 2 || data["total_delay_seconds"] != float64(300) {
+		t.Fatalf("hops = %v, total = %v", hops, data["total_delay_seconds"])
+	}
+	first, second := hops[0].(map[string]interface{}), hops[1].(map[string]interface{})
+	if first["by"] != "relay.example.net" || first["delay_seconds"] != nil {
+		t.Errorf("first hop = %v", first)
+	}
+	if second["by"] != "mx.icloud.com" || second["delay_seconds"] != float64(300) {
+		t.Errorf("second hop = %v", second)
+	}
+
+	// No Received headers is a result, not an error
+	result, _ = DeliveryTraceHandler(&MockEmailService{}, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "9"}))
+	if data := resultJSON(t, result); data["count"] != float64(0) || data["message"] == nil {
+		t.Errorf("empty trace = %v", data)
+	}
+}
+
+// --- TriageEmail ---
+
+func TestTriageEmailHandler(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+	tests := []struct {
+		name       string
+		email      *imappkg.Email
+		wantThread bool
+		wantPrev   string
+	}{
+		{
+			name: "new conversation",
+			email: &imappkg.Email{
+				ID: "12", From: "alice@example.com", Subject: "Lunch?", Unread: true, Size: 4096,
+				BestBody: "Are you free\n\n  tomorrow?", Attachments: []imappkg.Attachment{{Filename: "menu.pdf"}},
+			},
+			wantPrev: "Are you free tomorrow?",
+		},
+		{
+			name:       "reply by subject",
+			email:      &imappkg.Email{ID: "12", Subject: "RE: Lunch?", Answered: true, Flagged: true, BestBody: long},
+			wantThread: true,
+			wantPrev:   strings.TrimSpace(long[:157]) + "...",
+		},
+		{
+			name:       "reply by references",
+			email:      &imappkg.Email{ID: "12", Subject: "Lunch?", References: []string{"<1@x>"}},
+			wantThread: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Email: tt.email}
+			result, err := TriageEmailHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "12"}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+
+			// Only a peek: nothing that would set \Seen
+			if mock.LastMethod != "PeekEmail" || mock.CallCount != 1 {
+				t.Errorf("calls = %d, last %s; want a single PeekEmail", mock.CallCount, mock.LastMethod)
+			}
+
+			wantKeys := []string{"id", "folder", "from", "subject", "date", "unread", "answered", "flagged", "size", "attachment_count", "preview", "in_thread"}
+			if len(data) != len(wantKeys) {
+				t.Errorf("got %d keys, want %d: %v", len(data), len(wantKeys), data)
+			}
+			for _, k := range wantKeys {
+				if _, ok := data[k]; !ok {
+					t.Errorf("missing key %q", k)
+				}
+			}
+			if data["in_thread"] != tt.wantThread {
+				t.Errorf("in_thread = %v, want %v", data["in_thread"], tt.wantThread)
+			}
+			if data["preview"] != tt.wantPrev {
+				t.Errorf("preview = %q, want %q", data["preview"], tt.wantPrev)
+			}
+			if data["attachment_count"] != float64(len(tt.email.Attachments)) {
+				t.Errorf("attachment_count = %v", data["attachment_count"])
+			}
+			if data["unread"] != tt.email.Unread || data["answered"] != tt.email.Answered || data["flagged"] != tt.email.Flagged {
+				t.Errorf("status = %v/%v/%v", data["unread"], data["answered"], data["flagged"])
+			}
+		})
+	}
+
+	result, _ := TriageEmailHandler(&MockEmailService{}, "INBOX")(context.Background(), req(map[string]interface{}{}))
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("missing email_id code = %q", code)
+	}
+}
+
+// --- GetInvite ---
+
+func TestGetInviteHandler(t *testing.T) {
+	event := &imappkg.CalendarEvent{
+		Summary:   "Planning",
+		Start:     time.Date(2024, 1, 15, 15, 0, 0, 0, time.UTC),
+		End:       time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC),
+		Organizer: "organizer@example.com",
+		Location:  "Room 4",
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		mock    *MockEmailService
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "happy path",
+			args: map[string]interface{}{"email_id": "123"},
+			mock: &MockEmailService{Email: &imappkg.Email{ID: "123", Subject: "Invite", CalendarEvent: event}},
+		},
+		{
+			name:    "no invitation",
+			args:    map[string]interface{}{"email_id": "123"},
+			mock:    &MockEmailService{Email: &imappkg.Email{ID: "123", Subject: "Hi"}},
+			wantErr: true,
+			errMsg:  "does not contain a calendar invitation",
+		},
+		{
+			name:    "missing email_id",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailService{},
+			wantErr: true,
+			errMsg:  "email_id is required",
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{"email_id": "123"},
+			mock:    newErrMock("not found"),
+			wantErr: true,
+			errMsg:  "failed to get email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := GetInviteHandler(tt.mock, "INBOX")
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				msg := resultErrText(t, result)
+				if tt.errMsg != "" && !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			ev, ok := data["event"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected event object, got %v", data["event"])
+			}
+			if ev["summary"] != "Planning" || ev["location"] != "Room 4" {
+				t.Errorf("event = %v", ev)
+			}
+		})
+	}
+}
+
 // --- SearchEmails ---
 
 func TestSearchEmailsHandler(t *testing.T) {
@@ -273,25 +754,90 @@ func TestSearchEmailsHandler(t *testing.T) {
 			},
 		},
 		{
-			name:    "backend error",
-			args:    map[string]interface{}{},
-			mock:    newErrMock("IMAP error"),
+			name: "delivered_to passed to filters",
+			args: map[string]interface{}{"delivered_to": "Me <me+shopping@icloud.com>"},
+			mock: &MockEmailService{Emails: emails},
+			checkMock: func(t *testing.T, m *MockEmailService) {
+				if m.LastFilters.DeliveredTo != "me+shopping@icloud.com" {
+					t.Errorf("delivered_to = %q, want me+shopping@icloud.com", m.LastFilters.DeliveredTo)
+				}
+			},
+		},
+		{
+			name:    "invalid delivered_to",
+			args:    map[string]interface{}{"delivered_to": "shopping"},
+			mock:    &MockEmailService{Emails: emails},
 			wantErr: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			handler := SearchEmailsHandler(tt.mock)
-			result, err := handler(context.Background(), req(tt.args))
-			if err != nil {
-				t.Fatalf("unexpected Go error: %v", err)
-			}
-			if tt.wantErr {
-				if !result.IsError {
-					t.Fatal("expected error result")
-				}
-				return
+		{
+			name: "raw_query passed to filters",
+			args: map[string]interface{}{"raw_query": "OR FROM a@b.com SUBJECT foo"},
+			mock: &MockEmailService{Emails: emails},
+			checkMock: func(t *testing.T, m *MockEmailService) {
+				if m.LastFilters.RawQuery != "OR FROM a@b.com SUBJECT foo" {
+					t.Errorf("raw_query = %q", m.LastFilters.RawQuery)
+				}
+			},
+		},
+		{
+			name: "ids_only skips the fetch",
+			args: map[string]interface{}{"ids_only": true, "unread_only": true},
+			mock: &MockEmailService{Emails: emails},
+			checkMock: func(t *testing.T, m *MockEmailService) {
+				if m.LastMethod != "SearchUIDs" || m.CallCount != 1 {
+					t.Errorf("called %s %d times, want SearchUIDs once", m.LastMethod, m.CallCount)
+				}
+				if !m.LastFilters.UnreadOnly {
+					t.Error("filters not passed to SearchUIDs")
+				}
+			},
+		},
+		{
+			name:    "ids_only with group_by_thread",
+			args:    map[string]interface{}{"ids_only": true, "group_by_thread": true},
+			mock:    &MockEmailService{Emails: emails},
+			wantErr: true,
+		},
+		{
+			name:    "ids_only with exclude_folder",
+			args:    map[string]interface{}{"ids_only": true, "exclude_folder": "Done"},
+			mock:    &MockEmailService{Emails: emails},
+			wantErr: true,
+		},
+		{
+			name:    "malformed raw_query",
+			args:    map[string]interface{}{"raw_query": "FROM a@b.com\r\nA1 DELETE INBOX"},
+			mock:    &MockEmailService{Emails: emails},
+			wantErr: true,
+			checkMock: func(t *testing.T, m *MockEmailService) {
+				if m.CallCount != 0 {
+					t.Error("searched with a malformed raw_query")
+				}
+			},
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{},
+			mock:    newErrMock("IMAP error"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SearchEmailsHandler(tt.mock, "INBOX", false)
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if !result.IsError {
+					t.Fatal("expected error result")
+				}
+				if tt.checkMock != nil {
+					tt.checkMock(t, tt.mock)
+				}
+				return
 			}
 			data := resultJSON(t, result)
 			if data["folder"] != tt.mock.LastFolder {
@@ -307,6 +853,383 @@ func TestSearchEmailsHandler(t *testing.T) {
 	}
 }
 
+func TestSearchEmailsHandlerExplainEmpty(t *testing.T) {
+	mock := &MockEmailService{}
+	result, err := SearchEmailsHandler(mock, "INBOX", true)(context.Background(), req(map[string]interface{}{
+		"query":        "invoice",
+		"unread_only":  true,
+		"last_days":    float64(7),
+		"delivered_to": "me+bills@icloud.com",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	data := resultJSON(t, result)
+	if data["found"] != false || data["count"] != float64(0) {
+		t.Errorf("found = %v, count = %v; want false, 0", data["found"], data["count"])
+	}
+	msg, _ := data["message"].(string)
+	for _, want := range []string{"INBOX", `"invoice"`, "unread only", "last 7 days", "me+bills@icloud.com", "not an error"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q does not mention %q", msg, want)
+		}
+	}
+	filters, _ := data["filters"].(map[string]interface{})
+	want := map[string]interface{}{
+		"query":        "invoice",
+		"unread_only":  true,
+		"last_days":    float64(7),
+		"delivered_to": "me+bills@icloud.com",
+	}
+	if len(filters) != len(want) {
+		t.Errorf("filters = %v, want %v", filters, want)
+	}
+	for k, v := range want {
+		if filters[k] != v {
+			t.Errorf("filters[%s] = %v, want %v", k, filters[k], v)
+		}
+	}
+}
+
+func TestSearchEmailsHandlerExplainEmptyOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		explain     bool
+		args        map[string]interface{}
+		mock        *MockEmailService
+		wantFound   interface{}
+		wantMessage string
+	}{
+		{
+			name:      "off by default",
+			args:      map[string]interface{}{},
+			mock:      &MockEmailService{},
+			wantFound: nil,
+		},
+		{
+			name:      "parameter overrides default",
+			explain:   true,
+			args:      map[string]interface{}{"explain_empty": false},
+			mock:      &MockEmailService{},
+			wantFound: nil,
+		},
+		{
+			name:      "matches found",
+			explain:   true,
+			args:      map[string]interface{}{},
+			mock:      &MockEmailService{Emails: []imappkg.Email{{ID: "1"}}},
+			wantFound: true,
+		},
+		{
+			name:        "ids only",
+			args:        map[string]interface{}{"ids_only": true, "explain_empty": true, "raw_query": "UNSEEN"},
+			mock:        &MockEmailService{},
+			wantFound:   false,
+			wantMessage: `raw query "UNSEEN"`,
+		},
+		{
+			name:        "all excluded",
+			args:        map[string]interface{}{"exclude_folder": "Done", "explain_empty": true},
+			mock:        &MockEmailService{Emails: []imappkg.Email{{ID: "1", MessageID: "<a@x>"}}, FolderMessageIDs: map[string][]string{"Done": {"<a@x>"}}},
+			wantFound:   false,
+			wantMessage: "also in Done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SearchEmailsHandler(tt.mock, "INBOX", tt.explain)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+			if data["found"] != tt.wantFound {
+				t.Errorf("found = %v, want %v", data["found"], tt.wantFound)
+			}
+			msg, _ := data["message"].(string)
+			if !strings.Contains(msg, tt.wantMessage) || (tt.wantMessage == "" && msg != "") {
+				t.Errorf("message = %q, want it to contain %q", msg, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSearchEmailsHandlerIDsOnly(t *testing.T) {
+	mock := &MockEmailService{Emails: []imappkg.Email{{ID: "7"}, {ID: "9"}}}
+	result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(map[string]interface{}{"ids_only": true}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	ids, ok := data["ids"].([]interface{})
+	if !ok || len(ids) != 2 || ids[0] != "7" || ids[1] != "9" {
+		t.Errorf("ids = %v, want [7 9]", data["ids"])
+	}
+	if data["count"] != float64(2) || data["total"] != float64(2) {
+		t.Errorf("count/total = %v/%v, want 2/2", data["count"], data["total"])
+	}
+	if _, ok := data["emails"]; ok {
+		t.Error("ids_only response should not include emails")
+	}
+}
+
+func TestSearchEmailsHandlerUIDValidity(t *testing.T) {
+	for _, args := range []map[string]interface{}{{}, {"ids_only": true}} {
+		mock := &MockEmailService{Emails: []imappkg.Email{{ID: "7"}}, UIDValidityValue: 1700000000}
+		result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		if data := resultJSON(t, result); data["uidvalidity"] != float64(1700000000) {
+			t.Errorf("args %v: uidvalidity = %v, want 1700000000", args, data["uidvalidity"])
+		}
+		// It comes from the search itself, not a second call that a pool
+		// could serve from another connection
+		if mock.CallCount != 1 {
+			t.Errorf("args %v: %d calls, want only the search", args, mock.CallCount)
+		}
+	}
+
+	// Search results are still returned when the search reports no UIDVALIDITY
+	mock := &MockEmailService{Emails: []imappkg.Email{{ID: "7"}}}
+	result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if _, ok := data["uidvalidity"]; ok || data["count"] != float64(1) {
+		t.Errorf("uidvalidity = %v, count = %v; want no uidvalidity and 1 email", data["uidvalidity"], data["count"])
+	}
+}
+
+func TestSearchEmailsHandlerDateKeywords(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-01-15", time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)},
+		{"2024-01-15T14:30:00Z", time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)},
+		{"today", today},
+		{"yesterday", today.AddDate(0, 0, -1)},
+		{"this_week", monday},
+		{"last_week", monday.AddDate(0, 0, -7)},
+		{"this_month", firstOfMonth},
+		{"last_month", firstOfMonth.AddDate(0, -1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mock := &MockEmailService{}
+			args := map[string]interface{}{"since": tt.value, "before": tt.value}
+			result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			resultJSON(t, result)
+			if mock.LastFilters.Since == nil || !mock.LastFilters.Since.Equal(tt.want) {
+				t.Errorf("Since = %v, want %v", mock.LastFilters.Since, tt.want)
+			}
+			if mock.LastFilters.Before == nil || !mock.LastFilters.Before.Equal(tt.want) {
+				t.Errorf("Before = %v, want %v", mock.LastFilters.Before, tt.want)
+			}
+			if mock.LastFilters.LastDays != 0 {
+				t.Errorf("LastDays = %d, want 0 when since is set", mock.LastFilters.LastDays)
+			}
+		})
+	}
+}
+
+func TestSearchEmailsHandlerPartialResults(t *testing.T) {
+	mock := &MockEmailService{
+		Emails:     []imappkg.Email{{ID: "1"}, {ID: "2"}},
+		PartialErr: fmt.Errorf("%w: fetched 2 of 3 messages: timeout", imappkg.ErrPartialResults),
+	}
+	result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["partial"] != true {
+		t.Error("expected partial=true")
+	}
+	if w, _ := data["warning"].(string); !strings.Contains(w, "fetched 2 of 3") {
+		t.Errorf("warning = %q", w)
+	}
+	if int(data["count"].(float64)) != 2 {
+		t.Errorf("count = %v, want 2", data["count"])
+	}
+}
+
+func TestSearchEmailsHandlerGroupByThread(t *testing.T) {
+	mock := &MockEmailService{Emails: []imappkg.Email{
+		{ID: "1", Subject: "Budget", MessageID: "<a@x>", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "2", Subject: "Re: Budget", References: []string{"<a@x>"}, Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: "3", Subject: "Lunch", Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	handler := SearchEmailsHandler(mock, "INBOX", false)
+	result, err := handler(context.Background(), req(map[string]interface{}{"group_by_thread": true}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if _, ok := data["emails"]; ok {
+		t.Error("expected emails to be replaced by conversations")
+	}
+	convs, ok := data["conversations"].([]interface{})
+	if !ok || len(convs) != 2 {
+		t.Fatalf("conversations = %v, want 2", data["conversations"])
+	}
+	budget := convs[1].(map[string]interface{})
+	if int(budget["count"].(float64)) != 2 {
+		t.Errorf("budget count = %v, want 2", budget["count"])
+	}
+	if latest := budget["latest"].(map[string]interface{}); latest["id"] != "2" {
+		t.Errorf("budget latest id = %v, want 2", latest["id"])
+	}
+}
+
+func TestGroupByThreadSharedReference(t *testing.T) {
+	// The root and the middle reply are not in the result, and the last
+	// reply's subject was edited, but both replies reference the root
+	emails := []imappkg.Email{
+		{ID: "2", Subject: "Re: Budget", MessageID: "<b@x>", References: []string{"<a@x>"}, Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: "4", Subject: "Budget, revised numbers", MessageID: "<d@x>", References: []string{"<a@x>", "<c@x>"}, Date: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{ID: "5", Subject: "Lunch", MessageID: "<e@x>", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	convs := groupByThread(emails)
+	if len(convs) != 2 {
+		t.Fatalf("conversations = %+v, want 2", convs)
+	}
+	if got := strings.Join(convs[1].EmailIDs, ","); got != "2,4" {
+		t.Errorf("budget ids = %s, want 2,4", got)
+	}
+	if convs[1].Latest.ID != "4" {
+		t.Errorf("budget latest = %s, want 4", convs[1].Latest.ID)
+	}
+}
+
+func TestSearchEmailsHandlerExcludeFolder(t *testing.T) {
+	mock := &MockEmailService{
+		Emails: []imappkg.Email{
+			{ID: "1", Subject: "Invoice", MessageID: "<inv@shop.example>"},
+			{ID: "2", Subject: "Plans", MessageID: "<plans@example.com>"},
+			{ID: "3", Subject: "Lunch", MessageID: "<lunch@example.com>"},
+		},
+		FolderMessageIDs: map[string][]string{"Done": {"plans@example.com"}},
+	}
+	handler := SearchEmailsHandler(mock, "INBOX", false)
+
+	result, err := handler(context.Background(), req(map[string]interface{}{"exclude_folder": "Done"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := resultJSON(t, result)
+	emails := data["emails"].([]interface{})
+	if len(emails) != 2 || data["count"] != float64(2) || data["excluded"] != float64(1) {
+		t.Fatalf("count = %v, excluded = %v, emails = %v", data["count"], data["excluded"], emails)
+	}
+	for _, e := range emails {
+		if e.(map[string]interface{})["id"] == "2" {
+			t.Error("email in exclude_folder was not removed")
+		}
+	}
+	if mock.LastFolder != "INBOX" || data["exclude_folder"] != "Done" {
+		t.Errorf("searched %q excluding %v", mock.LastFolder, data["exclude_folder"])
+	}
+	if data["total"] != float64(2) {
+		t.Errorf("total = %v, want 2 after exclusion", data["total"])
+	}
+
+	// Exclusion comes before paging, so a page is not cut short by it
+	mock.Emails = []imappkg.Email{
+		{ID: "1", MessageID: "<1@x>"},
+		{ID: "2", MessageID: "<2@x>"},
+		{ID: "3", MessageID: "<3@x>"},
+		{ID: "4", MessageID: "<4@x>"},
+		{ID: "5", MessageID: "<5@x>"},
+	}
+	mock.FolderMessageIDs = map[string][]string{"Done": {"<4@x>"}}
+	result, err = handler(context.Background(), req(map[string]interface{}{"exclude_folder": "Done", "limit": float64(2), "offset": float64(1)}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data = resultJSON(t, result)
+	var ids []string
+	for _, e := range data["emails"].([]interface{}) {
+		ids = append(ids, e.(map[string]interface{})["id"].(string))
+	}
+	if strings.Join(ids, ",") != "2,3" || data["total"] != float64(4) || data["excluded"] != float64(1) {
+		t.Errorf("ids = %v, total = %v, excluded = %v; want 2,3 of 4 with 1 excluded", ids, data["total"], data["excluded"])
+	}
+	if mock.LastMethod != "SearchEmailsExcluding" || mock.LastFilters.Limit != 2 || mock.LastFilters.Offset != 1 {
+		t.Errorf("%s with limit %d offset %d, want the page passed to SearchEmailsExcluding", mock.LastMethod, mock.LastFilters.Limit, mock.LastFilters.Offset)
+	}
+
+	result, err = handler(context.Background(), req(map[string]interface{}{"exclude_folder": "../etc"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("invalid exclude_folder: code = %q, want %q", code, CodeInvalidArgument)
+	}
+}
+
+func TestSearchEmailsHandlerCompact(t *testing.T) {
+	mock := &MockEmailService{
+		Emails: []imappkg.Email{
+			{ID: "101", From: "alice@example.com", Subject: "Lunch?", Unread: true, Date: time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)},
+			{ID: "100", From: "Bob <bob@example.com>", Subject: "A | B\nsplit", Date: time.Date(2024, 1, 14, 9, 0, 0, 0, time.UTC)},
+			{ID: "99", From: "carol@example.com", Subject: "Undated"},
+		},
+	}
+
+	result, err := SearchEmailsHandler(mock, "INBOX", false)(context.Background(), req(map[string]interface{}{"format": "compact"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(resultText(t, result), "\n"), "\n")
+	want := []string{
+		"folder: INBOX | count: 3 | total: 3",
+		"id | date | from | subject | unread",
+		"101 | 2024-01-15T14:30:00Z | alice@example.com | Lunch? | true",
+		`100 | 2024-01-14T09:00:00Z | Bob <bob@example.com> | A \| B split | false`,
+		"99 |  | carol@example.com | Undated | false",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("compact output:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The first column is the id get_email expects
+	id := strings.TrimSpace(strings.SplitN(lines[2], "|", 2)[0])
+	getMock := &MockEmailService{Email: &imappkg.Email{ID: id}}
+	result, err = GetEmailHandler(getMock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(map[string]interface{}{"email_id": id}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	resultJSON(t, result)
+	if getMock.LastEmailID != "101" {
+		t.Errorf("get_email id = %q, want 101", getMock.LastEmailID)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"format": "csv"},
+		{"format": "compact", "group_by_thread": true},
+	} {
+		result, _ := SearchEmailsHandler(&MockEmailService{}, "INBOX", false)(context.Background(), req(args))
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("%v: code = %q, want invalid_argument", args, code)
+		}
+	}
+}
+
 // --- CountEmails ---
 
 func TestCountEmailsHandler(t *testing.T) {
@@ -339,7 +1262,7 @@ func TestCountEmailsHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := CountEmailsHandler(tt.mock)
+			handler := CountEmailsHandler(tt.mock, "INBOX")
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -396,7 +1319,7 @@ func TestMarkReadHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := MarkReadHandler(tt.mock)
+			handler := MarkReadHandler(tt.mock, "INBOX")
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -415,6 +1338,147 @@ func TestMarkReadHandler(t *testing.T) {
 	}
 }
 
+func TestMarkReadHandlerReturnEmail(t *testing.T) {
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "100", Subject: "Hello", Unread: false}}
+	result, err := MarkReadHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "100", "return_email": true}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	m := resultJSON(t, result)
+
+	email, ok := m["email"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("response has no email: %v", m)
+	}
+	if email["id"] != "100" || email["unread"] != false {
+		t.Errorf("email = %v, want id 100 marked read", email)
+	}
+	// Fetching the email must not itself change its read state
+	if mock.LastMethod != "PeekEmail" {
+		t.Errorf("fetched with %s, want PeekEmail", mock.LastMethod)
+	}
+
+	// Without the option no fetch is made
+	mock = &MockEmailService{}
+	result, _ = MarkReadHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "100"}))
+	if _, ok := resultJSON(t, result)["email"]; ok || mock.LastMethod != "MarkRead" {
+		t.Errorf("email included without return_email (last call %s)", mock.LastMethod)
+	}
+}
+
+func TestPreviewPlaintextHandler(t *testing.T) {
+	handler := PreviewPlaintextHandler()
+
+	result, err := handler(context.Background(), req(map[string]interface{}{
+		"html": "<p>Hi Sam,<br>your order &amp; receipt are attached.</p><div><b>Total:</b> $12</div>",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := resultJSON(t, result)
+	want := "Hi Sam,\nyour order & receipt are attached.\n\nTotal: $12"
+	if data["text"] != want {
+		t.Errorf("text = %q, want %q", data["text"], want)
+	}
+	if data["length"] != float64(len(want)) {
+		t.Errorf("length = %v, want %d", data["length"], len(want))
+	}
+
+	result, err = handler(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("missing html: code = %q, want %q", code, CodeInvalidArgument)
+	}
+}
+
+func TestFetchUnreadHandler(t *testing.T) {
+	unread := []imappkg.Email{
+		{ID: "11", From: "a@example.com", Subject: "One", Unread: true},
+		{ID: "12", From: "b@example.com", Subject: "Two", Unread: true},
+		{ID: "15", From: "c@example.com", Subject: "Three", Unread: true},
+	}
+	peeked := &imappkg.Email{BestBody: "hello"}
+
+	t.Run("fetched set equals unread set", func(t *testing.T) {
+		mock := &MockEmailService{Emails: unread, Email: peeked}
+		handler := FetchUnreadHandler(mock, "INBOX")
+
+		result, err := handler(context.Background(), req(map[string]interface{}{}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := resultJSON(t, result)
+		if !mock.LastFilters.UnreadOnly || mock.LastFilters.LastDays != 0 {
+			t.Errorf("filters = %+v, want unread only with no date window", mock.LastFilters)
+		}
+		emails := data["emails"].([]interface{})
+		if len(emails) != len(unread) {
+			t.Fatalf("got %d emails, want %d", len(emails), len(unread))
+		}
+		for i, e := range emails {
+			email := e.(map[string]interface{})
+			if email["id"] != unread[i].ID || email["body"] != "hello" {
+				t.Errorf("email %d = %v", i, email)
+			}
+		}
+		if mock.BulkReadCalls != 0 || data["marked_read"] != float64(0) {
+			t.Errorf("marked without mark_read_after: %d calls", mock.BulkReadCalls)
+		}
+	})
+
+	t.Run("mark_read_after stores once", func(t *testing.T) {
+		mock := &MockEmailService{Emails: unread, Email: peeked}
+		handler := FetchUnreadHandler(mock, "INBOX")
+
+		result, err := handler(context.Background(), req(map[string]interface{}{"mark_read_after": true}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := resultJSON(t, result)
+		if mock.BulkReadCalls != 1 {
+			t.Fatalf("MarkReadBulk called %d times, want 1", mock.BulkReadCalls)
+		}
+		if strings.Join(mock.LastEmailIDs, ",") != "11,12,15" || !mock.LastRead || mock.LastFolder != "INBOX" {
+			t.Errorf("marked %v read=%v in %q", mock.LastEmailIDs, mock.LastRead, mock.LastFolder)
+		}
+		if data["marked_read"] != float64(3) {
+			t.Errorf("marked_read = %v, want 3", data["marked_read"])
+		}
+	})
+
+	t.Run("no unread emails", func(t *testing.T) {
+		mock := &MockEmailService{}
+		handler := FetchUnreadHandler(mock, "INBOX")
+
+		result, err := handler(context.Background(), req(map[string]interface{}{"mark_read_after": true}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data := resultJSON(t, result)
+		if data["count"] != float64(0) || mock.BulkReadCalls != 0 {
+			t.Errorf("count = %v, bulk calls = %d", data["count"], mock.BulkReadCalls)
+		}
+	})
+
+	t.Run("retrieval failure marks nothing", func(t *testing.T) {
+		mock := &MockEmailService{Emails: unread, PeekErr: errors.New("fetch failed")}
+		handler := FetchUnreadHandler(mock, "INBOX")
+
+		result, err := handler(context.Background(), req(map[string]interface{}{"mark_read_after": true}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Fatal("expected error")
+		}
+		if mock.BulkReadCalls != 0 {
+			t.Errorf("MarkReadBulk called %d times after a failed fetch", mock.BulkReadCalls)
+		}
+	})
+}
+
 // --- MoveEmail ---
 
 func TestMoveEmailHandler(t *testing.T) {
@@ -459,7 +1523,7 @@ func TestMoveEmailHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := MoveEmailHandler(tt.mock)
+			handler := MoveEmailHandler(tt.mock, "INBOX")
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -479,66 +1543,575 @@ func TestMoveEmailHandler(t *testing.T) {
 	}
 }
 
-// --- DeleteEmail ---
-
-func TestDeleteEmailHandler(t *testing.T) {
+func TestMoveEmailHandlerSkipIfDuplicate(t *testing.T) {
 	tests := []struct {
-		name          string
-		args          map[string]interface{}
-		mock          *MockEmailService
-		wantErr       bool
-		wantPermanent bool
+		name        string
+		mock        *MockEmailService
+		wantSkipped bool
 	}{
 		{
-			name:          "move to trash (default)",
-			args:          map[string]interface{}{"email_id": "100"},
-			mock:          &MockEmailService{},
-			wantPermanent: false,
-		},
-		{
-			name:          "permanent delete",
-			args:          map[string]interface{}{"email_id": "100", "permanent": true},
-			mock:          &MockEmailService{},
-			wantPermanent: true,
-		},
-		{
-			name:    "missing email_id",
-			args:    map[string]interface{}{},
-			mock:    &MockEmailService{},
-			wantErr: true,
+			name:        "already present",
+			mock:        &MockEmailService{Skipped: true},
+			wantSkipped: true,
 		},
 		{
-			name:    "backend error",
-			args:    map[string]interface{}{"email_id": "100"},
-			mock:    newErrMock("fail"),
-			wantErr: true,
+			name: "not present",
+			mock: &MockEmailService{},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := DeleteEmailHandler(tt.mock)
-			result, err := handler(context.Background(), req(tt.args))
+			handler := MoveEmailHandler(tt.mock, "INBOX")
+			args := map[string]interface{}{"email_id": "100", "to_folder": "Archive", "skip_if_duplicate": true}
+			result, err := handler(context.Background(), req(args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
 			}
-			if tt.wantErr {
-				if !result.IsError {
-					t.Fatal("expected error result")
-				}
-				return
+			data := resultJSON(t, result)
+			if !tt.mock.LastMoveOpts.SkipIfDuplicate {
+				t.Error("expected SkipIfDuplicate to be passed to backend")
 			}
-			resultJSON(t, result)
-			if tt.mock.LastPermanent != tt.wantPermanent {
-				t.Errorf("permanent = %v, want %v", tt.mock.LastPermanent, tt.wantPermanent)
+			if skipped, _ := data["skipped"].(bool); skipped != tt.wantSkipped {
+				t.Errorf("skipped = %v, want %v", data["skipped"], tt.wantSkipped)
 			}
 		})
 	}
 }
 
-// --- FlagEmail ---
+func TestMoveEmailHandlerPreserveFlags(t *testing.T) {
+	for _, preserve := range []bool{false, true} {
+		mock := &MockEmailService{}
+		args := map[string]interface{}{"email_id": "100", "to_folder": "Archive", "preserve_flags": preserve}
+		if _, err := MoveEmailHandler(mock, "INBOX")(context.Background(), req(args)); err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		if mock.LastMoveOpts.PreserveFlags != preserve {
+			t.Errorf("PreserveFlags = %v, want %v", mock.LastMoveOpts.PreserveFlags, preserve)
+		}
+	}
+}
 
-func TestFlagEmailHandler(t *testing.T) {
+func TestMoveEmailHandlerReturnEmail(t *testing.T) {
+	mock := &MockEmailService{
+		Headers: map[string][]string{"Message-Id": {"<abc@example.com>"}},
+		FoundID: "7",
+		Email:   &imappkg.Email{ID: "7", Subject: "Hello", MessageID: "<abc@example.com>"},
+	}
+	args := map[string]interface{}{"email_id": "100", "to_folder": "Archive", "return_email": true}
+	result, err := MoveEmailHandler(mock, "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	m := resultJSON(t, result)
+
+	// The email is returned under its new UID, fetched from the destination
+	if m["email_id"] != "100" || m["new_email_id"] != "7" {
+		t.Errorf("email_id = %v, new_email_id = %v; want 100 and 7", m["email_id"], m["new_email_id"])
+	}
+	email, ok := m["email"].(map[string]interface{})
+	if !ok || email["id"] != "7" {
+		t.Fatalf("email = %v, want the moved copy", m["email"])
+	}
+	if mock.LastMethod != "PeekEmail" || mock.LastFolder != "Archive" || mock.LastEmailID != "7" {
+		t.Errorf("fetched %s %s/%s, want PeekEmail Archive/7", mock.LastMethod, mock.LastFolder, mock.LastEmailID)
+	}
+}
+
+func TestMoveEmailHandlerReturnEmailNotFound(t *testing.T) {
+	mock := &MockEmailService{Headers: map[string][]string{}}
+	args := map[string]interface{}{"email_id": "100", "to_folder": "Archive", "return_email": true}
+	result, err := MoveEmailHandler(mock, "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	// The move itself succeeded, so a missing Message-ID is only a warning
+	m := resultJSON(t, result)
+	if m["success"] != true || m["email"] != nil {
+		t.Errorf("response = %v, want success without email", m)
+	}
+	if warning, _ := m["warning"].(string); !strings.Contains(warning, "Message-ID") {
+		t.Errorf("warning = %q", warning)
+	}
+}
+
+// --- FileEmail ---
+
+func TestFileEmailHandler(t *testing.T) {
+	exists := fmt.Errorf("failed to create folder Project X: %w", imappkg.ErrAlreadyExists)
+
+	tests := []struct {
+		name        string
+		createErr   error
+		wantCreated bool
+	}{
+		{name: "creates then moves", wantCreated: true},
+		{name: "folder already exists", createErr: exists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{CreateErr: tt.createErr}
+			handler := FileEmailHandler(mock, "INBOX")
+			result, err := handler(context.Background(), req(map[string]interface{}{"email_id": "42", "folder": "Project X"}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+
+			data := resultJSON(t, result)
+			if data["created_folder"] != tt.wantCreated {
+				t.Errorf("created_folder = %v, want %v", data["created_folder"], tt.wantCreated)
+			}
+			if data["to_folder"] != "Project X" || data["from_folder"] != "INBOX" {
+				t.Errorf("moved %v -> %v, want INBOX -> Project X", data["from_folder"], data["to_folder"])
+			}
+			if mock.LastName != "Project X" || mock.LastMethod != "MoveEmail" || mock.LastEmailID != "42" || mock.LastToFolder != "Project X" {
+				t.Errorf("created %q, last call %s(%s -> %s)", mock.LastName, mock.LastMethod, mock.LastEmailID, mock.LastToFolder)
+			}
+		})
+	}
+}
+
+func TestFileEmailHandlerErrors(t *testing.T) {
+	// A create failure other than "already exists" stops before the move
+	mock := &MockEmailService{CreateErr: errors.New("NO [CANNOT] invalid name")}
+	result, _ := FileEmailHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "42", "folder": "Project X"}))
+	if !result.IsError || mock.LastMethod != "CreateFolder" {
+		t.Errorf("IsError = %v, last call %s; want create error and no move", result.IsError, mock.LastMethod)
+	}
+
+	result, _ = FileEmailHandler(&MockEmailService{}, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "42"}))
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("missing folder: code = %q, want %q", code, CodeInvalidArgument)
+	}
+}
+
+// --- MarkForReview ---
+
+func TestMarkForReviewHandler(t *testing.T) {
+	mock := &MockEmailService{CreateErr: fmt.Errorf("failed to create folder Review: %w", imappkg.ErrAlreadyExists)}
+	result, err := MarkForReviewHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "42"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	data := resultJSON(t, result)
+	if data["flagged"] != true || data["moved"] != true || data["created_folder"] != false {
+		t.Errorf("flagged %v, moved %v, created %v", data["flagged"], data["moved"], data["created_folder"])
+	}
+	if data["review_folder"] != "Review" || data["from_folder"] != "INBOX" {
+		t.Errorf("moved %v -> %v, want INBOX -> Review", data["from_folder"], data["review_folder"])
+	}
+	if mock.LastFlagType != "follow-up" || mock.LastFolder != "INBOX" {
+		t.Errorf("flagged %q in %q, want follow-up in INBOX", mock.LastFlagType, mock.LastFolder)
+	}
+	if mock.LastMethod != "MoveEmail" || mock.LastEmailID != "42" || mock.LastToFolder != "Review" || mock.CallCount != 3 {
+		t.Errorf("last call %s(%s -> %s) after %d calls, want flag, create, move", mock.LastMethod, mock.LastEmailID, mock.LastToFolder, mock.CallCount)
+	}
+}
+
+func TestMarkForReviewHandlerMoveFails(t *testing.T) {
+	mock := &MockEmailService{MoveErr: errors.New("NO [OVERQUOTA] mailbox full")}
+	result, err := MarkForReviewHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{
+		"email_id":      "42",
+		"review_folder": "Later",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	// The flag stays set and the error says so
+	if mock.LastFlagType != "follow-up" || mock.LastName != "Later" {
+		t.Errorf("flagged %q, created %q; want the flag set and folder created before the move", mock.LastFlagType, mock.LastName)
+	}
+	text := resultErrText(t, result)
+	if !strings.Contains(text, "email flagged for follow-up but not moved to 'Later'") || !strings.Contains(text, "mailbox full") {
+		t.Errorf("error = %q", text)
+	}
+}
+
+func TestMarkForReviewHandlerErrors(t *testing.T) {
+	// A failed flag stops before anything else changes
+	mock := newErrMock("connection reset")
+	result, _ := MarkForReviewHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "42"}))
+	if text := resultErrText(t, result); !strings.Contains(text, "failed to flag email") || mock.CallCount != 1 {
+		t.Errorf("error = %q after %d calls", text, mock.CallCount)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"email_id": "42", "review_folder": "../etc"},
+		{"email_id": "42", "review_folder": "INBOX"},
+	} {
+		result, _ := MarkForReviewHandler(&MockEmailService{}, "INBOX")(context.Background(), req(args))
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("%v: code = %q, want %q", args, code, CodeInvalidArgument)
+		}
+	}
+}
+
+// --- FileAndRead ---
+
+func TestFileAndReadHandler(t *testing.T) {
+	mock := &MockEmailService{}
+	args := map[string]interface{}{"email_id": "42", "to_folder": "Archive"}
+	result, err := FileAndReadHandler(mock, "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	data := resultJSON(t, result)
+	if data["marked_read"] != true || data["moved"] != true || data["to_folder"] != "Archive" {
+		t.Errorf("response = %v", data)
+	}
+	// Marked in the source, under its old UID, before the move
+	if !mock.LastRead || mock.LastFolder != "INBOX" {
+		t.Errorf("marked read %v in %q, want read in INBOX", mock.LastRead, mock.LastFolder)
+	}
+	if mock.LastMethod != "MoveEmail" || mock.LastFromFolder != "INBOX" || mock.LastToFolder != "Archive" || mock.CallCount != 2 {
+		t.Errorf("last call %s(%s -> %s) after %d calls, want mark then move", mock.LastMethod, mock.LastFromFolder, mock.LastToFolder, mock.CallCount)
+	}
+}
+
+func TestFileAndReadHandlerMoveFails(t *testing.T) {
+	mock := &MockEmailService{MoveErr: errors.New("NO [OVERQUOTA] mailbox full")}
+	args := map[string]interface{}{"email_id": "42", "to_folder": "Archive"}
+	result, err := FileAndReadHandler(mock, "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	// The email stays read and the error says so
+	if !mock.LastRead || mock.LastEmailID != "42" {
+		t.Errorf("read = %v for %q, want 42 marked read", mock.LastRead, mock.LastEmailID)
+	}
+	text := resultErrText(t, result)
+	if !strings.Contains(text, "email marked read but not moved to 'Archive'") || !strings.Contains(text, "mailbox full") {
+		t.Errorf("error = %q", text)
+	}
+
+	// A failed mark stops before the move
+	mock = newErrMock("connection reset")
+	result, _ = FileAndReadHandler(mock, "INBOX")(context.Background(), req(args))
+	if text := resultErrText(t, result); !strings.Contains(text, "failed to mark email read") || mock.CallCount != 1 {
+		t.Errorf("error = %q after %d calls", text, mock.CallCount)
+	}
+}
+
+// --- Snooze ---
+
+func TestSnoozeEmailHandler(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantCode string
+	}{
+		{name: "snoozed", args: map[string]interface{}{"email_id": "7", "until": tomorrow}},
+		{name: "missing until", args: map[string]interface{}{"email_id": "7"}, wantCode: CodeInvalidArgument},
+		{name: "past until", args: map[string]interface{}{"email_id": "7", "until": "2020-01-01"}, wantCode: CodeInvalidArgument},
+		{name: "bad until", args: map[string]interface{}{"email_id": "7", "until": "later"}, wantCode: CodeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{}
+			result, err := SnoozeEmailHandler(mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantCode != "" {
+				if code := resultErrCode(t, result); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+				if mock.CallCount != 0 {
+					t.Errorf("made %d calls for an invalid request", mock.CallCount)
+				}
+				return
+			}
+
+			data := resultJSON(t, result)
+			if data["until"] != tomorrow || mock.LastEmailID != "7" || mock.LastFolder != "INBOX" {
+				t.Errorf("until = %v, snoozed %s in %s", data["until"], mock.LastEmailID, mock.LastFolder)
+			}
+			if !strings.HasPrefix(data["snooze_folder"].(string), "Snoozed/") {
+				t.Errorf("snooze_folder = %v", data["snooze_folder"])
+			}
+		})
+	}
+}
+
+func TestFlushSnoozedHandler(t *testing.T) {
+	mock := &MockEmailService{Flushed: &imappkg.FlushResult{Returned: 3, Remaining: 2}}
+	result, err := FlushSnoozedHandler(mock)(context.Background(), req(nil))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["returned"] != float64(3) || data["remaining"] != float64(2) {
+		t.Errorf("returned %v, remaining %v", data["returned"], data["remaining"])
+	}
+
+	result, _ = FlushSnoozedHandler(newErrMock("connection reset"))(context.Background(), req(nil))
+	if !result.IsError {
+		t.Error("expected an error result")
+	}
+}
+
+// --- MoveBySender ---
+
+func TestMoveBySenderHandler(t *testing.T) {
+	inbox := []imappkg.Email{
+		{ID: "3", From: "Store News <newsletters@store.com>"},
+		{ID: "5", From: "old-newsletters@store.com"},
+		{ID: "8", From: "NEWSLETTERS@store.com"},
+	}
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		mock        *MockEmailService
+		wantIDs     []string
+		wantMoved   float64
+		wantCreated bool
+		wantErr     string
+	}{
+		{
+			name:      "exact matches only",
+			args:      map[string]interface{}{"sender": "newsletters@store.com", "to_folder": "Shopping"},
+			mock:      &MockEmailService{Emails: inbox},
+			wantIDs:   []string{"3", "8"},
+			wantMoved: 2,
+		},
+		{
+			name:        "creates missing folder",
+			args:        map[string]interface{}{"sender": "newsletters@store.com", "to_folder": "Shopping", "create_folder": true},
+			mock:        &MockEmailService{Emails: inbox, Folders: []string{"INBOX"}},
+			wantIDs:     []string{"3", "8"},
+			wantMoved:   2,
+			wantCreated: true,
+		},
+		{
+			name:      "existing folder not recreated",
+			args:      map[string]interface{}{"sender": "newsletters@store.com", "to_folder": "Shopping", "create_folder": true},
+			mock:      &MockEmailService{Emails: inbox, Folders: []string{"INBOX", "Shopping"}},
+			wantIDs:   []string{"3", "8"},
+			wantMoved: 2,
+		},
+		{
+			name: "nothing to move",
+			args: map[string]interface{}{"sender": "nobody@store.com", "to_folder": "Shopping", "create_folder": true},
+			mock: &MockEmailService{Emails: inbox},
+		},
+		{
+			name:    "invalid sender",
+			args:    map[string]interface{}{"sender": "not-an-address", "to_folder": "Shopping"},
+			mock:    &MockEmailService{},
+			wantErr: "invalid sender",
+		},
+		{
+			name:    "missing to_folder",
+			args:    map[string]interface{}{"sender": "newsletters@store.com"},
+			mock:    &MockEmailService{},
+			wantErr: "to_folder is required",
+		},
+		{
+			name:    "search error",
+			args:    map[string]interface{}{"sender": "newsletters@store.com", "to_folder": "Shopping"},
+			mock:    newErrMock("connection lost"),
+			wantErr: "failed to search emails",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MoveBySenderHandler(tt.mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want it to contain %q", msg, tt.wantErr)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["moved"] != tt.wantMoved {
+				t.Errorf("moved = %v, want %v", data["moved"], tt.wantMoved)
+			}
+			if tt.mock.LastFilters.From != "newsletters@store.com" && tt.mock.LastFilters.From != "nobody@store.com" {
+				t.Errorf("search From = %q", tt.mock.LastFilters.From)
+			}
+			if strings.Join(tt.mock.LastEmailIDs, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("moved IDs = %v, want %v", tt.mock.LastEmailIDs, tt.wantIDs)
+			}
+			if tt.wantIDs == nil {
+				if tt.mock.LastMethod != "SearchEmails" {
+					t.Errorf("last call = %s, want nothing after the search", tt.mock.LastMethod)
+				}
+				if msg, _ := data["message"].(string); !strings.HasPrefix(msg, "Nothing to move") {
+					t.Errorf("message = %q", msg)
+				}
+				return
+			}
+			if tt.mock.LastToFolder != "Shopping" || tt.mock.LastFromFolder != "INBOX" {
+				t.Errorf("moved %s -> %s", tt.mock.LastFromFolder, tt.mock.LastToFolder)
+			}
+			if (tt.mock.LastName == "Shopping") != tt.wantCreated || (data["created_folder"] == true) != tt.wantCreated {
+				t.Errorf("created folder = %q / %v, want %v", tt.mock.LastName, data["created_folder"], tt.wantCreated)
+			}
+		})
+	}
+}
+
+// --- DeleteEmail ---
+
+func TestDeleteEmailHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          map[string]interface{}
+		mock          *MockEmailService
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{
+			name:          "move to trash (default)",
+			args:          map[string]interface{}{"email_id": "100"},
+			mock:          &MockEmailService{},
+			wantPermanent: false,
+		},
+		{
+			name:    "missing email_id",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailService{},
+			wantErr: true,
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{"email_id": "100"},
+			mock:    newErrMock("fail"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := DeleteEmailHandler(tt.mock, "INBOX")
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if !result.IsError {
+					t.Fatal("expected error result")
+				}
+				return
+			}
+			resultJSON(t, result)
+			if tt.mock.LastPermanent != tt.wantPermanent {
+				t.Errorf("permanent = %v, want %v", tt.mock.LastPermanent, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestDeleteEmailHandlerConfirm(t *testing.T) {
+	mock := &MockEmailService{Headers: map[string][]string{"From": {"alice@example.com"}, "Subject": {"Invoice"}}}
+	handler := DeleteEmailHandler(mock, "INBOX")
+	call := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		return result
+	}
+
+	// The first call issues a token describing the email and deletes nothing
+	first := resultJSON(t, call(map[string]interface{}{"email_id": "100", "permanent": true}))
+	token, _ := first["confirm"].(string)
+	if first["confirmation_required"] != true || token == "" {
+		t.Fatalf("first call = %v, want a confirmation token", first)
+	}
+	if first["subject"] != "Invoice" || first["from"] != "alice@example.com" {
+		t.Errorf("description = %v, want the email's subject and sender", first)
+	}
+	if msg, _ := first["message"].(string); !strings.Contains(msg, "cannot be undone") {
+		t.Errorf("message = %q, want a warning that this cannot be undone", msg)
+	}
+	if len(mock.Deleted) != 0 {
+		t.Fatal("email deleted without confirmation")
+	}
+
+	// Echoing the token back deletes the email, once
+	done := resultJSON(t, call(map[string]interface{}{"email_id": "100", "permanent": true, "confirm": token}))
+	if done["success"] != true || !mock.LastPermanent || strings.Join(mock.Deleted, ",") != "100" {
+		t.Errorf("confirmed call = %v, deleted %v", done, mock.Deleted)
+	}
+	if code := resultErrCode(t, call(map[string]interface{}{"email_id": "100", "permanent": true, "confirm": token})); code != CodeInvalidArgument {
+		t.Errorf("reused token: code = %s, want %s", code, CodeInvalidArgument)
+	}
+
+	// A made-up token is refused, as is a real one for a different email
+	if code := resultErrCode(t, call(map[string]interface{}{"email_id": "100", "permanent": true, "confirm": "guess"})); code != CodeInvalidArgument {
+		t.Errorf("invalid token: code = %s, want %s", code, CodeInvalidArgument)
+	}
+	first = resultJSON(t, call(map[string]interface{}{"email_id": "100", "permanent": true}))
+	token, _ = first["confirm"].(string)
+	if code := resultErrCode(t, call(map[string]interface{}{"email_id": "101", "permanent": true, "confirm": token})); code != CodeInvalidArgument {
+		t.Errorf("token for another email: code = %s, want %s", code, CodeInvalidArgument)
+	}
+	if strings.Join(mock.Deleted, ",") != "100" {
+		t.Errorf("deleted %v, want only the confirmed email", mock.Deleted)
+	}
+}
+
+func TestConfirmStoreExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newConfirmStore(5 * time.Minute)
+	store.now = func() time.Time { return now }
+
+	token := store.issue("delete_email INBOX 100")
+	now = now.Add(6 * time.Minute)
+	if store.redeem(token, "delete_email INBOX 100") {
+		t.Error("expired token accepted")
+	}
+
+	token = store.issue("delete_email INBOX 100")
+	now = now.Add(4 * time.Minute)
+	if !store.redeem(token, "delete_email INBOX 100") {
+		t.Error("token rejected before it expired")
+	}
+}
+
+// --- PurgeDeleted ---
+
+func TestPurgeDeletedHandler(t *testing.T) {
+	mock := &MockEmailService{Aliases: map[string]string{"trash": "Deleted Messages"}, Purged: 3}
+	result, err := PurgeDeletedHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"folder": "trash"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["purged"] != float64(3) || data["folder"] != "Deleted Messages" {
+		t.Errorf("purged = %v, folder = %v", data["purged"], data["folder"])
+	}
+	if mock.LastMethod != "PurgeDeleted" || mock.LastFolder != "Deleted Messages" {
+		t.Errorf("called %s on %q", mock.LastMethod, mock.LastFolder)
+	}
+
+	result, err = PurgeDeletedHandler(newErrMock("expunge refused"), "INBOX")(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to purge deleted emails") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+// --- FlagEmail ---
+
+func TestFlagEmailHandler(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    map[string]interface{}
@@ -599,7 +2172,7 @@ func TestFlagEmailHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := FlagEmailHandler(tt.mock)
+			handler := FlagEmailHandler(tt.mock, "INBOX")
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -616,17 +2189,426 @@ func TestFlagEmailHandler(t *testing.T) {
 	}
 }
 
-// --- SendEmail ---
+func TestFlagEmailHandlerReturnEmail(t *testing.T) {
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "100", Flagged: true}}
+	args := map[string]interface{}{"email_id": "100", "flag": "important", "folder": "Work", "return_email": true}
+	result, err := FlagEmailHandler(mock, "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	m := resultJSON(t, result)
 
-func TestSendEmailHandler(t *testing.T) {
-	tests := []struct {
-		name    string
-		args    map[string]interface{}
-		mock    *MockEmailSender
-		wantErr bool
-		errMsg  string
-	}{
-		{
+	email, ok := m["email"].(map[string]interface{})
+	if !ok || email["flagged"] != true {
+		t.Fatalf("email = %v, want it flagged", m["email"])
+	}
+	if mock.LastMethod != "PeekEmail" || mock.LastFolder != "Work" {
+		t.Errorf("fetched with %s from %s, want PeekEmail from Work", mock.LastMethod, mock.LastFolder)
+	}
+}
+
+// --- FlagSearch ---
+
+func TestFlagSearchHandler(t *testing.T) {
+	mock := &MockEmailService{Emails: []imappkg.Email{{ID: "3"}, {ID: "8"}, {ID: "12"}}}
+	result, err := FlagSearchHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{
+		"flag":  "important",
+		"color": "red",
+		"from":  "pm@project.example",
+		"query": "Apollo",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	if mock.LastQuery != "Apollo" || mock.LastFilters.From != "pm@project.example" || mock.LastFilters.Limit != 200 {
+		t.Errorf("query = %q, filters = %+v", mock.LastQuery, mock.LastFilters)
+	}
+	if mock.BulkFlagCalls != 1 {
+		t.Fatalf("FlagEmailBulk called %d times, want 1", mock.BulkFlagCalls)
+	}
+	if got := strings.Join(mock.LastEmailIDs, ","); got != "3,8,12" || mock.LastFlagType != "important" || mock.LastColor != "red" {
+		t.Errorf("flagged %s as %s/%s, want 3,8,12 as important/red", got, mock.LastFlagType, mock.LastColor)
+	}
+	data := resultJSON(t, result)
+	if data["flagged"] != float64(3) {
+		t.Errorf("flagged = %v, want 3", data["flagged"])
+	}
+}
+
+func TestFlagSearchHandlerNoMatches(t *testing.T) {
+	mock := &MockEmailService{}
+	result, err := FlagSearchHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"flag": "follow-up"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if mock.BulkFlagCalls != 0 {
+		t.Errorf("FlagEmailBulk called %d times for an empty match set", mock.BulkFlagCalls)
+	}
+	data := resultJSON(t, result)
+	if data["success"] != true || data["flagged"] != float64(0) {
+		t.Errorf("success = %v, flagged = %v; want true, 0", data["success"], data["flagged"])
+	}
+}
+
+func TestFlagSearchHandlerInvalid(t *testing.T) {
+	for _, args := range []map[string]interface{}{
+		{},
+		{"flag": "urgent"},
+		{"flag": "important", "color": "pink"},
+		{"flag": "important", "raw_query": "FROM {5}"},
+	} {
+		mock := &MockEmailService{Emails: []imappkg.Email{{ID: "1"}}}
+		result, err := FlagSearchHandler(mock, "INBOX")(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("args %v: code = %q, want %q", args, code, CodeInvalidArgument)
+		}
+		if mock.CallCount != 0 {
+			t.Errorf("args %v: searched or flagged despite invalid arguments", args)
+		}
+	}
+}
+
+// --- ListFlagged ---
+
+func TestListFlaggedHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		wantKeywords string
+		wantErr      string
+	}{
+		{name: "all flagged", args: map[string]interface{}{}, wantKeywords: `\Flagged`},
+		{name: "follow-up", args: map[string]interface{}{"flag": "follow-up"}, wantKeywords: "$FollowUp"},
+		{name: "important", args: map[string]interface{}{"flag": "important"}, wantKeywords: "$Important"},
+		{name: "deadline", args: map[string]interface{}{"flag": "deadline"}, wantKeywords: "$Deadline"},
+		{name: "red", args: map[string]interface{}{"color": "red"}, wantKeywords: "$FlagRed"},
+		{name: "orange", args: map[string]interface{}{"color": "orange"}, wantKeywords: "$FlagOrange"},
+		{name: "yellow", args: map[string]interface{}{"color": "yellow"}, wantKeywords: "$FlagYellow"},
+		{name: "green", args: map[string]interface{}{"color": "green"}, wantKeywords: "$FlagGreen"},
+		{name: "blue", args: map[string]interface{}{"color": "blue"}, wantKeywords: "$FlagBlue"},
+		{name: "purple", args: map[string]interface{}{"color": "purple"}, wantKeywords: "$FlagPurple"},
+		{name: "type and color", args: map[string]interface{}{"flag": "deadline", "color": "blue"}, wantKeywords: "$Deadline,$FlagBlue"},
+		{name: "invalid flag", args: map[string]interface{}{"flag": "none"}, wantErr: "invalid flag type"},
+		{name: "invalid color", args: map[string]interface{}{"color": "magenta"}, wantErr: "invalid color"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Emails: []imappkg.Email{{ID: "4", Subject: "Invoice", Flagged: true}}}
+			result, err := ListFlaggedHandler(mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if code := resultErrCode(t, result); code != CodeInvalidArgument {
+					t.Errorf("code = %q, want %q", code, CodeInvalidArgument)
+				}
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want %q", msg, tt.wantErr)
+				}
+				if mock.CallCount != 0 {
+					t.Error("searched despite an invalid argument")
+				}
+				return
+			}
+			if got := strings.Join(mock.LastFilters.Keywords, ","); got != tt.wantKeywords {
+				t.Errorf("keywords = %q, want %q", got, tt.wantKeywords)
+			}
+			if mock.LastFilters.LastDays != 0 || mock.LastFilters.Limit != 50 {
+				t.Errorf("filters = %+v, want no date window and limit 50", mock.LastFilters)
+			}
+			if data := resultJSON(t, result); data["count"] != float64(1) {
+				t.Errorf("count = %v, want 1", data["count"])
+			}
+		})
+	}
+}
+
+// --- SentTo ---
+
+func TestSentToHandler(t *testing.T) {
+	mock := &MockEmailService{
+		Aliases: map[string]string{"sent": "Sent Messages"},
+		Emails:  []imappkg.Email{{ID: "12", Subject: "Re: Lunch", To: []string{"bob@example.com"}}},
+	}
+	result, err := SentToHandler(mock)(context.Background(), req(map[string]interface{}{
+		"address":   "Bob <Bob@Example.com>",
+		"last_days": float64(7),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	if mock.LastMethod != "SearchEmails" || mock.LastFolder != "Sent Messages" {
+		t.Errorf("called %s on %q, want SearchEmails on the Sent folder", mock.LastMethod, mock.LastFolder)
+	}
+	if mock.LastFilters.Recipient != "Bob@Example.com" || mock.LastFilters.LastDays != 7 || mock.LastFilters.Limit != 50 {
+		t.Errorf("filters = %+v", mock.LastFilters)
+	}
+	data := resultJSON(t, result)
+	if data["count"] != float64(1) || data["folder"] != "Sent Messages" {
+		t.Errorf("count = %v, folder = %v", data["count"], data["folder"])
+	}
+}
+
+func TestSentToHandlerInvalidAddress(t *testing.T) {
+	for _, args := range []map[string]interface{}{{}, {"address": "not an address"}} {
+		mock := &MockEmailService{}
+		result, err := SentToHandler(mock)(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("args %v: code = %q, want %q", args, code, CodeInvalidArgument)
+		}
+		if mock.CallCount != 0 {
+			t.Errorf("args %v: searched despite an invalid address", args)
+		}
+	}
+}
+
+// --- FindRelated ---
+
+func TestFindRelatedHandler(t *testing.T) {
+	date := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	mock := &MockEmailService{
+		Email: &imappkg.Email{ID: "1", Subject: "Re: Budget", Date: date},
+		Emails: []imappkg.Email{
+			{ID: "4", Subject: "Budget review"},
+			{ID: "3", Subject: "FW: budget"},
+			{ID: "2", Subject: "Budget"},
+			{ID: "1", Subject: "Re: Budget"},
+			{ID: "5", Subject: "Lunch"},
+		},
+	}
+	result, err := FindRelatedHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{
+		"email_id": "1",
+		"days":     float64(7),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	if mock.LastQuery != "Budget" {
+		t.Errorf("query = %q, want the normalized subject", mock.LastQuery)
+	}
+	since, before := mock.LastFilters.Since, mock.LastFilters.Before
+	if since == nil || !since.Equal(date.AddDate(0, 0, -7)) || before == nil || !before.Equal(date.AddDate(0, 0, 8)) {
+		t.Errorf("window = %v to %v, want 7 days either side of %v", since, before, date)
+	}
+
+	data := resultJSON(t, result)
+	var ids []string
+	for _, e := range data["emails"].([]interface{}) {
+		ids = append(ids, e.(map[string]interface{})["id"].(string))
+	}
+	if got := strings.Join(ids, ","); got != "3,2" {
+		t.Errorf("related = %s, want 3,2 (the email itself and other subjects excluded)", got)
+	}
+	if data["subject"] != "Budget" || data["count"] != float64(2) {
+		t.Errorf("subject = %v, count = %v", data["subject"], data["count"])
+	}
+}
+
+func TestFindRelatedHandlerNoSubject(t *testing.T) {
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "1", Subject: "Re: "}}
+	result, err := FindRelatedHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"email_id": "1"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("code = %q, want %q", code, CodeInvalidArgument)
+	}
+}
+
+// --- DiffFolders ---
+
+func TestDiffMessageIDs(t *testing.T) {
+	tests := []struct {
+		name                   string
+		a, b                   []string
+		wantA, wantB, wantBoth string
+	}{
+		{
+			name:     "overlapping",
+			a:        []string{"1@x", "2@x", "3@x"},
+			b:        []string{"3@x", "4@x", "2@x"},
+			wantA:    "1@x",
+			wantB:    "4@x",
+			wantBoth: "2@x,3@x",
+		},
+		{
+			name:  "disjoint",
+			a:     []string{"1@x", "2@x"},
+			b:     []string{"3@x"},
+			wantA: "1@x,2@x",
+			wantB: "3@x",
+		},
+		{
+			name:     "identical",
+			a:        []string{"1@x", "2@x"},
+			b:        []string{"2@x", "1@x"},
+			wantBoth: "1@x,2@x",
+		},
+		{
+			name:     "duplicates counted once",
+			a:        []string{"1@x", "1@x", "2@x"},
+			b:        []string{"2@x", "3@x", "3@x"},
+			wantA:    "1@x",
+			wantB:    "3@x",
+			wantBoth: "2@x",
+		},
+		{
+			name:  "empty folder",
+			b:     []string{"1@x"},
+			wantB: "1@x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onlyA, onlyB, both := diffMessageIDs(tt.a, tt.b)
+			if got := strings.Join(onlyA, ","); got != tt.wantA {
+				t.Errorf("only in a = %s, want %s", got, tt.wantA)
+			}
+			if got := strings.Join(onlyB, ","); got != tt.wantB {
+				t.Errorf("only in b = %s, want %s", got, tt.wantB)
+			}
+			if got := strings.Join(both, ","); got != tt.wantBoth {
+				t.Errorf("in both = %s, want %s", got, tt.wantBoth)
+			}
+		})
+	}
+}
+
+func TestDiffFoldersHandler(t *testing.T) {
+	folders := map[string][]string{
+		"Old":     {"1@x", "2@x", "3@x"},
+		"Archive": {"2@x", "3@x", "4@x"},
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		mock     *MockEmailService
+		wantA    []interface{}
+		wantB    []interface{}
+		wantBoth []interface{}
+		wantSync bool
+		wantCode string
+	}{
+		{
+			name:     "overlapping folders",
+			args:     map[string]interface{}{"folder_a": "Old", "folder_b": "Archive"},
+			mock:     &MockEmailService{FolderMessageIDs: folders},
+			wantA:    []interface{}{"1@x"},
+			wantB:    []interface{}{"4@x"},
+			wantBoth: []interface{}{"2@x", "3@x"},
+		},
+		{
+			name:     "migration complete",
+			args:     map[string]interface{}{"folder_a": "Old", "folder_b": "New"},
+			mock:     &MockEmailService{FolderMessageIDs: map[string][]string{"Old": {"1@x", "2@x"}, "New": {"2@x", "1@x"}}},
+			wantA:    []interface{}{},
+			wantB:    []interface{}{},
+			wantBoth: []interface{}{"1@x", "2@x"},
+			wantSync: true,
+		},
+		{
+			name:     "missing folder_b",
+			args:     map[string]interface{}{"folder_a": "Old"},
+			mock:     &MockEmailService{},
+			wantCode: CodeInvalidArgument,
+		},
+		{
+			name:     "same folder through an alias",
+			args:     map[string]interface{}{"folder_a": "sent", "folder_b": "Sent Messages"},
+			mock:     &MockEmailService{Aliases: map[string]string{"sent": "Sent Messages"}},
+			wantCode: CodeInvalidArgument,
+		},
+		{
+			name:     "invalid folder name",
+			args:     map[string]interface{}{"folder_a": "Old", "folder_b": "../etc"},
+			mock:     &MockEmailService{},
+			wantCode: CodeInvalidArgument,
+		},
+		{
+			name:     "fetch error",
+			args:     map[string]interface{}{"folder_a": "Old", "folder_b": "Archive"},
+			mock:     newErrMock("server unavailable"),
+			wantCode: CodeBackend,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DiffFoldersHandler(tt.mock)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantCode != "" {
+				if code := resultErrCode(t, result); code != tt.wantCode {
+					t.Errorf("code = %s, want %s", code, tt.wantCode)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			for key, want := range map[string][]interface{}{"only_in_a": tt.wantA, "only_in_b": tt.wantB, "in_both": tt.wantBoth} {
+				if fmt.Sprint(data[key]) != fmt.Sprint(want) {
+					t.Errorf("%s = %v, want %v", key, data[key], want)
+				}
+				if data[key+"_count"] != float64(len(want)) {
+					t.Errorf("%s_count = %v, want %d", key, data[key+"_count"], len(want))
+				}
+			}
+			if data["in_sync"] != tt.wantSync {
+				t.Errorf("in_sync = %v, want %v", data["in_sync"], tt.wantSync)
+			}
+			if data["truncated"] != nil {
+				t.Errorf("unexpected truncated = %v", data["truncated"])
+			}
+		})
+	}
+}
+
+func TestDiffFoldersHandlerLimit(t *testing.T) {
+	mock := &MockEmailService{FolderMessageIDs: map[string][]string{
+		"Old": {"1@x", "2@x", "3@x"},
+		"New": {"3@x"},
+	}}
+	result, err := DiffFoldersHandler(mock)(context.Background(), req(map[string]interface{}{
+		"folder_a": "Old", "folder_b": "New", "limit": float64(1),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if list := data["only_in_a"].([]interface{}); len(list) != 1 || list[0] != "1@x" {
+		t.Errorf("only_in_a = %v, want [1@x]", list)
+	}
+	if data["only_in_a_count"] != float64(2) {
+		t.Errorf("only_in_a_count = %v, want 2", data["only_in_a_count"])
+	}
+	if data["truncated"] != true {
+		t.Error("expected truncated = true")
+	}
+}
+
+// --- SendEmail ---
+
+func TestSendEmailHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		mock    *MockEmailSender
+		wantErr bool
+		errMsg  string
+	}{
+		{
 			name: "happy path",
 			args: map[string]interface{}{
 				"to":      "bob@example.com",
@@ -677,6 +2659,13 @@ func TestSendEmailHandler(t *testing.T) {
 			wantErr: true,
 			errMsg:  "body is required",
 		},
+		{
+			name:    "invalid utf-8 body",
+			args:    map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Caf\xe9"},
+			mock:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "not valid UTF-8",
+		},
 		{
 			name: "invalid to address",
 			args: map[string]interface{}{
@@ -689,121 +2678,1715 @@ func TestSendEmailHandler(t *testing.T) {
 			errMsg:  "invalid",
 		},
 		{
-			name: "backend error",
-			args: map[string]interface{}{
-				"to":      "bob@example.com",
-				"subject": "Hi",
-				"body":    "Hello",
+			name: "backend error",
+			args: map[string]interface{}{
+				"to":      "bob@example.com",
+				"subject": "Hi",
+				"body":    "Hello",
+			},
+			mock:    &MockEmailSender{Err: fmt.Errorf("SMTP fail")},
+			wantErr: true,
+			errMsg:  "failed to send email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SendEmailHandler(tt.mock, "me@icloud.com", nil)
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				msg := resultErrText(t, result)
+				if tt.errMsg != "" && !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["success"] != true {
+				t.Error("expected success=true")
+			}
+		})
+	}
+}
+
+func TestSendEmailHandlerOmitsBCC(t *testing.T) {
+	mock := &MockEmailSender{}
+	handler := SendEmailHandler(mock, "me@icloud.com", nil)
+	result, err := handler(context.Background(), req(map[string]interface{}{
+		"to":      "bob@example.com",
+		"cc":      "carol@example.com",
+		"bcc":     []interface{}{"dave@example.com", "Erin <erin@example.com>"},
+		"subject": "Hi",
+		"body":    "Hello",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if len(mock.LastOpts.BCC) != 2 {
+		t.Fatalf("BCC = %q, want both addresses sent", mock.LastOpts.BCC)
+	}
+
+	text := result.Content[0].(mcp.TextContent).Text
+	for _, addr := range []string{"dave@example.com", "erin@example.com", "Erin"} {
+		if strings.Contains(text, addr) {
+			t.Errorf("response leaks BCC %q:\n%s", addr, text)
+		}
+	}
+	data := resultJSON(t, result)
+	if data["message"] != "Email sent successfully to bob@example.com" {
+		t.Errorf("message = %v", data["message"])
+	}
+	if _, ok := data["bcc"]; ok {
+		t.Error("response has a bcc field")
+	}
+}
+
+func TestSendEmailHandlerReceipts(t *testing.T) {
+	base := func() map[string]interface{} {
+		return map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello"}
+	}
+
+	tests := []struct {
+		name         string
+		extra        map[string]interface{}
+		wantRead     bool
+		wantDelivery bool
+	}{
+		{name: "none requested"},
+		{name: "read receipt", extra: map[string]interface{}{"request_read_receipt": true}, wantRead: true},
+		{name: "delivery receipt", extra: map[string]interface{}{"request_delivery_receipt": true}, wantDelivery: true},
+		{
+			name:         "both",
+			extra:        map[string]interface{}{"request_read_receipt": true, "request_delivery_receipt": true},
+			wantRead:     true,
+			wantDelivery: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := base()
+			for k, v := range tt.extra {
+				args[k] = v
+			}
+			mock := &MockEmailSender{}
+			result, err := SendEmailHandler(mock, "me@icloud.com", nil)(context.Background(), req(args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			resultJSON(t, result)
+
+			read, hasRead := mock.LastOpts.Headers["Disposition-Notification-To"]
+			if hasRead != tt.wantRead || (hasRead && read != "me@icloud.com") {
+				t.Errorf("Disposition-Notification-To = %q (present=%v), want present=%v", read, hasRead, tt.wantRead)
+			}
+			delivery, hasDelivery := mock.LastOpts.Headers["Return-Receipt-To"]
+			if hasDelivery != tt.wantDelivery || (hasDelivery && delivery != "me@icloud.com") {
+				t.Errorf("Return-Receipt-To = %q (present=%v), want present=%v", delivery, hasDelivery, tt.wantDelivery)
+			}
+		})
+	}
+}
+
+func TestSendEmailHandlerPriority(t *testing.T) {
+	tests := []struct {
+		priority      string
+		wantXPriority string
+		wantImport    string
+		wantMSMail    string
+		wantErr       bool
+	}{
+		{priority: "high", wantXPriority: "1 (Highest)", wantImport: "high", wantMSMail: "High"},
+		{priority: "normal", wantXPriority: "3 (Normal)", wantImport: "normal", wantMSMail: "Normal"},
+		{priority: "low", wantXPriority: "5 (Lowest)", wantImport: "low", wantMSMail: "Low"},
+		{priority: ""},
+		{priority: "urgent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("priority="+tt.priority, func(t *testing.T) {
+			args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello"}
+			if tt.priority != "" {
+				args["priority"] = tt.priority
+			}
+			mock := &MockEmailSender{}
+			result, err := SendEmailHandler(mock, "me@icloud.com", nil)(context.Background(), req(args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				resultErrText(t, result)
+				return
+			}
+			resultJSON(t, result)
+
+			h := mock.LastOpts.Headers
+			if h["X-Priority"] != tt.wantXPriority || h["Importance"] != tt.wantImport || h["X-MSMail-Priority"] != tt.wantMSMail {
+				t.Errorf("headers = %v", h)
+			}
+			if tt.priority == "" {
+				for _, k := range []string{"X-Priority", "Importance", "X-MSMail-Priority"} {
+					if _, ok := h[k]; ok {
+						t.Errorf("unexpected %s header when priority omitted", k)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSendEmailHandlerIdempotencyKey(t *testing.T) {
+	send := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), key string) map[string]interface{} {
+		t.Helper()
+		args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello", "idempotency_key": key}
+		result, err := handler(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		return resultJSON(t, result)
+	}
+
+	t.Run("same key sends once", func(t *testing.T) {
+		mock := &MockEmailSender{}
+		handler := SendEmailHandler(mock, "me@icloud.com", nil)
+		first := send(handler, "abc")
+		second := send(handler, "abc")
+		if mock.CallCount != 1 {
+			t.Errorf("CallCount = %d, want 1", mock.CallCount)
+		}
+		if first["message"] != second["message"] {
+			t.Errorf("replayed result = %v, want %v", second, first)
+		}
+	})
+
+	t.Run("different keys both send", func(t *testing.T) {
+		mock := &MockEmailSender{}
+		handler := SendEmailHandler(mock, "me@icloud.com", nil)
+		send(handler, "abc")
+		send(handler, "def")
+		if mock.CallCount != 2 {
+			t.Errorf("CallCount = %d, want 2", mock.CallCount)
+		}
+	})
+
+	t.Run("failed send can be retried", func(t *testing.T) {
+		mock := &MockEmailSender{Err: fmt.Errorf("smtp down")}
+		handler := SendEmailHandler(mock, "me@icloud.com", nil)
+		args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello", "idempotency_key": "abc"}
+		result, _ := handler(context.Background(), req(args))
+		resultErrText(t, result)
+		mock.Err = nil
+		send(handler, "abc")
+		if mock.CallCount != 2 {
+			t.Errorf("CallCount = %d, want 2", mock.CallCount)
+		}
+	})
+}
+
+func TestSendEmailHandlerAutoBCC(t *testing.T) {
+	tests := []struct {
+		name     string
+		autoBCC  interface{}
+		wantSkip bool
+	}{
+		{name: "default"},
+		{name: "enabled", autoBCC: true},
+		{name: "disabled", autoBCC: false, wantSkip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello"}
+			if tt.autoBCC != nil {
+				args["auto_bcc"] = tt.autoBCC
+			}
+			mock := &MockEmailSender{}
+			result, err := SendEmailHandler(mock, "me@icloud.com", nil)(context.Background(), req(args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			resultJSON(t, result)
+			if mock.LastOpts.SkipAutoBCC != tt.wantSkip {
+				t.Errorf("SkipAutoBCC = %v, want %v", mock.LastOpts.SkipAutoBCC, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestSendEmailHandlerFrom(t *testing.T) {
+	allowed := []string{"alias@icloud.com"}
+
+	tests := []struct {
+		name     string
+		from     string
+		wantFrom string
+		wantErr  bool
+	}{
+		{name: "default", wantFrom: "me@icloud.com"},
+		{name: "allowed alias", from: "alias@icloud.com", wantFrom: "alias@icloud.com"},
+		{name: "case-insensitive", from: "Alias@iCloud.com", wantFrom: "alias@icloud.com"},
+		{name: "account address", from: "me@icloud.com", wantFrom: "me@icloud.com"},
+		{name: "disallowed", from: "ceo@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello"}
+			if tt.from != "" {
+				args["from"] = tt.from
+			}
+			mock := &MockEmailSender{}
+			result, err := SendEmailHandler(mock, "me@icloud.com", allowed)(context.Background(), req(args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				msg := resultErrText(t, result)
+				if !strings.Contains(msg, "not allowed") {
+					t.Errorf("error = %q, want containing %q", msg, "not allowed")
+				}
+				if mock.CallCount != 0 {
+					t.Error("disallowed From should not send")
+				}
+				return
+			}
+			resultJSON(t, result)
+			if mock.LastFrom != tt.wantFrom {
+				t.Errorf("From = %q, want %q", mock.LastFrom, tt.wantFrom)
+			}
+		})
+	}
+}
+
+// --- VerifyRecipient ---
+
+func TestVerifyRecipientHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		mock       *MockEmailSender
+		wantStatus string
+		wantErr    bool
+		errMsg     string
+	}{
+		{
+			name:       "accepted",
+			args:       map[string]interface{}{"address": "bob@example.com"},
+			mock:       &MockEmailSender{Accepted: true, Reply: "250 2.1.5 OK"},
+			wantStatus: "accepted",
+		},
+		{
+			name:       "rejected",
+			args:       map[string]interface{}{"address": "bob@example.com"},
+			mock:       &MockEmailSender{Reply: "550 5.1.1 No such user"},
+			wantStatus: "rejected",
+		},
+		{
+			name:       "greylisted",
+			args:       map[string]interface{}{"address": "bob@example.com"},
+			mock:       &MockEmailSender{Reply: "451 4.7.1 Try again later"},
+			wantStatus: "deferred",
+		},
+		{
+			name:    "missing address",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "address is required",
+		},
+		{
+			name:    "invalid address",
+			args:    map[string]interface{}{"address": "not-an-address"},
+			mock:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "invalid email address",
+		},
+		{
+			name:    "probe error",
+			args:    map[string]interface{}{"address": "bob@example.com"},
+			mock:    &MockEmailSender{Err: fmt.Errorf("connection refused")},
+			wantErr: true,
+			errMsg:  "failed to verify recipient",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyRecipientHandler(tt.mock)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				msg := resultErrText(t, result)
+				if !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["status"] != tt.wantStatus {
+				t.Errorf("status = %v, want %s", data["status"], tt.wantStatus)
+			}
+			if data["note"] == nil {
+				t.Error("expected catch-all note in response")
+			}
+		})
+	}
+}
+
+// --- CheckCredentials ---
+
+func TestCheckCredentialsHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     imappkg.CredentialCheck
+		wantValid bool
+		wantMsg   string
+	}{
+		{
+			name:      "valid",
+			check:     imappkg.CredentialCheck{Status: imappkg.CredentialsValid, Valid: true, ElapsedMs: 120},
+			wantValid: true,
+			wantMsg:   "fresh IMAP login succeeded",
+		},
+		{
+			name:    "auth failed",
+			check:   imappkg.CredentialCheck{Status: imappkg.CredentialsRejected, Error: "failed to login: Authentication failed"},
+			wantMsg: "appleid.apple.com",
+		},
+		{
+			name:    "network failed",
+			check:   imappkg.CredentialCheck{Status: imappkg.CredentialsNetwork, Error: "failed to connect to IMAP server: connection refused"},
+			wantMsg: "could not be reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{CredentialCheck: tt.check}
+			result, err := CheckCredentialsHandler(mock)(context.Background(), req(nil))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+			if data["status"] != tt.check.Status {
+				t.Errorf("status = %v, want %s", data["status"], tt.check.Status)
+			}
+			if data["valid"] != tt.wantValid {
+				t.Errorf("valid = %v, want %v", data["valid"], tt.wantValid)
+			}
+			if msg, _ := data["message"].(string); !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("message = %q, want containing %q", msg, tt.wantMsg)
+			}
+			if tt.check.Error != "" && data["error"] != tt.check.Error {
+				t.Errorf("error = %v, want %s", data["error"], tt.check.Error)
+			}
+			if tt.check.Error == "" && data["error"] != nil {
+				t.Errorf("unexpected error field %v", data["error"])
+			}
+		})
+	}
+}
+
+// --- CountBySender ---
+
+func TestCountBySenderHandler(t *testing.T) {
+	senders := []imappkg.SenderCount{
+		{Address: "news@shop.example", Count: 3},
+		{Address: "alice@example.com", Count: 2},
+	}
+
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		mock         *MockEmailService
+		wantLastDays int
+		wantLimit    int
+		wantErr      bool
+	}{
+		{
+			name:         "defaults",
+			args:         map[string]interface{}{},
+			mock:         &MockEmailService{Senders: senders},
+			wantLastDays: 30,
+			wantLimit:    10,
+		},
+		{
+			name:         "custom window and limit",
+			args:         map[string]interface{}{"folder": "Archive", "last_days": float64(7), "limit": float64(2)},
+			mock:         &MockEmailService{Senders: senders},
+			wantLastDays: 7,
+			wantLimit:    2,
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{},
+			mock:    newErrMock("search failed"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CountBySenderHandler(tt.mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if msg := resultErrText(t, result); !strings.Contains(msg, "failed to count by sender") {
+					t.Errorf("error = %q", msg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			got, ok := data["senders"].([]interface{})
+			if !ok || len(got) != 2 {
+				t.Fatalf("senders = %v", data["senders"])
+			}
+			if first := got[0].(map[string]interface{}); first["address"] != "news@shop.example" || first["count"] != float64(3) {
+				t.Errorf("first sender = %v", first)
+			}
+			if tt.mock.LastFilters.LastDays != tt.wantLastDays || tt.mock.LastFilters.Limit != tt.wantLimit {
+				t.Errorf("last_days/limit = %d/%d, want %d/%d", tt.mock.LastFilters.LastDays, tt.mock.LastFilters.Limit, tt.wantLastDays, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// --- FindDuplicates ---
+
+func TestFindDuplicatesHandler(t *testing.T) {
+	groups := []imappkg.DuplicateGroup{
+		{MessageID: "a@x", Subject: "Hi", IDs: []string{"3", "1", "7"}},
+		{MessageID: "b@x", Subject: "Bye", IDs: []string{"2", "5"}},
+	}
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		mock        *MockEmailService
+		wantMatch   bool
+		wantPartial bool
+		wantErr     string
+	}{
+		{
+			name: "report",
+			args: map[string]interface{}{},
+			mock: &MockEmailService{Duplicates: groups},
+		},
+		{
+			name:      "match headers",
+			args:      map[string]interface{}{"folder": "Archive", "match_headers": true},
+			mock:      &MockEmailService{Duplicates: groups},
+			wantMatch: true,
+		},
+		{
+			name:        "partial scan",
+			args:        map[string]interface{}{},
+			mock:        &MockEmailService{Duplicates: groups, PartialErr: fmt.Errorf("%w: fetched 10 of 20 messages", imappkg.ErrPartialResults)},
+			wantPartial: true,
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{},
+			mock:    newErrMock("search failed"),
+			wantErr: "failed to find duplicates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FindDuplicatesHandler(tt.mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want %q", msg, tt.wantErr)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["duplicates"] != float64(3) {
+				t.Errorf("duplicates = %v, want 3", data["duplicates"])
+			}
+			if got, ok := data["groups"].([]interface{}); !ok || len(got) != 2 {
+				t.Errorf("groups = %v", data["groups"])
+			}
+			if tt.mock.LastMatch != tt.wantMatch {
+				t.Errorf("match_headers = %v, want %v", tt.mock.LastMatch, tt.wantMatch)
+			}
+			if (data["partial"] == true) != tt.wantPartial {
+				t.Errorf("partial = %v, want %v", data["partial"], tt.wantPartial)
+			}
+			if len(tt.mock.Deleted) > 0 {
+				t.Errorf("deleted %v while only reporting", tt.mock.Deleted)
+			}
+		})
+	}
+}
+
+// --- RemoveDuplicates ---
+
+func TestRemoveDuplicatesHandler(t *testing.T) {
+	groups := []imappkg.DuplicateGroup{
+		{MessageID: "a@x", Subject: "Hi", IDs: []string{"3", "1", "7"}},
+		{MessageID: "b@x", Subject: "Bye", IDs: []string{"2", "5"}},
+	}
+	mock := &MockEmailService{Duplicates: groups}
+	handler := RemoveDuplicatesHandler(mock, "INBOX")
+	call := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		return result
+	}
+
+	// The first call lists the groups with a token and moves nothing
+	first := resultJSON(t, call(map[string]interface{}{"folder": "Archive", "match_headers": true}))
+	token, _ := first["confirm"].(string)
+	if first["confirmation_required"] != true || token == "" {
+		t.Fatalf("first call = %v, want a confirmation token", first)
+	}
+	if first["duplicates"] != float64(3) || !mock.LastMatch {
+		t.Errorf("first call = %v, match_headers = %v", first, mock.LastMatch)
+	}
+	if len(mock.Deleted) != 0 {
+		t.Fatal("duplicates moved without confirmation")
+	}
+
+	// A token for another folder is refused
+	if code := resultErrCode(t, call(map[string]interface{}{"confirm": token})); code != CodeInvalidArgument {
+		t.Errorf("token for another folder: code = %s, want %s", code, CodeInvalidArgument)
+	}
+
+	// The token covers the listed copies only: one more duplicate voids it
+	first = resultJSON(t, call(map[string]interface{}{"folder": "Archive"}))
+	token, _ = first["confirm"].(string)
+	mock.Duplicates = []imappkg.DuplicateGroup{groups[0], {MessageID: "b@x", IDs: []string{"2", "5", "9"}}}
+	if code := resultErrCode(t, call(map[string]interface{}{"folder": "Archive", "confirm": token})); code != CodeInvalidArgument {
+		t.Errorf("token after the duplicates changed: code = %s, want %s", code, CodeInvalidArgument)
+	}
+	mock.Duplicates = groups
+	if len(mock.Deleted) != 0 {
+		t.Fatalf("deleted %v without a valid token", mock.Deleted)
+	}
+
+	// Echoing the token back moves all but the oldest copy of each group
+	first = resultJSON(t, call(map[string]interface{}{"folder": "Archive"}))
+	token, _ = first["confirm"].(string)
+	done := resultJSON(t, call(map[string]interface{}{"folder": "Archive", "confirm": token}))
+	if done["success"] != true || done["removed"] != float64(3) {
+		t.Errorf("confirmed call = %v", done)
+	}
+	if got := strings.Join(mock.Deleted, ","); got != "1,7,5" {
+		t.Errorf("deleted %s, want 1,7,5", got)
+	}
+	if mock.LastPermanent {
+		t.Error("duplicates were deleted permanently, want moved to trash")
+	}
+
+	// Nothing to remove needs no token
+	none := &MockEmailService{}
+	result, err := RemoveDuplicatesHandler(none, "INBOX")(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if data := resultJSON(t, result); data["success"] != true || data["removed"] != float64(0) {
+		t.Errorf("no duplicates = %v", data)
+	}
+}
+
+func TestRemoveDuplicatesHandlerErrors(t *testing.T) {
+	groups := []imappkg.DuplicateGroup{{MessageID: "a@x", IDs: []string{"3", "1"}}}
+
+	tests := []struct {
+		name     string
+		mock     *MockEmailService
+		wantCode string
+		wantErr  string
+	}{
+		{
+			name:     "protected folder",
+			mock:     &MockEmailService{Duplicates: groups, ProtectErr: fmt.Errorf("%w: INBOX", imappkg.ErrProtectedFolder)},
+			wantCode: CodeProtected,
+			wantErr:  "cannot remove duplicates",
+		},
+		{
+			name:     "partial scan",
+			mock:     &MockEmailService{Duplicates: groups, PartialErr: fmt.Errorf("%w: fetched 1 of 2 messages", imappkg.ErrPartialResults)},
+			wantCode: CodeBackend,
+			wantErr:  "nothing was moved",
+		},
+		{
+			name:     "backend error",
+			mock:     newErrMock("search failed"),
+			wantCode: CodeBackend,
+			wantErr:  "failed to find duplicates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RemoveDuplicatesHandler(tt.mock, "INBOX")(context.Background(), req(map[string]interface{}{}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if code := resultErrCode(t, result); code != tt.wantCode {
+				t.Errorf("code = %s, want %s", code, tt.wantCode)
+			}
+			if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+				t.Errorf("error = %q, want %q", msg, tt.wantErr)
+			}
+			if len(tt.mock.Deleted) > 0 {
+				t.Errorf("deleted %v", tt.mock.Deleted)
+			}
+		})
+	}
+
+	// A failed move reports how far it got
+	mock := &MockEmailService{Duplicates: groups}
+	handler := RemoveDuplicatesHandler(mock, "INBOX")
+	result, _ := handler(context.Background(), req(map[string]interface{}{}))
+	token, _ := resultJSON(t, result)["confirm"].(string)
+	mock.DeleteErr = fmt.Errorf("move failed")
+	result, _ = handler(context.Background(), req(map[string]interface{}{"confirm": token}))
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to delete duplicate 1 after moving 0 to trash") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+// --- CountByDay ---
+
+func TestCountByDayHandler(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	mock := &MockEmailService{DayCounts: map[string]int{today: 4, "2000-01-01": 1}}
+
+	result, err := CountByDayHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"last_days": float64(7)}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if mock.LastFilters.LastDays != 7 || mock.LastFolder != "INBOX" {
+		t.Errorf("counted %q over %d days", mock.LastFolder, mock.LastFilters.LastDays)
+	}
+	if data["total"] != float64(5) {
+		t.Errorf("total = %v, want 5", data["total"])
+	}
+	days, ok := data["days"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("days = %v", data["days"])
+	}
+	if days[today] != float64(4) {
+		t.Errorf("today = %v, want 4", days[today])
+	}
+	// Seven days back plus today, and the day the server reported
+	if len(days) != 9 {
+		t.Errorf("got %d days, want every day of the window: %v", len(days), days)
+	}
+	if days[time.Now().AddDate(0, 0, -3).Format("2006-01-02")] != float64(0) {
+		t.Errorf("days without mail should count 0: %v", days)
+	}
+
+	result, err = CountByDayHandler(newErrMock("search failed"), "INBOX")(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to count by day") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+func TestFindLargeAttachmentsHandler(t *testing.T) {
+	found := []imappkg.LargeAttachment{
+		{EmailID: "7", Folder: "Archive", Filename: "video.mov", AttachmentSize: 5_700_000},
+		{EmailID: "2", Folder: "INBOX", Filename: "scan.pdf", AttachmentSize: 2_700_000},
+	}
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		wantFolders string
+		wantMinSize uint32
+		wantErr     bool
+	}{
+		{name: "defaults", args: map[string]interface{}{}, wantFolders: "INBOX", wantMinSize: 5 * 1024 * 1024},
+		{
+			name:        "several folders",
+			args:        map[string]interface{}{"folders": []interface{}{"INBOX", "Archive"}, "min_size_mb": 1.5},
+			wantFolders: "INBOX,Archive",
+			wantMinSize: 1536 * 1024,
+		},
+		{name: "invalid folder", args: map[string]interface{}{"folders": "../x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{LargeAttachments: found}
+			result, err := FindLargeAttachmentsHandler(mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if code := resultErrCode(t, result); code != CodeInvalidArgument {
+					t.Errorf("code = %q, want %q", code, CodeInvalidArgument)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			emails := data["emails"].([]interface{})
+			if len(emails) != 2 || emails[0].(map[string]interface{})["filename"] != "video.mov" {
+				t.Errorf("emails = %v", emails)
+			}
+			if got := strings.Join(mock.LastFolders, ","); got != tt.wantFolders || mock.LastMinSize != tt.wantMinSize {
+				t.Errorf("folders = %s, min size = %d; want %s, %d", got, mock.LastMinSize, tt.wantFolders, tt.wantMinSize)
+			}
+		})
+	}
+}
+
+func TestRecentSendersHandler(t *testing.T) {
+	recent := []imappkg.Correspondent{
+		{Address: "alice@example.com", Name: "Alice", LastContacted: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC)},
+		{Address: "bob@example.com", LastContacted: time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)},
+	}
+
+	mock := &MockEmailService{Correspondents: recent}
+	result, err := RecentSendersHandler(mock)(context.Background(), req(map[string]interface{}{"last_days": float64(14)}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	got := data["recipients"].([]interface{})
+	if len(got) != 2 || got[0].(map[string]interface{})["address"] != "alice@example.com" {
+		t.Fatalf("recipients = %v", got)
+	}
+	if got[0].(map[string]interface{})["last_contacted"] != "2024-01-12T09:00:00Z" {
+		t.Errorf("last_contacted = %v", got[0].(map[string]interface{})["last_contacted"])
+	}
+	if mock.LastFilters.LastDays != 14 || mock.LastFilters.Limit != 20 {
+		t.Errorf("last_days/limit = %d/%d, want 14/20", mock.LastFilters.LastDays, mock.LastFilters.Limit)
+	}
+
+	// A partial scan is still returned, with a warning
+	mock = &MockEmailService{Correspondents: recent[:1], PartialErr: fmt.Errorf("%w: fetched 1 of 2", imappkg.ErrPartialResults)}
+	result, err = RecentSendersHandler(mock)(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if data := resultJSON(t, result); data["partial"] != true || data["count"] != float64(1) {
+		t.Errorf("partial = %v, count = %v", data["partial"], data["count"])
+	}
+
+	result, err = RecentSendersHandler(newErrMock("list failed"))(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to list recent recipients") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+// --- GetNamespace ---
+
+func TestGetNamespaceHandler(t *testing.T) {
+	mock := &MockEmailService{Prefix: "INBOX.", Delimiter: "."}
+	result, err := GetNamespaceHandler(mock)(context.Background(), req(nil))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["prefix"] != "INBOX." || data["delimiter"] != "." {
+		t.Errorf("got %v", data)
+	}
+	if data["example"] != "INBOX.Parent.Child" {
+		t.Errorf("example = %v", data["example"])
+	}
+
+	result, err = GetNamespaceHandler(newErrMock("connection lost"))(context.Background(), req(nil))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to get namespace") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+// --- FolderFlags ---
+
+func TestFolderFlagsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		mock       *MockEmailService
+		wantCustom bool
+		wantErr    bool
+	}{
+		{
+			name:       "custom keywords allowed",
+			mock:       &MockEmailService{Flags: []string{`\Seen`, `\Flagged`}, PermFlags: []string{`\Seen`, `\Flagged`, `\*`}},
+			wantCustom: true,
+		},
+		{
+			name: "fixed flags only",
+			mock: &MockEmailService{Flags: []string{`\Seen`}, PermFlags: []string{`\Seen`}},
+		},
+		{
+			name:    "backend error",
+			mock:    newErrMock("no such folder"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FolderFlagsHandler(tt.mock, "INBOX")(context.Background(), req(map[string]interface{}{}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if msg := resultErrText(t, result); !strings.Contains(msg, "failed to get folder flags") {
+					t.Errorf("error = %q", msg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["custom_keywords_allowed"] != tt.wantCustom {
+				t.Errorf("custom_keywords_allowed = %v, want %v", data["custom_keywords_allowed"], tt.wantCustom)
+			}
+			if tt.mock.LastFolder != "INBOX" {
+				t.Errorf("folder = %q, want INBOX", tt.mock.LastFolder)
+			}
+		})
+	}
+}
+
+// --- FolderStatus ---
+
+func TestFolderStatusHandler(t *testing.T) {
+	mock := &MockEmailService{
+		Aliases: map[string]string{"archive": "Archive"},
+		Status:  &imappkg.FolderStatus{Folder: "Archive", Messages: 42, UIDNext: 120, UIDValidity: 1700000000},
+	}
+	result, err := FolderStatusHandler(mock, "INBOX")(context.Background(), req(map[string]interface{}{"folder": "archive"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["uidValidity"] != float64(1700000000) || data["uidNext"] != float64(120) || data["messages"] != float64(42) {
+		t.Errorf("response = %v", data)
+	}
+	if mock.LastMethod != "FolderStatus" || mock.LastFolder != "Archive" {
+		t.Errorf("called %s on %q, want FolderStatus on Archive", mock.LastMethod, mock.LastFolder)
+	}
+
+	result, err = FolderStatusHandler(newErrMock("no such folder"), "INBOX")(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if msg := resultErrText(t, result); !strings.Contains(msg, "failed to get folder status") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+// --- ExportFolder ---
+
+func TestExportFolderHandler(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		mock       *MockEmailService
+		wantFormat string
+		wantErr    string
+	}{
+		{
+			name:       "defaults to mbox",
+			args:       map[string]interface{}{"save_path": dir + "/inbox.mbox"},
+			mock:       &MockEmailService{Count: 2, Exported: 4096},
+			wantFormat: "mbox",
+		},
+		{
+			name:       "zip",
+			args:       map[string]interface{}{"save_path": dir + "/inbox.zip", "format": "zip", "folder": "Archive"},
+			mock:       &MockEmailService{Count: 2, Exported: 4096},
+			wantFormat: "zip",
+		},
+		{
+			name:    "missing save_path",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailService{},
+			wantErr: "save_path is required",
+		},
+		{
+			name:    "relative save_path",
+			args:    map[string]interface{}{"save_path": "inbox.mbox"},
+			mock:    &MockEmailService{},
+			wantErr: "absolute path",
+		},
+		{
+			name:    "missing directory",
+			args:    map[string]interface{}{"save_path": dir + "/nope/inbox.mbox"},
+			mock:    &MockEmailService{},
+			wantErr: "does not exist",
+		},
+		{
+			name:    "unknown format",
+			args:    map[string]interface{}{"save_path": dir + "/inbox.tar", "format": "tar"},
+			mock:    &MockEmailService{},
+			wantErr: "format must be one of",
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{"save_path": dir + "/inbox.mbox"},
+			mock:    newErrMock("connection lost"),
+			wantErr: "failed to export folder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExportFolderHandler(tt.mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want it to contain %q", msg, tt.wantErr)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["count"] != float64(2) || data["total_bytes"] != float64(4096) {
+				t.Errorf("count/total_bytes = %v/%v", data["count"], data["total_bytes"])
+			}
+			if tt.mock.LastFormat != tt.wantFormat || data["format"] != tt.wantFormat {
+				t.Errorf("format = %q, want %q", tt.mock.LastFormat, tt.wantFormat)
+			}
+			if tt.mock.LastPath != tt.args["save_path"] {
+				t.Errorf("path = %q", tt.mock.LastPath)
+			}
+		})
+	}
+}
+
+// --- ExportEmail ---
+
+func TestExportEmailHandler(t *testing.T) {
+	dir := t.TempDir()
+	mock := &MockEmailService{ExportedEmail: &imappkg.ExportedEmail{
+		Entries: []string{"42.eml", "report.pdf"},
+		Skipped: []string{"setup.exe"},
+		Size:    2048,
+	}}
+	handler := ExportEmailHandler(mock, "INBOX", AttachmentPolicy{Blocked: []string{".exe"}})
+	result, err := handler(context.Background(), req(map[string]interface{}{
+		"email_id":  "42",
+		"save_path": dir + "/report.zip",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+
+	if mock.LastEmailID != "42" || mock.LastFolder != "INBOX" || mock.LastPath != dir+"/report.zip" {
+		t.Errorf("exported %s from %s to %s", mock.LastEmailID, mock.LastFolder, mock.LastPath)
+	}
+	if mock.LastFilter == nil || mock.LastFilter("setup.exe", "application/octet-stream") == nil || mock.LastFilter("report.pdf", "application/pdf") != nil {
+		t.Error("attachment policy not passed as the export filter")
+	}
+	data := resultJSON(t, result)
+	if data["entry_count"] != float64(2) || data["path"] != dir+"/report.zip" {
+		t.Errorf("entry_count = %v, path = %v", data["entry_count"], data["path"])
+	}
+	if _, ok := data["warning"].(string); !ok {
+		t.Error("expected a warning for the skipped attachment")
+	}
+}
+
+func TestExportEmailHandlerInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range []map[string]interface{}{
+		{"save_path": dir + "/a.zip"},
+		{"email_id": "42"},
+		{"email_id": "42", "save_path": "a.zip"},
+		{"email_id": "42", "save_path": dir + "/../a.zip"},
+		{"email_id": "42", "save_path": dir + "/nope/a.zip"},
+	} {
+		mock := &MockEmailService{}
+		result, err := ExportEmailHandler(mock, "INBOX", AttachmentPolicy{})(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		if code := resultErrCode(t, result); code != CodeInvalidArgument {
+			t.Errorf("args %v: code = %q, want %q", args, code, CodeInvalidArgument)
+		}
+		if mock.CallCount != 0 {
+			t.Errorf("args %v: exported despite invalid arguments", args)
+		}
+	}
+}
+
+// --- ImportMbox ---
+
+func TestImportMboxHandler(t *testing.T) {
+	dir := t.TempDir()
+	mboxPath := dir + "/backup.mbox"
+	mbox := "From alice@example.com Mon Jan 15 10:00:00 2024\n" +
+		"Subject: one\n\nHello\n\n" +
+		"From bob@example.com Tue Jan 16 08:30:00 2024\n" +
+		"Subject: two\n\n>From here\n"
+	if err := os.WriteFile(mboxPath, []byte(mbox), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		mock         *MockEmailService
+		wantImported float64
+		wantErr      string
+	}{
+		{
+			name:         "two messages",
+			args:         map[string]interface{}{"mbox_path": mboxPath, "folder": "Restored"},
+			mock:         &MockEmailService{},
+			wantImported: 2,
+		},
+		{
+			name:    "missing mbox_path",
+			args:    map[string]interface{}{"folder": "Restored"},
+			mock:    &MockEmailService{},
+			wantErr: "mbox_path is required",
+		},
+		{
+			name:    "traversal rejected",
+			args:    map[string]interface{}{"mbox_path": dir + "/../backup.mbox", "folder": "Restored"},
+			mock:    &MockEmailService{},
+			wantErr: "mbox_path must not contain path traversal",
+		},
+		{
+			name:    "missing folder",
+			args:    map[string]interface{}{"mbox_path": mboxPath},
+			mock:    &MockEmailService{},
+			wantErr: "folder is required",
+		},
+		{
+			name:    "file not found",
+			args:    map[string]interface{}{"mbox_path": dir + "/nope.mbox", "folder": "Restored"},
+			mock:    &MockEmailService{},
+			wantErr: "failed to open mbox file",
+		},
+		{
+			name:    "append fails",
+			args:    map[string]interface{}{"mbox_path": mboxPath, "folder": "Restored"},
+			mock:    newErrMock("over quota"),
+			wantErr: "after 0 messages: over quota",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ImportMboxHandler(tt.mock)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr != "" {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErr) {
+					t.Errorf("error = %q, want it to contain %q", msg, tt.wantErr)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["imported"] != tt.wantImported {
+				t.Errorf("imported = %v, want %v", data["imported"], tt.wantImported)
+			}
+			if len(tt.mock.Appended) != 2 || tt.mock.LastFolder != "Restored" {
+				t.Fatalf("appended %d to %q", len(tt.mock.Appended), tt.mock.LastFolder)
+			}
+			if !strings.HasSuffix(tt.mock.Appended[1], "\r\n\r\nFrom here\r\n") {
+				t.Errorf("second message = %q", tt.mock.Appended[1])
+			}
+		})
+	}
+}
+
+// --- ListDrafts / DeleteDraft ---
+
+func TestListDraftsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		mock        *MockEmailService
+		wantCount   float64
+		wantPartial bool
+		wantErr     bool
+	}{
+		{
+			name:      "happy path",
+			mock:      &MockEmailService{Emails: []imappkg.Email{{ID: "7", Subject: "Draft one", To: []string{"bob@example.com"}}, {ID: "9", Subject: "Draft two"}}},
+			wantCount: 2,
+		},
+		{
+			name:      "no drafts",
+			mock:      &MockEmailService{},
+			wantCount: 0,
+		},
+		{
+			name:        "partial",
+			mock:        &MockEmailService{Emails: []imappkg.Email{{ID: "7"}}, PartialErr: fmt.Errorf("%w: fetched 1 of 2 messages", imappkg.ErrPartialResults)},
+			wantCount:   1,
+			wantPartial: true,
+		},
+		{
+			name:    "backend error",
+			mock:    newErrMock("connection lost"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ListDraftsHandler(tt.mock)(context.Background(), req(map[string]interface{}{}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if msg := resultErrText(t, result); !strings.Contains(msg, "failed to list drafts") {
+					t.Errorf("error = %q", msg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["count"] != tt.wantCount {
+				t.Errorf("count = %v, want %v", data["count"], tt.wantCount)
+			}
+			if _, ok := data["partial"]; ok != tt.wantPartial {
+				t.Errorf("partial present = %v, want %v", ok, tt.wantPartial)
+			}
+		})
+	}
+}
+
+func TestDeleteDraftHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		mock    *MockEmailService
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "happy path",
+			args: map[string]interface{}{"email_id": "7"},
+			mock: &MockEmailService{},
+		},
+		{
+			name:    "missing email_id",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailService{},
+			wantErr: true,
+			errMsg:  "email_id is required",
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{"email_id": "7"},
+			mock:    newErrMock("expunge failed"),
+			wantErr: true,
+			errMsg:  "failed to delete draft",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DeleteDraftHandler(tt.mock)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			resultJSON(t, result)
+			if tt.mock.LastMethod != "DeleteDraft" || tt.mock.LastEmailID != "7" {
+				t.Errorf("called %s(%s), want DeleteDraft(7)", tt.mock.LastMethod, tt.mock.LastEmailID)
+			}
+		})
+	}
+}
+
+// --- BlockSender ---
+
+func TestBlockSenderHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		mock        *MockEmailService
+		wantFolder  string
+		wantMessage string
+		wantErr     bool
+		errMsg      string
+	}{
+		{
+			name:        "new sender",
+			args:        map[string]interface{}{"email_id": "42"},
+			mock:        &MockEmailService{Block: &imappkg.BlockResult{Sender: "spam@example.com", JunkFolder: "Junk", Blocked: []string{"spam@example.com"}}},
+			wantFolder:  "INBOX",
+			wantMessage: "Moved to Junk and blocked spam@example.com",
+		},
+		{
+			name:        "already blocked",
+			args:        map[string]interface{}{"email_id": "42", "folder": "Promotions"},
+			mock:        &MockEmailService{Block: &imappkg.BlockResult{Sender: "spam@example.com", JunkFolder: "Junk", AlreadyBlocked: true, Blocked: []string{"spam@example.com"}}},
+			wantFolder:  "Promotions",
+			wantMessage: "Moved to Junk; spam@example.com was already blocked",
+		},
+		{
+			name:    "missing email_id",
+			args:    map[string]interface{}{},
+			mock:    &MockEmailService{},
+			wantErr: true,
+			errMsg:  "email_id is required",
+		},
+		{
+			name:    "backend error",
+			args:    map[string]interface{}{"email_id": "42"},
+			mock:    newErrMock("move failed"),
+			wantErr: true,
+			errMsg:  "failed to block sender",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := BlockSenderHandler(tt.mock, "INBOX")(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				if msg := resultErrText(t, result); !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["message"] != tt.wantMessage {
+				t.Errorf("message = %v, want %q", data["message"], tt.wantMessage)
+			}
+			if tt.mock.LastFolder != tt.wantFolder {
+				t.Errorf("folder = %q, want %q", tt.mock.LastFolder, tt.wantFolder)
+			}
+			if blocked, ok := data["blocked_senders"].([]interface{}); !ok || len(blocked) != 1 {
+				t.Errorf("blocked_senders = %v", data["blocked_senders"])
+			}
+		})
+	}
+}
+
+// --- ReplyEmail ---
+
+func TestReplyEmailHandler(t *testing.T) {
+	original := &imappkg.Email{
+		ID:        "100",
+		From:      "alice@example.com",
+		Subject:   "Original",
+		MessageID: "<msg@example.com>",
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		imap    *MockEmailService
+		smtp    *MockEmailSender
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "happy path",
+			args: map[string]interface{}{"email_id": "100", "body": "Thanks!"},
+			imap: &MockEmailService{Email: original},
+			smtp: &MockEmailSender{},
+		},
+		{
+			name: "reply all with HTML",
+			args: map[string]interface{}{"email_id": "100", "body": "<p>Thanks!</p>", "reply_all": true, "html": true},
+			imap: &MockEmailService{Email: original},
+			smtp: &MockEmailSender{},
+		},
+		{
+			name:    "missing email_id",
+			args:    map[string]interface{}{"body": "reply"},
+			imap:    &MockEmailService{},
+			smtp:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "email_id is required",
+		},
+		{
+			name:    "missing body",
+			args:    map[string]interface{}{"email_id": "100"},
+			imap:    &MockEmailService{},
+			smtp:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "body is required",
+		},
+		{
+			name:    "IMAP error fetching original",
+			args:    map[string]interface{}{"email_id": "100", "body": "reply"},
+			imap:    newErrMock("not found"),
+			smtp:    &MockEmailSender{},
+			wantErr: true,
+			errMsg:  "failed to get original email",
+		},
+		{
+			name:    "SMTP error sending reply",
+			args:    map[string]interface{}{"email_id": "100", "body": "reply"},
+			imap:    &MockEmailService{Email: original},
+			smtp:    &MockEmailSender{Err: fmt.Errorf("SMTP fail")},
+			wantErr: true,
+			errMsg:  "failed to send reply",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ReplyEmailHandler(tt.imap, tt.smtp, "INBOX", false)
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if tt.wantErr {
+				msg := resultErrText(t, result)
+				if tt.errMsg != "" && !strings.Contains(msg, tt.errMsg) {
+					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["success"] != true {
+				t.Error("expected success=true")
+			}
+		})
+	}
+}
+
+func TestReplyEmailHandlerReplyAllDefault(t *testing.T) {
+	original := &imappkg.Email{ID: "100", From: "alice@example.com", Subject: "Original"}
+
+	tests := []struct {
+		name       string
+		defaultAll bool
+		args       map[string]interface{}
+		want       bool
+	}{
+		{name: "default off", args: map[string]interface{}{}, want: false},
+		{name: "default on", defaultAll: true, args: map[string]interface{}{}, want: true},
+		{name: "argument overrides default", defaultAll: true, args: map[string]interface{}{"reply_all": false}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smtp := &MockEmailSender{}
+			handler := ReplyEmailHandler(&MockEmailService{Email: original}, smtp, "INBOX", tt.defaultAll)
+			tt.args["email_id"] = "100"
+			tt.args["body"] = "Thanks!"
+			result, err := handler(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			resultJSON(t, result)
+			if smtp.LastReplyAll != tt.want {
+				t.Errorf("replyAll = %v, want %v", smtp.LastReplyAll, tt.want)
+			}
+		})
+	}
+}
+
+// --- PreviewReply ---
+
+func TestPreviewReplyHandler(t *testing.T) {
+	original := &imappkg.Email{ID: "100", From: "alice@example.com", Subject: "Original"}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{name: "reply to sender", args: map[string]interface{}{"email_id": "100"}},
+		{name: "reply all", args: map[string]interface{}{"email_id": "100", "reply_all": true}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imapMock := &MockEmailService{Email: original}
+			smtp := &MockEmailSender{Preview: smtppkg.ReplyPreview{
+				To:        []string{"alice@example.com"},
+				CC:        []string{"bob@example.com"},
+				Subject:   "Re: Original",
+				InReplyTo: "<1@example.com>",
+			}}
+			result, err := PreviewReplyHandler(imapMock, smtp, "INBOX", false)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+
+			if smtp.LastMethod != "PreviewReply" || smtp.LastOriginal != original || smtp.LastReplyAll != tt.want {
+				t.Errorf("called %s with replyAll %v", smtp.LastMethod, smtp.LastReplyAll)
+			}
+			preview, _ := data["preview"].(map[string]interface{})
+			if preview["subject"] != "Re: Original" || preview["inReplyTo"] != "<1@example.com>" {
+				t.Errorf("preview = %v", preview)
+			}
+			if data["reply_all"] != tt.want {
+				t.Errorf("reply_all = %v, want %v", data["reply_all"], tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewReplyHandlerMissingID(t *testing.T) {
+	smtp := &MockEmailSender{}
+	result, err := PreviewReplyHandler(&MockEmailService{}, smtp, "INBOX", false)(context.Background(), req(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("code = %q, want %q", code, CodeInvalidArgument)
+	}
+	if smtp.CallCount != 0 {
+		t.Error("previewed without an email_id")
+	}
+}
+
+// --- DEFAULT_FOLDER ---
+
+func TestDefaultFolder(t *testing.T) {
+	email := &imappkg.Email{ID: "12", Subject: "Hi"}
+	tests := []struct {
+		name    string
+		call    func(mock *MockEmailService, args map[string]interface{}) (*mcp.CallToolResult, error)
+		args    map[string]interface{}
+		usedArg func(mock *MockEmailService) string
+	}{
+		{
+			name: "search_emails",
+			call: func(m *MockEmailService, a map[string]interface{}) (*mcp.CallToolResult, error) {
+				return SearchEmailsHandler(m, "All Mail", false)(context.Background(), req(a))
+			},
+			args:    map[string]interface{}{},
+			usedArg: func(m *MockEmailService) string { return m.LastFolder },
+		},
+		{
+			name: "get_email",
+			call: func(m *MockEmailService, a map[string]interface{}) (*mcp.CallToolResult, error) {
+				return GetEmailHandler(m, "All Mail", 0, AttachmentPolicy{})(context.Background(), req(a))
+			},
+			args:    map[string]interface{}{"email_id": "12"},
+			usedArg: func(m *MockEmailService) string { return m.LastFolder },
+		},
+		{
+			name: "count_emails",
+			call: func(m *MockEmailService, a map[string]interface{}) (*mcp.CallToolResult, error) {
+				return CountEmailsHandler(m, "All Mail")(context.Background(), req(a))
+			},
+			args:    map[string]interface{}{},
+			usedArg: func(m *MockEmailService) string { return m.LastFolder },
+		},
+		{
+			name: "move_email from_folder",
+			call: func(m *MockEmailService, a map[string]interface{}) (*mcp.CallToolResult, error) {
+				return MoveEmailHandler(m, "All Mail")(context.Background(), req(a))
 			},
-			mock:    &MockEmailSender{Err: fmt.Errorf("SMTP fail")},
-			wantErr: true,
-			errMsg:  "failed to send email",
+			args:    map[string]interface{}{"email_id": "12", "to_folder": "Archive"},
+			usedArg: func(m *MockEmailService) string { return m.LastFromFolder },
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := SendEmailHandler(tt.mock, "me@icloud.com")
-			result, err := handler(context.Background(), req(tt.args))
-			if err != nil {
+			// Omitted folder uses the configured default
+			mock := &MockEmailService{Email: email}
+			if _, err := tt.call(mock, tt.args); err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
 			}
-			if tt.wantErr {
-				msg := resultErrText(t, result)
-				if tt.errMsg != "" && !strings.Contains(msg, tt.errMsg) {
-					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
-				}
-				return
+			if got := tt.usedArg(mock); got != "All Mail" {
+				t.Errorf("folder = %q, want the configured default", got)
 			}
-			data := resultJSON(t, result)
-			if data["success"] != true {
-				t.Error("expected success=true")
+
+			// An explicit folder still wins
+			args := map[string]interface{}{"folder": "Work", "from_folder": "Work"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			mock = &MockEmailService{Email: email}
+			if _, err := tt.call(mock, args); err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			if got := tt.usedArg(mock); got != "Work" {
+				t.Errorf("folder = %q, want the explicit folder", got)
 			}
 		})
 	}
 }
 
-// --- ReplyEmail ---
+// --- Resend ---
 
-func TestReplyEmailHandler(t *testing.T) {
-	original := &imappkg.Email{
-		ID:        "100",
-		From:      "alice@example.com",
-		Subject:   "Original",
-		MessageID: "<msg@example.com>",
+func TestResendHandler(t *testing.T) {
+	stored := &imappkg.StoredMessage{
+		From:       "me@icloud.com",
+		To:         []string{"alice@example.com"},
+		CC:         []string{"carol@example.com"},
+		Subject:    "Quarterly report",
+		Body:       "<p>See attached</p>",
+		HTML:       true,
+		InReplyTo:  "<2@x>",
+		References: []string{"<1@x>", "<2@x>"},
 	}
 
-	tests := []struct {
-		name    string
-		args    map[string]interface{}
-		imap    *MockEmailService
-		smtp    *MockEmailSender
-		wantErr bool
-		errMsg  string
+	t.Run("reconstructs the original send", func(t *testing.T) {
+		imapMock := &MockEmailService{Stored: stored}
+		smtpMock := &MockEmailSender{}
+		result, err := ResendHandler(imapMock, smtpMock, "me@icloud.com", nil)(context.Background(), req(map[string]interface{}{"email_id": "7"}))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		data := resultJSON(t, result)
+		if data["success"] != true {
+			t.Error("expected success=true")
+		}
+		if imapMock.LastFolder != "sent" || imapMock.LastEmailID != "7" {
+			t.Errorf("fetched %s/%s, want sent/7", imapMock.LastFolder, imapMock.LastEmailID)
+		}
+		if smtpMock.LastFrom != "me@icloud.com" || strings.Join(smtpMock.LastTo, ",") != "alice@example.com" {
+			t.Errorf("from = %q, to = %v", smtpMock.LastFrom, smtpMock.LastTo)
+		}
+		if smtpMock.LastSubject != "Quarterly report" || smtpMock.LastBody != "<p>See attached</p>" || !smtpMock.LastOpts.HTML {
+			t.Errorf("subject = %q, body = %q, html = %v", smtpMock.LastSubject, smtpMock.LastBody, smtpMock.LastOpts.HTML)
+		}
+		if strings.Join(smtpMock.LastOpts.CC, ",") != "carol@example.com" {
+			t.Errorf("cc = %v", smtpMock.LastOpts.CC)
+		}
+		if smtpMock.LastOpts.Headers["In-Reply-To"] != "<2@x>" || smtpMock.LastOpts.Headers["References"] != "<1@x> <2@x>" {
+			t.Errorf("threading headers = %v", smtpMock.LastOpts.Headers)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		imapMock := &MockEmailService{Stored: stored}
+		smtpMock := &MockEmailSender{}
+		args := map[string]interface{}{
+			"email_id": "7",
+			"folder":   "Failed",
+			"to":       []interface{}{"dave@example.com", "erin@example.com"},
+			"cc":       []interface{}{},
+			"subject":  "Quarterly report (resent)",
+			"body":     "Resending, the first one bounced.",
+		}
+		result, err := ResendHandler(imapMock, smtpMock, "me@icloud.com", nil)(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		resultJSON(t, result)
+		if imapMock.LastFolder != "Failed" {
+			t.Errorf("folder = %q, want Failed", imapMock.LastFolder)
+		}
+		if strings.Join(smtpMock.LastTo, ",") != "dave@example.com,erin@example.com" {
+			t.Errorf("to = %v", smtpMock.LastTo)
+		}
+		if len(smtpMock.LastOpts.CC) != 0 {
+			t.Errorf("cc = %v, want cleared", smtpMock.LastOpts.CC)
+		}
+		if smtpMock.LastSubject != "Quarterly report (resent)" || smtpMock.LastBody != "Resending, the first one bounced." {
+			t.Errorf("subject = %q, body = %q", smtpMock.LastSubject, smtpMock.LastBody)
+		}
+		// A replacement body is plain text unless html says otherwise
+		if smtpMock.LastOpts.HTML {
+			t.Error("expected plain text body")
+		}
+		if smtpMock.LastOpts.Headers["References"] != "<1@x> <2@x>" {
+			t.Errorf("references = %q, want preserved", smtpMock.LastOpts.Headers["References"])
+		}
+		// The stored message returned by the reader is left untouched
+		if strings.Join(stored.To, ",") != "alice@example.com" {
+			t.Errorf("stored message modified: %v", stored.To)
+		}
+	})
+
+	errTests := []struct {
+		name     string
+		args     map[string]interface{}
+		imap     *MockEmailService
+		smtp     *MockEmailSender
+		wantCode string
+		errMsg   string
 	}{
 		{
-			name: "happy path",
-			args: map[string]interface{}{"email_id": "100", "body": "Thanks!"},
-			imap: &MockEmailService{Email: original},
-			smtp: &MockEmailSender{},
+			name:     "missing email_id",
+			args:     map[string]interface{}{},
+			imap:     &MockEmailService{},
+			wantCode: CodeInvalidArgument,
+			errMsg:   "email_id is required",
 		},
 		{
-			name: "reply all with HTML",
-			args: map[string]interface{}{"email_id": "100", "body": "<p>Thanks!</p>", "reply_all": true, "html": true},
-			imap: &MockEmailService{Email: original},
-			smtp: &MockEmailSender{},
+			name:     "invalid override",
+			args:     map[string]interface{}{"email_id": "7", "to": "not an address"},
+			imap:     &MockEmailService{Stored: stored},
+			wantCode: CodeInvalidArgument,
 		},
 		{
-			name:    "missing email_id",
-			args:    map[string]interface{}{"body": "reply"},
-			imap:    &MockEmailService{},
-			smtp:    &MockEmailSender{},
-			wantErr: true,
-			errMsg:  "email_id is required",
+			name:     "sender not allowed",
+			args:     map[string]interface{}{"email_id": "7", "from": "someone@else.com"},
+			imap:     &MockEmailService{Stored: stored},
+			wantCode: CodeInvalidArgument,
+			errMsg:   "is not allowed",
 		},
 		{
-			name:    "missing body",
-			args:    map[string]interface{}{"email_id": "100"},
-			imap:    &MockEmailService{},
-			smtp:    &MockEmailSender{},
-			wantErr: true,
-			errMsg:  "body is required",
+			name:     "no recipients",
+			args:     map[string]interface{}{"email_id": "7"},
+			imap:     &MockEmailService{Stored: &imappkg.StoredMessage{From: "me@icloud.com", Subject: "x"}},
+			wantCode: CodeInvalidArgument,
+			errMsg:   "no recipients",
 		},
 		{
-			name:    "IMAP error fetching original",
-			args:    map[string]interface{}{"email_id": "100", "body": "reply"},
-			imap:    newErrMock("not found"),
-			smtp:    &MockEmailSender{},
-			wantErr: true,
-			errMsg:  "failed to get original email",
+			name:     "message not found",
+			args:     map[string]interface{}{"email_id": "7"},
+			imap:     &MockEmailService{Err: fmt.Errorf("email %w", imappkg.ErrNotFound)},
+			wantCode: CodeNotFound,
+			errMsg:   "failed to get original message",
 		},
 		{
-			name:    "SMTP error sending reply",
-			args:    map[string]interface{}{"email_id": "100", "body": "reply"},
-			imap:    &MockEmailService{Email: original},
-			smtp:    &MockEmailSender{Err: fmt.Errorf("SMTP fail")},
-			wantErr: true,
-			errMsg:  "failed to send reply",
+			name:     "SMTP failure",
+			args:     map[string]interface{}{"email_id": "7"},
+			imap:     &MockEmailService{Stored: stored},
+			smtp:     &MockEmailSender{Err: fmt.Errorf("SMTP fail")},
+			wantCode: CodeBackend,
+			errMsg:   "failed to resend email",
 		},
 	}
 
-	for _, tt := range tests {
+	for _, tt := range errTests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := ReplyEmailHandler(tt.imap, tt.smtp)
-			result, err := handler(context.Background(), req(tt.args))
+			smtpMock := tt.smtp
+			if smtpMock == nil {
+				smtpMock = &MockEmailSender{}
+			}
+			result, err := ResendHandler(tt.imap, smtpMock, "me@icloud.com", nil)(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
 			}
-			if tt.wantErr {
-				msg := resultErrText(t, result)
-				if tt.errMsg != "" && !strings.Contains(msg, tt.errMsg) {
-					t.Errorf("error = %q, want containing %q", msg, tt.errMsg)
-				}
-				return
+			payload := resultErr(t, result)
+			if payload.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", payload.Code, tt.wantCode)
 			}
-			data := resultJSON(t, result)
-			if data["success"] != true {
-				t.Error("expected success=true")
+			if tt.errMsg != "" && !strings.Contains(payload.Message, tt.errMsg) {
+				t.Errorf("error = %q, want containing %q", payload.Message, tt.errMsg)
+			}
+			if tt.smtp == nil && smtpMock.CallCount != 0 {
+				t.Error("expected no send")
 			}
 		})
 	}
@@ -875,7 +4458,7 @@ func TestDraftEmailHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := DraftEmailHandler(tt.mock, "me@icloud.com")
+			handler := DraftEmailHandler(tt.mock, "me@icloud.com", "INBOX")
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -898,6 +4481,28 @@ func TestDraftEmailHandler(t *testing.T) {
 	}
 }
 
+func TestDraftEmailHandlerPriority(t *testing.T) {
+	args := map[string]interface{}{"to": "bob@example.com", "subject": "Hi", "body": "Hello", "priority": "low"}
+	mock := &MockEmailService{DraftID: "1"}
+	result, err := DraftEmailHandler(mock, "me@icloud.com", "INBOX")(context.Background(), req(args))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	resultJSON(t, result)
+	if got := mock.LastDraftOpts.Headers["X-Priority"]; got != "5 (Lowest)" {
+		t.Errorf("X-Priority = %q, want 5 (Lowest)", got)
+	}
+
+	mock = &MockEmailService{DraftID: "1"}
+	delete(args, "priority")
+	if _, err := DraftEmailHandler(mock, "me@icloud.com", "INBOX")(context.Background(), req(args)); err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if len(mock.LastDraftOpts.Headers) != 0 {
+		t.Errorf("expected no headers without priority, got %v", mock.LastDraftOpts.Headers)
+	}
+}
+
 // --- GetAttachment ---
 
 func TestGetAttachmentHandler(t *testing.T) {
@@ -945,7 +4550,7 @@ func TestGetAttachmentHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := GetAttachmentHandler(tt.mock)
+			handler := GetAttachmentHandler(tt.mock, "INBOX", AttachmentPolicy{})
 			result, err := handler(context.Background(), req(tt.args))
 			if err != nil {
 				t.Fatalf("unexpected Go error: %v", err)
@@ -968,6 +4573,105 @@ func TestGetAttachmentHandler(t *testing.T) {
 	}
 }
 
+func TestGetAttachmentHandlerCompress(t *testing.T) {
+	original := []byte(strings.Repeat("quarterly report line,", 500))
+	mock := &MockEmailService{Attachment: &imappkg.AttachmentData{
+		Filename: "report.csv",
+		Content:  original,
+		MIMEType: "text/csv",
+		Size:     int64(len(original)),
+	}}
+	handler := GetAttachmentHandler(mock, "INBOX", AttachmentPolicy{})
+
+	for _, compress := range []bool{false, true} {
+		result, err := handler(context.Background(), req(map[string]interface{}{"email_id": "100", "filename": "report.csv", "compress": compress}))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		data := resultJSON(t, result)
+		raw, err := base64.StdEncoding.DecodeString(data["data"].(string))
+		if err != nil {
+			t.Fatalf("compress=%v: invalid base64: %v", compress, err)
+		}
+
+		if !compress {
+			if data["encoding"] != "base64" || !bytes.Equal(raw, original) {
+				t.Errorf("uncompressed: encoding = %v, content mismatch = %v", data["encoding"], !bytes.Equal(raw, original))
+			}
+			continue
+		}
+
+		if data["encoding"] != "gzip+base64" {
+			t.Errorf("encoding = %v, want gzip+base64", data["encoding"])
+		}
+		if len(raw) >= len(original) {
+			t.Errorf("compressed %d bytes to %d", len(original), len(raw))
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("invalid gzip: %v", err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("failed to decompress: %v", err)
+		}
+		if !bytes.Equal(got, original) {
+			t.Error("decompressed content differs from the attachment")
+		}
+	}
+}
+
+func TestGetAttachmentHandlerPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		filename   string
+		mimeType   string
+		policy     AttachmentPolicy
+		wantDenied bool
+	}{
+		{name: "no policy", filename: "setup.exe", mimeType: "application/x-msdownload"},
+		{name: "blocked extension", filename: "setup.exe", mimeType: "application/x-msdownload", policy: AttachmentPolicy{Blocked: []string{".exe"}}, wantDenied: true},
+		{name: "blocked mime type", filename: "setup.bin", mimeType: "application/x-msdownload", policy: AttachmentPolicy{Blocked: []string{"application/x-msdownload"}}, wantDenied: true},
+		{name: "blocked extension behind octet-stream", filename: "SETUP.EXE", mimeType: "application/octet-stream", policy: AttachmentPolicy{Blocked: []string{".exe"}}, wantDenied: true},
+		{name: "not blocked", filename: "report.pdf", mimeType: "application/pdf", policy: AttachmentPolicy{Blocked: []string{".exe"}}},
+		{name: "allowed mime type", filename: "report.pdf", mimeType: "application/pdf", policy: AttachmentPolicy{Allowed: []string{"application/pdf"}}},
+		{name: "allowed wildcard", filename: "photo.png", mimeType: "image/png", policy: AttachmentPolicy{Allowed: []string{"image/*"}}},
+		{name: "allowed via extension behind octet-stream", filename: "report.pdf", mimeType: "application/octet-stream", policy: AttachmentPolicy{Allowed: []string{"application/pdf"}}},
+		{name: "not allowed", filename: "notes.zip", mimeType: "application/zip", policy: AttachmentPolicy{Allowed: []string{"application/pdf"}}, wantDenied: true},
+		{name: "blocked wins over allowed", filename: "macro.docm", mimeType: "application/octet-stream", policy: AttachmentPolicy{Allowed: []string{".docm"}, Blocked: []string{".docm"}}, wantDenied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{Attachment: &imappkg.AttachmentData{
+				Filename: tt.filename,
+				Content:  []byte("content"),
+				MIMEType: tt.mimeType,
+				Size:     7,
+			}}
+			handler := GetAttachmentHandler(mock, "INBOX", tt.policy)
+			result, err := handler(context.Background(), req(map[string]interface{}{"email_id": "100", "filename": tt.filename}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+
+			if tt.wantDenied {
+				if code := resultErrCode(t, result); code != CodePermission {
+					t.Errorf("code = %q, want %q", code, CodePermission)
+				}
+				if msg := resultErrText(t, result); !strings.Contains(msg, "ATTACHMENT_TYPES") {
+					t.Errorf("error %q does not name the setting", msg)
+				}
+				return
+			}
+			data := resultJSON(t, result)
+			if data["filename"] != tt.filename {
+				t.Errorf("filename = %v, want %s", data["filename"], tt.filename)
+			}
+		})
+	}
+}
+
 // --- CreateFolder ---
 
 func TestCreateFolderHandler(t *testing.T) {
@@ -1023,6 +4727,106 @@ func TestCreateFolderHandler(t *testing.T) {
 			}
 		})
 	}
+
+	// The reported path is the one the server created, not a "/" join
+	mock := &MockEmailService{Delimiter: "."}
+	result, err := CreateFolderHandler(mock)(context.Background(), req(map[string]interface{}{"name": "Work", "parent": "Projects"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if data := resultJSON(t, result); data["path"] != "Projects.Work" {
+		t.Errorf("path = %v, want Projects.Work", data["path"])
+	}
+}
+
+func TestCreateFolderHandlerUse(t *testing.T) {
+	mock := &MockEmailService{}
+	result, err := CreateFolderHandler(mock)(context.Background(), req(map[string]interface{}{"name": "Archive", "use": "archive"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if data := resultJSON(t, result); data["use"] != "archive" {
+		t.Errorf("use = %v, want archive", data["use"])
+	}
+	if mock.LastCreateOpts.Use != "archive" {
+		t.Errorf("CreateFolderOptions.Use = %q, want archive", mock.LastCreateOpts.Use)
+	}
+
+	mock = &MockEmailService{}
+	result, _ = CreateFolderHandler(mock)(context.Background(), req(map[string]interface{}{"name": "Stuff", "use": "important"}))
+	if code := resultErrCode(t, result); code != CodeInvalidArgument || mock.CallCount != 0 {
+		t.Errorf("code = %s after %d calls, want %s before any call", code, mock.CallCount, CodeInvalidArgument)
+	}
+}
+
+func TestCreateFolderHandlerSubscribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantSkip bool
+	}{
+		{name: "subscribed by default", args: map[string]interface{}{"name": "Projects"}},
+		{name: "opt out", args: map[string]interface{}{"name": "Projects", "subscribe": false}, wantSkip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{}
+			result, err := CreateFolderHandler(mock)(context.Background(), req(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+			if mock.LastCreateOpts.SkipSubscribe != tt.wantSkip {
+				t.Errorf("SkipSubscribe = %v, want %v", mock.LastCreateOpts.SkipSubscribe, tt.wantSkip)
+			}
+			if data["subscribed"] != !tt.wantSkip {
+				t.Errorf("subscribed = %v", data["subscribed"])
+			}
+		})
+	}
+}
+
+func TestSubscribeFolderHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		wantMethod string
+		wantSub    bool
+		wantErrMsg string
+	}{
+		{name: "subscribe", handler: SubscribeFolderHandler, wantMethod: "Subscribe", wantSub: true, wantErrMsg: "failed to subscribe to folder"},
+		{name: "unsubscribe", handler: UnsubscribeFolderHandler, wantMethod: "Unsubscribe", wantErrMsg: "failed to unsubscribe from folder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockEmailService{}
+			result, err := tt.handler(mock)(context.Background(), req(map[string]interface{}{"name": "Work/Projects"}))
+			if err != nil {
+				t.Fatalf("unexpected Go error: %v", err)
+			}
+			data := resultJSON(t, result)
+			if mock.LastMethod != tt.wantMethod || mock.LastFolder != "Work/Projects" {
+				t.Errorf("called %s(%q), want %s(Work/Projects)", mock.LastMethod, mock.LastFolder, tt.wantMethod)
+			}
+			if data["subscribed"] != tt.wantSub {
+				t.Errorf("subscribed = %v, want %v", data["subscribed"], tt.wantSub)
+			}
+
+			// Validation happens before any server call
+			mock = &MockEmailService{}
+			result, _ = tt.handler(mock)(context.Background(), req(map[string]interface{}{"name": "Work/*"}))
+			if code := resultErrCode(t, result); code != CodeInvalidArgument || mock.CallCount != 0 {
+				t.Errorf("code = %q, calls = %d; want invalid_argument and no call", code, mock.CallCount)
+			}
+
+			result, _ = tt.handler(newErrMock("NO denied"))(context.Background(), req(map[string]interface{}{"name": "Work"}))
+			if msg := resultErrText(t, result); !strings.Contains(msg, tt.wantErrMsg) {
+				t.Errorf("error = %q, want containing %q", msg, tt.wantErrMsg)
+			}
+		})
+	}
 }
 
 // --- DeleteFolder ---
@@ -1039,11 +4843,6 @@ func TestDeleteFolderHandler(t *testing.T) {
 			args: map[string]interface{}{"name": "OldFolder"},
 			mock: &MockEmailService{WasEmpty: true, EmailCount: 0},
 		},
-		{
-			name: "non-empty with force",
-			args: map[string]interface{}{"name": "OldFolder", "force": true},
-			mock: &MockEmailService{WasEmpty: false, EmailCount: 5},
-		},
 		{
 			name: "non-empty without force returns structured error",
 			args: map[string]interface{}{"name": "OldFolder"},
@@ -1081,8 +4880,244 @@ func TestDeleteFolderHandler(t *testing.T) {
 	}
 }
 
+func TestDeleteFolderHandlerConfirm(t *testing.T) {
+	mock := &MockEmailService{EmailCount: 5, Status: &imappkg.FolderStatus{Folder: "OldFolder", Messages: 5}}
+	handler := DeleteFolderHandler(mock)
+	call := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler(context.Background(), req(args))
+		if err != nil {
+			t.Fatalf("unexpected Go error: %v", err)
+		}
+		return result
+	}
+
+	// The first forced call only describes what would be lost
+	first := resultJSON(t, call(map[string]interface{}{"name": "OldFolder", "force": true}))
+	if first["confirmation_required"] != true || first["email_count"] != 5.0 {
+		t.Fatalf("first call = %v, want a confirmation request for 5 emails", first)
+	}
+	if mock.LastMethod != "FolderStatus" {
+		t.Fatalf("first call reached %s", mock.LastMethod)
+	}
+	token, _ := first["confirm"].(string)
+
+	// A token for another folder does not carry over
+	if code := resultErrCode(t, call(map[string]interface{}{"name": "Other", "force": true, "confirm": token})); code != CodeInvalidArgument {
+		t.Errorf("code = %s, want %s", code, CodeInvalidArgument)
+	}
+	if mock.LastMethod == "DeleteFolder" {
+		t.Fatal("folder deleted with a token for another folder")
+	}
+
+	// A mismatched redeem used the token up, so get a fresh one
+	first = resultJSON(t, call(map[string]interface{}{"name": "OldFolder", "force": true}))
+	token, _ = first["confirm"].(string)
+	done := resultJSON(t, call(map[string]interface{}{"name": "OldFolder", "force": true, "confirm": token}))
+	if done["success"] != true || mock.LastMethod != "DeleteFolder" || !mock.LastForce {
+		t.Errorf("confirmed call = %v (last call %s), want the folder deleted", done, mock.LastMethod)
+	}
+}
+
+// --- DescribeTools ---
+
+func TestDescribeToolsHandler(t *testing.T) {
+	defs := []mcp.Tool{
+		mcp.NewTool("search_emails",
+			mcp.WithDescription("Search emails."),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithString("folder", mcp.DefaultString("INBOX"), mcp.Description("Folder.")),
+			mcp.WithNumber("limit", mcp.DefaultNumber(50), mcp.Min(1), mcp.Max(200)),
+			mcp.WithString("format", mcp.Enum("json", "compact")),
+		),
+		mcp.NewTool("delete_email",
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithString("email_id", mcp.Required()),
+		),
+	}
+	handler := DescribeToolsHandler(func() []mcp.Tool { return defs })
+
+	result, err := handler(context.Background(), req(nil))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	described := data["tools"].([]interface{})
+	if len(described) != 2 {
+		t.Fatalf("described %d tools, want 2", len(described))
+	}
+
+	// Sorted by name for a stable shape
+	deleteTool, search := described[0].(map[string]interface{}), described[1].(map[string]interface{})
+	if deleteTool["name"] != "delete_email" || search["name"] != "search_emails" {
+		t.Fatalf("tools = %v, %v; want sorted by name", deleteTool["name"], search["name"])
+	}
+	if search["read_only"] != true || search["destructive"] != false || deleteTool["destructive"] != true {
+		t.Errorf("hints: search %v/%v, delete destructive %v", search["read_only"], search["destructive"], deleteTool["destructive"])
+	}
+
+	params := map[string]map[string]interface{}{}
+	for _, p := range search["parameters"].([]interface{}) {
+		param := p.(map[string]interface{})
+		params[param["name"].(string)] = param
+	}
+	if params["folder"]["default"] != "INBOX" || params["folder"]["type"] != "string" || params["folder"]["required"] != false {
+		t.Errorf("folder = %v", params["folder"])
+	}
+	if params["limit"]["default"] != float64(50) || params["limit"]["maximum"] != float64(200) {
+		t.Errorf("limit = %v", params["limit"])
+	}
+	if enum, _ := params["format"]["enum"].([]interface{}); len(enum) != 2 || enum[0] != "json" {
+		t.Errorf("format enum = %v", params["format"]["enum"])
+	}
+	emailID := deleteTool["parameters"].([]interface{})[0].(map[string]interface{})
+	if emailID["name"] != "email_id" || emailID["required"] != true {
+		t.Errorf("email_id = %v", emailID)
+	}
+
+	// One tool by name, or not_found
+	result, _ = handler(context.Background(), req(map[string]interface{}{"tool": "delete_email"}))
+	if data := resultJSON(t, result); data["count"] != float64(1) {
+		t.Errorf("count = %v, want 1", data["count"])
+	}
+	result, _ = handler(context.Background(), req(map[string]interface{}{"tool": "nope"}))
+	if code := resultErrCode(t, result); code != CodeNotFound {
+		t.Errorf("code = %q, want %q", code, CodeNotFound)
+	}
+}
+
+// --- MailboxStats ---
+
+func TestMailboxStatsHandler(t *testing.T) {
+	mock := &MockEmailService{Stats: &imappkg.Stats{
+		Total:  5,
+		Unread: 2,
+		Size:   7200,
+		Folders: []imappkg.FolderStats{
+			{Folder: "Archive", Total: 2, Size: 1200},
+			{Folder: "INBOX", Total: 3, Unread: 2, Size: 6000},
+		},
+	}}
+	handler := MailboxStatsHandler(mock)
+
+	result, err := handler(context.Background(), req(map[string]interface{}{
+		"folders":      []interface{}{"INBOX", "Archive"},
+		"include_size": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	data := resultJSON(t, result)
+	if data["total"] != float64(5) || data["unread"] != float64(2) || data["size"] != float64(7200) {
+		t.Errorf("totals = %v/%v/%v", data["total"], data["unread"], data["size"])
+	}
+	if data["count"] != float64(2) {
+		t.Errorf("count = %v, want 2", data["count"])
+	}
+	if got := mock.LastStatsOpts; !got.IncludeSize || len(got.Folders) != 2 || got.Folders[0] != "INBOX" {
+		t.Errorf("options = %+v", got)
+	}
+
+	// Size is left out unless requested
+	result, _ = handler(context.Background(), req(nil))
+	if data := resultJSON(t, result); data["size"] != nil {
+		t.Errorf("size = %v without include_size", data["size"])
+	}
+	if mock.LastStatsOpts.IncludeSize || len(mock.LastStatsOpts.Folders) != 0 {
+		t.Errorf("default options = %+v", mock.LastStatsOpts)
+	}
+}
+
+func TestMailboxStatsHandlerErrors(t *testing.T) {
+	handler := MailboxStatsHandler(&MockEmailService{})
+	result, _ := handler(context.Background(), req(map[string]interface{}{"folders": []interface{}{"../etc"}}))
+	if code := resultErrCode(t, result); code != CodeInvalidArgument {
+		t.Errorf("code = %q, want %q", code, CodeInvalidArgument)
+	}
+
+	result, _ = MailboxStatsHandler(newErrMock("connection reset"))(context.Background(), req(nil))
+	if text := resultErrText(t, result); !strings.Contains(text, "failed to compute mailbox stats") {
+		t.Errorf("error = %q", text)
+	}
+}
+
 // --- Helpers ---
 
+// failingResolver is a FolderResolver whose lookups always fail
+type failingResolver struct{ err error }
+
+func (r failingResolver) ResolveFolder(ctx context.Context, name string) (string, error) {
+	return "", r.err
+}
+
+func TestResolveFolderArg(t *testing.T) {
+	mock := &MockEmailService{Aliases: map[string]string{"sent": "Sent Messages"}}
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{name: "default applied", args: map[string]interface{}{}, want: "All Mail"},
+		{name: "empty uses default", args: map[string]interface{}{"folder": ""}, want: "All Mail"},
+		{name: "explicit override", args: map[string]interface{}{"folder": "Work/Projects"}, want: "Work/Projects"},
+		{name: "alias resolved", args: map[string]interface{}{"folder": "sent"}, want: "Sent Messages"},
+		{name: "traversal rejected", args: map[string]interface{}{"folder": "../INBOX"}, wantErr: "invalid folder: folder name must not contain '..'"},
+		{name: "wildcard rejected", args: map[string]interface{}{"folder": "Work*"}, wantErr: "invalid folder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveFolderArg(context.Background(), mock, tt.args, "folder", "All Mail")
+			if tt.wantErr != "" {
+				var argErr *argumentError
+				if !errors.As(err, &argErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want argumentError containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("folder = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Server failures are not argument errors
+	_, err := resolveFolderArg(context.Background(), failingResolver{errors.New("connection lost")}, map[string]interface{}{"folder": "sent"}, "folder", "INBOX")
+	if code := resultErrCode(t, argumentResult("failed to resolve folder", err)); code != CodeBackend {
+		t.Errorf("code = %q, want %q", code, CodeBackend)
+	}
+}
+
+func TestHandlerRejectsInvalidFolder(t *testing.T) {
+	mock := &MockEmailService{Email: &imappkg.Email{ID: "12"}}
+	result, err := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(map[string]interface{}{"email_id": "12", "folder": "INBOX\r\nA1 LOGOUT"}))
+	if err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	payload := resultErr(t, result)
+	if payload.Code != CodeInvalidArgument || !strings.Contains(payload.Message, "control characters") {
+		t.Errorf("error = %+v, want invalid_argument about control characters", payload)
+	}
+	if mock.CallCount != 0 {
+		t.Error("expected no server call for an invalid folder")
+	}
+
+	// The resolved name is what reaches the server
+	mock = &MockEmailService{Email: &imappkg.Email{ID: "12"}, Aliases: map[string]string{"trash": "Deleted Messages"}}
+	if _, err := GetEmailHandler(mock, "INBOX", 0, AttachmentPolicy{})(context.Background(), req(map[string]interface{}{"email_id": "12", "folder": "trash"})); err != nil {
+		t.Fatalf("unexpected Go error: %v", err)
+	}
+	if mock.LastFolder != "Deleted Messages" {
+		t.Errorf("folder = %q, want Deleted Messages", mock.LastFolder)
+	}
+}
+
 func TestParseAddressList(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1,33 +1,59 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"net/mail"
+	"strings"
+	"time"
 )
 
-// parseAddressList extracts a string or []interface{} argument into a validated email address list.
-// Returns a non-nil error if the value is present but invalid.
-func parseAddressList(args map[string]interface{}, key string) ([]string, error) {
+// attachUpdatedEmail adds the email as it now stands to response under
+// "email", for handlers given return_email. It is peeked so that fetching it
+// does not itself mark it read. The change has already been made, so a
+// failed fetch becomes a warning rather than an error.
+func attachUpdatedEmail(ctx context.Context, client EmailReader, folder, emailID string, response map[string]interface{}) {
+	email, err := client.PeekEmail(ctx, folder, emailID)
+	if err != nil {
+		response["warning"] = fmt.Sprintf("email updated but could not be fetched: %v", err)
+		return
+	}
+	response["email"] = email
+}
+
+// parseStringList extracts a string or []interface{} argument into a list of
+// non-empty strings. Returns a non-nil error if the value has another type.
+func parseStringList(args map[string]interface{}, key string) ([]string, error) {
 	val, ok := args[key]
 	if !ok || val == nil {
 		return nil, nil
 	}
 
-	var raw []string
+	var list []string
 	switch v := val.(type) {
 	case string:
 		if v != "" {
-			raw = []string{v}
+			list = []string{v}
 		}
 	case []interface{}:
 		for _, item := range v {
 			if str, ok := item.(string); ok && str != "" {
-				raw = append(raw, str)
+				list = append(list, str)
 			}
 		}
 	default:
 		return nil, fmt.Errorf("%s must be a string or array of strings", key)
 	}
+	return list, nil
+}
+
+// parseAddressList extracts a string or []interface{} argument into a validated email address list.
+// Returns a non-nil error if the value is present but invalid.
+func parseAddressList(args map[string]interface{}, key string) ([]string, error) {
+	raw, err := parseStringList(args, key)
+	if err != nil {
+		return nil, err
+	}
 
 	// Validate each address
 	for _, addr := range raw {
@@ -50,3 +76,107 @@ func requireAddressList(args map[string]interface{}, key string) ([]string, erro
 	}
 	return addrs, nil
 }
+
+// priorityHeaders maps a priority level to the headers understood by common
+// mail clients. An empty priority yields no headers.
+func priorityHeaders(priority string) (map[string]string, error) {
+	switch priority {
+	case "":
+		return nil, nil
+	case "high":
+		return map[string]string{"X-Priority": "1 (Highest)", "Importance": "high", "X-MSMail-Priority": "High"}, nil
+	case "normal":
+		return map[string]string{"X-Priority": "3 (Normal)", "Importance": "normal", "X-MSMail-Priority": "Normal"}, nil
+	case "low":
+		return map[string]string{"X-Priority": "5 (Lowest)", "Importance": "low", "X-MSMail-Priority": "Low"}, nil
+	default:
+		return nil, fmt.Errorf("priority must be one of: high, normal, low")
+	}
+}
+
+// parseDateArg parses a since/before value into a time bound. It accepts
+// RFC 3339 timestamps, date-only values (2024-01-15), and the keywords today,
+// yesterday, this_week, last_week, this_month, and last_month. Dates and
+// keywords resolve to the start of that day, week (Monday), or month in the
+// server's local time zone.
+func parseDateArg(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Days since Monday (time.Sunday is 0)
+	weekday := (int(today.Weekday()) + 6) % 7
+	thisWeek := today.AddDate(0, 0, -weekday)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch value {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "this_week":
+		return thisWeek, nil
+	case "last_week":
+		return thisWeek.AddDate(0, 0, -7), nil
+	case "this_month":
+		return thisMonth, nil
+	case "last_month":
+		return thisMonth.AddDate(0, -1, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q (use RFC 3339 like '2024-01-15T14:30:00Z', a date like '2024-01-15', or one of today, yesterday, this_week, last_week, this_month, last_month)", value)
+}
+
+// folderArg returns the folder named by args[key], or defaultFolder when the
+// argument is omitted or empty.
+func folderArg(args map[string]interface{}, key, defaultFolder string) string {
+	if folder, _ := args[key].(string); folder != "" {
+		return folder
+	}
+	return defaultFolder
+}
+
+// resolveFolderArg returns the folder named by args[key], or defaultFolder
+// when it is omitted, after validating the name and mapping aliases such as
+// "sent" to the server's folder name. Validation failures are
+// *argumentError; report errors with argumentResult.
+func resolveFolderArg(ctx context.Context, client FolderResolver, args map[string]interface{}, key, defaultFolder string) (string, error) {
+	folder := folderArg(args, key, defaultFolder)
+	if err := validateFolderName(folder); err != nil {
+		return "", &argumentError{msg: fmt.Sprintf("invalid %s: %v", key, err)}
+	}
+	return client.ResolveFolder(ctx, folder)
+}
+
+// bySequenceArg reports whether args["id_type"] selects sequence numbers
+// ("seq") rather than UIDs ("uid", the default).
+func bySequenceArg(args map[string]interface{}) (bool, error) {
+	idType, _ := args["id_type"].(string)
+	switch idType {
+	case "", "uid":
+		return false, nil
+	case "seq":
+		return true, nil
+	}
+	return false, fmt.Errorf("id_type must be one of: uid, seq")
+}
+
+// resolveFrom returns the From address for a send. Without a "from" argument
+// it is the account address; otherwise the argument must match the account
+// address or one of the allowed aliases (case-insensitively).
+func resolveFrom(args map[string]interface{}, account string, allowed []string) (string, error) {
+	from, _ := args["from"].(string)
+	if from == "" {
+		return account, nil
+	}
+	for _, addr := range append([]string{account}, allowed...) {
+		if strings.EqualFold(from, addr) {
+			return addr, nil
+		}
+	}
+	return "", fmt.Errorf("from address %q is not allowed (configure aliases in ALLOWED_FROM)", from)
+}
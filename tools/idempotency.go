@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"sync"
+	"time"
+)
+
+// idempotencyTTL is how long a completed send is remembered.
+const idempotencyTTL = time.Hour
+
+// idempotencyStore remembers the results of recent calls by client-supplied
+// key so that retries of a non-idempotent tool replay the first result.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	result   string // empty while the first call is in flight
+	inFlight bool
+	expires  time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]idempotencyEntry),
+	}
+}
+
+// claim reserves key for a new call. If a call with the same key already
+// completed, its result is returned with done=true. If one is still running,
+// busy is true. Otherwise the caller owns the key and must call complete or
+// release.
+func (s *idempotencyStore) claim(key string) (result string, done, busy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, e := range s.entries {
+		if !e.inFlight && now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if e, ok := s.entries[key]; ok {
+		if e.inFlight {
+			return "", false, true
+		}
+		return e.result, true, false
+	}
+
+	s.entries[key] = idempotencyEntry{inFlight: true}
+	return "", false, false
+}
+
+// complete records the result for a claimed key.
+func (s *idempotencyStore) complete(key, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = idempotencyEntry{result: result, expires: s.now().Add(s.ttl)}
+}
+
+// release gives up a claimed key after a failed call so it can be retried.
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// ImportMboxHandler creates a handler for loading an mbox file into a folder
+func ImportMboxHandler(imapClient EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required mbox_path and validate against path traversal
+		mboxPath, ok := args["mbox_path"].(string)
+		if !ok || mboxPath == "" {
+			return invalidArgument("mbox_path is required"), nil
+		}
+		if err := validatePath("mbox_path", mboxPath); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get required target folder
+		folder, ok := args["folder"].(string)
+		if !ok || folder == "" {
+			return invalidArgument("folder is required"), nil
+		}
+		if err := validateFolderName(folder); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		f, err := os.Open(mboxPath)
+		if err != nil {
+			return operationError("failed to open mbox file", err), nil
+		}
+		defer f.Close()
+
+		// Append each message as it is read
+		imported := 0
+		err = imap.ReadMbox(f, func(raw []byte) error {
+			if err := imapClient.AppendMessage(ctx, folder, raw, nil); err != nil {
+				return err
+			}
+			imported++
+			return nil
+		})
+		if err != nil {
+			return operationError(fmt.Sprintf("failed to import mbox after %d messages", imported), err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":  true,
+			"folder":   folder,
+			"imported": imported,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -2,39 +2,101 @@ package tools
 
 import (
 	"context"
+	"time"
 
 	"github.com/rgabriel/mcp-icloud-email/imap"
 	smtppkg "github.com/rgabriel/mcp-icloud-email/smtp"
 )
 
+// FolderResolver maps folder aliases to the server's folder names.
+type FolderResolver interface {
+	ResolveFolder(ctx context.Context, name string) (string, error)
+}
+
 // EmailReader defines read-only IMAP operations.
 type EmailReader interface {
+	FolderResolver
 	ListFolders(ctx context.Context) ([]string, error)
-	SearchEmails(ctx context.Context, folder, query string, filters imap.EmailFilters) ([]imap.Email, int, error)
+	Namespace(ctx context.Context) (prefix, delimiter string, err error)
+	SearchEmails(ctx context.Context, folder, query string, filters imap.EmailFilters) (emails []imap.Email, total int, uidValidity uint32, err error)
+	SearchUIDs(ctx context.Context, folder, query string, filters imap.EmailFilters) (ids []string, total int, uidValidity uint32, err error)
+	SearchEmailsExcluding(ctx context.Context, folder, query string, filters imap.EmailFilters, exclude []string) (emails []imap.Email, total, excluded int, uidValidity uint32, err error)
 	GetEmail(ctx context.Context, folder, emailID string) (*imap.Email, error)
+	PeekEmail(ctx context.Context, folder, emailID string) (*imap.Email, error)
+	FetchEmail(ctx context.Context, folder, emailID string, opts imap.FetchOptions) (*imap.Email, error)
+	GetStoredMessage(ctx context.Context, folder, emailID string) (*imap.StoredMessage, error)
+	GetHeaders(ctx context.Context, folder, emailID string, fields []string) (map[string][]string, error)
 	CountEmails(ctx context.Context, folder string, filters imap.EmailFilters) (int, error)
+	MessageIDs(ctx context.Context, folder string) ([]string, error)
+	FindByMessageID(ctx context.Context, folder, messageID string) (string, error)
 	GetAttachment(ctx context.Context, folder, emailID, filename string) (*imap.AttachmentData, error)
+	FindLargeAttachments(ctx context.Context, folders []string, minSize uint32, limit int) ([]imap.LargeAttachment, error)
+	ListDrafts(ctx context.Context) ([]imap.Email, error)
+	CountBySender(ctx context.Context, folder string, lastDays, limit int) ([]imap.SenderCount, error)
+	CountByDay(ctx context.Context, folder string, lastDays int) (map[string]int, error)
+	RecentRecipients(ctx context.Context, lastDays, limit int) ([]imap.Correspondent, error)
+	FindDuplicates(ctx context.Context, folder string, matchHeaders bool) ([]imap.DuplicateGroup, error)
+	MailboxStats(ctx context.Context, opts imap.StatsOptions) (*imap.Stats, error)
+	FolderFlags(ctx context.Context, folder string) (flags []string, permanentFlags []string, err error)
+	FolderStatus(ctx context.Context, folder string) (*imap.FolderStatus, error)
+	UIDValidity(ctx context.Context, folder string) (uint32, error)
+	ExportFolder(ctx context.Context, folder, destPath, format string) (int, int64, error)
+	ExportEmail(ctx context.Context, folder, emailID, destPath string, filter func(filename, mimeType string) error) (*imap.ExportedEmail, error)
 }
 
 // EmailWriter defines mutating IMAP operations.
 type EmailWriter interface {
+	FolderResolver
 	MarkRead(ctx context.Context, folder, emailID string, read bool) error
-	MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string) error
+	MarkReadBulk(ctx context.Context, folder string, emailIDs []string, read bool) (int, error)
+	MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string, opts imap.MoveOptions) (skipped bool, err error)
+	MoveEmailBulk(ctx context.Context, fromFolder, toFolder string, emailIDs []string) (int, error)
+	SnoozeEmail(ctx context.Context, folder, emailID string, until time.Time) (*imap.SnoozedEmail, error)
+	FlushSnoozed(ctx context.Context, now time.Time) (*imap.FlushResult, error)
 	DeleteEmail(ctx context.Context, folder, emailID string, permanent bool) error
+	PurgeDeleted(ctx context.Context, folder string) (int, error)
+	CheckProtected(ctx context.Context, name string) error
 	FlagEmail(ctx context.Context, folder, emailID, flagType, color string) error
+	FlagEmailBulk(ctx context.Context, folder string, emailIDs []string, flagType, color string) error
 	SaveDraft(ctx context.Context, from string, to []string, subject, body string, opts imap.DraftOptions) (string, error)
-	CreateFolder(ctx context.Context, name, parent string) error
+	DeleteDraft(ctx context.Context, emailID string) error
+	BlockSender(ctx context.Context, folder, emailID string) (*imap.BlockResult, error)
+	AppendMessage(ctx context.Context, folder string, raw []byte, flags []string) error
+	CreateFolder(ctx context.Context, name, parent string, opts imap.CreateFolderOptions) (path string, err error)
+	Subscribe(ctx context.Context, folder string) error
+	Unsubscribe(ctx context.Context, folder string) error
 	DeleteFolder(ctx context.Context, name string, force bool) (wasEmpty bool, emailCount int, err error)
 }
 
-// EmailService combines all IMAP operations. The concrete *imap.Client satisfies this.
+// EmailService combines all IMAP operations. main.go passes an *imap.Pool;
+// a single *imap.Client satisfies it too.
 type EmailService interface {
 	EmailReader
 	EmailWriter
 }
 
+var (
+	_ EmailService = (*imap.Pool)(nil)
+	_ EmailService = (*imap.Client)(nil)
+)
+
 // EmailSender defines SMTP operations.
 type EmailSender interface {
 	SendEmail(ctx context.Context, from string, to []string, subject, body string, opts smtppkg.SendOptions) error
 	ReplyToEmail(ctx context.Context, original *imap.Email, body string, replyAll bool, opts smtppkg.SendOptions) error
 }
+
+// ReplyPreviewer computes a reply's recipients and headers without sending.
+type ReplyPreviewer interface {
+	PreviewReply(original *imap.Email, replyAll bool, opts smtppkg.SendOptions) smtppkg.ReplyPreview
+}
+
+// CredentialChecker tests whether the account credentials are accepted.
+type CredentialChecker interface {
+	CheckCredentials(ctx context.Context) *imap.CredentialCheck
+}
+
+// RecipientVerifier probes whether a remote server accepts a recipient.
+type RecipientVerifier interface {
+	VerifyRecipient(ctx context.Context, addr string) (bool, string, error)
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// ListDraftsHandler creates a handler for listing saved drafts
+func ListDraftsHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// List drafts. A partial result still carries usable drafts.
+		drafts, err := client.ListDrafts(ctx)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to list drafts", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"count":  len(drafts),
+			"drafts": drafts,
+		}
+
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// ListFlaggedHandler creates a handler for listing the emails flagged with a
+// given flag type or color, or all flagged emails when neither is given
+func ListFlaggedHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		flagType, _ := args["flag"].(string)
+		color, _ := args["color"].(string)
+		keywords, err := imap.FlagKeywords(flagType, color)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		if len(keywords) == 0 {
+			keywords = []string{imap.FlaggedFlag}
+		}
+
+		// Flags are often left on old mail, so there is no date window
+		filters := imap.EmailFilters{
+			Keywords: keywords,
+			Limit:    50,
+		}
+		if limit, ok := args["limit"].(float64); ok && limit > 0 {
+			filters.Limit = int(limit)
+			if filters.Limit > 200 {
+				filters.Limit = 200 // Max limit
+			}
+		}
+
+		// Search emails. A partial result still carries usable emails.
+		emails, total, _, err := client.SearchEmails(ctx, folder, "", filters)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to search emails", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"count":    len(emails),
+			"total":    total,
+			"emails":   emails,
+			"folder":   folder,
+			"keywords": keywords,
+		}
+		if flagType != "" {
+			response["flag"] = flagType
+		}
+		if color != "" {
+			response["color"] = color
+		}
+
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -4,18 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// ListFoldersHandler creates a handler for listing available folders
-func ListFoldersHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// ListFoldersHandler creates a handler for listing available folders. Only
+// folders passing filter (see filterFolders) are listed; the rest can still
+// be used by name in other tools.
+func ListFoldersHandler(client EmailReader, filter []string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// List folders
 		folders, err := client.ListFolders(ctx)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to list folders: %v", err)), nil
+			return operationError("failed to list folders", err), nil
 		}
+		folders = filterFolders(folders, filter)
 
 		// Format response
 		response := map[string]interface{}{
@@ -25,9 +30,51 @@ func ListFoldersHandler(client EmailReader) func(context.Context, mcp.CallToolRe
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
 	}
 }
+
+// filterFolders keeps the folders matching the filter's path.Match
+// patterns. When the filter has patterns without a leading !, a folder must
+// match one of them; a folder matching a !pattern is dropped either way.
+// Patterns are validated when the configuration is loaded, so a bad one
+// simply matches nothing here.
+func filterFolders(folders, filter []string) []string {
+	if len(filter) == 0 {
+		return folders
+	}
+
+	var include, exclude []string
+	for _, pattern := range filter {
+		if p, ok := strings.CutPrefix(pattern, "!"); ok {
+			exclude = append(exclude, p)
+		} else {
+			include = append(include, pattern)
+		}
+	}
+
+	kept := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		if len(include) > 0 && !matchesAny(folder, include) {
+			continue
+		}
+		if matchesAny(folder, exclude) {
+			continue
+		}
+		kept = append(kept, folder)
+	}
+	return kept
+}
+
+// matchesAny reports whether name matches one of the path.Match patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// MailboxStatsHandler creates a handler that summarizes the mailbox: message
+// and unread counts, date ranges, and optionally sizes, per folder and in total
+func MailboxStatsHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folders (default to all folders)
+		folders, err := parseStringList(args, "folders")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		for _, folder := range folders {
+			if err := validateFolderName(folder); err != nil {
+				return invalidArgument(fmt.Sprintf("invalid folders: %v", err)), nil
+			}
+		}
+
+		// Parse include_size (default false)
+		includeSize, _ := args["include_size"].(bool)
+
+		stats, err := client.MailboxStats(ctx, imap.StatsOptions{
+			Folders:     folders,
+			IncludeSize: includeSize,
+		})
+		if err != nil {
+			return operationError("failed to compute mailbox stats", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"total":   stats.Total,
+			"unread":  stats.Unread,
+			"count":   len(stats.Folders),
+			"folders": stats.Folders,
+		}
+		if includeSize {
+			response["size"] = stats.Size
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// defaultReviewFolder is where mark_for_review files emails unless told otherwise
+const defaultReviewFolder = "Review"
+
+// MarkForReviewHandler creates a handler that flags an email for follow-up
+// and moves it into a review folder, creating the folder if it is missing.
+// The flag is set first so that a failed move still leaves the email flagged.
+func MarkForReviewHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		fromFolder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Get review_folder (default to Review)
+		reviewFolder := defaultReviewFolder
+		if v, ok := args["review_folder"].(string); ok && v != "" {
+			if err := validateFolderName(v); err != nil {
+				return invalidArgument(err.Error()), nil
+			}
+			reviewFolder = v
+		}
+		if reviewFolder == fromFolder {
+			return invalidArgument("email is already in the review folder"), nil
+		}
+
+		if err := client.FlagEmail(ctx, fromFolder, emailID, "follow-up", ""); err != nil {
+			return operationError("failed to flag email", err), nil
+		}
+
+		// From here on the email stays flagged whatever else fails
+		created := true
+		toFolder, err := client.CreateFolder(ctx, reviewFolder, "", imap.CreateFolderOptions{})
+		if errors.Is(err, imap.ErrAlreadyExists) {
+			created, toFolder = false, reviewFolder
+		} else if err != nil {
+			return operationError(fmt.Sprintf("email flagged for follow-up but not moved: failed to create folder '%s'", reviewFolder), err), nil
+		}
+
+		if _, err := client.MoveEmail(ctx, fromFolder, toFolder, emailID, imap.MoveOptions{}); err != nil {
+			return operationError(fmt.Sprintf("email flagged for follow-up but not moved to '%s'", toFolder), err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":        true,
+			"email_id":       emailID,
+			"from_folder":    fromFolder,
+			"review_folder":  toFolder,
+			"flagged":        true,
+			"moved":          true,
+			"created_folder": created,
+			"message":        fmt.Sprintf("Email flagged for follow-up and moved to '%s'", toFolder),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -9,20 +9,20 @@ import (
 )
 
 // MarkReadHandler creates a handler for marking emails as read/unread
-func MarkReadHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func MarkReadHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required email_id
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Get read status (default to true)
@@ -32,9 +32,9 @@ func MarkReadHandler(client EmailWriter) func(context.Context, mcp.CallToolReque
 		}
 
 		// Mark email
-		err := client.MarkRead(ctx, folder, emailID, read)
+		err = client.MarkRead(ctx, folder, emailID, read)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to mark email: %v", err)), nil
+			return operationError("failed to mark email", err), nil
 		}
 
 		// Format response
@@ -49,9 +49,13 @@ func MarkReadHandler(client EmailWriter) func(context.Context, mcp.CallToolReque
 			"message": fmt.Sprintf("Email marked as %s successfully", status),
 		}
 
+		if returnEmail, _ := args["return_email"].(bool); returnEmail {
+			attachUpdatedEmail(ctx, client, folder, emailID, response)
+		}
+
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
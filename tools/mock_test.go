@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/rgabriel/mcp-icloud-email/imap"
 	smtppkg "github.com/rgabriel/mcp-icloud-email/smtp"
@@ -19,9 +20,40 @@ type MockEmailService struct {
 	DraftID    string
 	WasEmpty   bool
 	EmailCount int
+	Skipped    bool
+	Block      *imap.BlockResult
+	Senders    []imap.SenderCount
+	Flags      []string
+	PermFlags  []string
+	Exported   int64
+	Headers    map[string][]string
+	Stored     *imap.StoredMessage
+	Aliases    map[string]string
+	Prefix     string
+	Delimiter  string
 
 	// Error injection
-	Err error
+	Err        error
+	PartialErr error // returned alongside results by SearchEmails, ListDrafts, CountBySender, CountByDay, RecentRecipients, and FindDuplicates
+	PeekErr    error // returned by PeekEmail when set
+	CreateErr  error // returned by CreateFolder when set
+	MoveErr    error // returned by MoveEmail when set
+	DeleteErr  error // returned by DeleteEmail when set
+	ProtectErr error // returned by CheckProtected when set
+
+	FolderMessageIDs map[string][]string // returned by MessageIDs, keyed by folder
+	Correspondents   []imap.Correspondent
+	LargeAttachments []imap.LargeAttachment
+	Flushed          *imap.FlushResult
+	Stats            *imap.Stats
+	Duplicates       []imap.DuplicateGroup
+	DayCounts        map[string]int
+	Status           *imap.FolderStatus
+	UIDValidityValue uint32 // returned by UIDValidity, SearchEmails, and SearchUIDs; 0 makes UIDValidity fail
+	Purged           int    // returned by PurgeDeleted
+	FoundID          string // returned by FindByMessageID; empty makes it fail
+	ExportedEmail    *imap.ExportedEmail
+	CredentialCheck  imap.CredentialCheck // returned by CheckCredentials
 
 	// Call tracking
 	LastMethod     string
@@ -32,6 +64,7 @@ type MockEmailService struct {
 	LastRead       bool
 	LastFromFolder string
 	LastToFolder   string
+	LastMoveOpts   imap.MoveOptions
 	LastPermanent  bool
 	LastFlagType   string
 	LastColor      string
@@ -42,9 +75,25 @@ type MockEmailService struct {
 	LastDraftOpts  imap.DraftOptions
 	LastName       string
 	LastParent     string
+	LastCreateOpts imap.CreateFolderOptions
+	LastFetchOpts  imap.FetchOptions
+	LastFolders    []string
+	LastMinSize    uint32
 	LastForce      bool
 	LastFilename   string
+	LastPath       string
+	LastFormat     string
+	LastFilter     func(filename, mimeType string) error
+	Appended       []string
+	LastFields     []string
+	LastEmailIDs   []string
+	LastTime       time.Time
+	LastStatsOpts  imap.StatsOptions
+	LastMatch      bool
+	Deleted        []string
 	CallCount      int
+	BulkReadCalls  int
+	BulkFlagCalls  int
 }
 
 func (m *MockEmailService) ListFolders(ctx context.Context) ([]string, error) {
@@ -56,16 +105,66 @@ func (m *MockEmailService) ListFolders(ctx context.Context) ([]string, error) {
 	return m.Folders, nil
 }
 
-func (m *MockEmailService) SearchEmails(ctx context.Context, folder, query string, filters imap.EmailFilters) ([]imap.Email, int, error) {
+func (m *MockEmailService) SearchEmails(ctx context.Context, folder, query string, filters imap.EmailFilters) ([]imap.Email, int, uint32, error) {
 	m.LastMethod = "SearchEmails"
 	m.LastFolder = folder
 	m.LastQuery = query
 	m.LastFilters = filters
 	m.CallCount++
 	if m.Err != nil {
-		return nil, 0, m.Err
+		return nil, 0, 0, m.Err
 	}
-	return m.Emails, len(m.Emails), nil
+	return m.Emails, len(m.Emails), m.UIDValidityValue, m.PartialErr
+}
+
+// SearchEmailsExcluding drops the Emails whose Message-ID is in exclude,
+// then applies offset and limit, oldest first, as the server-side search does
+func (m *MockEmailService) SearchEmailsExcluding(ctx context.Context, folder, query string, filters imap.EmailFilters, exclude []string) ([]imap.Email, int, int, uint32, error) {
+	m.LastMethod = "SearchEmailsExcluding"
+	m.LastFolder = folder
+	m.LastQuery = query
+	m.LastFilters = filters
+	m.CallCount++
+	if m.Err != nil {
+		return nil, 0, 0, 0, m.Err
+	}
+
+	skip := map[string]bool{}
+	for _, id := range exclude {
+		skip[imap.NormalizeMessageID(id)] = true
+	}
+	kept := []imap.Email{}
+	for _, e := range m.Emails {
+		if id := imap.NormalizeMessageID(e.MessageID); id == "" || !skip[id] {
+			kept = append(kept, e)
+		}
+	}
+	page := kept
+	if filters.Offset >= len(page) {
+		page = []imap.Email{}
+	} else {
+		page = page[:len(page)-filters.Offset]
+	}
+	if filters.Limit > 0 && len(page) > filters.Limit {
+		page = page[len(page)-filters.Limit:]
+	}
+	return page, len(kept), len(m.Emails) - len(kept), m.UIDValidityValue, m.PartialErr
+}
+
+func (m *MockEmailService) SearchUIDs(ctx context.Context, folder, query string, filters imap.EmailFilters) ([]string, int, uint32, error) {
+	m.LastMethod = "SearchUIDs"
+	m.LastFolder = folder
+	m.LastQuery = query
+	m.LastFilters = filters
+	m.CallCount++
+	if m.Err != nil {
+		return nil, 0, 0, m.Err
+	}
+	ids := make([]string, len(m.Emails))
+	for i, email := range m.Emails {
+		ids[i] = email.ID
+	}
+	return ids, len(m.Emails), m.UIDValidityValue, nil
 }
 
 func (m *MockEmailService) GetEmail(ctx context.Context, folder, emailID string) (*imap.Email, error) {
@@ -79,6 +178,83 @@ func (m *MockEmailService) GetEmail(ctx context.Context, folder, emailID string)
 	return m.Email, nil
 }
 
+func (m *MockEmailService) PeekEmail(ctx context.Context, folder, emailID string) (*imap.Email, error) {
+	m.LastMethod = "PeekEmail"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.CallCount++
+	if m.PeekErr != nil {
+		return nil, m.PeekErr
+	}
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Email, nil
+}
+
+func (m *MockEmailService) FetchEmail(ctx context.Context, folder, emailID string, opts imap.FetchOptions) (*imap.Email, error) {
+	m.LastMethod = "FetchEmail"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.LastFetchOpts = opts
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Email, nil
+}
+
+// ResolveFolder maps names through Aliases. It is not counted as a call and
+// ignores Err so handler tests see only the operation under test.
+func (m *MockEmailService) ResolveFolder(ctx context.Context, name string) (string, error) {
+	if resolved, ok := m.Aliases[name]; ok {
+		return resolved, nil
+	}
+	return name, nil
+}
+
+func (m *MockEmailService) Namespace(ctx context.Context) (string, string, error) {
+	m.LastMethod = "Namespace"
+	m.CallCount++
+	if m.Err != nil {
+		return "", "", m.Err
+	}
+	return m.Prefix, m.Delimiter, nil
+}
+
+func (m *MockEmailService) GetStoredMessage(ctx context.Context, folder, emailID string) (*imap.StoredMessage, error) {
+	m.LastMethod = "GetStoredMessage"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	stored := *m.Stored
+	return &stored, nil
+}
+
+func (m *MockEmailService) GetHeaders(ctx context.Context, folder, emailID string, fields []string) (map[string][]string, error) {
+	m.LastMethod = "GetHeaders"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.LastFields = fields
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Headers, nil
+}
+
+func (m *MockEmailService) MessageIDs(ctx context.Context, folder string) ([]string, error) {
+	m.LastMethod = "MessageIDs"
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.FolderMessageIDs[folder], nil
+}
+
 func (m *MockEmailService) CountEmails(ctx context.Context, folder string, filters imap.EmailFilters) (int, error) {
 	m.LastMethod = "CountEmails"
 	m.LastFolder = folder
@@ -102,6 +278,167 @@ func (m *MockEmailService) GetAttachment(ctx context.Context, folder, emailID, f
 	return m.Attachment, nil
 }
 
+func (m *MockEmailService) CountByDay(ctx context.Context, folder string, lastDays int) (map[string]int, error) {
+	m.LastMethod = "CountByDay"
+	m.LastFolder = folder
+	m.LastFilters = imap.EmailFilters{LastDays: lastDays}
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.DayCounts, m.PartialErr
+}
+
+func (m *MockEmailService) RecentRecipients(ctx context.Context, lastDays, limit int) ([]imap.Correspondent, error) {
+	m.LastMethod = "RecentRecipients"
+	m.LastFilters = imap.EmailFilters{LastDays: lastDays, Limit: limit}
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Correspondents, m.PartialErr
+}
+
+func (m *MockEmailService) FindDuplicates(ctx context.Context, folder string, matchHeaders bool) ([]imap.DuplicateGroup, error) {
+	m.LastMethod = "FindDuplicates"
+	m.LastFolder = folder
+	m.LastMatch = matchHeaders
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Duplicates, m.PartialErr
+}
+
+func (m *MockEmailService) MailboxStats(ctx context.Context, opts imap.StatsOptions) (*imap.Stats, error) {
+	m.LastMethod = "MailboxStats"
+	m.LastStatsOpts = opts
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.Stats == nil {
+		return &imap.Stats{Folders: []imap.FolderStats{}}, nil
+	}
+	return m.Stats, nil
+}
+
+func (m *MockEmailService) FindLargeAttachments(ctx context.Context, folders []string, minSize uint32, limit int) ([]imap.LargeAttachment, error) {
+	m.LastMethod = "FindLargeAttachments"
+	m.LastFolders = folders
+	m.LastMinSize = minSize
+	m.LastFilters = imap.EmailFilters{Limit: limit}
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.LargeAttachments, nil
+}
+
+func (m *MockEmailService) CountBySender(ctx context.Context, folder string, lastDays, limit int) ([]imap.SenderCount, error) {
+	m.LastMethod = "CountBySender"
+	m.LastFolder = folder
+	m.LastFilters = imap.EmailFilters{LastDays: lastDays, Limit: limit}
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Senders, m.PartialErr
+}
+
+func (m *MockEmailService) FolderFlags(ctx context.Context, folder string) ([]string, []string, error) {
+	m.LastMethod = "FolderFlags"
+	m.LastFolder = folder
+	m.CallCount++
+	if m.Err != nil {
+		return nil, nil, m.Err
+	}
+	return m.Flags, m.PermFlags, nil
+}
+
+func (m *MockEmailService) FolderStatus(ctx context.Context, folder string) (*imap.FolderStatus, error) {
+	m.LastMethod = "FolderStatus"
+	m.LastFolder = folder
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Status, nil
+}
+
+// UIDValidity leaves the call tracking alone: handlers only call it
+// alongside the search they report on
+func (m *MockEmailService) FindByMessageID(ctx context.Context, folder, messageID string) (string, error) {
+	m.LastMethod = "FindByMessageID"
+	m.LastFolder = folder
+	m.CallCount++
+	if m.FoundID == "" {
+		return "", fmt.Errorf("email %w in %s", imap.ErrNotFound, folder)
+	}
+	return m.FoundID, nil
+}
+
+func (m *MockEmailService) UIDValidity(ctx context.Context, folder string) (uint32, error) {
+	m.LastMethod = "UIDValidity"
+	m.CallCount++
+	if m.UIDValidityValue == 0 {
+		return 0, fmt.Errorf("no UIDVALIDITY for %s", folder)
+	}
+	return m.UIDValidityValue, nil
+}
+
+func (m *MockEmailService) ExportFolder(ctx context.Context, folder, destPath, format string) (int, int64, error) {
+	m.LastMethod = "ExportFolder"
+	m.LastFolder = folder
+	m.LastPath = destPath
+	m.LastFormat = format
+	m.CallCount++
+	if m.Err != nil {
+		return 0, 0, m.Err
+	}
+	return m.Count, m.Exported, nil
+}
+
+func (m *MockEmailService) ExportEmail(ctx context.Context, folder, emailID, destPath string, filter func(filename, mimeType string) error) (*imap.ExportedEmail, error) {
+	m.LastMethod = "ExportEmail"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.LastPath = destPath
+	m.LastFilter = filter
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.ExportedEmail, nil
+}
+
+func (m *MockEmailService) CheckCredentials(ctx context.Context) *imap.CredentialCheck {
+	m.LastMethod = "CheckCredentials"
+	m.CallCount++
+	check := m.CredentialCheck
+	return &check
+}
+
+func (m *MockEmailService) AppendMessage(ctx context.Context, folder string, raw []byte, flags []string) error {
+	m.LastMethod = "AppendMessage"
+	m.LastFolder = folder
+	m.CallCount++
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Appended = append(m.Appended, string(raw))
+	return nil
+}
+
+func (m *MockEmailService) ListDrafts(ctx context.Context) ([]imap.Email, error) {
+	m.LastMethod = "ListDrafts"
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Emails, m.PartialErr
+}
+
 func (m *MockEmailService) MarkRead(ctx context.Context, folder, emailID string, read bool) error {
 	m.LastMethod = "MarkRead"
 	m.LastFolder = folder
@@ -111,13 +448,70 @@ func (m *MockEmailService) MarkRead(ctx context.Context, folder, emailID string,
 	return m.Err
 }
 
-func (m *MockEmailService) MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string) error {
+func (m *MockEmailService) MarkReadBulk(ctx context.Context, folder string, emailIDs []string, read bool) (int, error) {
+	m.LastMethod = "MarkReadBulk"
+	m.LastFolder = folder
+	m.LastEmailIDs = emailIDs
+	m.LastRead = read
+	m.CallCount++
+	m.BulkReadCalls++
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return len(emailIDs), nil
+}
+
+func (m *MockEmailService) MoveEmail(ctx context.Context, fromFolder, toFolder, emailID string, opts imap.MoveOptions) (bool, error) {
 	m.LastMethod = "MoveEmail"
 	m.LastFromFolder = fromFolder
 	m.LastToFolder = toFolder
 	m.LastEmailID = emailID
+	m.LastMoveOpts = opts
 	m.CallCount++
-	return m.Err
+	if m.MoveErr != nil {
+		return false, m.MoveErr
+	}
+	if m.Err != nil {
+		return false, m.Err
+	}
+	return m.Skipped, nil
+}
+
+func (m *MockEmailService) SnoozeEmail(ctx context.Context, folder, emailID string, until time.Time) (*imap.SnoozedEmail, error) {
+	m.LastMethod = "SnoozeEmail"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.LastTime = until
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return &imap.SnoozedEmail{Folder: "Snoozed/" + until.Format("2006-01-02"), Until: until}, nil
+}
+
+func (m *MockEmailService) FlushSnoozed(ctx context.Context, now time.Time) (*imap.FlushResult, error) {
+	m.LastMethod = "FlushSnoozed"
+	m.LastTime = now
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.Flushed == nil {
+		return &imap.FlushResult{}, nil
+	}
+	return m.Flushed, nil
+}
+
+func (m *MockEmailService) MoveEmailBulk(ctx context.Context, fromFolder, toFolder string, emailIDs []string) (int, error) {
+	m.LastMethod = "MoveEmailBulk"
+	m.LastFromFolder = fromFolder
+	m.LastToFolder = toFolder
+	m.LastEmailIDs = emailIDs
+	m.CallCount++
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return len(emailIDs), nil
 }
 
 func (m *MockEmailService) DeleteEmail(ctx context.Context, folder, emailID string, permanent bool) error {
@@ -126,9 +520,49 @@ func (m *MockEmailService) DeleteEmail(ctx context.Context, folder, emailID stri
 	m.LastEmailID = emailID
 	m.LastPermanent = permanent
 	m.CallCount++
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.DeleteErr != nil {
+		return m.DeleteErr
+	}
+	m.Deleted = append(m.Deleted, emailID)
+	return nil
+}
+
+func (m *MockEmailService) CheckProtected(ctx context.Context, name string) error {
+	m.LastFolder = name
+	return m.ProtectErr
+}
+
+func (m *MockEmailService) PurgeDeleted(ctx context.Context, folder string) (int, error) {
+	m.LastMethod = "PurgeDeleted"
+	m.LastFolder = folder
+	m.CallCount++
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return m.Purged, nil
+}
+
+func (m *MockEmailService) DeleteDraft(ctx context.Context, emailID string) error {
+	m.LastMethod = "DeleteDraft"
+	m.LastEmailID = emailID
+	m.CallCount++
 	return m.Err
 }
 
+func (m *MockEmailService) BlockSender(ctx context.Context, folder, emailID string) (*imap.BlockResult, error) {
+	m.LastMethod = "BlockSender"
+	m.LastFolder = folder
+	m.LastEmailID = emailID
+	m.CallCount++
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Block, nil
+}
+
 func (m *MockEmailService) FlagEmail(ctx context.Context, folder, emailID, flagType, color string) error {
 	m.LastMethod = "FlagEmail"
 	m.LastFolder = folder
@@ -139,6 +573,17 @@ func (m *MockEmailService) FlagEmail(ctx context.Context, folder, emailID, flagT
 	return m.Err
 }
 
+func (m *MockEmailService) FlagEmailBulk(ctx context.Context, folder string, emailIDs []string, flagType, color string) error {
+	m.LastMethod = "FlagEmailBulk"
+	m.LastFolder = folder
+	m.LastEmailIDs = emailIDs
+	m.LastFlagType = flagType
+	m.LastColor = color
+	m.CallCount++
+	m.BulkFlagCalls++
+	return m.Err
+}
+
 func (m *MockEmailService) SaveDraft(ctx context.Context, from string, to []string, subject, body string, opts imap.DraftOptions) (string, error) {
 	m.LastMethod = "SaveDraft"
 	m.LastFrom = from
@@ -153,10 +598,38 @@ func (m *MockEmailService) SaveDraft(ctx context.Context, from string, to []stri
 	return m.DraftID, nil
 }
 
-func (m *MockEmailService) CreateFolder(ctx context.Context, name, parent string) error {
+func (m *MockEmailService) CreateFolder(ctx context.Context, name, parent string, opts imap.CreateFolderOptions) (string, error) {
 	m.LastMethod = "CreateFolder"
 	m.LastName = name
 	m.LastParent = parent
+	m.LastCreateOpts = opts
+	m.CallCount++
+	if m.Err != nil {
+		return "", m.Err
+	}
+	if m.CreateErr != nil {
+		return "", m.CreateErr
+	}
+	if parent == "" {
+		return name, nil
+	}
+	delimiter := m.Delimiter
+	if delimiter == "" {
+		delimiter = "/"
+	}
+	return parent + delimiter + name, nil
+}
+
+func (m *MockEmailService) Subscribe(ctx context.Context, folder string) error {
+	m.LastMethod = "Subscribe"
+	m.LastFolder = folder
+	m.CallCount++
+	return m.Err
+}
+
+func (m *MockEmailService) Unsubscribe(ctx context.Context, folder string) error {
+	m.LastMethod = "Unsubscribe"
+	m.LastFolder = folder
 	m.CallCount++
 	return m.Err
 }
@@ -184,6 +657,13 @@ type MockEmailSender struct {
 	LastOriginal *imap.Email
 	LastReplyAll bool
 	CallCount    int
+
+	// VerifyRecipient results
+	Accepted bool
+	Reply    string
+
+	// PreviewReply result
+	Preview smtppkg.ReplyPreview
 }
 
 func (m *MockEmailSender) SendEmail(ctx context.Context, from string, to []string, subject, body string, opts smtppkg.SendOptions) error {
@@ -207,6 +687,25 @@ func (m *MockEmailSender) ReplyToEmail(ctx context.Context, original *imap.Email
 	return m.Err
 }
 
+func (m *MockEmailSender) PreviewReply(original *imap.Email, replyAll bool, opts smtppkg.SendOptions) smtppkg.ReplyPreview {
+	m.LastMethod = "PreviewReply"
+	m.LastOriginal = original
+	m.LastReplyAll = replyAll
+	m.LastOpts = opts
+	m.CallCount++
+	return m.Preview
+}
+
+func (m *MockEmailSender) VerifyRecipient(ctx context.Context, addr string) (bool, string, error) {
+	m.LastMethod = "VerifyRecipient"
+	m.LastTo = []string{addr}
+	m.CallCount++
+	if m.Err != nil {
+		return false, "", m.Err
+	}
+	return m.Accepted, m.Reply, nil
+}
+
 // newErrMock returns a mock with an error pre-configured
 func newErrMock(msg string) *MockEmailService {
 	return &MockEmailService{Err: fmt.Errorf("%s", msg)}
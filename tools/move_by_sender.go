@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// MoveBySenderHandler creates a handler for filing every email from one
+// sender into a folder
+func MoveBySenderHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required sender address
+		senderArg, ok := args["sender"].(string)
+		if !ok || senderArg == "" {
+			return invalidArgument("sender is required"), nil
+		}
+		parsed, err := mail.ParseAddress(senderArg)
+		if err != nil {
+			return invalidArgument(fmt.Sprintf("invalid sender email address '%s': %v", senderArg, err)), nil
+		}
+		sender := strings.ToLower(parsed.Address)
+
+		toFolder, ok := args["to_folder"].(string)
+		if !ok || toFolder == "" {
+			return invalidArgument("to_folder is required"), nil
+		}
+		if err := validateFolderName(toFolder); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get from_folder (default to DEFAULT_FOLDER)
+		fromFolder, err := resolveFolderArg(ctx, client, args, "from_folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		createFolder := false
+		if c, ok := args["create_folder"].(bool); ok {
+			createFolder = c
+		}
+
+		// FROM search is a substring match, so keep only exact addresses
+		emails, _, _, err := client.SearchEmails(ctx, fromFolder, "", imap.EmailFilters{From: sender})
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to search emails", err), nil
+		}
+		var ids []string
+		for _, email := range emails {
+			addr := email.From
+			if p, err := mail.ParseAddress(email.From); err == nil {
+				addr = p.Address
+			}
+			if strings.EqualFold(addr, sender) {
+				ids = append(ids, email.ID)
+			}
+		}
+
+		response := map[string]interface{}{
+			"success":     true,
+			"sender":      sender,
+			"from_folder": fromFolder,
+			"to_folder":   toFolder,
+			"moved":       0,
+		}
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		if len(ids) == 0 {
+			response["message"] = fmt.Sprintf("Nothing to move: no emails from %s in '%s'", sender, fromFolder)
+			jsonData, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(jsonData)), nil
+		}
+
+		// Create the destination first if asked and it is missing
+		if createFolder {
+			folders, err := client.ListFolders(ctx)
+			if err != nil {
+				return operationError("failed to list folders", err), nil
+			}
+			exists := false
+			for _, f := range folders {
+				if f == toFolder {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				if _, err := client.CreateFolder(ctx, toFolder, "", imap.CreateFolderOptions{}); err != nil {
+					return operationError("failed to create folder", err), nil
+				}
+				response["created_folder"] = true
+			}
+		}
+
+		moved, err := client.MoveEmailBulk(ctx, fromFolder, toFolder, ids)
+		if err != nil {
+			return operationError("failed to move emails", err), nil
+		}
+
+		response["moved"] = moved
+		response["email_ids"] = ids
+		response["message"] = fmt.Sprintf("Moved %d emails from %s to '%s'", moved, sender, toFolder)
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
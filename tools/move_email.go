@@ -6,34 +6,58 @@ import (
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
 )
 
 // MoveEmailHandler creates a handler for moving emails between folders
-func MoveEmailHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func MoveEmailHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required parameters
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 
 		toFolder, ok := args["to_folder"].(string)
 		if !ok || toFolder == "" {
-			return mcp.NewToolResultError("to_folder is required"), nil
+			return invalidArgument("to_folder is required"), nil
 		}
 
-		// Get from_folder (default to INBOX)
-		fromFolder, _ := args["from_folder"].(string)
-		if fromFolder == "" {
-			fromFolder = "INBOX"
+		// Get from_folder (default to DEFAULT_FOLDER)
+		fromFolder, err := resolveFolderArg(ctx, client, args, "from_folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Build move options
+		opts := imap.MoveOptions{}
+		if skip, ok := args["skip_if_duplicate"].(bool); ok {
+			opts.SkipIfDuplicate = skip
+		}
+		if preserve, ok := args["preserve_flags"].(bool); ok {
+			opts.PreserveFlags = preserve
+		}
+
+		// The moved email gets a new UID in the destination; it is found
+		// again by Message-ID, which has to be read before the move
+		returnEmail, _ := args["return_email"].(bool)
+		var messageID string
+		if returnEmail {
+			headers, err := client.GetHeaders(ctx, fromFolder, emailID, []string{"Message-Id"})
+			if err != nil {
+				return operationError("failed to read email before moving", err), nil
+			}
+			if ids := headers["Message-Id"]; len(ids) > 0 {
+				messageID = ids[0]
+			}
 		}
 
 		// Move email
-		err := client.MoveEmail(ctx, fromFolder, toFolder, emailID)
+		skipped, err := client.MoveEmail(ctx, fromFolder, toFolder, emailID, opts)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to move email: %v", err)), nil
+			return operationError("failed to move email", err), nil
 		}
 
 		// Format response
@@ -45,9 +69,25 @@ func MoveEmailHandler(client EmailWriter) func(context.Context, mcp.CallToolRequ
 			"message":     fmt.Sprintf("Email moved from '%s' to '%s' successfully", fromFolder, toFolder),
 		}
 
+		if skipped {
+			response["skipped"] = true
+			response["message"] = fmt.Sprintf("Email already present in '%s'; not moved", toFolder)
+		}
+
+		if returnEmail {
+			if messageID == "" {
+				response["warning"] = "email has no Message-ID, so it cannot be found in the destination"
+			} else if newID, err := client.FindByMessageID(ctx, toFolder, messageID); err != nil {
+				response["warning"] = fmt.Sprintf("email moved but could not be found in '%s': %v", toFolder, err)
+			} else {
+				response["new_email_id"] = newID
+				attachUpdatedEmail(ctx, client, toFolder, newID, response)
+			}
+		}
+
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/internal/htmltext"
+)
+
+// PreviewPlaintextHandler creates a handler that renders an HTML body to the
+// plain-text alternative send_email would generate for it, without sending
+func PreviewPlaintextHandler() func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required html
+		html, ok := args["html"].(string)
+		if !ok || html == "" {
+			return invalidArgument("html is required"), nil
+		}
+		if err := validateBodySize(html); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Same conversion the SMTP client uses for the text/plain part
+		text := htmltext.ToText(html)
+
+		// Format response
+		response := map[string]interface{}{
+			"text":   text,
+			"length": len([]rune(text)),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/smtp"
+)
+
+// PreviewReplyHandler creates a handler that shows who a reply would go to
+// and which headers it would carry, without sending it. replyAllDefault
+// applies when the reply_all argument is omitted, as for reply_email.
+func PreviewReplyHandler(imapClient EmailReader, previewer ReplyPreviewer, defaultFolder string, replyAllDefault bool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required parameters
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		// Get optional parameters
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		replyAll := replyAllDefault
+		if ra, ok := args["reply_all"].(bool); ok {
+			replyAll = ra
+		}
+
+		// Fetch the original email
+		originalEmail, err := imapClient.GetEmail(ctx, folder, emailID)
+		if err != nil {
+			return operationError("failed to get original email", err), nil
+		}
+
+		preview := previewer.PreviewReply(originalEmail, replyAll, smtp.SendOptions{})
+
+		// Format response
+		response := map[string]interface{}{
+			"email_id":  emailID,
+			"reply_all": replyAll,
+			"preview":   preview,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
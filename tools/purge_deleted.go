@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// PurgeDeletedHandler creates a handler that expunges the messages in a
+// folder already marked \Deleted, leaving every other message alone
+func PurgeDeletedHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		purged, err := client.PurgeDeleted(ctx, folder)
+		if err != nil {
+			return operationError("failed to purge deleted emails", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success": true,
+			"folder":  folder,
+			"purged":  purged,
+			"message": fmt.Sprintf("Permanently removed %d emails marked as deleted from %s", purged, folder),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// RecentSendersHandler creates a handler for listing the addresses recently
+// sent to, most recent first, for address auto-completion
+func RecentSendersHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Parse last_days (default 90)
+		lastDays := 90
+		if ld, ok := args["last_days"].(float64); ok && ld > 0 {
+			lastDays = int(ld)
+		}
+
+		// Parse limit (default 20)
+		limit := 20
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+		}
+
+		// Scan Sent. A partial result still carries usable addresses.
+		recipients, err := client.RecentRecipients(ctx, lastDays, limit)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to list recent recipients", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"last_days":  lastDays,
+			"count":      len(recipients),
+			"recipients": recipients,
+		}
+
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// RemoveDuplicatesHandler creates a handler that moves all but the oldest
+// copy of each duplicate group in a folder to the trash. It takes two calls:
+// the first lists the groups and returns a confirmation token, and only a
+// repeat carrying that token, while the folder still holds the same
+// duplicates, moves anything.
+func RemoveDuplicatesHandler(client EmailService, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	confirms := newConfirmStore(confirmTTL)
+
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		matchHeaders := false
+		if v, ok := args["match_headers"].(bool); ok {
+			matchHeaders = v
+		}
+
+		if err := client.CheckProtected(ctx, folder); err != nil {
+			return operationError("cannot remove duplicates", err), nil
+		}
+
+		// Only a complete scan is acted on: a copy missing from a partial
+		// one could be the oldest, so the wrong email would be kept
+		groups, err := client.FindDuplicates(ctx, folder, matchHeaders)
+		if errors.Is(err, imap.ErrPartialResults) {
+			return operationError("scan for duplicates was incomplete, nothing was moved", err), nil
+		}
+		if err != nil {
+			return operationError("failed to find duplicates", err), nil
+		}
+
+		var ids []string
+		for _, group := range groups {
+			ids = append(ids, group.IDs[1:]...)
+		}
+
+		// The token covers exactly these emails, so it cannot confirm a
+		// different set if the folder changed in between
+		if len(ids) > 0 {
+			operation := fmt.Sprintf("remove_duplicates %s %s", folder, strings.Join(ids, ","))
+			token, _ := args["confirm"].(string)
+			if token == "" {
+				response := map[string]interface{}{
+					"folder":     folder,
+					"groups":     groups,
+					"duplicates": len(ids),
+				}
+				action := fmt.Sprintf("%d duplicate emails in '%s' will be moved to trash, keeping the oldest copy of each. They can be moved back from the trash", len(ids), folder)
+				return confirmationRequired(confirms, operation, action, response), nil
+			}
+			if !confirms.redeem(token, operation) {
+				return invalidArgument(errInvalidConfirm), nil
+			}
+		}
+
+		removed := 0
+		for _, id := range ids {
+			if err := client.DeleteEmail(ctx, folder, id, false); err != nil {
+				return operationError(fmt.Sprintf("failed to delete duplicate %s after moving %d to trash", id, removed), err), nil
+			}
+			removed++
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success": true,
+			"folder":  folder,
+			"removed": removed,
+			"message": fmt.Sprintf("Moved %d duplicate emails to trash, keeping the oldest copy of each", removed),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -9,29 +9,33 @@ import (
 	"github.com/rgabriel/mcp-icloud-email/smtp"
 )
 
-// ReplyEmailHandler creates a handler for replying to emails
-func ReplyEmailHandler(imapClient EmailReader, smtpClient EmailSender) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// ReplyEmailHandler creates a handler for replying to emails. replyAllDefault
+// applies when the reply_all argument is omitted.
+func ReplyEmailHandler(imapClient EmailReader, smtpClient EmailSender, defaultFolder string, replyAllDefault bool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required parameters
 		emailID, ok := args["email_id"].(string)
 		if !ok || emailID == "" {
-			return mcp.NewToolResultError("email_id is required"), nil
+			return invalidArgument("email_id is required"), nil
 		}
 
 		body, ok := args["body"].(string)
 		if !ok || body == "" {
-			return mcp.NewToolResultError("body is required"), nil
+			return invalidArgument("body is required"), nil
+		}
+		if err := validateBodySize(body); err != nil {
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Get optional parameters
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
-		replyAll := false
+		replyAll := replyAllDefault
 		if ra, ok := args["reply_all"].(bool); ok {
 			replyAll = ra
 		}
@@ -44,18 +48,24 @@ func ReplyEmailHandler(imapClient EmailReader, smtpClient EmailSender) func(cont
 		// Fetch the original email
 		originalEmail, err := imapClient.GetEmail(ctx, folder, emailID)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get original email: %v", err)), nil
+			return operationError("failed to get original email", err), nil
 		}
 
 		// Build send options
 		opts := smtp.SendOptions{
 			HTML: html,
 		}
+		if quote, ok := args["quote_original"].(bool); ok {
+			opts.QuoteOriginal = quote
+		}
+		if autoBCC, ok := args["auto_bcc"].(bool); ok {
+			opts.SkipAutoBCC = !autoBCC
+		}
 
 		// Reply to the email
 		err = smtpClient.ReplyToEmail(ctx, originalEmail, body, replyAll, opts)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to send reply: %v", err)), nil
+			return operationError("failed to send reply", err), nil
 		}
 
 		// Format response
@@ -72,7 +82,7 @@ func ReplyEmailHandler(imapClient EmailReader, smtpClient EmailSender) func(cont
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/smtp"
+)
+
+// ResendHandler creates a handler for sending a stored message again, with
+// optional changes to its recipients, subject, or body
+func ResendHandler(imapClient EmailReader, smtpClient EmailSender, fromEmail string, allowedFrom []string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+		if err := validateEmailID(emailID); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get folder (default to the Sent folder)
+		folder, err := resolveFolderArg(ctx, imapClient, args, "folder", "sent")
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Parse overrides before touching the server
+		to, err := parseAddressList(args, "to")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		cc, err := parseAddressList(args, "cc")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		bcc, err := parseAddressList(args, "bcc")
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		subject, _ := args["subject"].(string)
+		if err := validateSubjectSize(subject); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		body, _ := args["body"].(string)
+		if err := validateBodySize(body); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		stored, err := imapClient.GetStoredMessage(ctx, folder, emailID)
+		if err != nil {
+			return operationError("failed to get original message", err), nil
+		}
+
+		// Apply overrides; an empty cc or bcc list clears the original
+		if _, ok := args["to"]; ok {
+			stored.To = to
+		}
+		if _, ok := args["cc"]; ok {
+			stored.CC = cc
+		}
+		if _, ok := args["bcc"]; ok {
+			stored.BCC = bcc
+		}
+		if subject != "" {
+			stored.Subject = subject
+		}
+		if body != "" {
+			stored.Body = body
+			stored.HTML = false
+		}
+		if h, ok := args["html"].(bool); ok {
+			stored.HTML = h
+		}
+		if len(stored.To) == 0 {
+			return invalidArgument("original message has no recipients; provide to"), nil
+		}
+
+		// Send from the original address when it is ours to use
+		fromArgs := map[string]interface{}{"from": stored.From}
+		if override, ok := args["from"].(string); ok && override != "" {
+			fromArgs["from"] = override
+		}
+		from, err := resolveFrom(fromArgs, fromEmail, allowedFrom)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Keep the message in its original thread
+		opts := smtp.SendOptions{
+			CC:      stored.CC,
+			BCC:     stored.BCC,
+			HTML:    stored.HTML,
+			Headers: map[string]string{},
+		}
+		if stored.InReplyTo != "" {
+			opts.Headers["In-Reply-To"] = stored.InReplyTo
+		}
+		if len(stored.References) > 0 {
+			opts.Headers["References"] = strings.Join(stored.References, " ")
+		}
+
+		if err := smtpClient.SendEmail(ctx, from, stored.To, stored.Subject, stored.Body, opts); err != nil {
+			return operationError("failed to resend email", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":  true,
+			"email_id": emailID,
+			"from":     from,
+			"to":       stored.To,
+			"subject":  stored.Subject,
+			"message":  fmt.Sprintf("Resent to %s", strings.Join(stored.To, ", ")),
+		}
+		if len(stored.CC) > 0 {
+			response["cc"] = stored.CC
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
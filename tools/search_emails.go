@@ -3,27 +3,51 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/rgabriel/mcp-icloud-email/imap"
 )
 
-// SearchEmailsHandler creates a handler for searching emails
-func SearchEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// SearchEmailsHandler creates a handler for searching emails. explainEmpty
+// is used when explain_empty is omitted.
+func SearchEmailsHandler(client EmailReader, defaultFolder string, explainEmpty bool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
-		// Get folder (default to INBOX)
-		folder, _ := args["folder"].(string)
-		if folder == "" {
-			folder = "INBOX"
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
 		}
 
 		// Get search query (optional)
 		query, _ := args["query"].(string)
 
+		// Get output format (default to json)
+		format, _ := args["format"].(string)
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "compact" {
+			return invalidArgument("format must be one of: json, compact"), nil
+		}
+		groupThreads, _ := args["group_by_thread"].(bool)
+		if format == "compact" && groupThreads {
+			return invalidArgument("format=compact cannot be combined with group_by_thread"), nil
+		}
+		idsOnly, _ := args["ids_only"].(bool)
+		if v, ok := args["explain_empty"].(bool); ok {
+			explainEmpty = v
+		}
+		if exclude, _ := args["exclude_folder"].(string); idsOnly && (groupThreads || format == "compact" || exclude != "") {
+			return invalidArgument("ids_only cannot be combined with group_by_thread, exclude_folder, or format=compact"), nil
+		}
+
 		// Build filters
 		filters := imap.EmailFilters{
 			LastDays: 30, // Default to 30 days
@@ -53,11 +77,21 @@ func SearchEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolR
 			filters.UnreadOnly = unreadOnly
 		}
 
+		// Parse delivered_to, e.g. a plus-address alias
+		if deliveredTo, ok := args["delivered_to"].(string); ok && deliveredTo != "" {
+			addr, err := mail.ParseAddress(deliveredTo)
+			if err != nil {
+				return invalidArgument(fmt.Sprintf("invalid delivered_to email address '%s': %v", deliveredTo, err)), nil
+			}
+			filters.DeliveredTo = addr.Address
+		}
+
 		// Parse since (overrides last_days if provided)
+		now := time.Now()
 		if sinceStr, ok := args["since"].(string); ok && sinceStr != "" {
-			t, err := time.Parse(time.RFC3339, sinceStr)
+			t, err := parseDateArg(sinceStr, now)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("invalid since format: %v (use ISO 8601 format like '2024-01-15T14:30:00Z')", err)), nil
+				return invalidArgument(fmt.Sprintf("invalid since format: %v", err)), nil
 			}
 			filters.Since = &t
 			filters.LastDays = 0 // Clear last_days when since is provided
@@ -65,17 +99,88 @@ func SearchEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolR
 
 		// Parse before
 		if beforeStr, ok := args["before"].(string); ok && beforeStr != "" {
-			t, err := time.Parse(time.RFC3339, beforeStr)
+			t, err := parseDateArg(beforeStr, now)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("invalid before format: %v (use ISO 8601 format like '2024-01-15T14:30:00Z')", err)), nil
+				return invalidArgument(fmt.Sprintf("invalid before format: %v", err)), nil
 			}
 			filters.Before = &t
 		}
 
-		// Search emails
-		emails, total, err := client.SearchEmails(ctx, folder, query, filters)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to search emails: %v", err)), nil
+		// Parse raw_query; the server-side search then uses it alone
+		if raw, ok := args["raw_query"].(string); ok && raw != "" {
+			if _, err := imap.ParseRawQuery(raw); err != nil {
+				return invalidArgument(err.Error()), nil
+			}
+			filters.RawQuery = raw
+		}
+
+		// Optional folder whose messages are left out of the results
+		var excludeFolder string
+		if v, ok := args["exclude_folder"].(string); ok && v != "" {
+			excludeFolder, err = resolveFolderArg(ctx, client, args, "exclude_folder", "")
+			if err != nil {
+				return argumentResult("failed to resolve exclude_folder", err), nil
+			}
+		}
+
+		// UIDs alone need no fetch at all
+		if idsOnly {
+			ids, total, uidValidity, err := client.SearchUIDs(ctx, folder, query, filters)
+			if err != nil {
+				return operationError("failed to search emails", err), nil
+			}
+
+			// Format response
+			response := map[string]interface{}{
+				"count":  len(ids),
+				"total":  total,
+				"ids":    ids,
+				"folder": folder,
+			}
+			if query != "" {
+				response["query"] = query
+			}
+			if filters.RawQuery != "" {
+				response["raw_query"] = filters.RawQuery
+			}
+			if explainEmpty {
+				explainSearchResult(response, len(ids), folder, query, filters, total, 0, "")
+			}
+			addUIDValidity(response, uidValidity)
+
+			jsonData, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(jsonData)), nil
+		}
+
+		// Search emails. A partial result still carries usable emails.
+		// With exclude_folder the excluded emails are dropped before offset
+		// and limit, so pages stay full.
+		var emails []imap.Email
+		var total, excluded int
+		var uidValidity uint32
+		if excludeFolder != "" {
+			ids, exErr := client.MessageIDs(ctx, excludeFolder)
+			if exErr != nil {
+				return operationError("failed to read exclude_folder", exErr), nil
+			}
+			emails, total, excluded, uidValidity, err = client.SearchEmailsExcluding(ctx, folder, query, filters, ids)
+		} else {
+			emails, total, uidValidity, err = client.SearchEmails(ctx, folder, query, filters)
+		}
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to search emails", err), nil
+		}
+
+		if format == "compact" {
+			var warning string
+			if partial {
+				warning = err.Error()
+			}
+			return mcp.NewToolResultText(compactEmailTable(folder, total, emails, warning)), nil
 		}
 
 		// Format response
@@ -86,15 +191,90 @@ func SearchEmailsHandler(client EmailReader) func(context.Context, mcp.CallToolR
 			"folder": folder,
 		}
 
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+		if excludeFolder != "" {
+			response["exclude_folder"] = excludeFolder
+			response["excluded"] = excluded
+		}
+
+		// Optionally cluster results into conversations
+		if groupThreads {
+			conversations := groupByThread(emails)
+			delete(response, "emails")
+			response["conversations"] = conversations
+			response["conversation_count"] = len(conversations)
+		}
+
 		if query != "" {
 			response["query"] = query
 		}
+		if filters.RawQuery != "" {
+			response["raw_query"] = filters.RawQuery
+		}
+		if explainEmpty {
+			explainSearchResult(response, len(emails), folder, query, filters, total, excluded, excludeFolder)
+		}
+		addUIDValidity(response, uidValidity)
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonData)), nil
 	}
 }
+
+// explainSearchResult adds "found" to a search response, so that no matches
+// cannot be mistaken for a failure, and when count is 0 a "message" saying
+// why along with the "filters" that were applied.
+func explainSearchResult(response map[string]interface{}, count int, folder, query string, filters imap.EmailFilters, total, excluded int, excludeFolder string) {
+	response["found"] = count > 0
+	if count > 0 {
+		return
+	}
+	response["message"] = emptySearchMessage(folder, query, filters, total, excluded, excludeFolder)
+	response["filters"] = searchFilterSummary(query, filters, excludeFolder)
+}
+
+// addUIDValidity records the UIDVALIDITY the search reported in response as
+// "uidvalidity", so that clients caching the returned IDs can tell when the
+// server has renumbered the folder. It is left out when unknown (0).
+func addUIDValidity(response map[string]interface{}, uidValidity uint32) {
+	if uidValidity != 0 {
+		response["uidvalidity"] = uidValidity
+	}
+}
+
+// compactCell makes a value safe for one cell of a compact table
+var compactCell = strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ")
+
+// compactEmailTable renders search results as a summary line followed by one
+// "id | date | from | subject | unread" row per email. It carries the same
+// ids as the JSON format in far fewer tokens. An email without a date gets
+// an empty date cell.
+func compactEmailTable(folder string, total int, emails []imap.Email, warning string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "folder: %s | count: %d | total: %d\n", folder, len(emails), total)
+	if warning != "" {
+		fmt.Fprintf(&b, "warning: partial results: %s\n", compactCell.Replace(warning))
+	}
+	b.WriteString("id | date | from | subject | unread\n")
+	for _, e := range emails {
+		date := ""
+		if !e.Date.IsZero() {
+			date = e.Date.Format(time.RFC3339)
+		}
+		fmt.Fprintf(&b, "%s | %s | %s | %s | %t\n",
+			e.ID,
+			date,
+			compactCell.Replace(e.From),
+			compactCell.Replace(e.Subject),
+			e.Unread,
+		)
+	}
+	return b.String()
+}
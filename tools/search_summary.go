@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// searchFilterSummary lists the filters a search applied, keyed by the
+// search_emails parameter that set them. Unset filters are left out.
+func searchFilterSummary(query string, filters imap.EmailFilters, excludeFolder string) map[string]interface{} {
+	summary := map[string]interface{}{}
+	if filters.RawQuery != "" {
+		// raw_query replaces the other server-side filters
+		summary["raw_query"] = filters.RawQuery
+	} else {
+		if query != "" {
+			summary["query"] = query
+		}
+		if filters.LastDays > 0 {
+			summary["last_days"] = filters.LastDays
+		}
+		if filters.Since != nil {
+			summary["since"] = filters.Since.Format(time.RFC3339)
+		}
+		if filters.Before != nil {
+			summary["before"] = filters.Before.Format(time.RFC3339)
+		}
+		if filters.UnreadOnly {
+			summary["unread_only"] = true
+		}
+		if filters.DeliveredTo != "" {
+			summary["delivered_to"] = filters.DeliveredTo
+		}
+	}
+	if filters.Offset > 0 {
+		summary["offset"] = filters.Offset
+	}
+	if excludeFolder != "" {
+		summary["exclude_folder"] = excludeFolder
+	}
+	return summary
+}
+
+// emptySearchMessage explains in a sentence why a search returned no
+// emails: nothing matched the filters, the offset paged past every match,
+// or exclude_folder dropped them all. total and excluded are as in the
+// search_emails response.
+func emptySearchMessage(folder, query string, filters imap.EmailFilters, total, excluded int, excludeFolder string) string {
+	if excluded > 0 && total == 0 {
+		return fmt.Sprintf("No emails to show in %s: all %d matches were left out because they are also in %s.", folder, excluded, excludeFolder)
+	}
+	if total > 0 && filters.Offset > 0 {
+		return fmt.Sprintf("No emails on this page of %s: offset %d is past all %d matches. Use a smaller offset.", folder, filters.Offset, total)
+	}
+
+	var parts []string
+	if filters.RawQuery != "" {
+		parts = append(parts, fmt.Sprintf("raw query %q", filters.RawQuery))
+	} else {
+		if query != "" {
+			parts = append(parts, fmt.Sprintf("containing %q", query))
+		}
+		if filters.DeliveredTo != "" {
+			parts = append(parts, "delivered to "+filters.DeliveredTo)
+		}
+		if filters.UnreadOnly {
+			parts = append(parts, "unread only")
+		}
+		switch {
+		case filters.Since != nil:
+			parts = append(parts, "since "+filters.Since.Format("2006-01-02"))
+		case filters.LastDays > 0:
+			parts = append(parts, fmt.Sprintf("from the last %d days", filters.LastDays))
+		}
+		if filters.Before != nil {
+			parts = append(parts, "before "+filters.Before.Format("2006-01-02"))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("No emails found in %s. The folder is empty; this is not an error.", folder)
+	}
+	return fmt.Sprintf("No emails found in %s matching: %s. This is not an error; try a wider date range or fewer filters.", folder, strings.Join(parts, ", "))
+}
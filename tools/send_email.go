@@ -4,37 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/rgabriel/mcp-icloud-email/smtp"
 )
 
-// SendEmailHandler creates a handler for sending emails
-func SendEmailHandler(smtpClient EmailSender, fromEmail string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// SendEmailHandler creates a handler for sending emails. Mail is sent from
+// fromEmail unless the caller picks one of the allowedFrom aliases. Calls that
+// repeat an idempotency_key within the last hour replay the first result
+// without sending.
+func SendEmailHandler(smtpClient EmailSender, fromEmail string, allowedFrom []string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sent := newIdempotencyStore(idempotencyTTL)
+
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args := req.GetArguments()
 
 		// Get required parameters
 		subject, ok := args["subject"].(string)
 		if !ok || subject == "" {
-			return mcp.NewToolResultError("subject is required"), nil
+			return invalidArgument("subject is required"), nil
 		}
 		if err := validateSubjectSize(subject); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		body, ok := args["body"].(string)
 		if !ok || body == "" {
-			return mcp.NewToolResultError("body is required"), nil
+			return invalidArgument("body is required"), nil
 		}
 		if err := validateBodySize(body); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse and validate To addresses
 		to, err := requireAddressList(args, "to")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Resolve the From address against the alias allow-list
+		from, err := resolveFrom(args, fromEmail, allowedFrom)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Build send options
@@ -43,13 +55,13 @@ func SendEmailHandler(smtpClient EmailSender, fromEmail string) func(context.Con
 		// Parse CC addresses
 		opts.CC, err = parseAddressList(args, "cc")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse BCC addresses
 		opts.BCC, err = parseAddressList(args, "bcc")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return invalidArgument(err.Error()), nil
 		}
 
 		// Parse HTML flag
@@ -57,23 +69,76 @@ func SendEmailHandler(smtpClient EmailSender, fromEmail string) func(context.Con
 			opts.HTML = html
 		}
 
+		// The configured auto BCC can be turned off per message
+		if autoBCC, ok := args["auto_bcc"].(bool); ok {
+			opts.SkipAutoBCC = !autoBCC
+		}
+
+		// Request receipts, addressed back to the sender
+		opts.Headers = map[string]string{}
+		if readReceipt, ok := args["request_read_receipt"].(bool); ok && readReceipt {
+			opts.Headers["Disposition-Notification-To"] = from
+		}
+		if deliveryReceipt, ok := args["request_delivery_receipt"].(bool); ok && deliveryReceipt {
+			opts.Headers["Return-Receipt-To"] = from
+		}
+
+		// Parse priority
+		priority, _ := args["priority"].(string)
+		prioHeaders, err := priorityHeaders(priority)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		for k, v := range prioHeaders {
+			opts.Headers[k] = v
+		}
+
+		// Replay a previous send with the same idempotency key
+		idempotencyKey, _ := args["idempotency_key"].(string)
+		if idempotencyKey != "" {
+			prior, done, busy := sent.claim(idempotencyKey)
+			if busy {
+				return toolError(CodeConflict, "a send with this idempotency_key is already in progress"), nil
+			}
+			if done {
+				return mcp.NewToolResultText(prior), nil
+			}
+		}
+
 		// Send email
-		if err := smtpClient.SendEmail(ctx, fromEmail, to, subject, body, opts); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to send email: %v", err)), nil
+		if err := smtpClient.SendEmail(ctx, from, to, subject, body, opts); err != nil {
+			if idempotencyKey != "" {
+				sent.release(idempotencyKey)
+			}
+			return operationError("failed to send email", err), nil
+		}
+
+		// The email is out, so the key must leave the in-flight state on
+		// every path from here; otherwise a retry would neither send nor
+		// replay until the entry expired
+		result := `{"success": true}`
+		if idempotencyKey != "" {
+			defer func() { sent.complete(idempotencyKey, result) }()
 		}
 
-		// Format response
+		// Format response. BCC recipients are blind and never echoed back.
 		response := map[string]interface{}{
 			"success": true,
-			"message": fmt.Sprintf("Email sent successfully to %v", to),
+			"message": fmt.Sprintf("Email sent successfully to %s", strings.Join(to, ", ")),
+			"to":      to,
 			"subject": subject,
+			"from":    from,
+		}
+		if len(opts.CC) > 0 {
+			response["cc"] = opts.CC
 		}
 
 		jsonData, err := json.MarshalIndent(response, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to format response: %v", err)), nil
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
 		}
+		result = string(jsonData)
 
-		return mcp.NewToolResultText(string(jsonData)), nil
+		return mcp.NewToolResultText(result), nil
 	}
 }
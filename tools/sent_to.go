@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+// SentToHandler creates a handler that searches the Sent folder for messages
+// addressed to a recipient in To or CC, e.g. to check for an earlier reply
+func SentToHandler(client EmailReader) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get address (required)
+		address, ok := args["address"].(string)
+		if !ok || address == "" {
+			return invalidArgument("address is required"), nil
+		}
+		addr, err := mail.ParseAddress(address)
+		if err != nil {
+			return invalidArgument(fmt.Sprintf("invalid email address '%s': %v", address, err)), nil
+		}
+
+		// The Sent folder is found by its \Sent special-use attribute
+		folder, err := client.ResolveFolder(ctx, "sent")
+		if err != nil {
+			return operationError("failed to resolve Sent folder", err), nil
+		}
+
+		// Parse last_days (default 30) and limit (default 50)
+		filters := imap.EmailFilters{
+			Recipient: addr.Address,
+			LastDays:  30,
+			Limit:     50,
+		}
+		if ld, ok := args["last_days"].(float64); ok && ld > 0 {
+			filters.LastDays = int(ld)
+		}
+		if limit, ok := args["limit"].(float64); ok && limit > 0 {
+			filters.Limit = int(limit)
+			if filters.Limit > 200 {
+				filters.Limit = 200 // Max limit
+			}
+		}
+
+		// Search emails. A partial result still carries usable emails.
+		emails, total, _, err := client.SearchEmails(ctx, folder, "", filters)
+		partial := errors.Is(err, imap.ErrPartialResults)
+		if err != nil && !partial {
+			return operationError("failed to search sent emails", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"count":     len(emails),
+			"total":     total,
+			"emails":    emails,
+			"folder":    folder,
+			"address":   addr.Address,
+			"last_days": filters.LastDays,
+		}
+		if partial {
+			response["partial"] = true
+			response["warning"] = err.Error()
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
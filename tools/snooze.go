@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// SnoozeEmailHandler creates a handler that hides an email in a dated Snoozed
+// subfolder until flush_snoozed brings it back
+func SnoozeEmailHandler(client EmailWriter, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required parameters
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+
+		untilStr, ok := args["until"].(string)
+		if !ok || untilStr == "" {
+			return invalidArgument("until is required"), nil
+		}
+		now := time.Now()
+		until, err := parseDateArg(untilStr, now)
+		if err != nil {
+			return invalidArgument(fmt.Sprintf("invalid until: %v", err)), nil
+		}
+		if !until.After(now) {
+			return invalidArgument("until must be in the future"), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		snoozed, err := client.SnoozeEmail(ctx, folder, emailID, until)
+		if err != nil {
+			return operationError("failed to snooze email", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"success":       true,
+			"email_id":      emailID,
+			"from_folder":   folder,
+			"snooze_folder": snoozed.Folder,
+			"until":         snoozed.Until.Format(time.RFC3339),
+			"message":       fmt.Sprintf("Email snoozed until %s; run flush_snoozed to bring due emails back", snoozed.Until.Format(time.RFC1123)),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
+
+// FlushSnoozedHandler creates a handler that moves snoozed emails whose time
+// has come back to INBOX
+func FlushSnoozedHandler(client EmailWriter) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := client.FlushSnoozed(ctx, time.Now())
+		if err != nil {
+			return operationError("failed to flush snoozed emails", err), nil
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"returned":  result.Returned,
+			"remaining": result.Remaining,
+			"message":   fmt.Sprintf("Moved %d snoozed emails back to INBOX; %d still snoozed", result.Returned, result.Remaining),
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/subject"
+)
+
+// conversation is a group of related emails in a search result.
+type conversation struct {
+	Subject  string     `json:"subject"`
+	Count    int        `json:"count"`
+	EmailIDs []string   `json:"email_ids"`
+	Latest   imap.Email `json:"latest"`
+}
+
+// groupByThread clusters emails into conversations. Two emails belong to the
+// same conversation if their normalized subjects match or if they share a
+// message ID, counting each email's own Message-ID and every ID in its
+// References. A shared root therefore links replies whose parent is missing
+// from the result or whose subject was edited. Conversations are ordered by
+// their latest message, newest first.
+func groupByThread(emails []imap.Email) []conversation {
+	parent := make([]int, len(emails))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	bySubject := map[string]int{}
+	byMessageID := map[string]int{}
+	for i, e := range emails {
+		if subj := strings.ToLower(subject.Normalize(e.Subject)); subj != "" {
+			if j, ok := bySubject[subj]; ok {
+				union(j, i)
+			} else {
+				bySubject[subj] = i
+			}
+		}
+		for _, id := range append([]string{e.MessageID}, e.References...) {
+			if id == "" {
+				continue
+			}
+			if j, ok := byMessageID[id]; ok {
+				union(j, i)
+			} else {
+				byMessageID[id] = i
+			}
+		}
+	}
+
+	// Collect members per root, preserving input order
+	groups := map[int]*conversation{}
+	var roots []int
+	for i, e := range emails {
+		root := find(i)
+		conv, ok := groups[root]
+		if !ok {
+			conv = &conversation{Subject: subject.Normalize(e.Subject), Latest: e}
+			groups[root] = conv
+			roots = append(roots, root)
+		}
+		conv.Count++
+		conv.EmailIDs = append(conv.EmailIDs, e.ID)
+		if e.Date.After(conv.Latest.Date) {
+			conv.Latest = e
+		}
+	}
+
+	conversations := make([]conversation, 0, len(roots))
+	for _, root := range roots {
+		conversations = append(conversations, *groups[root])
+	}
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].Latest.Date.After(conversations[j].Latest.Date)
+	})
+	return conversations
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	imappkg "github.com/rgabriel/mcp-icloud-email/imap"
+)
+
+func TestGroupByThread(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 9, 0, 0, 0, time.UTC) }
+	emails := []imappkg.Email{
+		{ID: "1", Subject: "Budget", MessageID: "<a@x>", Date: day(1)},
+		// Different subject but References links it to the budget thread
+		{ID: "2", Subject: "Numbers attached", MessageID: "<b@x>", References: []string{"<a@x>"}, Date: day(3)},
+		{ID: "3", Subject: "Lunch?", MessageID: "<c@x>", Date: day(2)},
+		{ID: "4", Subject: "Re: Budget", MessageID: "<d@x>", Date: day(4)},
+		{ID: "5", Subject: "Unrelated", MessageID: "<e@x>", Date: day(5)},
+	}
+
+	convs := groupByThread(emails)
+	if len(convs) != 3 {
+		t.Fatalf("got %d conversations, want 3: %+v", len(convs), convs)
+	}
+
+	// Newest first: Unrelated (5th), Budget (4th), Lunch (2nd)
+	if convs[0].Latest.ID != "5" || convs[0].Count != 1 {
+		t.Errorf("conv[0] = %+v, want single Unrelated", convs[0])
+	}
+	budget := convs[1]
+	if budget.Subject != "Budget" || budget.Count != 3 {
+		t.Errorf("budget conversation = %+v, want 3 messages", budget)
+	}
+	if budget.Latest.ID != "4" {
+		t.Errorf("budget latest = %s, want 4", budget.Latest.ID)
+	}
+	if convs[2].Latest.ID != "3" || convs[2].Count != 1 {
+		t.Errorf("conv[2] = %+v, want single Lunch", convs[2])
+	}
+}
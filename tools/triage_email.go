@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rgabriel/mcp-icloud-email/imap"
+	"github.com/rgabriel/mcp-icloud-email/internal/subject"
+)
+
+// triagePreviewLen is the maximum length, in characters, of a triage preview.
+const triagePreviewLen = 160
+
+// TriageEmailHandler creates a handler for a compact, read-only summary of
+// one email, enough to decide what to do with it without get_email
+func TriageEmailHandler(client EmailReader, defaultFolder string) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required email_id
+		emailID, ok := args["email_id"].(string)
+		if !ok || emailID == "" {
+			return invalidArgument("email_id is required"), nil
+		}
+		if err := validateEmailID(emailID); err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+		bySeq, err := bySequenceArg(args)
+		if err != nil {
+			return invalidArgument(err.Error()), nil
+		}
+
+		// Get folder (default to DEFAULT_FOLDER)
+		folder, err := resolveFolderArg(ctx, client, args, "folder", defaultFolder)
+		if err != nil {
+			return argumentResult("failed to resolve folder", err), nil
+		}
+
+		// Peek so triage never marks the message as read
+		var email *imap.Email
+		if bySeq {
+			email, err = client.FetchEmail(ctx, folder, emailID, imap.FetchOptions{BySequence: true, Peek: true})
+		} else {
+			email, err = client.PeekEmail(ctx, folder, emailID)
+		}
+		if err != nil {
+			return operationError("failed to get email", err), nil
+		}
+
+		inThread := len(email.References) > 0 || subject.Normalize(email.Subject) != strings.TrimSpace(email.Subject)
+
+		// Format response
+		response := map[string]interface{}{
+			"id":               email.ID,
+			"folder":           folder,
+			"from":             email.From,
+			"subject":          email.Subject,
+			"date":             email.Date,
+			"unread":           email.Unread,
+			"answered":         email.Answered,
+			"flagged":          email.Flagged,
+			"size":             email.Size,
+			"attachment_count": len(email.Attachments),
+			"preview":          preview(email.BestBody, triagePreviewLen),
+			"in_thread":        inThread,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}
+
+// preview collapses whitespace in text and truncates it to at most n
+// characters, ending in "..." when shortened.
+func preview(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n-3])) + "..."
+}
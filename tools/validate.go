@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -13,24 +14,30 @@ const (
 
 // validateSavePath rejects paths that could escape intended directories.
 func validateSavePath(path string) error {
+	return validatePath("save_path", path)
+}
+
+// validatePath rejects paths that could escape intended directories, naming
+// param in the error.
+func validatePath(param, path string) error {
 	if path == "" {
 		return nil
 	}
 
 	// Reject null bytes
 	if strings.ContainsRune(path, 0) {
-		return fmt.Errorf("save_path must not contain null bytes")
+		return fmt.Errorf("%s must not contain null bytes", param)
 	}
 
 	// Reject raw traversal sequences before cleaning
 	if strings.Contains(path, "..") {
-		return fmt.Errorf("save_path must not contain path traversal (..)")
+		return fmt.Errorf("%s must not contain path traversal (..)", param)
 	}
 
 	// Must be absolute
 	cleaned := filepath.Clean(path)
 	if !filepath.IsAbs(cleaned) {
-		return fmt.Errorf("save_path must be an absolute path")
+		return fmt.Errorf("%s must be an absolute path", param)
 	}
 
 	return nil
@@ -83,11 +90,15 @@ func validateEmailID(id string) error {
 	return nil
 }
 
-// validateBodySize checks that body content doesn't exceed limits.
+// validateBodySize checks that body content is valid UTF-8 and doesn't
+// exceed limits.
 func validateBodySize(body string) error {
 	if len(body) > maxBodySize {
 		return fmt.Errorf("body exceeds maximum size of %d bytes", maxBodySize)
 	}
+	if !utf8.ValidString(body) {
+		return fmt.Errorf("body is not valid UTF-8")
+	}
 	return nil
 }
 
@@ -99,6 +110,20 @@ func validateSubjectSize(subject string) error {
 	return nil
 }
 
+// validateHeaderName checks that name is a valid RFC 5322 header field name:
+// printable ASCII other than space and colon.
+func validateHeaderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("header name must not be empty")
+	}
+	for _, r := range name {
+		if r <= ' ' || r > '~' || r == ':' {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
 // validateFilename rejects filenames with path traversal characters.
 func validateFilename(name string) error {
 	if name == "" {
@@ -101,6 +101,21 @@ func TestValidateEmailID(t *testing.T) {
 	}
 }
 
+func TestValidateHeaderName(t *testing.T) {
+	valid := []string{"Subject", "X-Mailer", "list-id", "DKIM-Signature"}
+	for _, name := range valid {
+		if err := validateHeaderName(name); err != nil {
+			t.Errorf("validateHeaderName(%q) = %v", name, err)
+		}
+	}
+	invalid := []string{"", "List Id", "Subject:", "X-\x00", "Sübject", "X\r\nBcc"}
+	for _, name := range invalid {
+		if err := validateHeaderName(name); err == nil {
+			t.Errorf("validateHeaderName(%q) accepted", name)
+		}
+	}
+}
+
 func TestValidateFilename(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// catchAllNote explains why an accepted probe is not proof of delivery.
+const catchAllNote = "Best-effort check only: many servers accept every recipient (catch-all) and reject later, and some block probes entirely."
+
+// VerifyRecipientHandler creates a handler for probing whether an address can receive mail
+func VerifyRecipientHandler(verifier RecipientVerifier) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := req.GetArguments()
+
+		// Get required address
+		address, ok := args["address"].(string)
+		if !ok || address == "" {
+			return invalidArgument("address is required"), nil
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return invalidArgument(fmt.Sprintf("invalid email address: %s", address)), nil
+		}
+
+		accepted, reply, err := verifier.VerifyRecipient(ctx, address)
+		if err != nil {
+			return operationError("failed to verify recipient", err), nil
+		}
+
+		// Classify the RCPT reply: 2xx accepted, 4xx deferred (e.g. greylisting), 5xx rejected
+		status := "rejected"
+		switch {
+		case accepted:
+			status = "accepted"
+		case len(reply) > 0 && reply[0] == '4':
+			status = "deferred"
+		}
+
+		// Format response
+		response := map[string]interface{}{
+			"address":      address,
+			"accepted":     accepted,
+			"status":       status,
+			"server_reply": reply,
+			"note":         catchAllNote,
+		}
+
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return toolError(CodeInternal, fmt.Sprintf("failed to format response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+}